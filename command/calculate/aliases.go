@@ -0,0 +1,126 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"cto-stats/connectors/config"
+)
+
+// defaultColumnAliases are the column names used for the issues of projects missing from config, per stage,
+// when column_aliases does not list the stage. Those issues get no QA start unless qa is aliased.
+var defaultColumnAliases = map[string][]string{
+	"backlog": {"Backlog"},
+	"ready":   {"Ready", "In Ready", "Ready for Dev"},
+	"dev":     {"In Progress"},
+	"review":  {"In Review"},
+	"qa":      nil,
+	"done":    {"Done"},
+	"archive": {"Archive"},
+}
+
+// columnAliases holds the column_aliases config, keyed by stage, with the columns normalized.
+type columnAliases struct {
+	// configured are the stages of column_aliases only; byColumn maps each of their columns to its stage
+	configured map[string][]string
+	byColumn   map[string]string
+}
+
+func normalizeColumn(c string) string {
+	return strings.ToLower(strings.TrimSpace(c))
+}
+
+// newColumnAliases normalizes the stages and columns of cfg. Unknown stages are kept: ValidateConfig reports them.
+func newColumnAliases(cfg map[string][]string) columnAliases {
+	a := columnAliases{configured: map[string][]string{}, byColumn: map[string]string{}}
+	for stage, cols := range cfg {
+		stage = normalizeColumn(stage)
+		for _, c := range cols {
+			if c = normalizeColumn(c); c != "" {
+				a.configured[stage] = append(a.configured[stage], c)
+				a.byColumn[c] = stage
+			}
+		}
+	}
+	return a
+}
+
+// columns returns the columns of stage: its configured aliases, or else the built-in names.
+func (a columnAliases) columns(stage string) []string {
+	if cols, ok := a.configured[stage]; ok {
+		return cols
+	}
+	return defaultColumnAliases[stage]
+}
+
+// expand returns cols with every alias of the stages they name or belong to, so a project configured with
+// dev_start_columns: [In Progress] also matches WIP when dev is aliased to both. Other columns are kept as is.
+func (a columnAliases) expand(cols []string) []string {
+	if len(cols) == 0 || len(a.configured) == 0 {
+		return cols
+	}
+	seen := map[string]bool{}
+	var res []string
+	add := func(c string) {
+		if !seen[c] {
+			seen[c] = true
+			res = append(res, c)
+		}
+	}
+	for _, c := range cols {
+		c = normalizeColumn(c)
+		stage, ok := a.byColumn[c]
+		if !ok {
+			if _, named := a.configured[c]; named {
+				stage, ok = c, true
+			}
+		}
+		add(c)
+		if ok {
+			for _, alias := range a.configured[stage] {
+				add(alias)
+			}
+		}
+	}
+	return res
+}
+
+// expandProject returns pc with the columns of every stage group expanded with the aliases.
+func (a columnAliases) expandProject(pc config.Project) config.Project {
+	pc.LeadTimeColumns = a.expand(pc.LeadTimeColumns)
+	pc.CycleTimeColumns = a.expand(pc.CycleTimeColumns)
+	pc.DevStartColumns = a.expand(pc.DevStartColumns)
+	pc.ReviewStartColumns = a.expand(pc.ReviewStartColumns)
+	pc.QAStartColumns = a.expand(pc.QAStartColumns)
+	pc.PutInReadyColumns = a.expand(pc.PutInReadyColumns)
+	pc.WaitingToProdStartCols = a.expand(pc.WaitingToProdStartCols)
+	pc.InProdStartColumns = a.expand(pc.InProdStartColumns)
+	pc.CommittedColumns = a.expand(pc.CommittedColumns)
+	pc.TriageColumns = a.expand(pc.TriageColumns)
+	return pc
+}
+
+// validateColumnAliases reports unknown stages and columns listed under two stages.
+func validateColumnAliases(cfg map[string][]string) []error {
+	var errs []error
+	stages := make([]string, 0, len(cfg))
+	for stage := range cfg {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	owner := map[string]string{}
+	for _, stage := range stages {
+		if _, ok := defaultColumnAliases[normalizeColumn(stage)]; !ok {
+			errs = append(errs, fmt.Errorf("column_aliases: unknown stage %q (expected backlog, ready, dev, review, qa, done or archive)", stage))
+		}
+		for _, c := range cfg[stage] {
+			n := normalizeColumn(c)
+			if prev, ok := owner[n]; ok && prev != stage {
+				errs = append(errs, fmt.Errorf("column_aliases: column %q is listed under both %s and %s", c, prev, stage))
+			}
+			owner[n] = stage
+		}
+	}
+	return errs
+}
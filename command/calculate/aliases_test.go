@@ -0,0 +1,68 @@
+package calculate
+
+import (
+	"testing"
+	"time"
+
+	"cto-stats/connectors/config"
+)
+
+func TestColumnAliasesMapToDev(t *testing.T) {
+	aliases := newColumnAliases(map[string][]string{"Dev": {" In Progress ", "WIP"}})
+	at := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		aliases columnAliases
+		column  string
+		wantDev bool
+	}{
+		{"in progress", aliases, "in progress", true},
+		{"In Progress", aliases, "In Progress", true},
+		{"WIP", aliases, "WIP", true},
+		{"wip with spaces", aliases, "  wip ", true},
+		{"other column", aliases, "In Review", false},
+		{"WIP without aliases", newColumnAliases(nil), "WIP", false},
+		{"in progress without aliases", newColumnAliases(nil), "in progress", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := []projectEventRow{{ToColumn: tt.column, At: at, EventType: "moved"}}
+
+			// projects missing from config: the legacy stages
+			legacy := firstMoveToAny(events, tt.aliases.columns("dev")) != nil
+			if legacy != tt.wantDev {
+				t.Errorf("legacy dev start: %v, want %v", legacy, tt.wantDev)
+			}
+			moves := stageMovesOf(events, legacyStageColumns(tt.aliases))
+			if got := moves[0].stage == "in_dev"; got != tt.wantDev {
+				t.Errorf("legacy stage %q, want in_dev %v", moves[0].stage, tt.wantDev)
+			}
+
+			// configured projects: dev_start_columns lists one alias only
+			pc := tt.aliases.expandProject(config.Project{DevStartColumns: []string{"In Progress"}})
+			configured := firstMoveToAny(events, pc.DevStartColumns) != nil
+			if configured != tt.wantDev {
+				t.Errorf("configured dev start with %v: %v, want %v", pc.DevStartColumns, configured, tt.wantDev)
+			}
+		})
+	}
+}
+
+func TestValidateColumnAliases(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      map[string][]string
+		wantErrs int
+	}{
+		{"valid", map[string][]string{"dev": {"WIP"}, "Review": {"In Review"}}, 0},
+		{"unknown stage", map[string][]string{"coding": {"WIP"}}, 1},
+		{"column under two stages", map[string][]string{"dev": {"WIP"}, "review": {" wip"}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := validateColumnAliases(tt.cfg); len(errs) != tt.wantErrs {
+				t.Errorf("got %v, want %d errors", errs, tt.wantErrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package calculate
+
+import (
+	"log/slog"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// clockSkewTolerance is how far a timestamp may precede the one it should follow before it is a clock anomaly,
+// so that events recorded in the same instant as the issue creation are not flagged.
+const clockSkewTolerance = time.Minute
+
+// clockAnomaly is one row of anomalies.csv: stage of the issue starts at At, before reference at ReferenceAt.
+type clockAnomaly struct {
+	IssueID     string
+	ProjectID   string
+	Rule        string // stage_before_creation or end_before_start
+	Stage       string
+	At          time.Time
+	Reference   string
+	ReferenceAt time.Time
+}
+
+// clockAnomaliesOf returns the timestamps of r that cannot be right: a stage (end included) before the creation
+// of the issue, or an end before the lead or cycle time start, which would make a duration negative.
+func clockAnomaliesOf(r calculatedIssue) []clockAnomaly {
+	stages := []struct {
+		name string
+		at   *time.Time
+	}{
+		{"lead_time_start", r.LeadTimeStartDatetime},
+		{"cycle_time_start", r.CycleTimeStartDatetime},
+		{"put_in_ready_start", r.PutInReadyStartDatetime},
+		{"dev_start", r.DevStartDatetime},
+		{"review_start", r.ReviewStartDatetime},
+		{"qa_start", r.QAStartDatetime},
+		{"waiting_to_prod_start", r.WaitingToPodStartDatetime},
+		{"end", r.EndDatetime},
+	}
+	var res []clockAnomaly
+	add := func(rule, stage string, at time.Time, reference string, referenceAt time.Time) {
+		res = append(res, clockAnomaly{IssueID: r.ID, ProjectID: r.ProjectID, Rule: rule, Stage: stage, At: at, Reference: reference, ReferenceAt: referenceAt})
+	}
+	for _, s := range stages {
+		if s.at != nil && s.at.Add(clockSkewTolerance).Before(r.CreationDatetime) {
+			add("stage_before_creation", s.name, *s.at, "created", r.CreationDatetime)
+		}
+	}
+	if r.EndDatetime != nil {
+		for _, s := range stages[:2] {
+			if s.at != nil && r.EndDatetime.Add(clockSkewTolerance).Before(*s.at) {
+				add("end_before_start", "end", *r.EndDatetime, s.name, *s.at)
+			}
+		}
+	}
+	return res
+}
+
+// markClockAnomalies flags the rows with a clock anomaly, which the durations then leave out (see
+// calculatedIssue.leadDays), writes the anomalies to path and logs how many issues are excluded.
+func markClockAnomalies(path string, rows []calculatedIssue, bom bool) error {
+	var anomalies []clockAnomaly
+	issues := 0
+	for i := range rows {
+		found := clockAnomaliesOf(rows[i])
+		if len(found) == 0 {
+			continue
+		}
+		rows[i].ClockAnomaly = true
+		anomalies = append(anomalies, found...)
+		issues++
+	}
+	out := make([][]string, 0, len(anomalies))
+	for _, a := range anomalies {
+		out = append(out, []string{
+			a.IssueID,
+			a.ProjectID,
+			a.Rule,
+			a.Stage,
+			a.At.UTC().Format(time.RFC3339),
+			a.Reference,
+			a.ReferenceAt.UTC().Format(time.RFC3339),
+		})
+	}
+	if err := writeCSVFile(path, schema.Headers("anomalies.csv"), out, bom); err != nil {
+		return err
+	}
+	if issues > 0 {
+		slog.Warn("calculate.anomalies", "issues", issues, "anomalies", len(anomalies), "output", path,
+			"hint", "these issues are left out of the lead, cycle and time-to-PR durations")
+	}
+	return nil
+}
+
+// daysBetween returns the days from start to end, false when either is missing or end precedes start.
+func daysBetween(start, end *time.Time) (float64, bool) {
+	if start == nil || end == nil || end.Before(*start) {
+		return 0, false
+	}
+	return end.Sub(*start).Hours() / 24, true
+}
+
+// leadDays returns the lead time of r in days, false when it has none: not closed, no lead time start, a
+// negative duration or a clock anomaly.
+func (r calculatedIssue) leadDays() (float64, bool) {
+	if r.ClockAnomaly {
+		return 0, false
+	}
+	return daysBetween(r.LeadTimeStartDatetime, r.EndDatetime)
+}
+
+// cycleDays returns the cycle time of r in days, with the same exclusions as leadDays.
+func (r calculatedIssue) cycleDays() (float64, bool) {
+	if r.ClockAnomaly {
+		return 0, false
+	}
+	return daysBetween(r.CycleTimeStartDatetime, r.EndDatetime)
+}
+
+// timeToPRDays returns the days from development start to review start of r, false when either is missing,
+// review started first (e.g. the issue skipped the development column) or r has a clock anomaly.
+func (r calculatedIssue) timeToPRDays() (float64, bool) {
+	if r.ClockAnomaly {
+		return 0, false
+	}
+	return daysBetween(r.DevStartDatetime, r.ReviewStartDatetime)
+}
@@ -0,0 +1,96 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// defaultBacklogAgeBuckets are the upper bounds (days, inclusive) used when backlog.age_buckets is not set.
+var defaultBacklogAgeBuckets = []int{7, 30, 90, 180}
+
+// writeBacklogAgeHistogram writes backlog_age_histogram.csv from open issues: one row per (project, bucket)
+// counting issues by age since creation, plus a "summary" row per project with the total and the p50/p90 age.
+// An ALL project aggregates every open issue. bounds are inclusive upper bounds in days; the last bucket is open-ended.
+func writeBacklogAgeHistogram(path string, rows []calculatedIssue, bounds []int, now time.Time, bom bool) error {
+	if len(bounds) == 0 {
+		bounds = defaultBacklogAgeBuckets
+	}
+	bounds = append([]int(nil), bounds...)
+	sort.Ints(bounds)
+	type bucket struct {
+		label    string
+		min, max int // max < 0 means open-ended
+	}
+	var buckets []bucket
+	lo := 0
+	for _, b := range bounds {
+		if b < lo {
+			continue
+		}
+		buckets = append(buckets, bucket{label: fmt.Sprintf("%d-%dd", lo, b), min: lo, max: b})
+		lo = b + 1
+	}
+	buckets = append(buckets, bucket{label: fmt.Sprintf("%d+d", lo), min: lo, max: -1})
+
+	type proj struct{ id, name string }
+	ages := map[proj][]float64{}
+	for _, r := range rows {
+		if r.EndDatetime != nil {
+			continue
+		}
+		age := now.Sub(r.CreationDatetime.UTC()).Hours() / 24.0
+		if age < 0 {
+			age = 0
+		}
+		p := proj{id: r.ProjectID, name: r.ProjectName}
+		ages[p] = append(ages[p], age)
+		all := proj{id: "ALL", name: "ALL"}
+		ages[all] = append(ages[all], age)
+	}
+	projects := make([]proj, 0, len(ages))
+	for p := range ages {
+		if p.id != "ALL" {
+			projects = append(projects, p)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].id != projects[j].id {
+			return projects[i].id < projects[j].id
+		}
+		return projects[i].name < projects[j].name
+	})
+	if len(ages) > 0 {
+		projects = append(projects, proj{id: "ALL", name: "ALL"})
+	}
+
+	var out [][]string
+	for _, p := range projects {
+		vals := ages[p]
+		counts := make([]int, len(buckets))
+		for _, a := range vals {
+			days := int(a) // whole days elapsed
+			for i, b := range buckets {
+				if days >= b.min && (b.max < 0 || days <= b.max) {
+					counts[i]++
+					break
+				}
+			}
+		}
+		for i, b := range buckets {
+			max := ""
+			if b.max >= 0 {
+				max = fmt.Sprintf("%d", b.max)
+			}
+			out = append(out, []string{p.id, p.name, b.label, fmt.Sprintf("%d", b.min), max, fmt.Sprintf("%d", counts[i]), "", ""})
+		}
+		out = append(out, []string{
+			p.id, p.name, "summary", "", "", fmt.Sprintf("%d", len(vals)),
+			fmt.Sprintf("%.6f", percentile(vals, 0.5)),
+			fmt.Sprintf("%.6f", percentile(vals, 0.9)),
+		})
+	}
+	return writeCSVFile(path, schema.Headers("backlog_age_histogram.csv"), out, bom)
+}
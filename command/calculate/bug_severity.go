@@ -0,0 +1,131 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// unclassifiedSeverity is the severity of the open bugs without any of github.severity_labels.
+const unclassifiedSeverity = "unclassified"
+
+// severityChange is a change of the severity of an issue at a point in time; severity is empty once the issue
+// lost its last severity label.
+type severityChange struct {
+	at       time.Time
+	severity string
+}
+
+// parseSeverityChanges parses the severity_changes column of issue.csv ("at/severity;at/"), skipping malformed
+// changes.
+func parseSeverityChanges(s string) []severityChange {
+	var res []severityChange
+	for _, part := range strings.Split(s, ";") {
+		atStr, sev, ok := strings.Cut(strings.TrimSpace(part), "/")
+		if !ok {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			continue
+		}
+		res = append(res, severityChange{at: at, severity: sev})
+	}
+	return res
+}
+
+// severityAt returns the severity of r at t: the last of its severity changes up to t or, without any change
+// (issue.csv older than the severity history, or no label event seen), its severity at import.
+func (r calculatedIssue) severityAt(t time.Time) string {
+	if len(r.SeverityChanges) == 0 {
+		return r.Severity
+	}
+	sev := ""
+	for _, c := range r.SeverityChanges {
+		if c.at.After(t) {
+			break
+		}
+		sev = c.severity
+	}
+	return sev
+}
+
+// writeWeeklyBugStockBySeverity writes bug_stock_week.csv: the open bugs at the end of each ISO week (Sunday
+// 23:59:59 in loc) per org, split by the severities of github.severity_labels plus unclassifiedSeverity. A bug is
+// open the way opened_bugs of stocks_week.csv counts it. Weeks run from the first bug to now, clamped to the
+// -since/-until window of filter. Bugs with a severity but no severity change in their history count under it
+// for every week, with a severity_without_history warning.
+func writeWeeklyBugStockBySeverity(path string, rows []calculatedIssue, loc *time.Location, filter issueFilter, severities []string, now time.Time, q *dataQuality, bom bool) error {
+	classes := append(append([]string(nil), severities...), unclassifiedSeverity)
+	classOf := func(sev string) int {
+		for i, s := range severities {
+			if strings.EqualFold(strings.TrimSpace(s), strings.TrimSpace(sev)) {
+				return i
+			}
+		}
+		return len(severities)
+	}
+	var bugs []calculatedIssue
+	var first *time.Time
+	for _, r := range rows {
+		if len(r.BugPeriods) == 0 {
+			continue
+		}
+		bugs = append(bugs, r)
+		for _, p := range r.BugPeriods {
+			if since := p.since; first == nil || since.Before(*first) {
+				first = &since
+			}
+		}
+		if len(severities) > 0 && r.Severity != "" && len(r.SeverityChanges) == 0 {
+			q.add(severityWarning, "severity_without_history", r.ID, fmt.Sprintf("no severity label event in the issue history: its current severity %s counts for every week", r.Severity))
+		}
+	}
+	if first == nil {
+		return writeCSVFile(path, schema.Headers("bug_stock_week.csv"), nil, bom)
+	}
+	from, to := first.In(loc), now.In(loc)
+	if filter.Since != nil && filter.Since.After(from) {
+		from = filter.Since.In(loc)
+	}
+	if filter.Until != nil {
+		to = filter.Until.In(loc)
+	}
+	monday := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	}
+	orgSet := map[string]bool{}
+	for _, r := range bugs {
+		orgSet[r.Org] = true
+	}
+	orgs := make([]string, 0, len(orgSet))
+	for org := range orgSet {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+
+	var out [][]string
+	for cur, end := monday(from), monday(to); !cur.After(end); cur = cur.AddDate(0, 0, 7) {
+		cutoff := time.Date(cur.Year(), cur.Month(), cur.Day()+6, 23, 59, 59, int(time.Second-time.Nanosecond), loc)
+		counts := map[string][]int{}
+		for _, org := range orgs {
+			counts[org] = make([]int, len(classes))
+		}
+		for _, r := range bugs {
+			if r.CreationDatetime.After(cutoff) || (r.EndDatetime != nil && r.EndDatetime.Before(cur)) || !bugAt(r.BugPeriods, cutoff) {
+				continue
+			}
+			counts[r.Org][classOf(r.severityAt(cutoff))]++
+		}
+		y, w := cur.ISOWeek()
+		for _, org := range orgs {
+			for i, class := range classes {
+				out = append(out, []string{fmt.Sprintf("%d", y), fmt.Sprintf("%d", w), org, class, fmt.Sprintf("%d", counts[org][i])})
+			}
+		}
+	}
+	return writeCSVFile(path, schema.Headers("bug_stock_week.csv"), out, bom)
+}
@@ -0,0 +1,78 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// writeBurnupWeekly writes burnup_week.csv: per project and ISO week (in loc), the issues created and closed by the
+// end of the week (Sunday 23:59:59) over the full issue set, and those created in the week. Weeks run from the
+// first creation of each project to now, the rows clamped to the -since/-until window of filter; the counts
+// before -since still add up.
+func writeBurnupWeekly(path string, rows []calculatedIssue, loc *time.Location, filter issueFilter, now time.Time, bom bool) error {
+	type project struct{ id, name string }
+	byProject := map[project][]calculatedIssue{}
+	for _, r := range rows {
+		p := project{r.ProjectID, r.ProjectName}
+		byProject[p] = append(byProject[p], r)
+	}
+	projects := make([]project, 0, len(byProject))
+	for p := range byProject {
+		projects = append(projects, p)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].id != projects[j].id {
+			return projects[i].id < projects[j].id
+		}
+		return projects[i].name < projects[j].name
+	})
+	last := now
+	if filter.Until != nil {
+		last = *filter.Until
+	}
+
+	var out [][]string
+	for _, p := range projects {
+		issues := byProject[p]
+		first := issues[0].CreationDatetime
+		for _, r := range issues {
+			if r.CreationDatetime.Before(first) {
+				first = r.CreationDatetime
+			}
+		}
+		start := isoWeekOf(first, loc)
+		for cur := isoWeekMonday(start.Year, start.Week, loc); !cur.After(last); cur = cur.AddDate(0, 0, 7) {
+			cutoff := time.Date(cur.Year(), cur.Month(), cur.Day()+6, 23, 59, 59, int(time.Second-time.Nanosecond), loc)
+			if filter.Since != nil && cutoff.Before(*filter.Since) {
+				continue
+			}
+			var created, closed, inWeek int
+			for _, r := range issues {
+				if r.CreationDatetime.After(cutoff) {
+					continue
+				}
+				created++
+				if !r.CreationDatetime.Before(cur) {
+					inWeek++
+				}
+				if r.EndDatetime != nil && !r.EndDatetime.After(cutoff) {
+					closed++
+				}
+			}
+			y, w := cur.ISOWeek()
+			out = append(out, []string{
+				fmt.Sprintf("%d", y),
+				fmt.Sprintf("%02d", w),
+				p.id,
+				p.name,
+				fmt.Sprintf("%d", created),
+				fmt.Sprintf("%d", closed),
+				fmt.Sprintf("%d", inWeek),
+			})
+		}
+	}
+	return writeCSVFile(path, schema.Headers("burnup_week.csv"), out, bom)
+}
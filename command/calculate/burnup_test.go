@@ -0,0 +1,134 @@
+package calculate
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// burnupIssue returns an issue of project created at created and closed at closed, open when closed is zero.
+func burnupIssue(project string, created, closed time.Time) calculatedIssue {
+	r := calculatedIssue{ID: fmt.Sprintf("acme/api#%d", created.Unix()), ProjectID: project, ProjectName: "P" + project, CreationDatetime: created}
+	if !closed.IsZero() {
+		r.EndDatetime = &closed
+	}
+	return r
+}
+
+// march returns day of March 2025 at hour:minute UTC.
+func march(day, hour, minute int) time.Time {
+	return time.Date(2025, 3, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestWriteBurnupWeekly(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := march(17, 0, 0)
+	// a backlog created over four weeks, closed in bursts and left partly open
+	var churn []calculatedIssue
+	for i := range 24 {
+		created := march(1+i, 9, 0)
+		var closed time.Time
+		if i%3 != 2 {
+			closed = created.AddDate(0, 0, 2+(i*5)%9)
+		}
+		churn = append(churn, burnupIssue([]string{"101", "102"}[i%2], created, closed))
+	}
+	tests := []struct {
+		name   string
+		issues []calculatedIssue
+		loc    *time.Location
+		filter issueFilter
+		now    time.Time
+		want   []string // year,week,project_id,cumulative_created,cumulative_closed,scope_change, nil to check monotonicity only
+	}{
+		{
+			name: "one project",
+			issues: []calculatedIssue{
+				burnupIssue("101", march(3, 9, 0), march(6, 9, 0)),
+				burnupIssue("101", march(5, 9, 0), march(20, 9, 0)),
+				burnupIssue("101", march(12, 9, 0), time.Time{}),
+			},
+			now:  march(26, 12, 0),
+			want: []string{"2025,10,101,2,1,2", "2025,11,101,3,1,1", "2025,12,101,3,2,0", "2025,13,101,3,2,0"},
+		},
+		{
+			name: "counts before -since still add up",
+			issues: []calculatedIssue{
+				burnupIssue("101", march(3, 9, 0), march(6, 9, 0)),
+				burnupIssue("101", march(5, 9, 0), march(20, 9, 0)),
+				burnupIssue("101", march(12, 9, 0), time.Time{}),
+			},
+			filter: issueFilter{Since: &since},
+			now:    march(26, 12, 0),
+			want:   []string{"2025,12,101,3,2,0", "2025,13,101,3,2,0"},
+		},
+		{
+			name: "weeks end on Sunday in the location",
+			issues: []calculatedIssue{
+				// Sunday 9 March 23:30 UTC is Monday 10 March in Paris
+				burnupIssue("101", march(3, 9, 0), march(9, 23, 30)),
+				burnupIssue("101", march(9, 23, 30), time.Time{}),
+			},
+			loc:  paris,
+			now:  march(12, 12, 0),
+			want: []string{"2025,10,101,1,0,1", "2025,11,101,2,1,1"},
+		},
+		{
+			name:   "projects with issues closed out of order",
+			issues: churn,
+			now:    march(31, 12, 0),
+		},
+		{
+			name:   "projects with issues closed out of order in Paris",
+			issues: churn,
+			loc:    paris,
+			filter: issueFilter{Since: &since},
+			now:    march(31, 12, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := time.UTC
+			if tt.loc != nil {
+				loc = tt.loc
+			}
+			path := filepath.Join(t.TempDir(), "burnup_week.csv")
+			if err := writeBurnupWeekly(path, tt.issues, loc, tt.filter, tt.now, false); err != nil {
+				t.Fatal(err)
+			}
+			idx, rows, err := readCSVFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			prev := map[string][2]int{} // cumulative created and closed of the previous week, by project
+			for _, r := range rows {
+				project := field(idx, r, "project_id")
+				created, _ := strconv.Atoi(field(idx, r, "cumulative_created"))
+				closed, _ := strconv.Atoi(field(idx, r, "cumulative_closed"))
+				got = append(got, strings.Join([]string{field(idx, r, "year"), field(idx, r, "week"), project,
+					field(idx, r, "cumulative_created"), field(idx, r, "cumulative_closed"), field(idx, r, "scope_change")}, ","))
+				if closed > created {
+					t.Errorf("%s: %d closed of %d created", got[len(got)-1], closed, created)
+				}
+				if p, ok := prev[project]; ok && (created < p[0] || closed < p[1]) {
+					t.Errorf("%s: cumulative counts went down from %d created, %d closed", got[len(got)-1], p[0], p[1])
+				}
+				prev[project] = [2]int{created, closed}
+			}
+			if len(rows) == 0 {
+				t.Fatal("no rows")
+			}
+			if tt.want != nil && !slices.Equal(got, tt.want) {
+				t.Errorf("rows %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
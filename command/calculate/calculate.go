@@ -10,10 +10,15 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"cto-stats/command/cli"
 	"cto-stats/connectors/config"
+	ccsv "cto-stats/connectors/csv"
+	"cto-stats/domain/buildinfo"
+	"cto-stats/domain/schema"
 
 	lo "github.com/samber/lo"
 )
@@ -25,9 +30,35 @@ type issueRow struct {
 	Repo      string
 	Number    string
 	Title     string
+	URL       string // GitHub page of the issue, empty for older issue.csv files without it
 	Type      string
 	IsBug     bool
 	CreatedAt time.Time
+	ClosedAt  *time.Time
+	// Milestone and its due date, empty/nil when the issue is not in a milestone
+	Milestone      string
+	MilestoneDueOn *time.Time
+	// Description flag from import; HasDescriptionData is false for older issue.csv files without it
+	HasDescription     bool
+	HasDescriptionData bool
+	// SizeWeight from github.size_weights, 1 for issues without a size label or older issue.csv files
+	SizeWeight float64
+	// BugPeriods are the periods the issue was a bug; from creation for bugs of older issue.csv files
+	BugPeriods []bugPeriod
+	Assignees  []string
+	// Severity is the severity label of the issue at import (github.severity_labels), SeverityChanges its history
+	// from the label events, empty when the import saw none
+	Severity        string
+	SeverityChanges []severityChange
+	// Epic is the epic label of the issue (github.epic_label_prefix), OtherEpics the further ones it carried
+	Epic       string
+	OtherEpics []string
+}
+
+// bugPeriod is a time range during which an issue was a bug; until is nil while it still is.
+type bugPeriod struct {
+	since time.Time
+	until *time.Time
 }
 
 type statusEventRow struct {
@@ -53,7 +84,10 @@ type projectEventRow struct {
 
 type calculatedIssue struct {
 	ID                        string
+	Org                       string // from the id key, so datasets of several orgs can share a data directory
 	Name                      string
+	URL                       string
+	Repo                      string
 	ProjectID                 string
 	ProjectName               string
 	CreationDatetime          time.Time
@@ -64,6 +98,7 @@ type calculatedIssue struct {
 	ReviewStartDatetime       *time.Time
 	QAStartDatetime           *time.Time
 	WaitingToPodStartDatetime *time.Time
+	CommittedDatetime         *time.Time // first move to a committed_columns column of its project
 	EndDatetime               *time.Time
 	Bug                       bool
 	BugCustomerFacing         bool
@@ -71,6 +106,16 @@ type calculatedIssue struct {
 	BugDevProcess             bool
 	Type                      string
 	CurrentColumn             string
+	SizeWeight                float64
+	BugPeriods                []bugPeriod
+	Severity                  string
+	SeverityChanges           []severityChange
+	Epic                      string
+	ClosedPeriods             []closedPeriod // close-to-reopen periods, nil unless reopened after a close
+	StageMoves                []stageMove    // stages of its column moves, for the weeks it is back in stock
+	ClockAnomaly              bool           // a timestamp precedes the creation or the end precedes a start, see anomalies.csv
+	FirstPRCreatedDatetime    *time.Time     // creation of the earliest linked pull request (pr_issue_link.csv)
+	Estimate                  *float64       // points of the estimate_field of its project, nil when unestimated
 }
 
 type projectCustomFieldRow struct {
@@ -83,19 +128,102 @@ type projectCustomFieldRow struct {
 	FieldValue  string
 }
 
-// Run executes the calculate command
+// Help describes the calculate subcommand for its -h output, the top-level usage and the completion scripts.
+var Help = cli.Command{
+	Name:     "calculate",
+	Summary:  "compute the KPI files from the imported data",
+	Synopsis: "[-issues] [-pr] [-cloudspending] [-project <id|name>] [-since <date>] [-until <date>] [-data <dir,...> -out <dir>] [flags]",
+	Description: `Reads the files written by import in data/ (or data/<repo>/) and writes the KPI files next to them. Without a
+scope flag, every scope runs. -project, -since and -until write the issue outputs to data/filtered/ instead.
+-data merges the imports of several directories, e.g. one per org, into the -out directory.`,
+	Examples: []string{
+		"cto-stats calculate",
+		"cto-stats calculate -pr -cr-count-mode rounds",
+		"cto-stats calculate -issues -project Platform -since 2025-01-01",
+		"cto-stats calculate -sheets 1AbC...xyz",
+		"cto-stats calculate -data data-product,data-platform -out data-company",
+	},
+	Env: []string{
+		"CONFIG_PATH  YAML config file with the project column mappings (default ./config.yml)",
+		"GCP_SERVICE_ACCOUNT_JSON  service account for -sheets, as JSON or a file path (default: application default credentials)",
+		"NOTIFY_WEBHOOK_URL  webhook posted the outcome of the run (overrides notifications.webhook_url)",
+	},
+}
+
+// Run executes the calculate command, then posts its outcome when notifications are configured
 func Run(args []string) error {
+	var s runSummary
+	err := run(args, &s)
+	notifyRun(s, err)
+	return err
+}
+
+func run(args []string, summary *runSummary) error {
 	fs := flag.NewFlagSet("calculate", flag.ContinueOnError)
+	cli.SetUsage(fs, Help)
 	issuesScope := fs.Bool("issues", false, "Process issues scope: calculate issue-based KPIs (cycle time, throughput, stocks)")
 	prScope := fs.Bool("pr", false, "Process pull-requests scope: change-requests KPIs only")
 	cloudSpendingScope := fs.Bool("cloudspending", false, "Process cloud spending scope: aggregate cost data")
-	if err := fs.Parse(args); err != nil {
+	bom := fs.Bool("bom", false, "Prepend a UTF-8 BOM to written CSV files (helps Excel display non-ASCII text)")
+	crCountMode := fs.String("cr-count-mode", "", "How CHANGES_REQUESTED reviews are counted per PR: total|distinct_reviewers|rounds (overrides pr.cr_count_mode, default total)")
+	projectFilter := fs.String("project", "", "Issues scope: restrict outputs to one project (ID or name); outputs go to data/filtered/")
+	sinceFilter := fs.String("since", "", "Issues scope: only count issues closed from this date (YYYY-MM-DD or RFC3339); outputs go to data/filtered/")
+	untilFilter := fs.String("until", "", "Issues scope: only count issues closed up to this date, inclusive (YYYY-MM-DD or RFC3339); outputs go to data/filtered/")
+	sparse := fs.Bool("sparse", false, "Issues scope: in stocks_week.csv, only write (week, project) rows with something to count instead of zero-filling every project for every week")
+	fromSnapshots := fs.Bool("from-snapshots", false, "Issues and PR scopes: rebuild the inputs from the raw GitHub pages in data/snapshots/ (import -snapshot) instead of the imported CSVs")
+	strict := fs.Bool("strict", false, "Issues scope: exit with status 3 when data_quality.csv holds errors (e.g. unparseable timestamps), after writing the outputs")
+	var dataDirs cli.StringList
+	fs.Var(&dataDirs, "data", "Issues and PR scopes: directories to read the imported files from (repeatable or comma-separated, default data); several are merged, e.g. one per org, and need -out")
+	outFlag := fs.String("out", "", "Issues and PR scopes: directory to write the outputs to (default: the -data directory)")
+	durationUnit := fs.String("duration-unit", "", "Issues scope: unit of the lead, cycle and time-to-PR durations: days|hours (overrides durations.unit, default days)")
+	durationPrecision := fs.Int("duration-precision", defaultDurationPrecision, "Issues scope: decimals of the lead, cycle and time-to-PR durations, 0-6 (overrides durations.precision)")
+	sheetsID := fs.String("sheets", "", "Also write the monthly, weekly and quarterly outputs into one tab each of this Google spreadsheet ID, replacing their contents (needs GCP credentials)")
+	nowFlag := fs.String("now", "", "Issues scope: reference time of the outputs (RFC3339) instead of the current time: current week, open issue ages, history dates; for reproducible runs")
+	if err := cli.Parse(fs, args); err != nil {
 		return err
 	}
+	now := time.Now()
+	if *nowFlag != "" {
+		t, err := time.Parse(time.RFC3339, *nowFlag)
+		if err != nil {
+			return fmt.Errorf("calculate: -now must be an RFC3339 time, got %q", *nowFlag)
+		}
+		now = t
+	}
+	if *sheetsID != "" && (*projectFilter != "" || *sinceFilter != "" || *untilFilter != "") {
+		return fmt.Errorf("calculate: -sheets cannot be combined with -project, -since or -until")
+	}
+
+	if len(dataDirs) == 0 {
+		dataDirs = cli.StringList{"data"}
+	}
+	if len(dataDirs) > 1 && *outFlag == "" {
+		return fmt.Errorf("calculate: several -data directories need -out, so that none of them is written to")
+	}
+	if len(dataDirs) > 1 && *fromSnapshots {
+		return fmt.Errorf("calculate: -from-snapshots reads a single -data directory")
+	}
+	for _, d := range dataDirs {
+		if len(dataDirs) > 1 && filepath.Clean(d) == filepath.Clean(*outFlag) {
+			return fmt.Errorf("calculate: -out %s must differ from the -data directories", *outFlag)
+		}
+	}
+
+	slog.Info("calculate.start", "version", buildinfo.Get().String())
 
 	// Cloud spending scope is independent
 	if *cloudSpendingScope {
-		return runCloudSpendingCalculate()
+		if *outFlag != "" || len(dataDirs) > 1 || dataDirs[0] != "data" {
+			return fmt.Errorf("calculate: -data and -out apply to the issues and PR scopes only")
+		}
+		if err := runCloudSpendingCalculate(*bom); err != nil {
+			return err
+		}
+		appendCalculateMeta("data", []string{"cloudspending"}, "", *bom)
+		if *sheetsID != "" {
+			return exportSheets(*sheetsID, "data")
+		}
+		return nil
 	}
 
 	// Backward compatibility: if no scope specified, process both
@@ -110,16 +238,61 @@ func Run(args []string) error {
 		cfgPath = "./config.yml"
 	}
 
-	// Read inputs from data/
-	base := "data"
+	// Read inputs from data/, or from data/<repo>/ when imported with -split-by-repo, or rebuild them from the
+	// raw pages of data/snapshots/ with -from-snapshots, or merge several -data directories. Outputs go to
+	// -out, by default next to the inputs.
+	base := dataDirs[0]
+	if *outFlag != "" {
+		base = *outFlag
+	}
+	quality := &dataQuality{}
+	var (
+		in            string
+		cleanupInputs func()
+		err           error
+	)
+	switch {
+	case *fromSnapshots:
+		c, loadErr := config.Load(cfgPath)
+		if loadErr != nil || c.GitHub.Org == "" {
+			return fmt.Errorf("calculate: -from-snapshots needs github.org in the config file")
+		}
+		in, cleanupInputs, err = snapshotInputDir(dataDirs[0], c)
+	case len(dataDirs) > 1:
+		in, cleanupInputs, err = mergeInputDirs(dataDirs, quality)
+	default:
+		in, cleanupInputs, err = resolveInputDir(dataDirs[0])
+	}
+	if err != nil {
+		return fmt.Errorf("calculate: %w", err)
+	}
+	defer cleanupInputs()
+	var imported string
+	for _, d := range dataDirs {
+		v := importVersion(d)
+		checkImportVersion(v)
+		if imported == "" {
+			imported = v
+		}
+	}
+	var scopes []string
+	if *issuesScope {
+		scopes = append(scopes, "issues")
+	}
+	if *prScope {
+		scopes = append(scopes, "pr")
+	}
 
 	var projCfgByID map[string]config.Project
 	projCfgByID = map[string]config.Project{}
 	var (
+		cfg          *config.Config
+		aliases      columnAliases
 		issues       map[string]issueRow
 		statusByID   map[string][]statusEventRow
 		projByID     map[string][]projectEventRow
 		customByID   map[string][]projectCustomFieldRow
+		currentByID  map[string]map[string]string
 		bugSourceCfg config.BugSource
 	)
 	if *issuesScope {
@@ -127,29 +300,31 @@ func Run(args []string) error {
 		if _, err := os.Stat(cfgPath); err != nil {
 			return fmt.Errorf("calculate: config file required for --issues (set CONFIG_PATH or provide ./config.yml): %w", err)
 		}
-		cfg, err := config.Load(cfgPath)
+		var err error
+		cfg, err = config.Load(cfgPath)
 		if err != nil {
 			return fmt.Errorf("calculate: failed to load config: %w", err)
 		}
 		bugSourceCfg = cfg.GitHub.BugSource
-		// Build a project lookup by ID for quick access
+		aliases = newColumnAliases(cfg.ColumnAliases)
+		// Build a project lookup by ID for quick access, with the stage columns expanded with their aliases
 		for _, p := range cfg.GitHub.Projects {
-			projCfgByID[p.ID] = p
+			projCfgByID[p.ID] = aliases.expandProject(p)
 		}
 
-		issues, err = readIssues(filepath.Join(base, "issue.csv"))
+		issues, err = readIssues(filepath.Join(in, "issue.csv"), quality)
 		if err != nil {
 			return err
 		}
-		statusByID, err = readStatus(filepath.Join(base, "issue_status_event.csv"))
+		statusByID, err = readStatus(filepath.Join(in, "issue_status_event.csv"), quality)
 		if err != nil {
 			return err
 		}
-		projByID, err = readProject(filepath.Join(base, "issue_project_event.csv"))
+		projByID, err = readProject(filepath.Join(in, "issue_project_event.csv"), quality)
 		if err != nil {
 			return err
 		}
-		customByID, err = readProjectCustomFields(filepath.Join(base, "issue_project_custom_field.csv"))
+		customByID, err = readProjectCustomFields(filepath.Join(in, "issue_project_custom_field.csv"))
 		if err != nil {
 			// for backward compatibility, if the file is missing we just ignore it
 			if !errors.Is(err, os.ErrNotExist) {
@@ -157,6 +332,65 @@ func Run(args []string) error {
 			}
 			customByID = map[string][]projectCustomFieldRow{}
 		}
+		currentByID, err = readCurrentColumns(filepath.Join(in, "issue_current_project.csv"))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Outside the issues scope the config is optional (github.bots exclusion list, dora settings).
+	if cfg == nil {
+		if _, err := os.Stat(cfgPath); err == nil {
+			if c, err := config.Load(cfgPath); err == nil {
+				cfg = c
+			}
+		}
+	}
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	loc, err := loadLocation(cfg.Timezone)
+	if err != nil {
+		return fmt.Errorf("calculate: %w", err)
+	}
+	gate := newSampleGate(cfg)
+	filter, err := newIssueFilter(*projectFilter, *sinceFilter, *untilFilter, loc)
+	if err != nil {
+		return fmt.Errorf("calculate: %w", err)
+	}
+	for _, w := range ConfigWarnings(cfg) {
+		slog.Warn("calculate.config.warning", "warning", w)
+	}
+	// Unit and decimals of the lead, cycle and time-to-PR durations; the flags win over the config
+	unitSetting, precisionSetting := cfg.Durations.Unit, cfg.Durations.Precision
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "duration-unit":
+			unitSetting = *durationUnit
+		case "duration-precision":
+			precisionSetting = durationPrecision
+		}
+	})
+	durations, err := newDurationFormat(unitSetting, precisionSetting)
+	if err != nil {
+		return fmt.Errorf("calculate: %w", err)
+	}
+	timeToPRSource, err := normalizeTimeToPRSource(cfg.TimeToPR.Source)
+	if err != nil {
+		return fmt.Errorf("calculate: %w", err)
+	}
+	// Target lines of the charts; a -project run picks that project's targets
+	targets := resolveTargets(cfg.Targets, filter.Project)
+
+	// Filtered runs write issue outputs next to, not over, the full outputs
+	outDir := base
+	if filter.active() {
+		outDir = filepath.Join(base, "filtered")
+		slog.Info("calculate.filter", "project", filter.Project, "since", *sinceFilter, "until", *untilFilter, "output", outDir)
+	}
+	summary.cfg, summary.loc = cfg, loc
+	if *issuesScope && !filter.active() {
+		summary.outDir = outDir
 	}
 
 	// Build output
@@ -169,10 +403,21 @@ func Run(args []string) error {
 
 			row := calculatedIssue{
 				ID:               id,
+				Org:              orgOf(id),
 				Name:             is.Title,
+				URL:              is.URL,
+				Repo:             is.Repo,
 				CreationDatetime: is.CreatedAt,
 				Bug:              is.IsBug,
 				Type:             is.Type,
+				SizeWeight:       is.SizeWeight,
+				BugPeriods:       is.BugPeriods,
+				Severity:         is.Severity,
+				SeverityChanges:  is.SeverityChanges,
+				Epic:             is.Epic,
+			}
+			if len(is.OtherEpics) > 0 {
+				quality.add(severityWarning, "multiple_epics", id, fmt.Sprintf("epic labels %s and %s; counted in %s only", is.Epic, strings.Join(is.OtherEpics, ", "), is.Epic))
 			}
 
 			// If it's a bug, check custom fields for source
@@ -216,6 +461,9 @@ func Run(args []string) error {
 						continue
 					}
 				}
+				if pc.EstimateField != "" {
+					row.Estimate = estimateOf(customFields, pid, pc.EstimateField, id, quality)
+				}
 				// Use configured columns for stage timestamps
 				choose := func(cols []string) *time.Time {
 					if len(cols) > 0 {
@@ -224,6 +472,9 @@ func Run(args []string) error {
 					return nil
 				}
 				row.LeadTimeStartDatetime = choose(pc.LeadTimeColumns)
+				if len(pc.TriageColumns) > 0 {
+					row.LeadTimeStartDatetime = firstMoveOutside(projEvents, pc.TriageColumns)
+				}
 				row.CycleTimeStartDatetime = choose(pc.CycleTimeColumns)
 				row.PutInReadyStartDatetime = choose(pc.PutInReadyColumns)
 
@@ -231,6 +482,7 @@ func Run(args []string) error {
 				row.ReviewStartDatetime = choose(pc.ReviewStartColumns)
 				row.QAStartDatetime = choose(pc.QAStartColumns)
 				row.WaitingToPodStartDatetime = choose(pc.WaitingToProdStartCols)
+				row.CommittedDatetime = choose(pc.CommittedColumns)
 				// End datetime: by default earliest of status closed and configured inprod columns (e.g., Archive/Done)
 				var endCandidates []*time.Time
 				if e := choose(pc.InProdStartColumns); e != nil {
@@ -251,22 +503,43 @@ func Run(args []string) error {
 					}
 				}
 			} else {
-				slog.Info("calculate.project_unknown", "issue_id", id, "id", pid, "name", pname, "type", is.Type, "events", projEvents, "status", st)
-				// No matching project in config: fallback to legacy behavior
-				row.LeadTimeStartDatetime = firstMoveToAny(projEvents, []string{"Backlog", "Ready"})
-				row.CycleTimeStartDatetime = firstMoveToAny(projEvents, []string{"In Progress", "In progress"})
+				slog.Debug("calculate.project_unknown", "issue_id", id, "id", pid, "name", pname, "type", is.Type, "events", projEvents, "status", st)
+				switch {
+				case pid != "":
+					quality.add(severityWarning, "unknown_project", id, fmt.Sprintf("project %s (%s) is not in github.projects; legacy column names applied", pid, pname))
+				case lo.ContainsBy(st, func(s statusEventRow) bool { return s.Type == "closed" }):
+					quality.add(severityWarning, "closed_without_board_history", id, "closed without ever being on a project board; only its closing date is known")
+				}
+				// No matching project in config: fallback to legacy behavior, with the columns of column_aliases
+				leadCols := append(append([]string{}, aliases.columns("backlog")...), aliases.columns("ready")...)
+				row.LeadTimeStartDatetime = firstMoveToAny(projEvents, leadCols)
+				row.CycleTimeStartDatetime = firstMoveToAny(projEvents, aliases.columns("dev"))
 				if row.CycleTimeStartDatetime == nil {
-					row.CycleTimeStartDatetime = firstMoveToAny(projEvents, []string{"Backlog", "Ready"})
+					row.CycleTimeStartDatetime = row.LeadTimeStartDatetime
 				}
-				row.DevStartDatetime = firstMoveToAny(projEvents, []string{"In Progress", "In progress"})
+				row.DevStartDatetime = firstMoveToAny(projEvents, aliases.columns("dev"))
 				if row.DevStartDatetime == nil {
-					row.DevStartDatetime = firstMoveToAny(projEvents, []string{"Backlog", "Ready"})
+					row.DevStartDatetime = row.LeadTimeStartDatetime
+				}
+				row.ReviewStartDatetime = firstMoveToAny(projEvents, aliases.columns("review"))
+				row.QAStartDatetime = firstMoveToAny(projEvents, aliases.columns("qa"))
+				row.PutInReadyStartDatetime = firstMoveToAny(projEvents, aliases.columns("ready"))
+				row.WaitingToPodStartDatetime = firstMoveToAny(projEvents, aliases.columns("done"))
+				row.EndDatetime = computeEnd(st, projEvents, aliases.columns("archive"))
+			}
+			if row.EndDatetime == nil {
+				row.CurrentColumn = currentByID[id][row.ProjectID]
+			}
+			if periods := closedPeriodsOf(st); periods != nil {
+				stages := legacyStageColumns(aliases)
+				if pc, ok := projCfgByID[pid]; ok {
+					stages = projectStageColumns(pc)
 				}
-				row.ReviewStartDatetime = firstMoveToAny(projEvents, []string{"In review", "In Review"})
-				row.QAStartDatetime = nil
-				row.PutInReadyStartDatetime = firstMoveToAny(projEvents, []string{"Ready", "In Ready", "Ready for Dev"})
-				row.WaitingToPodStartDatetime = firstMoveTo(projEvents, "Done")
-				row.EndDatetime = computeEnd(st, projEvents)
+				row.ClosedPeriods, row.StageMoves = periods, stageMovesOf(projEvents, stages)
+			}
+			quality.checkStageOrder(row)
+			if !filter.matchProject(row) || !filter.overlaps(row) {
+				continue
 			}
 
 			allIssues = append(allIssues, row)
@@ -275,62 +548,312 @@ func Run(args []string) error {
 		// Deterministic order
 		sort.Slice(allIssues, func(i, j int) bool { return allIssues[i].ID < allIssues[j].ID })
 
+		// Earliest linked PR of each issue, for the time to PR measured on the pull requests
+		firstPR, err := readFirstPRCreation(in)
+		if err != nil {
+			return err
+		}
+		for i := range allIssues {
+			if t, ok := firstPR[allIssues[i].ID]; ok {
+				allIssues[i].FirstPRCreatedDatetime = &t
+			}
+		}
+
+		// Step 1b: issues whose timestamps cannot be right are listed and left out of the durations
+		if err := markClockAnomalies(filepath.Join(outDir, "anomalies.csv"), allIssues, *bom); err != nil {
+			return err
+		}
+
 		// Build convenience slices using lo
-		closedIssues := lo.Filter(allIssues, func(ci calculatedIssue, _ int) bool { return ci.EndDatetime != nil })
+		closedIssues := lo.Filter(allIssues, func(ci calculatedIssue, _ int) bool { return ci.EndDatetime != nil && filter.contains(*ci.EndDatetime) })
 		openIssues := lo.Filter(allIssues, func(ci calculatedIssue, _ int) bool { return ci.EndDatetime == nil })
 
-		if err := writeOutput(filepath.Join(base, "calculated_issue.csv"), allIssues); err != nil {
+		if err := writeOutput(filepath.Join(outDir, "calculated_issue.csv"), allIssues, *bom); err != nil {
 			return err
 		}
 
 		// Step 2: calculate monthly lead time and cycle time in days, using all issues with an EndDatetime
-		if err := writeMonthlyCycleSummary(filepath.Join(base, "cycle_time.csv"), closedIssues); err != nil {
+		if err := writeMonthlyCycleSummary(filepath.Join(outDir, "cycle_time.csv"), closedIssues, loc, targets, durations, timeToPRSource, *bom); err != nil {
+			return err
+		}
+
+		// Step 2b: one dot per closed issue for the cycle time scatterplot
+		if err := writeCycleScatter(filepath.Join(outDir, "cycle_scatter.csv"), closedIssues, cfg.CycleScatter.Weeks, now, loc, durations, *bom); err != nil {
+			return err
+		}
+
+		// Step 2c: cycle time of issues with and without a description
+		if err := writeSpecQualityMonthly(filepath.Join(outDir, "spec_quality_month.csv"), allIssues, issues, loc, filter, *bom); err != nil {
+			return err
+		}
+
+		// Step 2d: committed-to-done delivery clock, for the projects with committed_columns
+		if err := writeCommittedToDoneMonthly(filepath.Join(outDir, "committed_to_done_month.csv"), closedIssues, loc, durations, gate, *bom); err != nil {
+			return err
+		}
+
+		// Step 2e: month-over-month drift of the cycle time percentiles
+		if err := writeCyclePercentileTrend(filepath.Join(outDir, "cycle_percentile_trend.csv"), closedIssues, loc, durations, gate, *bom); err != nil {
 			return err
 		}
 
 		// Step 3: weekly throughput with Shewhart control limits (c-chart)
-		if err := writeWeeklyThroughput(filepath.Join(base, "throughput_week.csv"), closedIssues); err != nil {
+		if err := writeWeeklyThroughput(filepath.Join(outDir, "throughput_week.csv"), closedIssues, loc, filter, targets, gate, now, *bom); err != nil {
+			return err
+		}
+
+		// Step 3a: cycle times and throughput per repo, the small repos of each month grouped under other, every
+		// repo on its own, and the ranking of the repos on the latest month
+		repoLabels := newRepoLabels(closedIssues, loc, cfg.RepoBreakdown.MinIssues)
+		if err := writeMonthlyCycleRepo(filepath.Join(outDir, "cycle_time_repo.csv"), closedIssues, loc, repoLabels, durations, gate, *bom); err != nil {
+			return err
+		}
+		if err := writeMonthlyCycleByRepo(filepath.Join(outDir, "cycle_time_by_repo.csv"), closedIssues, loc, durations, gate, *bom); err != nil {
+			return err
+		}
+		if err := writeWeeklyThroughputRepo(filepath.Join(outDir, "throughput_week_repo.csv"), closedIssues, loc, repoLabels, *bom); err != nil {
+			return err
+		}
+		rankingMin := defaultRankingMinIssues
+		if cfg.RepoBreakdown.RankingMinIssues != nil {
+			rankingMin = *cfg.RepoBreakdown.RankingMinIssues
+		}
+		if err := writeRepoRanking(filepath.Join(outDir, "repo_ranking.csv"), closedIssues, loc, rankingMin, durations, *bom); err != nil {
+			return err
+		}
+
+		// Step 3b: quarterly roll-ups for board reporting, by fiscal quarter
+		if err := writeCycleTimeQuarterly(filepath.Join(outDir, "cycle_time_quarter.csv"), closedIssues, loc, cfg.FiscalYearStartMonth, durations, gate, *bom); err != nil {
+			return err
+		}
+		if err := writeThroughputQuarterly(filepath.Join(outDir, "throughput_quarter.csv"), closedIssues, loc, cfg.FiscalYearStartMonth, *bom); err != nil {
+			return err
+		}
+
+		// Step 3c: estimate points closed per week, for the projects with an estimate_field
+		estimated := map[string]bool{}
+		for id, pc := range projCfgByID {
+			if pc.EstimateField != "" {
+				estimated[id] = true
+			}
+		}
+		if err := writeWeeklyVelocity(filepath.Join(outDir, "velocity_week.csv"), closedIssues, loc, estimated, *bom); err != nil {
+			return err
+		}
+
+		// Step 3d: the closed issues of each week, for the throughput drill-down
+		if err := writeFinishedDetailWeekly(filepath.Join(outDir, "finished_detail_week.csv"), closedIssues, loc, durations, *bom); err != nil {
 			return err
 		}
 
 		// Step 4: current stocks for not-closed issues by stage
-		if err := writeStocks(filepath.Join(base, "stocks.csv"), openIssues); err != nil {
+		if err := writeStocks(filepath.Join(outDir, "stocks.csv"), openIssues, *bom); err != nil {
+			return err
+		}
+
+		// Step 4a: keep today's stocks in the history (not for filtered runs, whose stocks are not the org's)
+		if !filter.active() {
+			if err := writeStocksHistory(filepath.Join(base, "stocks_history.csv"), filepath.Join(base, "stocks.csv"), now, cfg.Stocks.HistoryDays, loc, *bom); err != nil {
+				return err
+			}
+		}
+
+		// Step 4b: open issues with their derived stage and literal board column (stocks drill-down)
+		if err := writeStocksDetail(filepath.Join(outDir, "stocks_detail.csv"), allIssues, *bom); err != nil {
+			return err
+		}
+
+		// Step 4c: in-progress issues per assignee against the personal WIP limit, unless the org opted out of
+		// individual metrics
+		wipPath := filepath.Join(outDir, "wip_per_person.csv")
+		if cfg.Privacy.DisableIndividualMetrics {
+			// make sure a file from an earlier run is not left behind and served
+			if err := os.Remove(wipPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		} else if err := writeWIPPerPerson(wipPath, allIssues, issues, cfg.GitHub.Bots, cfg.WIP.PersonalLimit, now, *bom); err != nil {
+			return err
+		}
+
+		// Step 5: weekly stocks per project by ISO year-week (cutoff at Sunday 23:59:59 in loc)
+		if err := writeWeeklyStocks(filepath.Join(outDir, "stocks_week.csv"), allIssues, loc, filter, *sparse, now, *bom); err != nil {
+			return err
+		}
+
+		// Step 5a: open bugs per week by severity label
+		if err := writeWeeklyBugStockBySeverity(filepath.Join(outDir, "bug_stock_week.csv"), allIssues, loc, filter, cfg.GitHub.SeverityLabels, now, quality, *bom); err != nil {
+			return err
+		}
+
+		// Step 5b: Little's Law consistency between weekly WIP, throughput and measured cycle time
+		if err := writeLittlesLawMonthly(filepath.Join(outDir, "littles_law_month.csv"), filepath.Join(outDir, "stocks_week.csv"), closedIssues, loc, *bom); err != nil {
+			return err
+		}
+
+		// Step 5c: progress of each epic label
+		if err := writeEpicProgress(filepath.Join(outDir, "epic_progress.csv"), allIssues, *bom); err != nil {
+			return err
+		}
+
+		// Step 5d: cumulative created vs closed issues per project for the release burn-up
+		if err := writeBurnupWeekly(filepath.Join(outDir, "burnup_week.csv"), allIssues, loc, filter, now, *bom); err != nil {
+			return err
+		}
+
+		// Step 6: age distribution of the open backlog
+		if err := writeBacklogAgeHistogram(filepath.Join(outDir, "backlog_age_histogram.csv"), openIssues, cfg.Backlog.AgeBuckets, now.UTC(), *bom); err != nil {
+			return err
+		}
+
+		// Step 7: moves back to an earlier stage (e.g. QA -> In Progress)
+		regressions := findStageRegressions(allIssues, projByID, projCfgByID)
+		windowed := lo.Filter(regressions, func(g stageRegression, _ int) bool { return filter.contains(g.At) })
+		if err := writeStageRegressions(filepath.Join(outDir, "stage_regressions.csv"), windowed, *bom); err != nil {
+			return err
+		}
+		if err := writeStageRegressionsMonthly(filepath.Join(outDir, "stage_regressions_month.csv"), regressions, closedIssues, loc, filter, *bom); err != nil {
 			return err
 		}
 
-		// Step 5: weekly stocks per project by ISO year-week (cutoff at Sunday 23:59:59 UTC)
-		if err := writeWeeklyStocks(filepath.Join(base, "stocks_week.csv"), allIssues); err != nil {
+		// Step 7b: time from a close to the reopening of the issue, for each close/reopen cycle
+		reopenings := lo.Filter(findReopenings(allIssues, statusByID), func(g reopening, _ int) bool { return filter.contains(g.ReopenedAt) })
+		if err := writeReopenLatency(filepath.Join(outDir, "reopen_latency.csv"), reopenings, *bom); err != nil {
 			return err
 		}
+		if err := writeReopenLatencyMonthly(filepath.Join(outDir, "reopen_latency_month.csv"), reopenings, loc, gate, *bom); err != nil {
+			return err
+		}
+
+		// Steps 8-10 read issue.csv directly: they are not tied to projects, so not for filtered runs
+		if !filter.active() {
+			// Step 8: milestone burndown
+			if err := writeMilestoneBurndown(filepath.Join(base, "milestone_burndown.csv"), issues, now, loc, *bom); err != nil {
+				return err
+			}
+			// Step 9: created-to-closed age of closed issues, a baseline that needs no column mapping
+			if err := writeCloseAge(filepath.Join(base, "close_age.csv"), issues, loc, *bom); err != nil {
+				return err
+			}
+			// Step 10: issues never added to a board, which the flow metrics leave in the legacy backlog bucket
+			if err := writeUnboardedIssues(filepath.Join(base, "unboarded_issues.csv"), filepath.Join(base, "unboarded_issues_repo.csv"), issues, projByID, now, *bom); err != nil {
+				return err
+			}
+		}
+		// Step 11: data quality findings met on the way (unknown projects, unparseable timestamps, ...)
+		if err := quality.write(filepath.Join(outDir, "data_quality.csv"), *bom); err != nil {
+			return err
+		}
+	}
+	// -strict fails the run on data quality errors, once every output is written
+	strictErr := func() error {
+		if n := quality.errorCount(); *strict && n > 0 {
+			return &cli.ExitError{Code: 3, Err: fmt.Errorf("calculate: %d data quality errors, see %s", n, filepath.Join(outDir, "data_quality.csv"))}
+		}
+		return nil
+	}
+
+	// PR and mixed outputs have no project, so a filtered run only refreshes the issue outputs
+	if filter.active() {
+		appendCalculateMeta(outDir, scopes, imported, *bom)
+		slog.Info("calculate.done (issues, filtered)", "output", outDir)
+		return strictErr()
 	}
 
 	// PR scope calculations (do not require config)
 	if *prScope {
+		crModeSetting := cfg.PR.CRCountMode
+		if *crCountMode != "" {
+			crModeSetting = *crCountMode
+		}
+		crMode, err := normalizeCRCountMode(crModeSetting)
+		if err != nil {
+			return fmt.Errorf("calculate: %w", err)
+		}
+		roundGap := crRoundGapOf(cfg.PR.CRRoundGapHours)
 		// weekly PR change-requests stats (avg, median, p90) by PR open week
-		if err := writePRChangeRequestsWeekly(filepath.Join(base, "pr_change_requests_week.csv"), base); err != nil {
+		if err := writePRChangeRequestsWeekly(filepath.Join(base, "pr_change_requests_week.csv"), in, crMode, roundGap, loc, gate, *bom); err != nil {
 			return err
 		}
 		// per-repo PR change-requests stats (median per repo) and distribution
-		if err := writePRChangeRequestsPerRepo(filepath.Join(base, "pr_change_requests_repo.csv"), base); err != nil {
+		if err := writePRChangeRequestsPerRepo(filepath.Join(base, "pr_change_requests_repo.csv"), in, crMode, roundGap, gate, *bom); err != nil {
+			return err
+		}
+		if err := writePRChangeRequestsRepoDist(filepath.Join(base, "pr_change_requests_repo_dist.csv"), in, crMode, roundGap, *bom); err != nil {
+			return err
+		}
+		// merged PRs per ISO merge week (delivery cadence proxy)
+		if err := writePRMergedWeekly(filepath.Join(base, "pr_merged_week.csv"), in, loc, *bom); err != nil {
+			return err
+		}
+		// PR cycle time (open to merge) per ISO merge week, with abandoned PRs per close week
+		if err := writePRCycleTimeWeekly(filepath.Join(base, "pr_cycle_time_week.csv"), in, cfg.GitHub.Bots, loc, *bom); err != nil {
 			return err
 		}
-		if err := writePRChangeRequestsRepoDist(filepath.Join(base, "pr_change_requests_repo_dist.csv"), base); err != nil {
+		// review comments per 100 changed lines per ISO merge week
+		if err := writePRReviewDepthWeekly(filepath.Join(base, "pr_review_depth_week.csv"), in, cfg.PR.ReviewDepthMinLines, loc, *bom); err != nil {
+			return err
+		}
+		// hours to the first and Nth approval per ISO merge week
+		if err := writePRApprovalLatencyWeekly(filepath.Join(base, "pr_approval_latency_week.csv"), in, cfg.PR.ApprovalsRequired, cfg.GitHub.Bots, loc, *bom); err != nil {
+			return err
+		}
+		// review threads resolved before merge per ISO merge week
+		if err := writePRThreadResolutionWeekly(filepath.Join(base, "pr_thread_resolution_week.csv"), in, loc, *bom); err != nil {
 			return err
 		}
 	}
 
+	// Outputs below mix issue and PR data, so they are refreshed with either scope.
+	if err := writeContributorsMonthly(filepath.Join(base, "contributors_month.csv"), filepath.Join(base, "contributors_month_repo.csv"), in, cfg.GitHub.Bots, loc, *bom); err != nil {
+		return err
+	}
+	// Per-person leaderboard, unless the org opted out of individual metrics
+	leaderboardPath := filepath.Join(base, "leaderboard_month.csv")
+	if cfg.Privacy.DisableIndividualMetrics {
+		// make sure a file from an earlier run is not left behind and served
+		if err := os.Remove(leaderboardPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	} else if err := writeLeaderboardMonthly(leaderboardPath, in, cfg.GitHub.Bots, loc, *bom); err != nil {
+		return err
+	}
+	// Issues closed per person as continuous monthly series, for individual trend charts
+	closedByAuthorPath := filepath.Join(base, "closed_by_author_month.csv")
+	if cfg.Privacy.DisableIndividualMetrics {
+		if err := os.Remove(closedByAuthorPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	} else if err := writeClosedByAuthorMonthly(closedByAuthorPath, in, cfg.GitHub.Bots, loc, *bom); err != nil {
+		return err
+	}
+	// Coding time of the first PR closing each issue, against the issue's dev-to-review stage time
+	if err := writeCodingTime(filepath.Join(base, "coding_time.csv"), in, base, *bom); err != nil {
+		return err
+	}
+	// Change failure rate: deployments (release.csv) followed by a failure within dora.failure_window_days
+	if err := writeChangeFailureRate(filepath.Join(base, "change_failure_rate_month.csv"), in, cfg.DORA, loc, *bom); err != nil {
+		return err
+	}
+
 	if *issuesScope {
 		slog.Info(fmt.Sprintf("calculate.done (issues)"))
 	}
 	if *prScope {
 		slog.Info(fmt.Sprintf("calculate.done (pr)"))
 	}
-	return nil
+	appendCalculateMeta(base, scopes, imported, *bom)
+	if *sheetsID != "" {
+		if err := exportSheets(*sheetsID, base); err != nil {
+			return err
+		}
+	}
+	return strictErr()
 }
 
 func key(org, repo, number string) string { return org + "/" + repo + "#" + number }
 
-func readIssues(path string) (map[string]issueRow, error) {
+// readIssues reads issue.csv, recording unparseable timestamps and repeated issues in q.
+func readIssues(path string, q *dataQuality) (map[string]issueRow, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -341,7 +864,7 @@ func readIssues(path string) (map[string]issueRow, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Expect headers: org,repo,number,title,url,state,type,is_bug,creator,assignees,created_at,closed_at,committer
+	// Expect headers: org,repo,number,title,url,state,type,is_bug,creator,assignees,created_at,closed_at,committer,milestone,milestone_due_on
 	idx := indexMap(rec)
 	required := []string{"org", "repo", "number", "title", "created_at"}
 	for _, col := range required {
@@ -352,6 +875,7 @@ func readIssues(path string) (map[string]issueRow, error) {
 	// Optional columns for backward compatibility
 	_, hasType := idx["type"]
 	_, hasIsBug := idx["is_bug"]
+	_, hasDescription := idx["has_description"]
 
 	res := map[string]issueRow{}
 	for {
@@ -380,13 +904,83 @@ func readIssues(path string) (map[string]issueRow, error) {
 		if hasIsBug {
 			isBug = parseBool(rec[idx["is_bug"]])
 		}
-		created, _ := time.Parse(time.RFC3339, rec[idx["created_at"]])
-		res[key(org, repo, num)] = issueRow{Org: org, Repo: repo, Number: num, Title: title, Type: typeVal, IsBug: isBug, CreatedAt: created}
+		id := key(org, repo, num)
+		if _, dup := res[id]; dup {
+			q.add(severityWarning, "duplicate_row", id, "issue.csv lists the issue more than once; the last row is kept")
+		}
+		created := q.parseTime("issue.csv", "created_at", id, rec[idx["created_at"]])
+		var closedAt *time.Time
+		if t := q.parseTime("issue.csv", "closed_at", id, field(idx, rec, "closed_at")); !t.IsZero() {
+			closedAt = &t
+		}
+		periods := parseBugPeriods(field(idx, rec, "bug_periods"))
+		if _, hasPeriods := idx["bug_periods"]; !hasPeriods && isBug {
+			periods = []bugPeriod{{since: created}}
+		}
+		weight, werr := strconv.ParseFloat(field(idx, rec, "size_weight"), 64)
+		if werr != nil || weight <= 0 {
+			weight = 1
+		}
+		res[id] = issueRow{
+			Org: org, Repo: repo, Number: num, Title: title, URL: field(idx, rec, "url"), Type: typeVal, IsBug: isBug, CreatedAt: created,
+			ClosedAt:       closedAt,
+			Milestone:      field(idx, rec, "milestone"),
+			MilestoneDueOn: parseOptionalTime(field(idx, rec, "milestone_due_on")),
+
+			HasDescription:     parseBool(field(idx, rec, "has_description")),
+			HasDescriptionData: hasDescription,
+			SizeWeight:         weight,
+			BugPeriods:         periods,
+			Assignees:          splitList(field(idx, rec, "assignees")),
+			Severity:           field(idx, rec, "severity"),
+			SeverityChanges:    parseSeverityChanges(field(idx, rec, "severity_changes")),
+			Epic:               field(idx, rec, "epic"),
+			OtherEpics:         splitList(field(idx, rec, "other_epics")),
+		}
 	}
 	return res, nil
 }
 
-func readStatus(path string) (map[string][]statusEventRow, error) {
+// splitList splits a ;-separated issue.csv list such as assignees, dropping empty items.
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// parseBugPeriods parses the bug_periods column of issue.csv ("since/until;since/"), skipping malformed periods.
+func parseBugPeriods(s string) []bugPeriod {
+	var res []bugPeriod
+	for _, part := range strings.Split(s, ";") {
+		sinceStr, untilStr, ok := strings.Cut(strings.TrimSpace(part), "/")
+		if !ok {
+			continue
+		}
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			continue
+		}
+		res = append(res, bugPeriod{since: since, until: parseOptionalTime(untilStr)})
+	}
+	return res
+}
+
+// bugAt reports whether one of periods covers t.
+func bugAt(periods []bugPeriod, t time.Time) bool {
+	for _, p := range periods {
+		if !p.since.After(t) && (p.until == nil || p.until.After(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readStatus reads issue_status_event.csv, recording unparseable timestamps and repeated rows in q.
+func readStatus(path string, q *dataQuality) (map[string][]statusEventRow, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -405,6 +999,7 @@ func readStatus(path string) (map[string][]statusEventRow, error) {
 		}
 	}
 	res := map[string][]statusEventRow{}
+	seen := map[string]bool{}
 	for {
 		rec, err := r.Read()
 		if err != nil {
@@ -417,8 +1012,17 @@ func readStatus(path string) (map[string][]statusEventRow, error) {
 		repo := rec[idx["repo"]]
 		num := rec[idx["number"]]
 		typ := rec[idx["type"]]
-		at, _ := time.Parse(time.RFC3339, rec[idx["at"]])
 		id := key(org, repo, num)
+		if row := strings.Join(rec, "\x1f"); seen[row] {
+			q.add(severityWarning, "duplicate_row", id, fmt.Sprintf("issue_status_event.csv repeats the %s event at %s", typ, rec[idx["at"]]))
+		} else {
+			seen[row] = true
+		}
+		at := q.parseTime("issue_status_event.csv", "at", id, rec[idx["at"]])
+		if at.IsZero() {
+			// an event at year 1 would sort first and drive the stage dates
+			continue
+		}
 		res[id] = append(res[id], statusEventRow{Org: org, Repo: repo, Number: num, Type: typ, At: at})
 	}
 	// Sort by time
@@ -428,7 +1032,8 @@ func readStatus(path string) (map[string][]statusEventRow, error) {
 	return res, nil
 }
 
-func readProject(path string) (map[string][]projectEventRow, error) {
+// readProject reads issue_project_event.csv, recording unparseable timestamps and repeated rows in q.
+func readProject(path string, q *dataQuality) (map[string][]projectEventRow, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -447,6 +1052,7 @@ func readProject(path string) (map[string][]projectEventRow, error) {
 		}
 	}
 	res := map[string][]projectEventRow{}
+	seen := map[string]bool{}
 	for {
 		rec, err := r.Read()
 		if err != nil {
@@ -461,9 +1067,18 @@ func readProject(path string) (map[string][]projectEventRow, error) {
 		projID := rec[idx["project_id"]]
 		projName := rec[idx["project_name"]]
 		toCol := rec[idx["to_column"]]
-		at, _ := time.Parse(time.RFC3339, rec[idx["at"]])
 		typ := rec[idx["type"]]
 		id := key(org, repo, num)
+		if row := strings.Join(rec, "\x1f"); seen[row] {
+			q.add(severityWarning, "duplicate_row", id, fmt.Sprintf("issue_project_event.csv repeats the move to %q at %s", toCol, rec[idx["at"]]))
+		} else {
+			seen[row] = true
+		}
+		at := q.parseTime("issue_project_event.csv", "at", id, rec[idx["at"]])
+		if at.IsZero() {
+			// an event at year 1 would sort first and drive the stage dates
+			continue
+		}
 		res[id] = append(res[id], projectEventRow{Org: org, Repo: repo, Number: num, ProjectID: projID, ProjectName: projName, ToColumn: toCol, At: at, EventType: typ})
 	}
 	for _, v := range res {
@@ -523,6 +1138,9 @@ func readProjectCustomFields(path string) (map[string][]projectCustomFieldRow, e
 func indexMap(headers []string) map[string]int {
 	m := map[string]int{}
 	for i, h := range headers {
+		if i == 0 {
+			h = ccsv.TrimBOM(h)
+		}
 		m[strings.TrimSpace(strings.ToLower(h))] = i
 	}
 	return m
@@ -535,43 +1153,39 @@ func parseBool(s string) bool {
 
 // Independent rules per field
 
-func firstMoveTo(events []projectEventRow, column string) *time.Time {
+func firstMoveToAny(events []projectEventRow, columns []string) *time.Time {
 	if len(events) == 0 {
 		return nil
 	}
-	col := strings.ToLower(strings.TrimSpace(column))
-	// Only consider moved events
+	set := lo.SliceToMap(columns, func(s string) (string, struct{}) { return strings.ToLower(strings.TrimSpace(s)), struct{}{} })
 	if ev, ok := lo.Find(events, func(e projectEventRow) bool {
-		return e.EventType == "moved" && strings.ToLower(strings.TrimSpace(e.ToColumn)) == col
+		_, wanted := set[strings.ToLower(strings.TrimSpace(e.ToColumn))]
+		return e.EventType == "moved" && wanted
 	}); ok {
 		return &ev.At
 	}
 	return nil
 }
 
-func firstMoveToAny(events []projectEventRow, columns []string) *time.Time {
-	if len(events) == 0 {
-		return nil
-	}
+// firstMoveOutside returns the time of the first move to a column that is not one of columns, nil when the issue
+// never left them.
+func firstMoveOutside(events []projectEventRow, columns []string) *time.Time {
 	set := lo.SliceToMap(columns, func(s string) (string, struct{}) { return strings.ToLower(strings.TrimSpace(s)), struct{}{} })
 	if ev, ok := lo.Find(events, func(e projectEventRow) bool {
-		_, wanted := set[strings.ToLower(strings.TrimSpace(e.ToColumn))]
-		return e.EventType == "moved" && wanted
+		_, excluded := set[strings.ToLower(strings.TrimSpace(e.ToColumn))]
+		return e.EventType == "moved" && !excluded
 	}); ok {
 		return &ev.At
 	}
 	return nil
 }
 
-func computeEnd(status []statusEventRow, proj []projectEventRow) *time.Time {
+func computeEnd(status []statusEventRow, proj []projectEventRow, archiveCols []string) *time.Time {
 	var closed *time.Time
 	if ev, ok := lo.Find(status, func(s statusEventRow) bool { return s.Type == "closed" }); ok {
 		closed = &ev.At
 	}
-	var archived *time.Time
-	if ev, ok := lo.Find(proj, func(p projectEventRow) bool { return p.EventType == "moved" && equalFoldTrim(p.ToColumn, "Archive") }); ok {
-		archived = &ev.At
-	}
+	archived := firstMoveToAny(proj, archiveCols)
 	if closed == nil && archived == nil {
 		return nil
 	}
@@ -606,24 +1220,25 @@ func earliest(ts []*time.Time) *time.Time {
 	return res
 }
 
-func writeOutput(path string, rows []calculatedIssue) error {
+func writeOutput(path string, rows []calculatedIssue, bom bool) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
-	f, err := os.Create(path)
+	f, err := ccsv.Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	headers := []string{"id", "name", "project_id", "project_name", "creationdatetime", "leadtimestartdatetime", "cycletimestartdatetime", "putinreadystartdatetime", "devstartdatetime", "reviewstartdatetime", "qastartdatetime", "waitingtopodstartdateime", "enddatetime", "bug", "bug_customer_facing", "bug_internal", "bug_dev_process", "type"}
+	headers := schema.Headers("calculated_issue.csv")
 	if err := w.Write(headers); err != nil {
 		return err
 	}
 	for _, r := range rows {
 		row := []string{
 			r.ID,
+			r.Org,
 			r.Name,
 			r.ProjectID,
 			r.ProjectName,
@@ -641,6 +1256,13 @@ func writeOutput(path string, rows []calculatedIssue) error {
 			fmt.Sprintf("%t", r.BugInternal),
 			fmt.Sprintf("%t", r.BugDevProcess),
 			r.Type,
+			r.CurrentColumn,
+			strconv.FormatFloat(r.SizeWeight, 'f', -1, 64),
+			r.URL,
+			r.Repo,
+			formatOptionalNumber(r.Estimate),
+			formatTime(r.CommittedDatetime),
+			formatCommittedToDone(r),
 		}
 		if err := w.Write(row); err != nil {
 			return err
@@ -657,24 +1279,37 @@ func formatTime(t *time.Time) string {
 }
 
 // Step 2 helpers: monthly summary of lead/cycle times in days
-func writeMonthlyCycleSummary(path string, rows []calculatedIssue) error {
-	byMonth := map[string][]calculatedIssue{}
+// Each month has one row per org followed by an ALL row. The lead and cycle time targets are repeated on every row,
+// and the lead and cycle time averages are also given weighted by the size weights of the issues. Durations, targets
+// included, are written with df. The time to PR is given from the board (time_to_pr) and from the linked pull
+// requests (time_to_pr_actual, empty without any); tprSource tells dashboards which one to show.
+func writeMonthlyCycleSummary(path string, rows []calculatedIssue, loc *time.Location, targets config.TargetValues, df durationFormat, tprSource string, bom bool) error {
+	byMonth := map[string]map[string][]calculatedIssue{}
 	for _, r := range rows {
 		if r.EndDatetime == nil {
 			continue
 		}
-		m := r.EndDatetime.UTC().Format("2006-01")
-		byMonth[m] = append(byMonth[m], r)
+		m := r.EndDatetime.In(loc).Format("2006-01")
+		if byMonth[m] == nil {
+			byMonth[m] = map[string][]calculatedIssue{}
+		}
+		byMonth[m][r.Org] = append(byMonth[m][r.Org], r)
+		byMonth[m][allOrgs] = append(byMonth[m][allOrgs], r)
 	}
-	// prepare output rows sorted by month
+	// prepare output rows sorted by month, then org
 	type outRow struct {
 		Month        string
+		Org          string
 		IssueCount   int
 		LeadDaysAvg  float64
 		LeadCount    int
 		CycleDaysAvg float64
 		CycleCount   int
 		TimeToPRAvg  float64
+		// average time to the earliest linked PR, nil when no issue has one
+		TimeToPRActualAvg *float64
+		// averages weighted by the issue size weights
+		WeightedLeadAvg, WeightedCycleAvg float64
 	}
 	var months []string
 	for m := range byMonth {
@@ -683,71 +1318,100 @@ func writeMonthlyCycleSummary(path string, rows []calculatedIssue) error {
 	sort.Strings(months)
 	var outs []outRow
 	for _, m := range months {
-		issues := byMonth[m]
-		var leadSum float64
-		var leadCnt int
-		var cycleSum float64
-		var cycleCnt int
-		var tprSum float64
-		var tprCnt int
-		for _, r := range issues {
-			end := r.EndDatetime.UTC()
-			if r.LeadTimeStartDatetime != nil {
-				lead := end.Sub(r.LeadTimeStartDatetime.UTC()).Hours() / 24.0
-				leadSum += lead
-				leadCnt++
-			}
-			if r.CycleTimeStartDatetime != nil {
-				cycle := end.Sub(r.CycleTimeStartDatetime.UTC()).Hours() / 24.0
-				cycleSum += cycle
-				cycleCnt++
-			}
-			// Time to PR = review_start - dev_start (in days)
-			if r.DevStartDatetime != nil && r.ReviewStartDatetime != nil {
-				dev := r.DevStartDatetime.UTC()
-				rev := r.ReviewStartDatetime.UTC()
-				if !rev.Before(dev) {
-					tpr := rev.Sub(dev).Hours() / 24.0
+		for _, org := range sortedOrgs(byMonth[m]) {
+			issues := byMonth[m][org]
+			var leadSum float64
+			var leadCnt int
+			var cycleSum float64
+			var cycleCnt int
+			var tprSum float64
+			var tprCnt int
+			var actualSum float64
+			var actualCnt int
+			var wLeadSum, wLeadTotal, wCycleSum, wCycleTotal float64
+			for _, r := range issues {
+				weight := r.SizeWeight
+				if weight <= 0 {
+					weight = 1
+				}
+				if lead, ok := r.leadDays(); ok {
+					leadSum += lead
+					leadCnt++
+					wLeadSum += lead * weight
+					wLeadTotal += weight
+				}
+				if cycle, ok := r.cycleDays(); ok {
+					cycleSum += cycle
+					cycleCnt++
+					wCycleSum += cycle * weight
+					wCycleTotal += weight
+				}
+				// Time to PR = review_start - dev_start (in days)
+				if tpr, ok := r.timeToPRDays(); ok {
 					tprSum += tpr
 					tprCnt++
 				}
+				if tpr, ok := r.timeToPRActualDays(); ok {
+					actualSum += tpr
+					actualCnt++
+				}
 			}
+			var leadAvg, cycleAvg, tprAvg float64
+			if leadCnt > 0 {
+				leadAvg = leadSum / float64(leadCnt)
+			}
+			if cycleCnt > 0 {
+				cycleAvg = cycleSum / float64(cycleCnt)
+			}
+			if tprCnt > 0 {
+				tprAvg = tprSum / float64(tprCnt)
+			}
+			var actualAvg *float64
+			if actualCnt > 0 {
+				v := actualSum / float64(actualCnt)
+				actualAvg = &v
+			}
+			var wLeadAvg, wCycleAvg float64
+			if wLeadTotal > 0 {
+				wLeadAvg = wLeadSum / wLeadTotal
+			}
+			if wCycleTotal > 0 {
+				wCycleAvg = wCycleSum / wCycleTotal
+			}
+			outs = append(outs, outRow{Month: m, Org: org, IssueCount: len(issues), LeadDaysAvg: leadAvg, LeadCount: leadCnt, CycleDaysAvg: cycleAvg, CycleCount: cycleCnt, TimeToPRAvg: tprAvg, TimeToPRActualAvg: actualAvg, WeightedLeadAvg: wLeadAvg, WeightedCycleAvg: wCycleAvg})
 		}
-		var leadAvg, cycleAvg, tprAvg float64
-		if leadCnt > 0 {
-			leadAvg = leadSum / float64(leadCnt)
-		}
-		if cycleCnt > 0 {
-			cycleAvg = cycleSum / float64(cycleCnt)
-		}
-		if tprCnt > 0 {
-			tprAvg = tprSum / float64(tprCnt)
-		}
-		outs = append(outs, outRow{Month: m, IssueCount: len(issues), LeadDaysAvg: leadAvg, LeadCount: leadCnt, CycleDaysAvg: cycleAvg, CycleCount: cycleCnt, TimeToPRAvg: tprAvg})
 	}
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
-	f, err := os.Create(path)
+	f, err := ccsv.Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	headers := []string{"month", "issues_count", "leadtime_days_avg", "lead_count", "cycletime_days_avg", "cycle_count", "time_to_pr"}
+	headers := schema.Headers("cycle_time.csv")
 	if err := w.Write(headers); err != nil {
 		return err
 	}
 	for _, r := range outs {
 		row := []string{
 			r.Month,
+			r.Org,
 			fmt.Sprintf("%d", r.IssueCount),
-			fmt.Sprintf("%.6f", r.LeadDaysAvg),
+			df.format(r.LeadDaysAvg),
 			fmt.Sprintf("%d", r.LeadCount),
-			fmt.Sprintf("%.6f", r.CycleDaysAvg),
+			df.format(r.CycleDaysAvg),
 			fmt.Sprintf("%d", r.CycleCount),
-			fmt.Sprintf("%.6f", r.TimeToPRAvg),
+			df.format(r.TimeToPRAvg),
+			df.formatOptional(targets.LeadTimeDays),
+			df.formatOptional(targets.CycleTimeDays),
+			df.format(r.WeightedLeadAvg),
+			df.format(r.WeightedCycleAvg),
+			df.unit,
+			df.formatOptional(r.TimeToPRActualAvg),
+			tprSource,
 		}
 		if err := w.Write(row); err != nil {
 			return err
@@ -756,19 +1420,33 @@ func writeMonthlyCycleSummary(path string, rows []calculatedIssue) error {
 	return w.Error()
 }
 
-// Step 3 helpers: weekly throughput with Shewhart control limits (c-chart)
-func writeWeeklyThroughput(path string, rows []calculatedIssue) error {
-	// Aggregate counts by ISO year-week
+// Step 3 helpers: weekly throughput with Shewhart control limits (c-chart); weeks follow loc
+// The -since/-until window of filter, when set, replaces the first/last closing week as the range bounds.
+// Each week has one row per org, with its own control limits and trend, followed by an ALL row. The bug ratio is
+// the share of bugs among the issues closed in the week, empty without any. The throughput and bug ratio targets
+// are repeated on every row. The week of now, still running, is left out.
+func writeWeeklyThroughput(path string, rows []calculatedIssue, loc *time.Location, filter issueFilter, targets config.TargetValues, gate sampleGate, now time.Time, bom bool) error {
+	// Aggregate counts by org and ISO year-week
 	type wk struct{ Year, Week int }
-	counts := map[wk]int{}
+	counts := map[string]map[wk]int{}
+	bugs := map[string]map[wk]int{}
 	var minTime, maxTime *time.Time
 	for _, r := range rows {
 		if r.EndDatetime == nil {
 			continue
 		}
-		end := r.EndDatetime.UTC()
+		end := r.EndDatetime.In(loc)
 		y, w := end.ISOWeek()
-		counts[wk{Year: y, Week: w}]++
+		for _, org := range []string{r.Org, allOrgs} {
+			if counts[org] == nil {
+				counts[org] = map[wk]int{}
+				bugs[org] = map[wk]int{}
+			}
+			counts[org][wk{Year: y, Week: w}]++
+			if r.Bug {
+				bugs[org][wk{Year: y, Week: w}]++
+			}
+		}
 		if minTime == nil || end.Before(*minTime) {
 			t := end
 			minTime = &t
@@ -778,16 +1456,24 @@ func writeWeeklyThroughput(path string, rows []calculatedIssue) error {
 			maxTime = &t
 		}
 	}
+	if filter.Since != nil {
+		t := filter.Since.In(loc)
+		minTime = &t
+	}
+	if filter.Until != nil {
+		t := filter.Until.In(loc)
+		maxTime = &t
+	}
+	// Align to Monday (start of ISO week)
+	alignToMonday := func(t time.Time) time.Time {
+		wd := int(t.Weekday()) // Sunday=0, Monday=1, ..., Saturday=6
+		offset := (wd + 6) % 7 // 0 for Monday, 6 for Sunday
+		tt := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return tt.AddDate(0, 0, -offset)
+	}
 	// Build ordered continuous list of ISO weeks between min and max (include zero-throughput weeks)
 	var keys []wk
 	if minTime != nil && maxTime != nil {
-		// Align to Monday (start of ISO week)
-		alignToMonday := func(t time.Time) time.Time {
-			wd := int(t.Weekday()) // Sunday=0, Monday=1, ..., Saturday=6
-			offset := (wd + 6) % 7 // 0 for Monday, 6 for Sunday
-			tt := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
-			return tt.AddDate(0, 0, -offset)
-		}
 		start := alignToMonday(*minTime)
 		end := alignToMonday(*maxTime)
 		for cur := start; !cur.After(end); cur = cur.AddDate(0, 0, 7) {
@@ -795,10 +1481,11 @@ func writeWeeklyThroughput(path string, rows []calculatedIssue) error {
 			keys = append(keys, wk{Year: y, Week: w})
 		}
 	}
-	// Prepare arrays for per-week limits
-	centers := make([]float64, len(keys))
-	ucls := make([]float64, len(keys))
-	lcls := make([]float64, len(keys))
+	headers := schema.Headers("throughput_week.csv")
+	// If no weeks, just write headers
+	if len(keys) == 0 {
+		return writeCSVFile(path, headers, nil, bom)
+	}
 	// Helper to clamp LCL at 0
 	clamp0 := func(v float64) float64 {
 		if v < 0 {
@@ -806,113 +1493,124 @@ func writeWeeklyThroughput(path string, rows []calculatedIssue) error {
 		}
 		return v
 	}
-	// If no weeks, just write headers
-	if len(keys) == 0 {
-		// Write CSV headers only
-		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-			return err
-		}
-		f, err := os.Create(path)
-		if err != nil {
-			return err
-		}
-		defer f.Close()
-		w := csv.NewWriter(f)
-		defer w.Flush()
-		headers := []string{"year", "week", "throughput", "center", "ucl", "lcl"}
-		if err := w.Write(headers); err != nil {
-			return err
-		}
-		return w.Error()
-	}
-	if len(keys) < 6 {
-		// Fewer than 6 total weeks: compute from available weeks and apply to all
-		var sum float64
-		for _, k := range keys {
-			sum += float64(counts[k])
-		}
-		mean := sum / float64(len(keys))
-		ucl := mean + 3.0*math.Sqrt(mean)
-		lcl := clamp0(mean - 3.0*math.Sqrt(mean))
-		for i := range keys {
-			centers[i] = mean
-			ucls[i] = ucl
-			lcls[i] = lcl
-		}
-	} else {
-		// 6-week cadence: compute at week 6,12,18,... and apply for each 6-week block
-		lastAssigned := -1
-		for blockEnd := 5; blockEnd < len(keys); blockEnd += 6 {
-			// Compute mean over the last 6 observed weeks ending at blockEnd
+	// series holds the per-week values of one org, aligned on keys
+	type series struct {
+		centers, ucls, lcls  []float64
+		rolling, slopes, cvs []*float64
+		samples              []int // weeks the control limits were computed on
+	}
+	limits := func(counts map[wk]int) series {
+		centers := make([]float64, len(keys))
+		ucls := make([]float64, len(keys))
+		lcls := make([]float64, len(keys))
+		cvs := make([]*float64, len(keys))
+		samples := make([]int, len(keys))
+		if len(keys) < 6 {
+			// Fewer than 6 total weeks: compute from available weeks and apply to all
 			var sum float64
-			for j := blockEnd - 5; j <= blockEnd; j++ {
-				sum += float64(counts[keys[j]])
+			window := make([]float64, len(keys))
+			for i, k := range keys {
+				sum += float64(counts[k])
+				window[i] = float64(counts[k])
 			}
-			mean := sum / 6
+			mean := sum / float64(len(keys))
 			ucl := mean + 3.0*math.Sqrt(mean)
 			lcl := clamp0(mean - 3.0*math.Sqrt(mean))
-			// Assign the same limits for this 6-week block
-			blockStart := blockEnd - 5
-			for i := blockStart; i <= blockEnd && i < len(keys); i++ {
+			cv := coefficientOfVariation(window)
+			for i := range keys {
 				ucls[i] = ucl
 				lcls[i] = lcl
-				lastAssigned = i
+				cvs[i] = cv
+				samples[i] = len(keys)
 			}
-		}
-		// Tail: if any weeks remain after the last full block, reuse the last block's limits
-		if lastAssigned < len(keys)-1 {
-			lastUCL := ucls[lastAssigned]
-			lastLCL := lcls[lastAssigned]
-			for i := lastAssigned + 1; i < len(keys); i++ {
-				ucls[i] = lastUCL
-				lcls[i] = lastLCL
+		} else {
+			// 6-week cadence: compute at week 6,12,18,... and apply for each 6-week block
+			lastAssigned := -1
+			for blockEnd := 5; blockEnd < len(keys); blockEnd += 6 {
+				// Compute mean over the last 6 observed weeks ending at blockEnd
+				var sum float64
+				window := make([]float64, 0, 6)
+				for j := blockEnd - 5; j <= blockEnd; j++ {
+					sum += float64(counts[keys[j]])
+					window = append(window, float64(counts[keys[j]]))
+				}
+				mean := sum / 6
+				ucl := mean + 3.0*math.Sqrt(mean)
+				lcl := clamp0(mean - 3.0*math.Sqrt(mean))
+				cv := coefficientOfVariation(window)
+				// Assign the same limits for this 6-week block
+				blockStart := blockEnd - 5
+				for i := blockStart; i <= blockEnd && i < len(keys); i++ {
+					ucls[i] = ucl
+					lcls[i] = lcl
+					cvs[i] = cv
+					samples[i] = 6
+					lastAssigned = i
+				}
 			}
+			// Tail: if any weeks remain after the last full block, reuse the last block's limits
+			if lastAssigned < len(keys)-1 {
+				lastUCL := ucls[lastAssigned]
+				lastLCL := lcls[lastAssigned]
+				lastCV := cvs[lastAssigned]
+				for i := lastAssigned + 1; i < len(keys); i++ {
+					ucls[i] = lastUCL
+					lcls[i] = lastLCL
+					cvs[i] = lastCV
+					samples[i] = 6
+				}
+			}
+		}
+		// The center is the number of issues ended for each week (weekly throughput)
+		for i, k := range keys {
+			centers[i] = float64(counts[k])
 		}
+		// Trend columns on the zero-filled series, before the current week is dropped
+		return series{centers: centers, ucls: ucls, lcls: lcls, rolling: rollingMean(centers, 4), slopes: trailingSlope(centers, 12), cvs: cvs, samples: samples}
 	}
-	// Before writing, set center to the number of issues ended for each week (weekly throughput)
-	for i, k := range keys {
-		centers[i] = float64(counts[k])
+	orgs := sortedOrgs(counts)
+	byOrg := map[string]series{}
+	for _, org := range orgs {
+		byOrg[org] = limits(counts[org])
 	}
-	// Remove the last week (current week) from the output
-	if len(keys) > 0 {
+	// Remove the last week (current week) from the output, unless -until ends the range on a finished week
+	lastWeekDone := filter.Until != nil && filter.Until.Before(alignToMonday(now.In(loc)))
+	if !lastWeekDone {
 		keys = keys[:len(keys)-1]
-		centers = centers[:len(centers)-1]
-		ucls = ucls[:len(ucls)-1]
-		lcls = lcls[:len(lcls)-1]
-	}
-	// Write CSV
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return err
-	}
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	w := csv.NewWriter(f)
-	defer w.Flush()
-	headers := []string{"year", "week", "throughput", "center", "ucl", "lcl"}
-	if err := w.Write(headers); err != nil {
-		return err
 	}
+	var out [][]string
 	for i, k := range keys {
-		row := []string{
-			fmt.Sprintf("%d", k.Year),
-			fmt.Sprintf("%02d", k.Week),
-			fmt.Sprintf("%d", counts[k]),
-			fmt.Sprintf("%.6f", centers[i]),
-			fmt.Sprintf("%.6f", ucls[i]),
-			fmt.Sprintf("%.6f", lcls[i]),
-		}
-		if err := w.Write(row); err != nil {
-			return err
-		}
-	}
-	return w.Error()
+		for _, org := range orgs {
+			sr := byOrg[org]
+			var bugRatio *float64
+			if n := counts[org][k]; n > 0 {
+				v := float64(bugs[org][k]) / float64(n)
+				bugRatio = &v
+			}
+			out = append(out, []string{
+				fmt.Sprintf("%d", k.Year),
+				fmt.Sprintf("%02d", k.Week),
+				org,
+				fmt.Sprintf("%d", counts[org][k]),
+				fmt.Sprintf("%.6f", sr.centers[i]),
+				gate.format(sr.samples[i], sr.ucls[i], formatFloat6),
+				gate.format(sr.samples[i], sr.lcls[i], formatFloat6),
+				formatOptionalFloat(sr.rolling[i]),
+				formatOptionalFloat(sr.slopes[i]),
+				formatOptionalFloat(targets.ThroughputPerWeek),
+				formatOptionalFloat(sr.cvs[i]),
+				variabilityClass(sr.cvs[i]),
+				gate.lowConfidence(sr.samples[i]),
+				formatOptionalFloat(bugRatio),
+				formatOptionalFloat(targets.BugRatio),
+			})
+		}
+	}
+	return writeCSVFile(path, headers, out, bom)
 }
 
 // Step 4: stocks for not-closed issues by stage
-func writeStocks(path string, rows []calculatedIssue) error {
+func writeStocks(path string, rows []calculatedIssue, bom bool) error {
 	// aggregate by project
 	type agg struct {
 		OpenedBugs               int
@@ -927,6 +1625,7 @@ func writeStocks(path string, rows []calculatedIssue) error {
 		WaitingToProd            int
 	}
 	byProj := map[string]struct {
+		Org         string
 		ProjectID   string
 		ProjectName string
 		Agg         agg
@@ -964,8 +1663,9 @@ func writeStocks(path string, rows []calculatedIssue) error {
 		if r.EndDatetime != nil {
 			continue // only not-closed
 		}
-		key := r.ProjectID + "\u0000" + r.ProjectName
+		key := r.Org + "\u0000" + r.ProjectID + "\u0000" + r.ProjectName
 		rec := byProj[key]
+		rec.Org = r.Org
 		rec.ProjectID = r.ProjectID
 		rec.ProjectName = r.ProjectName
 		ob, bcf, bi, bd, ib, iready, id, ir, iq, iw := stageFlags(r)
@@ -1005,18 +1705,18 @@ func writeStocks(path string, rows []calculatedIssue) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
-	f, err := os.Create(path)
+	f, err := ccsv.Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	headers := []string{"project_id", "project_name", "opened_bugs", "opened_bugs_customer_facing", "opened_bugs_internal", "opened_bugs_dev_process", "in_backlogs", "in_ready", "in_dev", "in_review", "in_qa", "waiting_to_prod"}
+	headers := schema.Headers("stocks.csv")
 	if err := w.Write(headers); err != nil {
 		return err
 	}
-	// stable order by project_id then name
+	// stable order by org, project_id then name
 	var keys []string
 	for k := range byProj {
 		keys = append(keys, k)
@@ -1025,6 +1725,7 @@ func writeStocks(path string, rows []calculatedIssue) error {
 	for _, k := range keys {
 		rec := byProj[k]
 		row := []string{
+			rec.Org,
 			rec.ProjectID,
 			rec.ProjectName,
 			fmt.Sprintf("%d", rec.Agg.OpenedBugs),
@@ -1045,23 +1746,35 @@ func writeStocks(path string, rows []calculatedIssue) error {
 	return w.Error()
 }
 
-// Step 5: weekly stocks per project and ISO week with Sunday cutoff (UTC)
-func writeWeeklyStocks(path string, rows []calculatedIssue) error {
+// Step 5: weekly stocks per project and ISO week with Sunday cutoff (end of Sunday in loc)
+// The -since/-until window of filter, when set, clamps the range of weeks. Besides the stock levels, each row
+// counts the week's flow: issues created and issues closed (EndDatetime) during it.
+// Every project seen in the range gets a row for every week, zero-filled when it has nothing in stock, so charts
+// have no holes; sparse keeps only the (week, project) pairs with something to count. When no issue moved or
+// closed, the range ends on the week of now.
+func writeWeeklyStocks(path string, rows []calculatedIssue, loc *time.Location, filter issueFilter, sparse bool, now time.Time, bom bool) error {
 	// Determine range of weeks
-	timeUTC := func(t time.Time) time.Time { return t.UTC() }
+	inLoc := func(t time.Time) time.Time { return t.In(loc) }
 	var minT, maxT *time.Time
 	for _, r := range rows {
-		c := timeUTC(r.CreationDatetime)
+		c := inLoc(r.CreationDatetime)
 		if minT == nil || c.Before(*minT) {
 			t := c
 			minT = &t
 		}
 		cands := []*time.Time{r.LeadTimeStartDatetime, r.CycleTimeStartDatetime, r.PutInReadyStartDatetime, r.DevStartDatetime, r.ReviewStartDatetime, r.QAStartDatetime, r.WaitingToPodStartDatetime, r.EndDatetime}
+		for _, p := range r.ClosedPeriods {
+			cands = append(cands, &p.since, p.until)
+		}
+		// the moves of a reopened issue change its stage after its stage timestamps
+		for _, m := range r.StageMoves {
+			cands = append(cands, &m.at)
+		}
 		for _, p := range cands {
 			if p == nil {
 				continue
 			}
-			t := p.UTC()
+			t := inLoc(*p)
 			if maxT == nil || t.After(*maxT) {
 				u := t
 				maxT = &u
@@ -1073,28 +1786,36 @@ func writeWeeklyStocks(path string, rows []calculatedIssue) error {
 		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 			return err
 		}
-		f, err := os.Create(path)
+		f, err := ccsv.Create(path, bom)
 		if err != nil {
 			return err
 		}
 		defer f.Close()
 		w := csv.NewWriter(f)
 		defer w.Flush()
-		headers := []string{"year", "week", "project_id", "project_name", "opened_bugs", "opened_bugs_customer_facing", "opened_bugs_internal", "opened_bugs_dev_process", "in_backlogs", "in_ready", "in_dev", "in_review", "in_qa", "waiting_to_prod"}
+		headers := schema.Headers("stocks_week.csv")
 		if err := w.Write(headers); err != nil {
 			return err
 		}
 		return w.Error()
 	}
 	if maxT == nil {
-		m := time.Now().UTC()
+		m := now.In(loc)
 		maxT = &m
 	}
-	// Align to Monday 00:00 UTC of ISO week
+	if filter.Since != nil && filter.Since.After(*minT) {
+		t := filter.Since.In(loc)
+		minT = &t
+	}
+	if filter.Until != nil {
+		t := filter.Until.In(loc)
+		maxT = &t
+	}
+	// Align to Monday 00:00 (in loc) of ISO week; AddDate keeps wall-clock midnight across DST changes
 	alignToMonday := func(t time.Time) time.Time {
 		wd := int(t.Weekday())
 		offset := (wd + 6) % 7
-		tt := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		tt := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
 		return tt.AddDate(0, 0, -offset)
 	}
 	start := alignToMonday(*minT)
@@ -1112,28 +1833,35 @@ func writeWeeklyStocks(path string, rows []calculatedIssue) error {
 		InReview                 int
 		InQA                     int
 		WaitingToProd            int
+		// Flow: issues created / closed during the week, whether or not they are in stock
+		CreatedInWeek int
+		ClosedInWeek  int
 	}
 	type rec struct {
-		ProjectID, ProjectName string
-		Agg                    agg
+		Org, ProjectID, ProjectName string
+		Agg                         agg
 	}
 	// Helper: determine stage at cutoff
 	stageAt := func(r calculatedIssue, weekStart time.Time, cutoff time.Time) (openedBug bool, bugCF bool, bugInternal bool, bugDev bool, inBacklog bool, inReady bool, inDev bool, inReview bool, inQA bool, waiting bool) {
 		cu := cutoff
 		// Not yet created
-		if timeUTC(r.CreationDatetime).After(cu) {
+		if inLoc(r.CreationDatetime).After(cu) {
 			return false, false, false, false, false, false, false, false, false, false
 		}
-		// If ended before week start, it is not in stock for this week
-		if r.EndDatetime != nil && r.EndDatetime.UTC().Before(weekStart) {
+		// If ended before week start (and not reopened by the cutoff), it is not in stock for this week
+		if r.outOfStock(weekStart, cu) {
 			return false, false, false, false, false, false, false, false, false, false
 		}
-		// Bug is in stock from creation until closure
-		openedBug = r.Bug
-		bugCF = r.BugCustomerFacing
-		bugInternal = r.BugInternal
-		bugDev = r.BugDevProcess
+		// Bug is in stock while it is one (from its bug label or creation) until closure
+		openedBug = bugAt(r.BugPeriods, cu)
+		bugCF = openedBug && r.BugCustomerFacing
+		bugInternal = openedBug && r.BugInternal
+		bugDev = openedBug && r.BugDevProcess
 
+		// Reopened and back in stock: the stage of its latest column move
+		if stage, ok := r.reopenedStageAt(cu); ok {
+			return openedBug, bugCF, bugInternal, bugDev, stage == "in_backlogs", stage == "in_ready", stage == "in_dev", stage == "in_review", stage == "in_qa", stage == "waiting_to_prod"
+		}
 		// Helper to check ts <= cutoff
 		le := func(t *time.Time) bool { return t != nil && !t.UTC().After(cu) }
 		// Furthest stage reached as of cutoff (no later stage timestamp <= cutoff)
@@ -1162,18 +1890,23 @@ func writeWeeklyStocks(path string, rows []calculatedIssue) error {
 	}
 	// Aggregate per week per project
 	byWeekProj := map[wk]map[string]rec{}
+	seen := map[string]rec{}
 	for cur := start; !cur.After(end); cur = cur.AddDate(0, 0, 7) {
-		// Sunday end-of-day cutoff: Monday+6 days 23:59:59.999...
-		cutoff := time.Date(cur.Year(), cur.Month(), cur.Day(), 23, 59, 59, int(time.Second-time.Nanosecond), time.UTC).AddDate(0, 0, 6)
+		// Sunday end-of-day cutoff (in loc): Monday+6 days 23:59:59.999...
+		cutoff := time.Date(cur.Year(), cur.Month(), cur.Day()+6, 23, 59, 59, int(time.Second-time.Nanosecond), loc)
 		y, w := cur.ISOWeek()
 		projMap := map[string]rec{}
+		inWeek := func(t *time.Time) bool { return t != nil && !t.Before(cur) && !t.After(cutoff) }
 		for _, r := range rows {
 			ob, bcf, bi, bd, ib, iready, id, ir, iq, iw := stageAt(r, cur, cutoff)
-			if !(ob || ib || iready || id || ir || iq || iw) {
+			created := inWeek(&r.CreationDatetime)
+			closed := inWeek(r.EndDatetime)
+			if !(ob || ib || iready || id || ir || iq || iw || created || closed) {
 				continue
 			}
-			k := r.ProjectID + "\u0000" + r.ProjectName
+			k := r.Org + "\u0000" + r.ProjectID + "\u0000" + r.ProjectName
 			rr := projMap[k]
+			rr.Org = r.Org
 			rr.ProjectID = r.ProjectID
 			rr.ProjectName = r.ProjectName
 			if ob {
@@ -1206,22 +1939,38 @@ func writeWeeklyStocks(path string, rows []calculatedIssue) error {
 			if iw {
 				rr.Agg.WaitingToProd++
 			}
+			if created {
+				rr.Agg.CreatedInWeek++
+			}
+			if closed {
+				rr.Agg.ClosedInWeek++
+			}
 			projMap[k] = rr
+			seen[k] = rec{Org: r.Org, ProjectID: r.ProjectID, ProjectName: r.ProjectName}
 		}
 		byWeekProj[wk{Year: y, Week: w}] = projMap
 	}
+	if !sparse {
+		for _, projMap := range byWeekProj {
+			for k, zero := range seen {
+				if _, ok := projMap[k]; !ok {
+					projMap[k] = zero
+				}
+			}
+		}
+	}
 	// Write CSV
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
-	f, err := os.Create(path)
+	f, err := ccsv.Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	headers := []string{"year", "week", "project_id", "project_name", "opened_bugs", "opened_bugs_customer_facing", "opened_bugs_internal", "opened_bugs_dev_process", "in_backlogs", "in_ready", "in_dev", "in_review", "in_qa", "waiting_to_prod"}
+	headers := schema.Headers("stocks_week.csv")
 	if err := w.Write(headers); err != nil {
 		return err
 	}
@@ -1249,6 +1998,7 @@ func writeWeeklyStocks(path string, rows []calculatedIssue) error {
 			row := []string{
 				fmt.Sprintf("%d", k.Year),
 				fmt.Sprintf("%02d", k.Week),
+				rec.Org,
 				rec.ProjectID,
 				rec.ProjectName,
 				fmt.Sprintf("%d", rec.Agg.OpenedBugs),
@@ -1261,6 +2011,8 @@ func writeWeeklyStocks(path string, rows []calculatedIssue) error {
 				fmt.Sprintf("%d", rec.Agg.InReview),
 				fmt.Sprintf("%d", rec.Agg.InQA),
 				fmt.Sprintf("%d", rec.Agg.WaitingToProd),
+				fmt.Sprintf("%d", rec.Agg.CreatedInWeek),
+				fmt.Sprintf("%d", rec.Agg.ClosedInWeek),
 			}
 			if err := w.Write(row); err != nil {
 				return err
@@ -1274,7 +2026,7 @@ func writeWeeklyStocks(path string, rows []calculatedIssue) error {
 // Reads PRs from pr.csv and reviews from pr_review.csv in baseDir, computes per-week stats
 // for PRs opened in each ISO week: average, median, and 90th percentile of the number of
 // CHANGES_REQUESTED reviews per PR.
-func writePRChangeRequestsWeekly(outPath string, baseDir string) error {
+func writePRChangeRequestsWeekly(outPath string, baseDir string, mode string, roundGap time.Duration, loc *time.Location, gate sampleGate, bom bool) error {
 	// Collect PR created_at keyed by org/repo#number
 	type pr struct {
 		Org, Repo, Number string
@@ -1316,14 +2068,14 @@ func writePRChangeRequestsWeekly(outPath string, baseDir string) error {
 		if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
 			return err
 		}
-		f, err := os.Create(outPath)
+		f, err := ccsv.Create(outPath, bom)
 		if err != nil {
 			return err
 		}
 		defer f.Close()
 		w := csv.NewWriter(f)
 		defer w.Flush()
-		if err := w.Write([]string{"year", "week", "repo", "avg", "median", "p90", "pr_count", "cr_total"}); err != nil {
+		if err := w.Write(schema.Headers("pr_change_requests_week.csv")); err != nil {
 			return err
 		}
 		return w.Error()
@@ -1331,41 +2083,15 @@ func writePRChangeRequestsWeekly(outPath string, baseDir string) error {
 		return err
 	}
 	// Read reviews and count CHANGES_REQUESTED per PR
-	reqCount := map[string]int{}
-	{
-		path := filepath.Join(baseDir, "pr_review.csv")
-		f, err := os.Open(path)
-		if err == nil {
-			defer f.Close()
-			r := csv.NewReader(f)
-			head, err := r.Read()
-			if err == nil {
-				idx := indexMap(head)
-				for {
-					rec, err := r.Read()
-					if err != nil {
-						if err.Error() == "EOF" {
-							break
-						}
-						return err
-					}
-					org := rec[idx["org"]]
-					repo := rec[idx["repo"]]
-					num := rec[idx["number"]]
-					state := strings.TrimSpace(strings.ToUpper(rec[idx["state"]]))
-					if state == "CHANGES_REQUESTED" {
-						k := key(org, repo, num)
-						reqCount[k]++
-					}
-				}
-			}
-		}
+	reqCount, err := readChangeRequestCounts(baseDir, mode, roundGap)
+	if err != nil {
+		return err
 	}
 	// Group PRs by ISO week of CreatedAt and repo
 	type wk struct{ Year, Week int }
 	byWeekRepo := map[wk]map[string][]int{}
 	for _, p := range prs {
-		y, w := p.CreatedAt.UTC().ISOWeek()
+		y, w := p.CreatedAt.In(loc).ISOWeek()
 		cnt := reqCount[key(p.Org, p.Repo, p.Number)]
 		k := wk{Year: y, Week: w}
 		m := byWeekRepo[k]
@@ -1390,14 +2116,14 @@ func writePRChangeRequestsWeekly(outPath string, baseDir string) error {
 	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
 		return err
 	}
-	f, err := os.Create(outPath)
+	f, err := ccsv.Create(outPath, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	if err := w.Write([]string{"year", "week", "repo", "avg", "median", "p90", "pr_count", "cr_total"}); err != nil {
+	if err := w.Write(schema.Headers("pr_change_requests_week.csv")); err != nil {
 		return err
 	}
 	// Helper to write a row given values
@@ -1431,10 +2157,11 @@ func writePRChangeRequestsWeekly(outPath string, baseDir string) error {
 			fmt.Sprintf("%02d", week),
 			repo,
 			fmt.Sprintf("%.6f", avg),
-			fmt.Sprintf("%.6f", med),
-			fmt.Sprintf("%.6f", p90),
+			gate.format(n, med, formatFloat6),
+			gate.format(n, p90, formatFloat6),
 			fmt.Sprintf("%d", n),
 			fmt.Sprintf("%d", sum),
+			gate.lowConfidence(n),
 		}
 		return w.Write(row)
 	}
@@ -1464,7 +2191,7 @@ func writePRChangeRequestsWeekly(outPath string, baseDir string) error {
 // PR change-requests per-repo calculation
 // Reads PRs from pr.csv and reviews from pr_review.csv in baseDir, computes per-repo
 // median number of CHANGES_REQUESTED per PR and writes one line per repo.
-func writePRChangeRequestsPerRepo(outPath string, baseDir string) error {
+func writePRChangeRequestsPerRepo(outPath string, baseDir string, mode string, roundGap time.Duration, gate sampleGate, bom bool) error {
 	// Read PRs
 	type pr struct{ Org, Repo, Number string }
 	prsByRepo := map[string][]pr{}
@@ -1475,14 +2202,14 @@ func writePRChangeRequestsPerRepo(outPath string, baseDir string) error {
 			if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
 				return err
 			}
-			out, err := os.Create(outPath)
+			out, err := ccsv.Create(outPath, bom)
 			if err != nil {
 				return err
 			}
 			defer out.Close()
 			w := csv.NewWriter(out)
 			defer w.Flush()
-			if err := w.Write([]string{"repo", "median", "pr_count", "cr_total"}); err != nil {
+			if err := w.Write(schema.Headers("pr_change_requests_repo.csv")); err != nil {
 				return err
 			}
 			return w.Error()
@@ -1508,31 +2235,9 @@ func writePRChangeRequestsPerRepo(outPath string, baseDir string) error {
 		prsByRepo[p.Repo] = append(prsByRepo[p.Repo], p)
 	}
 	// Read reviews -> count CHANGES_REQUESTED per PR
-	reqCount := map[string]int{}
-	{
-		path := filepath.Join(baseDir, "pr_review.csv")
-		if rf, err := os.Open(path); err == nil {
-			defer rf.Close()
-			rr := csv.NewReader(rf)
-			head, err := rr.Read()
-			if err == nil {
-				idx := indexMap(head)
-				for {
-					rec, err := rr.Read()
-					if err != nil {
-						if err.Error() == "EOF" {
-							break
-						}
-						return err
-					}
-					state := strings.TrimSpace(strings.ToUpper(rec[idx["state"]]))
-					if state == "CHANGES_REQUESTED" {
-						k := key(rec[idx["org"]], rec[idx["repo"]], rec[idx["number"]])
-						reqCount[k]++
-					}
-				}
-			}
-		}
+	reqCount, err := readChangeRequestCounts(baseDir, mode, roundGap)
+	if err != nil {
+		return err
 	}
 	// Build counts per repo
 	type stat struct {
@@ -1552,14 +2257,14 @@ func writePRChangeRequestsPerRepo(outPath string, baseDir string) error {
 	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
 		return err
 	}
-	out, err := os.Create(outPath)
+	out, err := ccsv.Create(outPath, bom)
 	if err != nil {
 		return err
 	}
 	defer out.Close()
 	w := csv.NewWriter(out)
 	defer w.Flush()
-	if err := w.Write([]string{"repo", "median", "pr_count", "cr_total"}); err != nil {
+	if err := w.Write(schema.Headers("pr_change_requests_repo.csv")); err != nil {
 		return err
 	}
 	for _, s := range stats {
@@ -1579,7 +2284,7 @@ func writePRChangeRequestsPerRepo(outPath string, baseDir string) error {
 		} else {
 			med = (float64(vals[n/2-1]) + float64(vals[n/2])) / 2.0
 		}
-		row := []string{s.repo, fmt.Sprintf("%.6f", med), fmt.Sprintf("%d", n), fmt.Sprintf("%d", sum)}
+		row := []string{s.repo, gate.format(n, med, formatFloat6), fmt.Sprintf("%d", n), fmt.Sprintf("%d", sum), gate.lowConfidence(n)}
 		if err := w.Write(row); err != nil {
 			return err
 		}
@@ -1589,7 +2294,7 @@ func writePRChangeRequestsPerRepo(outPath string, baseDir string) error {
 
 // PR change-requests per-repo distribution
 // Writes rows: repo, cr (number of change requests), pr_count (number of PRs with that count)
-func writePRChangeRequestsRepoDist(outPath string, baseDir string) error {
+func writePRChangeRequestsRepoDist(outPath string, baseDir string, mode string, roundGap time.Duration, bom bool) error {
 	// Reuse the same reading of PRs
 	type pr struct{ Org, Repo, Number string }
 	var prs []pr
@@ -1600,14 +2305,14 @@ func writePRChangeRequestsRepoDist(outPath string, baseDir string) error {
 			if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
 				return err
 			}
-			out, err := os.Create(outPath)
+			out, err := ccsv.Create(outPath, bom)
 			if err != nil {
 				return err
 			}
 			defer out.Close()
 			w := csv.NewWriter(out)
 			defer w.Flush()
-			if err := w.Write([]string{"repo", "cr", "pr_count"}); err != nil {
+			if err := w.Write(schema.Headers("pr_change_requests_repo_dist.csv")); err != nil {
 				return err
 			}
 			return w.Error()
@@ -1632,31 +2337,9 @@ func writePRChangeRequestsRepoDist(outPath string, baseDir string) error {
 		prs = append(prs, pr{Org: rec[idx["org"]], Repo: rec[idx["repo"]], Number: rec[idx["number"]]})
 	}
 	// Count CHANGES_REQUESTED per PR
-	reqCount := map[string]int{}
-	{
-		path := filepath.Join(baseDir, "pr_review.csv")
-		if rf, err := os.Open(path); err == nil {
-			defer rf.Close()
-			rr := csv.NewReader(rf)
-			head, err := rr.Read()
-			if err == nil {
-				idx := indexMap(head)
-				for {
-					rec, err := rr.Read()
-					if err != nil {
-						if err.Error() == "EOF" {
-							break
-						}
-						return err
-					}
-					state := strings.TrimSpace(strings.ToUpper(rec[idx["state"]]))
-					if state == "CHANGES_REQUESTED" {
-						k := key(rec[idx["org"]], rec[idx["repo"]], rec[idx["number"]])
-						reqCount[k]++
-					}
-				}
-			}
-		}
+	reqCount, err := readChangeRequestCounts(baseDir, mode, roundGap)
+	if err != nil {
+		return err
 	}
 	// Build histogram per repo
 	byRepo := map[string]map[int]int{}
@@ -1678,14 +2361,14 @@ func writePRChangeRequestsRepoDist(outPath string, baseDir string) error {
 	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
 		return err
 	}
-	out, err := os.Create(outPath)
+	out, err := ccsv.Create(outPath, bom)
 	if err != nil {
 		return err
 	}
 	defer out.Close()
 	w := csv.NewWriter(out)
 	defer w.Flush()
-	if err := w.Write([]string{"repo", "cr", "pr_count"}); err != nil {
+	if err := w.Write(schema.Headers("pr_change_requests_repo_dist.csv")); err != nil {
 		return err
 	}
 	for _, repo := range repos {
@@ -1707,7 +2390,7 @@ func writePRChangeRequestsRepoDist(outPath string, baseDir string) error {
 }
 
 // runCloudSpendingCalculate aggregates cloud spending data
-func runCloudSpendingCalculate() error {
+func runCloudSpendingCalculate(bom bool) error {
 	slog.Info("cloudspending.calculate.start")
 
 	// Read config for service filter
@@ -1719,10 +2402,14 @@ func runCloudSpendingCalculate() error {
 	var serviceFilter []string
 	var groups []config.DetailedServiceGroup
 	var compared []config.ComparedService
+	var fiscalStart int
+	var includeOther bool
 	if _, err := os.Stat(cfgPath); err == nil {
 		cfg, err := config.Load(cfgPath)
 		if err == nil {
+			fiscalStart = cfg.FiscalYearStartMonth
 			serviceFilter = cfg.CloudSpending.Services
+			includeOther = cfg.CloudSpending.IncludeOther
 			if len(cfg.CloudSpending.DetailedService) > 0 {
 				groups = cfg.CloudSpending.DetailedService
 			}
@@ -1746,14 +2433,35 @@ func runCloudSpendingCalculate() error {
 
 	// Aggregate per provider per month
 	monthlyPath := filepath.Join("data", "cloud_spending_monthly.csv")
-	if err := writeCloudSpendingMonthly(monthlyPath, records); err != nil {
+	if err := writeCloudSpendingMonthly(monthlyPath, records, bom); err != nil {
 		return fmt.Errorf("failed to write monthly aggregation: %w", err)
 	}
 	slog.Info("cloudspending.calculate.monthly.done", "output", monthlyPath)
 
+	// Same totals per fiscal quarter for board reporting
+	quarterPath := filepath.Join("data", "cloud_spending_quarter.csv")
+	if err := writeCloudSpendingQuarterly(quarterPath, records, fiscalStart, bom); err != nil {
+		return fmt.Errorf("failed to write quarterly aggregation: %w", err)
+	}
+	slog.Info("cloudspending.calculate.quarter.done", "output", quarterPath)
+
+	// This month against the same month last year
+	yoyPath := filepath.Join("data", "cloud_spending_yoy.csv")
+	if err := writeCloudSpendingYoY(yoyPath, records, bom); err != nil {
+		return fmt.Errorf("failed to write year-over-year comparison: %w", err)
+	}
+	slog.Info("cloudspending.calculate.yoy.done", "output", yoyPath)
+
+	// Production versus non-production spending
+	envPath := filepath.Join("data", "cloud_spending_by_environment.csv")
+	if err := writeCloudSpendingByEnvironment(envPath, records, bom); err != nil {
+		return fmt.Errorf("failed to write environment aggregation: %w", err)
+	}
+	slog.Info("cloudspending.calculate.environment.done", "output", envPath)
+
 	// Aggregate per service group per month (if groups provided) or per service (filtered)
 	servicesPath := filepath.Join("data", "cloud_spending_services.csv")
-	if err := writeCloudSpendingServices(servicesPath, records, groups, serviceFilter); err != nil {
+	if err := writeCloudSpendingServices(servicesPath, records, groups, serviceFilter, includeOther, bom); err != nil {
 		return fmt.Errorf("failed to write services aggregation: %w", err)
 	}
 	slog.Info("cloudspending.calculate.services.done", "output", servicesPath)
@@ -1761,7 +2469,7 @@ func runCloudSpendingCalculate() error {
 	// Aggregate compared services
 	if len(compared) > 0 {
 		comparedPath := filepath.Join("data", "cloud_spending_compared.csv")
-		if err := writeCloudSpendingCompared(comparedPath, records, compared); err != nil {
+		if err := writeCloudSpendingCompared(comparedPath, records, compared, bom); err != nil {
 			return fmt.Errorf("failed to write compared aggregation: %w", err)
 		}
 		slog.Info("cloudspending.calculate.compared.done", "output", comparedPath)
@@ -1771,12 +2479,16 @@ func runCloudSpendingCalculate() error {
 	return nil
 }
 
+// otherServiceGroup names the services output row of the costs left out by the configured groups or services.
+const otherServiceGroup = "__other__"
+
 type cloudCostRecord struct {
-	Provider string
-	Service  string
-	Month    time.Time
-	Cost     float64
-	Currency string
+	Provider    string
+	Service     string
+	Month       time.Time
+	Cost        float64
+	Currency    string
+	Environment string // empty for unmapped accounts and older cloud_costs.csv files
 }
 
 // readCloudCosts reads the cloud_costs.csv file
@@ -1821,11 +2533,12 @@ func readCloudCosts(path string) ([]cloudCostRecord, error) {
 			currency = row[idx["currency"]]
 		}
 		records = append(records, cloudCostRecord{
-			Provider: row[idx["provider"]],
-			Service:  row[idx["service"]],
-			Month:    month,
-			Cost:     cost,
-			Currency: currency,
+			Provider:    row[idx["provider"]],
+			Service:     row[idx["service"]],
+			Month:       month,
+			Cost:        cost,
+			Currency:    currency,
+			Environment: field(idx, row, "environment"),
 		})
 	}
 
@@ -1833,7 +2546,7 @@ func readCloudCosts(path string) ([]cloudCostRecord, error) {
 }
 
 // writeCloudSpendingMonthly aggregates costs per provider per month
-func writeCloudSpendingMonthly(path string, records []cloudCostRecord) error {
+func writeCloudSpendingMonthly(path string, records []cloudCostRecord, bom bool) error {
 	// Aggregate by provider, month and currency to avoid mixing currencies
 	type key struct {
 		Provider string
@@ -1882,7 +2595,7 @@ func writeCloudSpendingMonthly(path string, records []cloudCostRecord) error {
 		return err
 	}
 
-	f, err := os.Create(path)
+	f, err := ccsv.Create(path, bom)
 	if err != nil {
 		return err
 	}
@@ -1891,7 +2604,7 @@ func writeCloudSpendingMonthly(path string, records []cloudCostRecord) error {
 	w := csv.NewWriter(f)
 	defer w.Flush()
 
-	if err := w.Write([]string{"month", "provider", "cost", "currency"}); err != nil {
+	if err := w.Write(schema.Headers("cloud_spending_monthly.csv")); err != nil {
 		return err
 	}
 
@@ -1905,8 +2618,9 @@ func writeCloudSpendingMonthly(path string, records []cloudCostRecord) error {
 }
 
 // writeCloudSpendingServices aggregates costs per logical group per month if groups provided,
-// else per service (optionally filtered by serviceFilter).
-func writeCloudSpendingServices(path string, records []cloudCostRecord, groups []config.DetailedServiceGroup, serviceFilter []string) error {
+// else per service (optionally filtered by serviceFilter). share_pct is the share of the provider-month total
+// (per currency, including the services left out); with includeOther, those left-out costs get an __other__ row.
+func writeCloudSpendingServices(path string, records []cloudCostRecord, groups []config.DetailedServiceGroup, serviceFilter []string, includeOther bool, bom bool) error {
 	// Build quick lookup: service -> group name
 	serviceToGroup := make(map[string]string)
 	if len(groups) > 0 {
@@ -1932,20 +2646,27 @@ func writeCloudSpendingServices(path string, records []cloudCostRecord, groups [
 		Currency string
 	}
 	agg := make(map[key]float64)
+	// Provider-month totals per currency, services left out included
+	totals := make(map[key]float64)
 
 	for _, r := range records {
 		month := r.Month.Format("2006-01")
 		currency := strings.TrimSpace(r.Currency)
+		totals[key{Provider: r.Provider, Month: month, Currency: currency}] += r.Cost
 		name := r.Service
 		if len(serviceToGroup) > 0 {
-			// Skip services that are not part of any group
+			// Services that are not part of any group only count as other
 			gname, ok := serviceToGroup[r.Service]
 			if !ok || gname == "" {
-				continue
+				name = otherServiceGroup
+			} else {
+				name = gname
 			}
-			name = gname
 		} else if len(filterSet) > 0 && !filterSet[r.Service] {
 			// No groups: apply flat filter
+			name = otherServiceGroup
+		}
+		if name == otherServiceGroup && !includeOther {
 			continue
 		}
 
@@ -1965,15 +2686,21 @@ func writeCloudSpendingServices(path string, records []cloudCostRecord, groups [
 		Name     string
 		Cost     float64
 		Currency string
+		Share    string
 	}
 	var rows []row
 	for k, cost := range agg {
+		share := ""
+		if total := totals[key{Provider: k.Provider, Month: k.Month, Currency: k.Currency}]; total != 0 {
+			share = fmt.Sprintf("%.2f", cost/total*100)
+		}
 		rows = append(rows, row{
 			Month:    k.Month,
 			Provider: k.Provider,
 			Name:     k.Name,
 			Cost:     cost,
 			Currency: k.Currency,
+			Share:    share,
 		})
 	}
 	sort.Slice(rows, func(i, j int) bool {
@@ -1984,6 +2711,10 @@ func writeCloudSpendingServices(path string, records []cloudCostRecord, groups [
 			return rows[i].Provider < rows[j].Provider
 		}
 		if rows[i].Name != rows[j].Name {
+			// __other__ last
+			if (rows[i].Name == otherServiceGroup) != (rows[j].Name == otherServiceGroup) {
+				return rows[j].Name == otherServiceGroup
+			}
 			return rows[i].Name < rows[j].Name
 		}
 		return rows[i].Currency < rows[j].Currency
@@ -1994,7 +2725,7 @@ func writeCloudSpendingServices(path string, records []cloudCostRecord, groups [
 		return err
 	}
 
-	f, err := os.Create(path)
+	f, err := ccsv.Create(path, bom)
 	if err != nil {
 		return err
 	}
@@ -2005,20 +2736,22 @@ func writeCloudSpendingServices(path string, records []cloudCostRecord, groups [
 
 	// Header: if grouped, use "group" column; else keep legacy "service"
 	if len(serviceToGroup) > 0 {
-		if err := w.Write([]string{"month", "provider", "group", "cost", "currency"}); err != nil {
+		if err := w.Write(schema.Headers("cloud_spending_services.csv")); err != nil {
 			return err
 		}
 		for _, r := range rows {
-			if err := w.Write([]string{r.Month, r.Provider, r.Name, fmt.Sprintf("%.2f", r.Cost), r.Currency}); err != nil {
+			if err := w.Write([]string{r.Month, r.Provider, r.Name, fmt.Sprintf("%.2f", r.Cost), r.Currency, r.Share}); err != nil {
 				return err
 			}
 		}
 	} else {
-		if err := w.Write([]string{"month", "provider", "service", "cost", "currency"}); err != nil {
+		headers := schema.Headers("cloud_spending_services.csv")
+		headers[2] = "service"
+		if err := w.Write(headers); err != nil {
 			return err
 		}
 		for _, r := range rows {
-			if err := w.Write([]string{r.Month, r.Provider, r.Name, fmt.Sprintf("%.2f", r.Cost), r.Currency}); err != nil {
+			if err := w.Write([]string{r.Month, r.Provider, r.Name, fmt.Sprintf("%.2f", r.Cost), r.Currency, r.Share}); err != nil {
 				return err
 			}
 		}
@@ -2027,7 +2760,7 @@ func writeCloudSpendingServices(path string, records []cloudCostRecord, groups [
 	return w.Error()
 }
 
-func writeCloudSpendingCompared(path string, records []cloudCostRecord, comparisons []config.ComparedService) error {
+func writeCloudSpendingCompared(path string, records []cloudCostRecord, comparisons []config.ComparedService, bom bool) error {
 	// Build map: comparison_name -> service -> group_name
 	compToServiceToGroup := make(map[string]map[string]string)
 	for _, comp := range comparisons {
@@ -2103,7 +2836,7 @@ func writeCloudSpendingCompared(path string, records []cloudCostRecord, comparis
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
 	}
-	f, err := os.Create(path)
+	f, err := ccsv.Create(path, bom)
 	if err != nil {
 		return err
 	}
@@ -2112,7 +2845,7 @@ func writeCloudSpendingCompared(path string, records []cloudCostRecord, comparis
 	w := csv.NewWriter(f)
 	defer w.Flush()
 
-	if err := w.Write([]string{"comparison", "month", "group", "cost", "currency"}); err != nil {
+	if err := w.Write(schema.Headers("cloud_spending_compared.csv")); err != nil {
 		return err
 	}
 	for _, r := range rows {
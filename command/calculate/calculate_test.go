@@ -0,0 +1,142 @@
+package calculate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cto-stats/connectors/config"
+)
+
+func TestWriteWeeklyStocksAcrossDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Paris moves to summer time on Sunday 2025-03-30: 21:30Z is still Sunday (week 13) but 22:30Z is Monday
+	// 00:30 (week 14), while a week earlier 22:30Z was Sunday 23:30 (week 12)
+	var rows []calculatedIssue
+	for _, created := range []string{"2025-03-23T22:30:00Z", "2025-03-30T21:30:00Z", "2025-03-30T22:30:00Z"} {
+		c, err := time.Parse(time.RFC3339, created)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, calculatedIssue{Org: "o", ProjectID: "P1", ProjectName: "Platform", CreationDatetime: c})
+	}
+	until := time.Date(2025, 4, 1, 0, 0, 0, 0, paris)
+	tests := []struct {
+		name string
+		loc  *time.Location
+		want string
+	}{
+		{
+			name: "Europe/Paris",
+			loc:  paris,
+			want: "2025,12,o,P1,Platform,0,0,0,0,1,0,0,0,0,0,1,0\n" +
+				"2025,13,o,P1,Platform,0,0,0,0,2,0,0,0,0,0,1,0\n" +
+				"2025,14,o,P1,Platform,0,0,0,0,3,0,0,0,0,0,1,0\n",
+		},
+		{
+			name: "UTC",
+			loc:  time.UTC,
+			want: "2025,12,o,P1,Platform,0,0,0,0,1,0,0,0,0,0,1,0\n" +
+				"2025,13,o,P1,Platform,0,0,0,0,3,0,0,0,0,0,2,0\n" +
+				"2025,14,o,P1,Platform,0,0,0,0,3,0,0,0,0,0,0,0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := writeWeeklyStocks(filepath.Join(dir, "stocks_week.csv"), rows, tt.loc, issueFilter{Until: &until}, false, time.Now(), false); err != nil {
+				t.Fatal(err)
+			}
+			got := strings.ReplaceAll(readTestFile(t, dir, "stocks_week.csv"), "\r\n", "\n")
+			_, got, _ = strings.Cut(got, "\n")
+			if got != tt.want {
+				t.Errorf("stocks_week.csv rows:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssueURLWithComma(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(filepath.Join("testdata", "multi", "product"))); err != nil {
+		t.Fatal(err)
+	}
+	// the URLs of acme/api#1 (closed) and #3 (open) hold a comma, quoted in issue.csv
+	url1, url3 := "https://github.com/acme/api/issues/1?q=a,b", "https://github.com/acme/api/issues/3?q=c,d"
+	issues := readTestFile(t, dir, "issue.csv")
+	issues = strings.Replace(issues, "https://github.com/acme/api/issues/1,", `"`+url1+`",`, 1)
+	issues = strings.Replace(issues, "https://github.com/acme/api/issues/3,", `"`+url3+`",`, 1)
+	writeTestFile(t, dir, "issue.csv", issues)
+	writeTestFile(t, dir, "config.yml", "github:\n  org: acme\ncycle_scatter:\n  weeks: 100000\n")
+	t.Setenv("CONFIG_PATH", filepath.Join(dir, "config.yml"))
+	t.Chdir(dir)
+	if err := Run([]string{"-data", ".", "-out", "out", "-issues"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		file string
+		id   string
+		want string
+	}{
+		{"calculated_issue.csv", "acme/api#1", url1},
+		{"calculated_issue.csv", "acme/api#3", url3},
+		{"calculated_issue.csv", "acme/web#5", "https://github.com/acme/web/issues/5"},
+		{"stocks_detail.csv", "acme/api#3", url3},
+		{"cycle_scatter.csv", "acme/api#1", url1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file+" "+tt.id, func(t *testing.T) {
+			urls := readColumnOf(t, filepath.Join("out", tt.file), "id", "url")
+			if got, ok := urls[tt.id]; !ok || got != tt.want {
+				t.Errorf("url %q, want %q", got, tt.want)
+			}
+		})
+	}
+	// the columns after url are read at the right index too
+	if repos := readColumnOf(t, filepath.Join("out", "calculated_issue.csv"), "id", "repo"); repos["acme/api#1"] != "api" {
+		t.Errorf("repo of acme/api#1 %q, want api", repos["acme/api#1"])
+	}
+}
+
+func TestWriteWeeklyThroughputBugRatio(t *testing.T) {
+	org, project := 0.2, 0.5
+	targets := resolveTargets(config.Targets{
+		TargetValues: config.TargetValues{BugRatio: &org},
+		Projects:     []config.ProjectTargets{{Project: "Platform", TargetValues: config.TargetValues{BugRatio: &project}}},
+	}, "platform")
+	// week 10 closes a bug and two other issues, week 11 nothing, week 12 is the running week
+	var rows []calculatedIssue
+	for _, c := range []struct {
+		end string
+		bug bool
+	}{{"2025-03-03T10:00:00Z", true}, {"2025-03-04T10:00:00Z", false}, {"2025-03-05T10:00:00Z", false}, {"2025-03-17T10:00:00Z", true}} {
+		end, err := time.Parse(time.RFC3339, c.end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, calculatedIssue{Org: "o", EndDatetime: &end, Bug: c.bug})
+	}
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 18, 0, 0, 0, 0, time.UTC)
+	if err := writeWeeklyThroughput(filepath.Join(dir, "t.csv"), rows, time.UTC, issueFilter{}, targets, 0, now, false); err != nil {
+		t.Fatal(err)
+	}
+	idx, got, err := readCSVFile(filepath.Join(dir, "t.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"0.333333", "0.500000"}, {"0.333333", "0.500000"}, {"", "0.500000"}, {"", "0.500000"}}
+	if len(got) != len(want) {
+		t.Fatalf("%d rows, want %d", len(got), len(want))
+	}
+	for i, rec := range got {
+		if g := [2]string{field(idx, rec, "bug_ratio"), field(idx, rec, "bug_ratio_target")}; g != want[i] {
+			t.Errorf("week %s %s: bug_ratio, bug_ratio_target %q, want %q", field(idx, rec, "week"), field(idx, rec, "org"), g, want[i])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// writeCloseAge writes, per closing month (in loc) and repository plus an ALL row, the p50/p85/p95 of the days
+// between created_at and closed_at of closed issues. It only uses issue.csv, so unlike cycle time it needs no
+// project column mapping and serves as a baseline to sanity-check the stage-based metrics.
+func writeCloseAge(path string, issues map[string]issueRow, loc *time.Location, bom bool) error {
+	byMonth := map[string]map[string][]float64{}
+	for _, is := range issues {
+		if is.ClosedAt == nil || is.ClosedAt.Before(is.CreatedAt) {
+			continue
+		}
+		m := is.ClosedAt.In(loc).Format("2006-01")
+		if byMonth[m] == nil {
+			byMonth[m] = map[string][]float64{}
+		}
+		days := is.ClosedAt.Sub(is.CreatedAt).Hours() / 24
+		byMonth[m][is.Repo] = append(byMonth[m][is.Repo], days)
+		byMonth[m]["ALL"] = append(byMonth[m]["ALL"], days)
+	}
+	months := make([]string, 0, len(byMonth))
+	for m := range byMonth {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	var out [][]string
+	for _, m := range months {
+		repos := make([]string, 0, len(byMonth[m]))
+		for r := range byMonth[m] {
+			if r != "ALL" {
+				repos = append(repos, r)
+			}
+		}
+		sort.Strings(repos)
+		for _, r := range append(repos, "ALL") {
+			days := byMonth[m][r]
+			out = append(out, []string{
+				m,
+				r,
+				fmt.Sprintf("%d", len(days)),
+				fmt.Sprintf("%.6f", percentile(days, 0.50)),
+				fmt.Sprintf("%.6f", percentile(days, 0.85)),
+				fmt.Sprintf("%.6f", percentile(days, 0.95)),
+			})
+		}
+	}
+	return writeCSVFile(path, schema.Headers("close_age.csv"), out, bom)
+}
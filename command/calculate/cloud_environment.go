@@ -0,0 +1,49 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"cto-stats/domain/schema"
+)
+
+// unmappedEnvironment is the environment of the costs of accounts missing from cloud_spending.environments.
+const unmappedEnvironment = "unmapped"
+
+// writeCloudSpendingByEnvironment sums the costs per month, environment (tagged by import, unmappedEnvironment
+// when untagged) and currency, so non-production spending can be followed. Currencies are never mixed.
+func writeCloudSpendingByEnvironment(path string, records []cloudCostRecord, bom bool) error {
+	type key struct{ Month, Environment, Currency string }
+	agg := map[key]float64{}
+	for _, r := range records {
+		env := strings.TrimSpace(r.Environment)
+		if env == "" {
+			env = unmappedEnvironment
+		}
+		agg[key{r.Month.Format("2006-01"), env, strings.TrimSpace(r.Currency)}] += r.Cost
+	}
+	keys := make([]key, 0, len(agg))
+	for k := range agg {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		// unmapped last
+		if (a.Environment == unmappedEnvironment) != (b.Environment == unmappedEnvironment) {
+			return b.Environment == unmappedEnvironment
+		}
+		return a.Environment < b.Environment
+	})
+	out := make([][]string, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, []string{k.Month, k.Environment, fmt.Sprintf("%.2f", agg[k]), k.Currency})
+	}
+	return writeCSVFile(path, schema.Headers("cloud_spending_by_environment.csv"), out, bom)
+}
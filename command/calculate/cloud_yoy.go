@@ -0,0 +1,72 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// writeCloudSpendingYoY compares, per month, provider and currency plus an ALL-providers row, the cost of the month
+// with the cost of the same month one year earlier. As in cloud_spending_monthly.csv currencies are never mixed,
+// so each currency gets its own rows. Months without a prior-year counterpart are skipped; change_pct is empty
+// when the prior-year cost is zero.
+func writeCloudSpendingYoY(path string, records []cloudCostRecord, bom bool) error {
+	type key struct {
+		Month    string
+		Provider string
+		Currency string
+	}
+	agg := map[key]float64{}
+	for _, r := range records {
+		m := r.Month.Format("2006-01")
+		cur := strings.TrimSpace(r.Currency)
+		agg[key{Month: m, Provider: r.Provider, Currency: cur}] += r.Cost
+		agg[key{Month: m, Provider: "ALL", Currency: cur}] += r.Cost
+	}
+	keys := make([]key, 0, len(agg))
+	for k := range agg {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		if (a.Provider == "ALL") != (b.Provider == "ALL") {
+			return b.Provider == "ALL"
+		}
+		return a.Provider < b.Provider
+	})
+	var out [][]string
+	for _, k := range keys {
+		m, err := time.Parse("2006-01", k.Month)
+		if err != nil {
+			continue
+		}
+		prior, ok := agg[key{Month: m.AddDate(-1, 0, 0).Format("2006-01"), Provider: k.Provider, Currency: k.Currency}]
+		if !ok {
+			continue
+		}
+		cost := agg[k]
+		pct := ""
+		if prior != 0 {
+			pct = fmt.Sprintf("%.2f", (cost-prior)/prior*100)
+		}
+		out = append(out, []string{
+			k.Month,
+			k.Provider,
+			k.Currency,
+			fmt.Sprintf("%.2f", cost),
+			fmt.Sprintf("%.2f", prior),
+			fmt.Sprintf("%.2f", cost-prior),
+			pct,
+		})
+	}
+	return writeCSVFile(path, schema.Headers("cloud_spending_yoy.csv"), out, bom)
+}
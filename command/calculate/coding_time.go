@@ -0,0 +1,114 @@
+package calculate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// writeCodingTime writes, per issue closed by at least one pull request (pr_issue_link.csv), the coding time of
+// its first linked PR (created_at to merged_at, from pr.csv) next to the dev-to-review stage time of the issue
+// (calculated_issue.csv in outDir). The git-based measure does not depend on how well the board is kept up to
+// date. Missing link or PR files yield a headers-only output.
+func writeCodingTime(path, baseDir, outDir string, bom bool) error {
+	headers := schema.Headers("coding_time.csv")
+	idx, rows, err := readCSVFile(filepath.Join(baseDir, "pr_issue_link.csv"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return writeCSVFile(path, headers, nil, bom)
+		}
+		return err
+	}
+	issuePRs := map[string][]string{}
+	for _, rec := range rows {
+		prID := key(field(idx, rec, "org"), field(idx, rec, "repo"), field(idx, rec, "number"))
+		issueID := key(field(idx, rec, "issue_org"), field(idx, rec, "issue_repo"), field(idx, rec, "issue_number"))
+		issuePRs[issueID] = append(issuePRs[issueID], prID)
+	}
+
+	type pr struct {
+		createdAt time.Time
+		mergedAt  *time.Time
+	}
+	prs := map[string]pr{}
+	idx, rows, err = readCSVFile(filepath.Join(baseDir, "pr.csv"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, rec := range rows {
+		created, err := time.Parse(time.RFC3339, field(idx, rec, "created_at"))
+		if err != nil {
+			continue
+		}
+		prs[key(field(idx, rec, "org"), field(idx, rec, "repo"), field(idx, rec, "number"))] = pr{createdAt: created, mergedAt: parseOptionalTime(field(idx, rec, "merged_at"))}
+	}
+
+	// dev-to-review stage time per issue
+	stage := map[string]*float64{}
+	idx, rows, err = readCSVFile(filepath.Join(outDir, "calculated_issue.csv"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, rec := range rows {
+		dev := parseOptionalTime(field(idx, rec, "devstartdatetime"))
+		review := parseOptionalTime(field(idx, rec, "reviewstartdatetime"))
+		if dev == nil || review == nil || review.Before(*dev) {
+			continue
+		}
+		d := review.Sub(*dev).Hours() / 24
+		stage[field(idx, rec, "id")] = &d
+	}
+
+	issueIDs := make([]string, 0, len(issuePRs))
+	for id := range issuePRs {
+		issueIDs = append(issueIDs, id)
+	}
+	sort.Strings(issueIDs)
+	var out [][]string
+	for _, id := range issueIDs {
+		// first linked PR by creation time, among the PRs present in pr.csv
+		var firstID string
+		var first pr
+		for _, prID := range issuePRs[id] {
+			p, ok := prs[prID]
+			if !ok {
+				continue
+			}
+			if firstID == "" || p.createdAt.Before(first.createdAt) {
+				firstID, first = prID, p
+			}
+		}
+		if firstID == "" {
+			continue
+		}
+		merged := ""
+		var coding *float64
+		if first.mergedAt != nil {
+			merged = first.mergedAt.UTC().Format(time.RFC3339)
+			d := first.mergedAt.Sub(first.createdAt).Hours() / 24
+			coding = &d
+		}
+		var diff *float64
+		if coding != nil && stage[id] != nil {
+			d := *coding - *stage[id]
+			diff = &d
+		}
+		out = append(out, []string{
+			id,
+			orgOf(id),
+			firstID,
+			strconv.Itoa(len(issuePRs[id])),
+			first.createdAt.UTC().Format(time.RFC3339),
+			merged,
+			formatOptionalFloat(coding),
+			formatOptionalFloat(stage[id]),
+			formatOptionalFloat(diff),
+		})
+	}
+	return writeCSVFile(path, headers, out, bom)
+}
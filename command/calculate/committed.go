@@ -0,0 +1,69 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// committedToDoneDays returns the days from the commitment of r (committed_columns) to its end, with the same
+// exclusions as leadDays.
+func (r calculatedIssue) committedToDoneDays() (float64, bool) {
+	if r.ClockAnomaly {
+		return 0, false
+	}
+	return daysBetween(r.CommittedDatetime, r.EndDatetime)
+}
+
+// formatCommittedToDone formats the committed_to_done column of calculated_issue.csv, in days.
+func formatCommittedToDone(r calculatedIssue) string {
+	if d, ok := r.committedToDoneDays(); ok {
+		return formatFloat6(d)
+	}
+	return ""
+}
+
+// writeCommittedToDoneMonthly writes committed_to_done_month.csv: per closing month (in loc) and org plus ALL, the
+// average, p50 and p85 of the committed-to-done times of the closed issues having one, durations written with
+// df. Months without any are left out; percentiles of too few issues for gate are left empty.
+func writeCommittedToDoneMonthly(path string, closed []calculatedIssue, loc *time.Location, df durationFormat, gate sampleGate, bom bool) error {
+	byMonth := map[string]map[string][]float64{}
+	for _, r := range closed {
+		d, ok := r.committedToDoneDays()
+		if !ok {
+			continue
+		}
+		m := r.EndDatetime.In(loc).Format("2006-01")
+		if byMonth[m] == nil {
+			byMonth[m] = map[string][]float64{}
+		}
+		for _, org := range []string{r.Org, allOrgs} {
+			byMonth[m][org] = append(byMonth[m][org], d)
+		}
+	}
+	months := make([]string, 0, len(byMonth))
+	for m := range byMonth {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	var out [][]string
+	for _, m := range months {
+		for _, org := range sortedOrgs(byMonth[m]) {
+			days := byMonth[m][org]
+			n := len(days)
+			out = append(out, []string{
+				m,
+				org,
+				fmt.Sprintf("%d", n),
+				df.format(mean(days)),
+				gate.format(n, percentile(days, 0.50), df.format),
+				gate.format(n, percentile(days, 0.85), df.format),
+				df.unit,
+				gate.lowConfidence(n),
+			})
+		}
+	}
+	return writeCSVFile(path, schema.Headers("committed_to_done_month.csv"), out, bom)
+}
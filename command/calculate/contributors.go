@@ -0,0 +1,321 @@
+package calculate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// botFilter reports whether a login belongs to a bot: either listed in config (github.bots)
+// or carrying the GitHub App "[bot]" suffix. Empty logins are treated as bots so they are never counted.
+func botFilter(bots []string) func(login string) bool {
+	set := map[string]struct{}{}
+	for _, b := range bots {
+		b = strings.ToLower(strings.TrimSpace(b))
+		if b != "" {
+			set[b] = struct{}{}
+		}
+	}
+	return func(login string) bool {
+		l := strings.ToLower(strings.TrimSpace(login))
+		if l == "" || strings.HasSuffix(l, "[bot]") {
+			return true
+		}
+		_, ok := set[l]
+		return ok
+	}
+}
+
+// contributorActivity is one dated action of a person on a repository.
+type contributorActivity struct {
+	Month string // YYYY-MM
+	Org   string
+	Repo  string
+	Login string
+	Kind  string // pr_author|reviewer|issue_closer
+}
+
+// readContributorActivities gathers PR authors (pr.csv), reviewers (pr_review.csv) and issue closers
+// (issue.csv committer) from baseDir, dated by their month in loc. Missing files are skipped.
+func readContributorActivities(baseDir string, isBot func(string) bool, loc *time.Location) ([]contributorActivity, error) {
+	var acts []contributorActivity
+	add := func(at, org, repo, login, kind string) {
+		if isBot(login) {
+			return
+		}
+		t := parseOptionalTime(at)
+		if t == nil {
+			return
+		}
+		acts = append(acts, contributorActivity{Month: t.In(loc).Format("2006-01"), Org: org, Repo: repo, Login: strings.ToLower(strings.TrimSpace(login)), Kind: kind})
+	}
+	sources := []struct {
+		file, atCol, loginCol, kind string
+	}{
+		{"pr.csv", "created_at", "creator", "pr_author"},
+		{"pr_review.csv", "submitted_at", "user", "reviewer"},
+		{"issue.csv", "closed_at", "committer", "issue_closer"},
+	}
+	for _, src := range sources {
+		idx, rows, err := readCSVFile(filepath.Join(baseDir, src.file))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, rec := range rows {
+			add(field(idx, rec, src.atCol), field(idx, rec, "org"), field(idx, rec, "repo"), field(idx, rec, src.loginCol), src.kind)
+		}
+	}
+	return acts, nil
+}
+
+// writeContributorsMonthly writes contributors_month.csv (org-wide) and contributors_month_repo.csv (per org and
+// repo), by month in loc. Each row counts distinct non-bot logins per activity kind plus the distinct union across
+// kinds. Repositories listed in repository.csv are emitted for every month, with zeros when nobody was active.
+func writeContributorsMonthly(outPath, repoOutPath, baseDir string, bots []string, loc *time.Location, bom bool) error {
+	acts, err := readContributorActivities(baseDir, botFilter(bots), loc)
+	if err != nil {
+		return err
+	}
+	type counter struct {
+		authors, reviewers, closers, all map[string]struct{}
+	}
+	newCounter := func() *counter {
+		return &counter{authors: map[string]struct{}{}, reviewers: map[string]struct{}{}, closers: map[string]struct{}{}, all: map[string]struct{}{}}
+	}
+	record := func(c *counter, a contributorActivity) {
+		switch a.Kind {
+		case "pr_author":
+			c.authors[a.Login] = struct{}{}
+		case "reviewer":
+			c.reviewers[a.Login] = struct{}{}
+		case "issue_closer":
+			c.closers[a.Login] = struct{}{}
+		}
+		c.all[a.Login] = struct{}{}
+	}
+	// same-named repos of different orgs (-data a,b) are kept apart
+	type repoKey struct{ org, repo string }
+	byMonth := map[string]*counter{}
+	byMonthRepo := map[string]map[repoKey]*counter{}
+	repoSet := map[repoKey]struct{}{}
+	for _, a := range acts {
+		if byMonth[a.Month] == nil {
+			byMonth[a.Month] = newCounter()
+			byMonthRepo[a.Month] = map[repoKey]*counter{}
+		}
+		record(byMonth[a.Month], a)
+		k := repoKey{a.Org, a.Repo}
+		if byMonthRepo[a.Month][k] == nil {
+			byMonthRepo[a.Month][k] = newCounter()
+		}
+		record(byMonthRepo[a.Month][k], a)
+		repoSet[k] = struct{}{}
+	}
+	// Known repositories without any activity still deserve a row (unstaffed repos)
+	if idx, rows, err := readCSVFile(filepath.Join(baseDir, "repository.csv")); err == nil {
+		for _, rec := range rows {
+			if r := field(idx, rec, "repo"); r != "" {
+				repoSet[repoKey{field(idx, rec, "org"), r}] = struct{}{}
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	months := continuousMonths(byMonth)
+	repos := make([]repoKey, 0, len(repoSet))
+	for r := range repoSet {
+		repos = append(repos, r)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if repos[i].org != repos[j].org {
+			return repos[i].org < repos[j].org
+		}
+		return repos[i].repo < repos[j].repo
+	})
+
+	counts := func(c *counter) []string {
+		if c == nil {
+			return []string{"0", "0", "0", "0"}
+		}
+		return []string{
+			fmt.Sprintf("%d", len(c.authors)),
+			fmt.Sprintf("%d", len(c.reviewers)),
+			fmt.Sprintf("%d", len(c.closers)),
+			fmt.Sprintf("%d", len(c.all)),
+		}
+	}
+	var orgRows, repoRows [][]string
+	for _, m := range months {
+		orgRows = append(orgRows, append([]string{m}, counts(byMonth[m])...))
+		for _, r := range repos {
+			var c *counter
+			if byMonthRepo[m] != nil {
+				c = byMonthRepo[m][r]
+			}
+			repoRows = append(repoRows, append([]string{m, r.org, r.repo}, counts(c)...))
+		}
+	}
+	if err := writeCSVFile(outPath, schema.Headers("contributors_month.csv"), orgRows, bom); err != nil {
+		return err
+	}
+	return writeCSVFile(repoOutPath, schema.Headers("contributors_month_repo.csv"), repoRows, bom)
+}
+
+// continuousMonths returns every YYYY-MM between the smallest and largest key of m, inclusive.
+func continuousMonths[T any](m map[string]T) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	start, err1 := time.Parse("2006-01", keys[0])
+	end, err2 := time.Parse("2006-01", keys[len(keys)-1])
+	if err1 != nil || err2 != nil {
+		return keys
+	}
+	var res []string
+	for cur := start; !cur.After(end); cur = cur.AddDate(0, 1, 0) {
+		res = append(res, cur.Format("2006-01"))
+	}
+	return res
+}
+
+// writeLeaderboardMonthly writes leaderboard_month.csv: per month and login, the number of issues closed
+// (issue.csv committer), PRs merged (pr.csv creator of merged PRs) and reviews given (pr_review.csv user),
+// dated by their month in loc. Bots are excluded. Rows are ordered by month, then total activity descending,
+// then login.
+func writeLeaderboardMonthly(outPath, baseDir string, bots []string, loc *time.Location, bom bool) error {
+	isBot := botFilter(bots)
+	type counts struct{ closed, merged, reviews int }
+	byMonthLogin := map[string]map[string]*counts{}
+	get := func(t *time.Time, login string) *counts {
+		m := t.In(loc).Format("2006-01")
+		l := strings.ToLower(strings.TrimSpace(login))
+		if byMonthLogin[m] == nil {
+			byMonthLogin[m] = map[string]*counts{}
+		}
+		if byMonthLogin[m][l] == nil {
+			byMonthLogin[m][l] = &counts{}
+		}
+		return byMonthLogin[m][l]
+	}
+
+	idx, rows, err := readCSVFile(filepath.Join(baseDir, "issue.csv"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, rec := range rows {
+		login := field(idx, rec, "committer")
+		if t := parseOptionalTime(field(idx, rec, "closed_at")); t != nil && !isBot(login) {
+			get(t, login).closed++
+		}
+	}
+	prs, err := readPullRequests(baseDir)
+	if err != nil {
+		return err
+	}
+	for _, p := range prs {
+		if p.MergedAt != nil && !isBot(p.Creator) {
+			get(p.MergedAt, p.Creator).merged++
+		}
+	}
+	idx, rows, err = readCSVFile(filepath.Join(baseDir, "pr_review.csv"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, rec := range rows {
+		login := field(idx, rec, "user")
+		if t := parseOptionalTime(field(idx, rec, "submitted_at")); t != nil && !isBot(login) {
+			get(t, login).reviews++
+		}
+	}
+
+	months := make([]string, 0, len(byMonthLogin))
+	for m := range byMonthLogin {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	var out [][]string
+	for _, m := range months {
+		logins := make([]string, 0, len(byMonthLogin[m]))
+		for l := range byMonthLogin[m] {
+			logins = append(logins, l)
+		}
+		total := func(l string) int {
+			c := byMonthLogin[m][l]
+			return c.closed + c.merged + c.reviews
+		}
+		sort.Slice(logins, func(i, j int) bool {
+			if ti, tj := total(logins[i]), total(logins[j]); ti != tj {
+				return ti > tj
+			}
+			return logins[i] < logins[j]
+		})
+		for _, l := range logins {
+			c := byMonthLogin[m][l]
+			out = append(out, []string{
+				m,
+				l,
+				fmt.Sprintf("%d", c.closed),
+				fmt.Sprintf("%d", c.merged),
+				fmt.Sprintf("%d", c.reviews),
+				fmt.Sprintf("%d", total(l)),
+			})
+		}
+	}
+	return writeCSVFile(outPath, schema.Headers("leaderboard_month.csv"), out, bom)
+}
+
+// writeClosedByAuthorMonthly writes closed_by_author_month.csv: per month and login, the number of issues closed
+// (issue.csv committer, by closed_at month in loc). Bots are excluded. Unlike leaderboard_month.csv, every login gets a
+// row for every month from the first close to the last, zero included, so each person is a continuous series.
+// Rows are ordered by month, then login.
+func writeClosedByAuthorMonthly(outPath, baseDir string, bots []string, loc *time.Location, bom bool) error {
+	isBot := botFilter(bots)
+	idx, rows, err := readCSVFile(filepath.Join(baseDir, "issue.csv"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	byMonth := map[string]map[string]int{}
+	loginSet := map[string]struct{}{}
+	for _, rec := range rows {
+		login := field(idx, rec, "committer")
+		t := parseOptionalTime(field(idx, rec, "closed_at"))
+		if t == nil || isBot(login) {
+			continue
+		}
+		m := t.In(loc).Format("2006-01")
+		l := strings.ToLower(strings.TrimSpace(login))
+		if byMonth[m] == nil {
+			byMonth[m] = map[string]int{}
+		}
+		byMonth[m][l]++
+		loginSet[l] = struct{}{}
+	}
+
+	logins := make([]string, 0, len(loginSet))
+	for l := range loginSet {
+		logins = append(logins, l)
+	}
+	sort.Strings(logins)
+	var out [][]string
+	for _, m := range continuousMonths(byMonth) {
+		for _, l := range logins {
+			out = append(out, []string{m, l, fmt.Sprintf("%d", byMonth[m][l])})
+		}
+	}
+	return writeCSVFile(outPath, schema.Headers("closed_by_author_month.csv"), out, bom)
+}
@@ -0,0 +1,98 @@
+package calculate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteContributorsMonthlyKeysReposByOrgInLocation(t *testing.T) {
+	dir := t.TempDir()
+	// 2025-01-31T23:30Z is already February in Paris; api exists in both orgs
+	writeTestFile(t, dir, "pr.csv", "org,repo,number,created_at,creator\n"+
+		"a,api,1,2025-01-31T23:30:00Z,ann\n"+
+		"b,api,2,2025-01-15T10:00:00Z,bob\n")
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	if err := writeContributorsMonthly(filepath.Join(dir, "m.csv"), filepath.Join(dir, "r.csv"), dir, nil, paris, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "month,org,repo,pr_authors,reviewers,issue_closers,active_contributors\n" +
+		"2025-01,a,api,0,0,0,0\n" +
+		"2025-01,b,api,1,0,0,1\n" +
+		"2025-02,a,api,1,0,0,1\n" +
+		"2025-02,b,api,0,0,0,0\n"
+	if got := strings.ReplaceAll(readTestFile(t, dir, "r.csv"), "\r\n", "\n"); got != want {
+		t.Errorf("contributors_month_repo.csv:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteLeaderboardMonthlyInLocation(t *testing.T) {
+	dir := t.TempDir()
+	// 2025-01-31T23:30Z is already February in Paris
+	writeTestFile(t, dir, "issue.csv", "org,repo,id,closed_at,committer\n"+
+		"o,api,1,2025-01-31T23:30:00Z,ann\n"+
+		"o,api,2,2025-01-31T22:30:00Z,ann\n")
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	if err := writeLeaderboardMonthly(filepath.Join(dir, "l.csv"), dir, nil, paris, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "month,login,issues_closed,prs_merged,reviews_given,total\n" +
+		"2025-01,ann,1,0,0,1\n" +
+		"2025-02,ann,1,0,0,1\n"
+	if got := strings.ReplaceAll(readTestFile(t, dir, "l.csv"), "\r\n", "\n"); got != want {
+		t.Errorf("leaderboard_month.csv:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteClosedByAuthorMonthlyInLocation(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	// 2025-01-31T23:30Z is already February in Paris; bob closes nothing in February in either location
+	writeTestFile(t, dir, "issue.csv", "org,repo,id,closed_at,committer\n"+
+		"o,api,1,2025-01-31T23:30:00Z,ann\n"+
+		"o,api,2,2025-01-10T10:00:00Z,Bob\n"+
+		"o,api,3,2025-01-12T10:00:00Z,dependabot[bot]\n")
+	tests := []struct {
+		name string
+		loc  *time.Location
+		want string
+	}{
+		{
+			name: "Europe/Paris",
+			loc:  paris,
+			want: "month,login,issues_closed\n" +
+				"2025-01,ann,0\n" +
+				"2025-01,bob,1\n" +
+				"2025-02,ann,1\n" +
+				"2025-02,bob,0\n",
+		},
+		{
+			name: "UTC",
+			loc:  time.UTC,
+			want: "month,login,issues_closed\n" +
+				"2025-01,ann,1\n" +
+				"2025-01,bob,1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := t.TempDir()
+			if err := writeClosedByAuthorMonthly(filepath.Join(out, "c.csv"), dir, []string{"dependabot[bot]"}, tt.loc, false); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.ReplaceAll(readTestFile(t, out, "c.csv"), "\r\n", "\n"); got != tt.want {
+				t.Errorf("closed_by_author_month.csv:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package calculate
+
+import (
+	ccsv "cto-stats/connectors/csv"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// readCSVFile loads a whole CSV file and returns the header index (see indexMap) and the data rows.
+// A missing file is returned as an error wrapping os.ErrNotExist so callers can decide to tolerate it.
+func readCSVFile(path string) (map[string]int, [][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	head, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return map[string]int{}, nil, nil
+		}
+		return nil, nil, err
+	}
+	idx := indexMap(head)
+	var rows [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, rec)
+	}
+	return idx, rows, nil
+}
+
+// field returns the value of column col in rec, or "" when the column is absent or the row is short.
+func field(idx map[string]int, rec []string, col string) string {
+	i, ok := idx[col]
+	if !ok || i >= len(rec) {
+		return ""
+	}
+	return rec[i]
+}
+
+// writeCSVFile writes headers and rows to path, creating the parent directory if needed.
+func writeCSVFile(path string, headers []string, rows [][]string, bom bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := ccsv.Create(path, bom)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.Write(headers); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// loadLocation resolves the configured IANA timezone, defaulting to UTC when empty.
+func loadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
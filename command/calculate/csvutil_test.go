@@ -0,0 +1,140 @@
+package calculate
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	ccsv "cto-stats/connectors/csv"
+	gh "cto-stats/domain/github"
+)
+
+func TestCSVFileRoundTrip(t *testing.T) {
+	headers := []string{"id", "title", "url"}
+	rows := [][]string{
+		{"1", `Fix "login", again`, "https://example.com/a,b"},
+		{"2", "Café, crème", ""},
+	}
+	tests := []struct {
+		name string
+		bom  bool
+	}{
+		{"without BOM", false},
+		{"with BOM", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "out.csv")
+			if err := writeCSVFile(path, headers, rows, tt.bom); err != nil {
+				t.Fatal(err)
+			}
+			raw := readTestFile(t, dir, "out.csv")
+			if got := strings.HasPrefix(raw, "\ufeff"); got != tt.bom {
+				t.Errorf("BOM written: %v, want %v", got, tt.bom)
+			}
+			if !strings.Contains(raw, `"Fix ""login"", again"`) {
+				t.Errorf("title not quoted:\n%s", raw)
+			}
+			idx, got, err := readCSVFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i, ok := idx["id"]; !ok || i != 0 {
+				t.Errorf("id column at %d (found %v), want 0", i, ok)
+			}
+			if len(got) != len(rows) {
+				t.Fatalf("read %d rows, want %d", len(got), len(rows))
+			}
+			for i, rec := range got {
+				if !slices.Equal(rec, rows[i]) {
+					t.Errorf("row %d: got %q, want %q", i, rec, rows[i])
+				}
+				if field(idx, rec, "url") != rows[i][2] {
+					t.Errorf("row %d: url %q, want %q", i, field(idx, rec, "url"), rows[i][2])
+				}
+			}
+		})
+	}
+}
+
+func TestIssueCSVRoundTrip(t *testing.T) {
+	created := time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC)
+	closed := time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+	reports := []gh.IssueReport{
+		{
+			Org: "acme", Repo: "api", Number: 1,
+			Title:     "Fix \"login\", again\nsecond line: café, crème brûlée",
+			URL:       "https://github.com/acme/api/issues/1?q=a,b",
+			State:     "closed",
+			Type:      "bug",
+			IsBug:     true,
+			Assignees: []string{"zoë", "ann"},
+			CreatedAt: created,
+			ClosedAt:  &closed,
+			Milestone: "Été, \"v2\"",
+			Epic:      "epic:Paiement à l'étranger",
+		},
+		{Org: "acme", Repo: "web", Number: 2, Title: "Übersicht", State: "open", CreatedAt: created},
+	}
+	tests := []struct {
+		name string
+		bom  bool
+	}{
+		{"without BOM", false},
+		{"with BOM", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "issue.csv")
+			if err := ccsv.WriteIssueCSV(path, reports, tt.bom); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.HasPrefix(readTestFile(t, dir, "issue.csv"), "\ufeff"); got != tt.bom {
+				t.Errorf("BOM written: %v, want %v", got, tt.bom)
+			}
+			var q dataQuality
+			issues, err := readIssues(path, &q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(q.findings) != 0 {
+				t.Errorf("data quality findings %+v, want none", q.findings)
+			}
+			if len(issues) != len(reports) {
+				t.Fatalf("read %d issues, want %d", len(issues), len(reports))
+			}
+			for _, rep := range reports {
+				id := key(rep.Org, rep.Repo, strconv.Itoa(rep.Number))
+				got, ok := issues[id]
+				if !ok {
+					t.Fatalf("%s not read back", id)
+				}
+				for _, c := range []struct{ col, got, want string }{
+					{"org", got.Org, rep.Org},
+					{"repo", got.Repo, rep.Repo},
+					{"title", got.Title, rep.Title},
+					{"url", got.URL, rep.URL},
+					{"type", got.Type, rep.Type},
+					{"milestone", got.Milestone, rep.Milestone},
+					{"epic", got.Epic, rep.Epic},
+				} {
+					if c.got != c.want {
+						t.Errorf("%s %s %q, want %q", id, c.col, c.got, c.want)
+					}
+				}
+				if got.IsBug != rep.IsBug || !got.CreatedAt.Equal(rep.CreatedAt) || !slices.Equal(got.Assignees, rep.Assignees) {
+					t.Errorf("%s is_bug %v, created_at %v, assignees %q, want %v, %v, %q", id,
+						got.IsBug, got.CreatedAt, got.Assignees, rep.IsBug, rep.CreatedAt, rep.Assignees)
+				}
+				if (got.ClosedAt == nil) != (rep.ClosedAt == nil) || got.ClosedAt != nil && !got.ClosedAt.Equal(*rep.ClosedAt) {
+					t.Errorf("%s closed_at %v, want %v", id, got.ClosedAt, rep.ClosedAt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+const (
+	defaultCycleScatterWeeks = 52
+	cycleScatterTrailingDays = 13 * 7
+)
+
+// writeCycleScatter writes cycle_scatter.csv: one row per closed issue with a cycle time start, sorted by end date.
+// An issue is flagged as outlier when its cycle time is above the p95 of the issues closed in the 13 weeks up to
+// and including its own end. Flags are computed on the full history, then only the last maxWeeks weeks (counted
+// back from now) are written to keep the dashboard payload small. Durations are written with df.
+func writeCycleScatter(path string, closed []calculatedIssue, maxWeeks int, now time.Time, loc *time.Location, df durationFormat, bom bool) error {
+	if maxWeeks <= 0 {
+		maxWeeks = defaultCycleScatterWeeks
+	}
+	type point struct {
+		r         calculatedIssue
+		cycleDays float64
+	}
+	var pts []point
+	for _, r := range closed {
+		if d, ok := r.cycleDays(); ok {
+			pts = append(pts, point{r: r, cycleDays: d})
+		}
+	}
+	sort.SliceStable(pts, func(i, j int) bool {
+		if !pts[i].r.EndDatetime.Equal(*pts[j].r.EndDatetime) {
+			return pts[i].r.EndDatetime.Before(*pts[j].r.EndDatetime)
+		}
+		return pts[i].r.ID < pts[j].r.ID
+	})
+
+	cutoff := now.AddDate(0, 0, -7*maxWeeks)
+	var out [][]string
+	start := 0
+	for i, p := range pts {
+		end := *p.r.EndDatetime
+		// pts is sorted, so the trailing window start only moves forward
+		for end.Sub(*pts[start].r.EndDatetime) >= cycleScatterTrailingDays*24*time.Hour {
+			start++
+		}
+		if end.Before(cutoff) {
+			continue
+		}
+		window := make([]float64, 0, i+1-start)
+		for _, q := range pts[start : i+1] {
+			window = append(window, q.cycleDays)
+		}
+		outlier := p.cycleDays > percentile(window, 0.95)
+		var leadDays *float64
+		if v, ok := p.r.leadDays(); ok {
+			leadDays = &v
+		}
+		out = append(out, []string{
+			end.In(loc).Format("2006-01-02"),
+			df.format(p.cycleDays),
+			df.formatOptional(leadDays),
+			p.r.ProjectName,
+			p.r.Type,
+			fmt.Sprintf("%t", p.r.Bug),
+			p.r.ID,
+			p.r.Name,
+			fmt.Sprintf("%t", outlier),
+			df.unit,
+			p.r.URL,
+		})
+	}
+	return writeCSVFile(path, schema.Headers("cycle_scatter.csv"), out, bom)
+}
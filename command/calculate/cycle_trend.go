@@ -0,0 +1,90 @@
+package calculate
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// driftAlertMonths is the number of consecutive monthly p85 increases that raises the drift alert of
+// cycle_percentile_trend.csv.
+const driftAlertMonths = 3
+
+// writeCyclePercentileTrend writes cycle_percentile_trend.csv: per closing month (in loc) and org plus ALL, the
+// p50/p85/p95 cycle time and their change since the previous calendar month, with the run of months the p85 has
+// been rising and an alert once it rose driftAlertMonths months in a row. Percentiles of too few cycle times for
+// gate are left empty, as are the changes against a month without percentiles, which also end the run.
+func writeCyclePercentileTrend(path string, closed []calculatedIssue, loc *time.Location, df durationFormat, gate sampleGate, bom bool) error {
+	byMonth := map[string]map[string][]float64{}
+	for _, r := range closed {
+		if r.EndDatetime == nil {
+			continue
+		}
+		d, ok := r.cycleDays()
+		if !ok {
+			continue
+		}
+		m := r.EndDatetime.In(loc).Format("2006-01")
+		if byMonth[m] == nil {
+			byMonth[m] = map[string][]float64{}
+		}
+		for _, org := range []string{r.Org, allOrgs} {
+			byMonth[m][org] = append(byMonth[m][org], d)
+		}
+	}
+	months := make([]string, 0, len(byMonth))
+	for m := range byMonth {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+
+	type point struct {
+		month   string
+		ps      [3]float64 // p50, p85, p95
+		rising  int
+		defined bool
+	}
+	last := map[string]point{}
+	var out [][]string
+	for _, m := range months {
+		prevMonth := ""
+		if t, err := time.Parse("2006-01", m); err == nil {
+			prevMonth = t.AddDate(0, -1, 0).Format("2006-01")
+		}
+		for _, org := range sortedOrgs(byMonth[m]) {
+			cycles := byMonth[m][org]
+			n := len(cycles)
+			cur := point{month: m, defined: !gate.low(n)}
+			if cur.defined {
+				cur.ps = [3]float64{percentile(cycles, 0.50), percentile(cycles, 0.85), percentile(cycles, 0.95)}
+			}
+			prev, ok := last[org]
+			compared := cur.defined && ok && prev.defined && prev.month == prevMonth
+			rec := []string{m, org, strconv.Itoa(n)}
+			for i := range cur.ps {
+				rec = append(rec, gate.format(n, cur.ps[i], df.format))
+			}
+			for i := range cur.ps {
+				delta := ""
+				if compared {
+					delta = df.format(cur.ps[i] - prev.ps[i])
+				}
+				rec = append(rec, delta)
+			}
+			if compared && cur.ps[1] > prev.ps[1] {
+				cur.rising = prev.rising + 1
+			}
+			last[org] = cur
+			rec = append(rec,
+				strconv.Itoa(cur.rising),
+				strconv.FormatBool(cur.rising >= driftAlertMonths),
+				df.unit,
+				gate.lowConfidence(n),
+			)
+			out = append(out, rec)
+		}
+	}
+	return writeCSVFile(path, schema.Headers("cycle_percentile_trend.csv"), out, bom)
+}
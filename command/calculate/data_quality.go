@@ -0,0 +1,128 @@
+package calculate
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// Severities of data quality findings. Errors make the numbers of an issue wrong; warnings make them less
+// precise (e.g. a legacy fallback was applied).
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// dataQualityFinding is one row of data_quality.csv.
+type dataQualityFinding struct {
+	Severity string
+	Rule     string
+	IssueID  string
+	Detail   string
+}
+
+// dataQuality collects the data quality findings of the issues scope. A nil collector drops them.
+type dataQuality struct {
+	findings []dataQualityFinding
+}
+
+func (q *dataQuality) add(severity, rule, issueID, detail string) {
+	if q == nil {
+		return
+	}
+	q.findings = append(q.findings, dataQualityFinding{Severity: severity, Rule: rule, IssueID: issueID, Detail: detail})
+}
+
+// parseTime parses the RFC3339 timestamp v of column in file for issueID, recording an unparseable_timestamp
+// error when it is neither empty nor valid. The zero time is returned then; the event readers skip such rows.
+func (q *dataQuality) parseTime(file, column, issueID, v string) time.Time {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		q.add(severityError, "unparseable_timestamp", issueID, fmt.Sprintf("%s %s: %q", file, column, v))
+		return time.Time{}
+	}
+	return t
+}
+
+// checkStageOrder records a negative_stage_gap warning when a stage of row starts before the previous one, e.g.
+// review before development, which makes the time spent in the earlier stage negative. Missing stages are skipped.
+func (q *dataQuality) checkStageOrder(row calculatedIssue) {
+	stages := []struct {
+		name string
+		at   *time.Time
+	}{
+		{"lead_time_start", row.LeadTimeStartDatetime},
+		{"dev_start", row.DevStartDatetime},
+		{"review_start", row.ReviewStartDatetime},
+		{"qa_start", row.QAStartDatetime},
+		{"waiting_to_prod_start", row.WaitingToPodStartDatetime},
+		{"end", row.EndDatetime},
+	}
+	prev := -1
+	for i, s := range stages {
+		if s.at == nil {
+			continue
+		}
+		if prev >= 0 && s.at.Before(*stages[prev].at) {
+			q.add(severityWarning, "negative_stage_gap", row.ID, fmt.Sprintf("%s %s is before %s %s",
+				s.name, s.at.Format(time.RFC3339), stages[prev].name, stages[prev].at.Format(time.RFC3339)))
+			return
+		}
+		prev = i
+	}
+}
+
+// errorCount returns the number of error findings.
+func (q *dataQuality) errorCount() int {
+	n := 0
+	for _, f := range q.findings {
+		if f.Severity == severityError {
+			n++
+		}
+	}
+	return n
+}
+
+// write writes the findings to path, errors first, then by rule and issue, and logs how many each rule found.
+func (q *dataQuality) write(path string, bom bool) error {
+	sort.SliceStable(q.findings, func(i, j int) bool {
+		a, b := q.findings[i], q.findings[j]
+		if a.Severity != b.Severity {
+			return a.Severity == severityError
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.IssueID < b.IssueID
+	})
+	type ruleCount struct {
+		severity string
+		count    int
+	}
+	counts := map[string]*ruleCount{}
+	var rules []string
+	out := make([][]string, 0, len(q.findings))
+	for _, f := range q.findings {
+		out = append(out, []string{f.Severity, f.Rule, f.IssueID, f.Detail})
+		if counts[f.Rule] == nil {
+			counts[f.Rule] = &ruleCount{severity: f.Severity}
+			rules = append(rules, f.Rule)
+		}
+		counts[f.Rule].count++
+	}
+	if err := writeCSVFile(path, schema.Headers("data_quality.csv"), out, bom); err != nil {
+		return err
+	}
+	for _, r := range rules {
+		slog.Warn("calculate.data_quality", "rule", r, "severity", counts[r].severity, "count", counts[r].count)
+	}
+	return nil
+}
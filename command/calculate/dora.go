@@ -0,0 +1,186 @@
+package calculate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cto-stats/connectors/config"
+	"cto-stats/domain/schema"
+)
+
+const defaultFailureWindowDays = 7
+
+// failureMatcher reports whether a deployment of repo at deployedAt was followed by a failure
+// before windowEnd.
+type failureMatcher func(repo string, deployedAt, windowEnd time.Time) bool
+
+// failureMatchers maps the dora.failure_match config value to a matcher builder reading from baseDir.
+var failureMatchers = map[string]func(baseDir string, cfg config.DORA) (failureMatcher, error){
+	"bug_issue": bugIssueFailureMatcher,
+	"hotfix_pr": hotfixPRFailureMatcher,
+}
+
+// bugIssueFailureMatcher matches bug issues (issue.csv is_bug) created on the same repo within the window.
+func bugIssueFailureMatcher(baseDir string, _ config.DORA) (failureMatcher, error) {
+	idx, rows, err := readCSVFile(filepath.Join(baseDir, "issue.csv"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	byRepo := map[string][]time.Time{}
+	for _, rec := range rows {
+		if !parseBool(field(idx, rec, "is_bug")) {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, field(idx, rec, "created_at"))
+		if err != nil {
+			continue
+		}
+		repo := field(idx, rec, "repo")
+		byRepo[repo] = append(byRepo[repo], t)
+	}
+	return timesInWindow(byRepo), nil
+}
+
+// defaultHotfixLabels are the dora.hotfix_labels used when the config leaves them unset.
+var defaultHotfixLabels = []string{"hotfix"}
+
+// hotfixPRFailureMatcher matches PRs merged on the same repo within the window carrying one of the
+// dora.hotfix_labels. A pr.csv from an import older than the labels column falls back to PRs whose title
+// mentions "hotfix".
+func hotfixPRFailureMatcher(baseDir string, cfg config.DORA) (failureMatcher, error) {
+	idx, rows, err := readCSVFile(filepath.Join(baseDir, "pr.csv"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	hotfixLabels := cfg.HotfixLabels
+	if len(hotfixLabels) == 0 {
+		hotfixLabels = defaultHotfixLabels
+	}
+	_, hasLabels := idx["labels"]
+	isHotfix := func(rec []string) bool {
+		if !hasLabels {
+			return strings.Contains(strings.ToLower(field(idx, rec, "title")), "hotfix")
+		}
+		for _, l := range splitList(field(idx, rec, "labels")) {
+			if containsFold(hotfixLabels, l) {
+				return true
+			}
+		}
+		return false
+	}
+	byRepo := map[string][]time.Time{}
+	for _, rec := range rows {
+		if !isHotfix(rec) {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, field(idx, rec, "merged_at"))
+		if err != nil {
+			continue
+		}
+		repo := field(idx, rec, "repo")
+		byRepo[repo] = append(byRepo[repo], t)
+	}
+	return timesInWindow(byRepo), nil
+}
+
+// timesInWindow builds a matcher that looks for any timestamp in (deployedAt, windowEnd] on the repo.
+func timesInWindow(byRepo map[string][]time.Time) failureMatcher {
+	return func(repo string, deployedAt, windowEnd time.Time) bool {
+		for _, t := range byRepo[repo] {
+			if t.After(deployedAt) && !t.After(windowEnd) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// writeChangeFailureRate reads deployments from release.csv (repo, published_at) and writes, per month in loc and
+// repo (plus an ALL row per month), the number of deployments, how many were followed by a failure within the
+// configured window, and the resulting rate. A missing release.csv yields a headers-only output.
+func writeChangeFailureRate(outPath, baseDir string, cfg config.DORA, loc *time.Location, bom bool) error {
+	headers := schema.Headers("change_failure_rate_month.csv")
+	idx, rows, err := readCSVFile(filepath.Join(baseDir, "release.csv"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return writeCSVFile(outPath, headers, nil, bom)
+		}
+		return err
+	}
+	window := cfg.FailureWindowDays
+	if window <= 0 {
+		window = defaultFailureWindowDays
+	}
+	matchName := strings.ToLower(strings.TrimSpace(cfg.FailureMatch))
+	if matchName == "" {
+		matchName = "bug_issue"
+	}
+	build, ok := failureMatchers[matchName]
+	if !ok {
+		return fmt.Errorf("unknown dora.failure_match %q (expected bug_issue or hotfix_pr)", cfg.FailureMatch)
+	}
+	isFailure, err := build(baseDir, cfg)
+	if err != nil {
+		return err
+	}
+
+	type agg struct{ deployments, failed int }
+	byMonthRepo := map[string]map[string]*agg{}
+	for _, rec := range rows {
+		at := field(idx, rec, "published_at")
+		if at == "" {
+			at = field(idx, rec, "created_at")
+		}
+		deployedAt, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			continue
+		}
+		repo := field(idx, rec, "repo")
+		month := deployedAt.In(loc).Format("2006-01")
+		if byMonthRepo[month] == nil {
+			byMonthRepo[month] = map[string]*agg{}
+		}
+		for _, r := range []string{repo, "ALL"} {
+			a := byMonthRepo[month][r]
+			if a == nil {
+				a = &agg{}
+				byMonthRepo[month][r] = a
+			}
+			a.deployments++
+			if isFailure(repo, deployedAt, deployedAt.AddDate(0, 0, window)) {
+				a.failed++
+			}
+		}
+	}
+	months := make([]string, 0, len(byMonthRepo))
+	for m := range byMonthRepo {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	var out [][]string
+	for _, m := range months {
+		var repos []string
+		for r := range byMonthRepo[m] {
+			if r != "ALL" {
+				repos = append(repos, r)
+			}
+		}
+		sort.Strings(repos)
+		for _, r := range append(repos, "ALL") {
+			a := byMonthRepo[m][r]
+			out = append(out, []string{
+				m,
+				r,
+				fmt.Sprintf("%d", a.deployments),
+				fmt.Sprintf("%d", a.failed),
+				fmt.Sprintf("%.6f", float64(a.failed)/float64(a.deployments)),
+			})
+		}
+	}
+	return writeCSVFile(outPath, headers, out, bom)
+}
@@ -0,0 +1,112 @@
+package calculate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cto-stats/connectors/config"
+)
+
+func TestHotfixPRFailureMatcher(t *testing.T) {
+	deployed := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	windowEnd := deployed.AddDate(0, 0, 7)
+	tests := []struct {
+		name string
+		prs  string
+		cfg  config.DORA
+		want map[string]bool // repo -> failure matched
+	}{
+		{
+			name: "label, not title",
+			prs: "org,repo,number,title,merged_at,labels\n" +
+				"o,api,1,Fix login,2025-03-02T10:00:00Z,bug;HotFix\n" +
+				"o,web,2,hotfix: css,2025-03-02T10:00:00Z,\n",
+			want: map[string]bool{"api": true, "web": false},
+		},
+		{
+			name: "configured labels",
+			prs: "org,repo,number,title,merged_at,labels\n" +
+				"o,api,1,Fix login,2025-03-02T10:00:00Z,hotfix\n" +
+				"o,web,2,Fix css,2025-03-02T10:00:00Z,incident\n",
+			cfg:  config.DORA{HotfixLabels: []string{"incident"}},
+			want: map[string]bool{"api": false, "web": true},
+		},
+		{
+			name: "older pr.csv falls back to the title",
+			prs: "org,repo,number,title,merged_at\n" +
+				"o,api,1,Hotfix login,2025-03-02T10:00:00Z\n" +
+				"o,web,2,Fix css,2025-03-02T10:00:00Z\n",
+			want: map[string]bool{"api": true, "web": false},
+		},
+		{
+			name: "merged outside the window",
+			prs: "org,repo,number,title,merged_at,labels\n" +
+				"o,api,1,Fix login,2025-03-09T10:00:00Z,hotfix\n",
+			want: map[string]bool{"api": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFile(t, dir, "pr.csv", tt.prs)
+			match, err := hotfixPRFailureMatcher(dir, tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for repo, want := range tt.want {
+				if got := match(repo, deployed, windowEnd); got != want {
+					t.Errorf("%s: matched %v, want %v", repo, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteChangeFailureRateInLocation(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	// the first release is published on 2025-01-31 UTC but already in February in Paris
+	writeTestFile(t, dir, "release.csv", "repo,published_at\n"+
+		"api,2025-01-31T23:30:00Z\n"+
+		"api,2025-01-15T10:00:00Z\n")
+	writeTestFile(t, dir, "pr.csv", "org,repo,number,title,merged_at,labels\n"+
+		"o,api,1,Fix login,2025-02-02T10:00:00Z,hotfix\n")
+	tests := []struct {
+		name string
+		loc  *time.Location
+		want string
+	}{
+		{
+			name: "Europe/Paris",
+			loc:  paris,
+			want: "month,repo,deployments,failed_deployments,failure_rate\n" +
+				"2025-01,api,1,0,0.000000\n" +
+				"2025-01,ALL,1,0,0.000000\n" +
+				"2025-02,api,1,1,1.000000\n" +
+				"2025-02,ALL,1,1,1.000000\n",
+		},
+		{
+			name: "UTC",
+			loc:  time.UTC,
+			want: "month,repo,deployments,failed_deployments,failure_rate\n" +
+				"2025-01,api,2,1,0.500000\n" +
+				"2025-01,ALL,2,1,0.500000\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "cfr.csv")
+			if err := writeChangeFailureRate(out, dir, config.DORA{FailureMatch: "hotfix_pr"}, tt.loc, false); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.ReplaceAll(readTestFile(t, filepath.Dir(out), "cfr.csv"), "\r\n", "\n"); got != tt.want {
+				t.Errorf("change_failure_rate_month.csv:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
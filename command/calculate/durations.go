@@ -0,0 +1,61 @@
+package calculate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Units of the lead, cycle and time-to-PR durations (durations.unit, -duration-unit).
+const (
+	durationDays  = "days"
+	durationHours = "hours"
+)
+
+const (
+	defaultDurationPrecision = 2
+	maxDurationPrecision     = 6
+)
+
+// durationFormat formats the lead, cycle and time-to-PR durations of cycle_time.csv, cycle_time_quarter.csv and
+// cycle_scatter.csv. They are computed in days; the format converts them to its unit and rounds them to its
+// number of decimals.
+type durationFormat struct {
+	unit      string
+	precision int
+}
+
+// newDurationFormat validates unit (days when empty) and precision (defaultDurationPrecision when nil).
+func newDurationFormat(unit string, precision *int) (durationFormat, error) {
+	f := durationFormat{unit: strings.ToLower(strings.TrimSpace(unit)), precision: defaultDurationPrecision}
+	switch f.unit {
+	case "":
+		f.unit = durationDays
+	case durationDays, durationHours:
+	default:
+		return durationFormat{}, fmt.Errorf("unknown duration unit %q (expected days or hours)", unit)
+	}
+	if precision != nil {
+		if *precision < 0 || *precision > maxDurationPrecision {
+			return durationFormat{}, fmt.Errorf("duration precision must be between 0 and %d, got %d", maxDurationPrecision, *precision)
+		}
+		f.precision = *precision
+	}
+	return f, nil
+}
+
+// format returns days in the unit of f, rounded to its precision.
+func (f durationFormat) format(days float64) string {
+	if f.unit == durationHours {
+		days *= 24
+	}
+	return strconv.FormatFloat(days, 'f', f.precision, 64)
+}
+
+// formatOptional is format for an optional duration, empty when nil.
+func (f durationFormat) formatOptional(days *float64) string {
+	if days == nil {
+		return ""
+	}
+	return f.format(*days)
+}
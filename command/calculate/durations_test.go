@@ -0,0 +1,63 @@
+package calculate
+
+import "testing"
+
+func TestDurationFormat(t *testing.T) {
+	prec := func(p int) *int { return &p }
+	tests := []struct {
+		name      string
+		unit      string
+		precision *int
+		days      float64
+		want      string
+	}{
+		{"default days, 2 decimals", "", nil, 1.23456, "1.23"},
+		{"days", "days", prec(2), 1.5, "1.50"},
+		{"hours", "hours", prec(2), 1.5, "36.00"},
+		{"hours of a fraction of a day", "Hours", prec(1), 0.1, "2.4"},
+		{"precision 0 rounds", "days", prec(0), 2.5001, "3"},
+		{"precision 0 in hours", "hours", prec(0), 0.07, "2"},
+		{"precision 3", "days", prec(3), 1.0 / 3, "0.333"},
+		{"precision 6", "days", prec(6), 1.0 / 3, "0.333333"},
+		{"precision 6 in hours", " HOURS ", prec(6), 1.0 / 3, "8.000000"},
+		{"zero", "hours", prec(4), 0, "0.0000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newDurationFormat(tt.unit, tt.precision)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.format(tt.days); got != tt.want {
+				t.Errorf("format(%v) = %q, want %q", tt.days, got, tt.want)
+			}
+			days := tt.days
+			if got := f.formatOptional(&days); got != tt.want {
+				t.Errorf("formatOptional(%v) = %q, want %q", tt.days, got, tt.want)
+			}
+			if got := f.formatOptional(nil); got != "" {
+				t.Errorf("formatOptional(nil) = %q, want empty", got)
+			}
+		})
+	}
+}
+
+func TestNewDurationFormatErrors(t *testing.T) {
+	prec := func(p int) *int { return &p }
+	tests := []struct {
+		name      string
+		unit      string
+		precision *int
+	}{
+		{"unknown unit", "minutes", nil},
+		{"negative precision", "days", prec(-1)},
+		{"precision above 6", "hours", prec(7)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newDurationFormat(tt.unit, tt.precision); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
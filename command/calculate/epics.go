@@ -0,0 +1,69 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// epicStages are the stocks.csv buckets the open issues of an epic are counted in, in epic_progress.csv order.
+var epicStages = []string{"in_backlogs", "in_ready", "in_dev", "in_review", "in_qa", "waiting_to_prod"}
+
+// writeEpicProgress writes epic_progress.csv: per epic of rows (github.epic_label_prefix), its issues, the closed
+// ones, the open ones by current stage, the earliest cycle time start and latest end of its issues and the share
+// of closed issues. Issues without an epic are left out.
+func writeEpicProgress(path string, rows []calculatedIssue, bom bool) error {
+	type epicStats struct {
+		issues, closed int
+		open           map[string]int
+		start, end     *time.Time
+	}
+	byEpic := map[string]*epicStats{}
+	for _, r := range rows {
+		if r.Epic == "" {
+			continue
+		}
+		s := byEpic[r.Epic]
+		if s == nil {
+			s = &epicStats{open: map[string]int{}}
+			byEpic[r.Epic] = s
+		}
+		s.issues++
+		if r.EndDatetime != nil {
+			s.closed++
+			if s.end == nil || r.EndDatetime.After(*s.end) {
+				s.end = r.EndDatetime
+			}
+		} else {
+			s.open[currentStage(r)]++
+		}
+		if t := r.CycleTimeStartDatetime; t != nil && (s.start == nil || t.Before(*s.start)) {
+			s.start = t
+		}
+	}
+	epics := make([]string, 0, len(byEpic))
+	for e := range byEpic {
+		epics = append(epics, e)
+	}
+	sort.Strings(epics)
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	out := make([][]string, 0, len(epics))
+	for _, e := range epics {
+		s := byEpic[e]
+		rec := []string{e, strconv.Itoa(s.issues), strconv.Itoa(s.closed), strconv.Itoa(s.issues - s.closed)}
+		for _, stage := range epicStages {
+			rec = append(rec, strconv.Itoa(s.open[stage]))
+		}
+		rec = append(rec, formatTime(s.start), formatTime(s.end), fmt.Sprintf("%.2f", 100*float64(s.closed)/float64(s.issues)))
+		out = append(out, rec)
+	}
+	return writeCSVFile(path, schema.Headers("epic_progress.csv"), out, bom)
+}
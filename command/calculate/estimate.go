@@ -0,0 +1,99 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// estimateOf returns the value of the field named field (github.projects[].estimate_field) of the project pid in
+// the custom fields of the issue id, nil when the issue has none. A value that is not a number is recorded as a
+// non_numeric_estimate warning and ignored.
+func estimateOf(fields []projectCustomFieldRow, pid, field, id string, q *dataQuality) *float64 {
+	for _, cf := range fields {
+		if cf.ProjectID != pid || !strings.EqualFold(strings.TrimSpace(cf.FieldName), strings.TrimSpace(field)) {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(cf.FieldValue), 64)
+		if err != nil {
+			q.add(severityWarning, "non_numeric_estimate", id, fmt.Sprintf("%s %q is not a number; the issue counts as unestimated", cf.FieldName, cf.FieldValue))
+			return nil
+		}
+		return &v
+	}
+	return nil
+}
+
+// formatOptionalNumber formats v with as few digits as needed, or "" when nil.
+func formatOptionalNumber(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// writeWeeklyVelocity writes velocity_week.csv: per ISO week (in loc) and project with an estimate_field in
+// estimated, the estimate points of the issues closed in the week, how many of them had an estimate and how many
+// had none. Only weeks with closed issues are written.
+func writeWeeklyVelocity(path string, closed []calculatedIssue, loc *time.Location, estimated map[string]bool, bom bool) error {
+	type groupKey struct {
+		year, week int
+		projectID  string
+	}
+	type agg struct {
+		projectName            string
+		points                 float64
+		estimated, unestimated int
+	}
+	groups := map[groupKey]*agg{}
+	for _, r := range closed {
+		if r.EndDatetime == nil || !estimated[r.ProjectID] {
+			continue
+		}
+		y, w := r.EndDatetime.In(loc).ISOWeek()
+		k := groupKey{y, w, r.ProjectID}
+		a := groups[k]
+		if a == nil {
+			a = &agg{projectName: r.ProjectName}
+			groups[k] = a
+		}
+		if r.Estimate == nil {
+			a.unestimated++
+			continue
+		}
+		a.points += *r.Estimate
+		a.estimated++
+	}
+	keys := make([]groupKey, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.year != b.year {
+			return a.year < b.year
+		}
+		if a.week != b.week {
+			return a.week < b.week
+		}
+		return a.projectID < b.projectID
+	})
+	out := make([][]string, 0, len(keys))
+	for _, k := range keys {
+		a := groups[k]
+		out = append(out, []string{
+			strconv.Itoa(k.year),
+			strconv.Itoa(k.week),
+			k.projectID,
+			a.projectName,
+			strconv.FormatFloat(a.points, 'f', -1, 64),
+			strconv.Itoa(a.estimated),
+			strconv.Itoa(a.unestimated),
+		})
+	}
+	return writeCSVFile(path, schema.Headers("velocity_week.csv"), out, bom)
+}
@@ -0,0 +1,81 @@
+package calculate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// issueFilter restricts issue-scope outputs to one project and/or a date window (calculate -project/-since/-until).
+type issueFilter struct {
+	Project string     // project ID or name, case-insensitive; empty means all projects
+	Since   *time.Time // inclusive lower bound, nil when open
+	Until   *time.Time // inclusive upper bound, nil when open
+}
+
+// newIssueFilter parses the -since/-until flag values. Dates (YYYY-MM-DD) are interpreted in loc:
+// since starts at 00:00 and until ends at 23:59:59 of the given day. RFC3339 timestamps are used as is.
+func newIssueFilter(project, since, until string, loc *time.Location) (issueFilter, error) {
+	f := issueFilter{Project: strings.TrimSpace(project)}
+	var err error
+	if f.Since, err = parseFilterDate(since, loc, false); err != nil {
+		return f, fmt.Errorf("invalid -since: %w", err)
+	}
+	if f.Until, err = parseFilterDate(until, loc, true); err != nil {
+		return f, fmt.Errorf("invalid -until: %w", err)
+	}
+	if f.Since != nil && f.Until != nil && f.Until.Before(*f.Since) {
+		return f, fmt.Errorf("-until %s is before -since %s", until, since)
+	}
+	return f, nil
+}
+
+func parseFilterDate(s string, loc *time.Location, endOfDay bool) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		return nil, fmt.Errorf("expected YYYY-MM-DD or RFC3339, got %q", s)
+	}
+	if endOfDay {
+		d = time.Date(d.Year(), d.Month(), d.Day(), 23, 59, 59, int(time.Second-time.Nanosecond), loc)
+	}
+	return &d, nil
+}
+
+// active reports whether any filter was given.
+func (f issueFilter) active() bool {
+	return f.Project != "" || f.Since != nil || f.Until != nil
+}
+
+// matchProject reports whether the issue belongs to the selected project (by ID or name).
+func (f issueFilter) matchProject(r calculatedIssue) bool {
+	return f.Project == "" || equalFoldTrim(r.ProjectID, f.Project) || equalFoldTrim(r.ProjectName, f.Project)
+}
+
+// contains reports whether t falls inside the date window.
+func (f issueFilter) contains(t time.Time) bool {
+	if f.Since != nil && t.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && t.After(*f.Until) {
+		return false
+	}
+	return true
+}
+
+// overlaps reports whether the issue existed during the window: created before its end and not ended before its start.
+func (f issueFilter) overlaps(r calculatedIssue) bool {
+	if f.Until != nil && r.CreationDatetime.After(*f.Until) {
+		return false
+	}
+	if f.Since != nil && r.EndDatetime != nil && r.EndDatetime.Before(*f.Since) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// writeFinishedDetailWeekly writes finished_detail_week.csv: one row per closed issue in the ISO week (in loc) of its
+// end, the issues counted by throughput_week.csv, for its drill-down. The cycle time is written with df, empty
+// when the issue has none.
+func writeFinishedDetailWeekly(path string, closed []calculatedIssue, loc *time.Location, df durationFormat, bom bool) error {
+	type finished struct {
+		year, week int
+		r          calculatedIssue
+	}
+	var rows []finished
+	for _, r := range closed {
+		if r.EndDatetime == nil {
+			continue
+		}
+		y, w := r.EndDatetime.In(loc).ISOWeek()
+		rows = append(rows, finished{y, w, r})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.year != b.year {
+			return a.year < b.year
+		}
+		if a.week != b.week {
+			return a.week < b.week
+		}
+		return a.r.ID < b.r.ID
+	})
+	out := make([][]string, 0, len(rows))
+	for _, f := range rows {
+		cycle := ""
+		if d, ok := f.r.cycleDays(); ok {
+			cycle = df.format(d)
+		}
+		out = append(out, []string{
+			fmt.Sprintf("%d", f.year),
+			fmt.Sprintf("%02d", f.week),
+			f.r.Org,
+			f.r.ID,
+			f.r.Name,
+			f.r.Type,
+			f.r.ProjectID,
+			f.r.ProjectName,
+			cycle,
+			df.unit,
+			f.r.URL,
+		})
+	}
+	return writeCSVFile(path, schema.Headers("finished_detail_week.csv"), out, bom)
+}
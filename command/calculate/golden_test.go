@@ -0,0 +1,97 @@
+package calculate
+
+import (
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// update rewrites the golden outputs of TestGoldenOutputs with the current ones.
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestGoldenOutputs runs calculate on the imported files of testdata/golden/input and compares every output with
+// testdata/golden/<case>. Run with -update to rewrite them after an intended change.
+func TestGoldenOutputs(t *testing.T) {
+	input, err := filepath.Abs(filepath.Join("testdata", "golden", "input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const config = "github:\n  org: acme\n  bug_labels: [bug]\n  severity_labels: [sev1, sev2]\nrepo_breakdown:\n  ranking_min_issues: 1\n"
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"issues", []string{"-issues"}},
+		{"pr", []string{"-pr"}},
+		{"project", []string{"-issues", "-project", "Product", "-since", "2025-02-10", "-until", "2025-03-02"}},
+		{"hours", []string{"-issues", "-duration-unit", "hours", "-duration-precision", "0", "-sparse"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFile(t, dir, "config.yml", config)
+			t.Setenv("CONFIG_PATH", filepath.Join(dir, "config.yml"))
+			t.Setenv("NOTIFY_WEBHOOK_URL", "")
+			t.Chdir(dir)
+			args := append([]string{"-data", input, "-out", "out", "-now", "2025-03-10T12:00:00Z"}, tt.args...)
+			if err := Run(args); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join(filepath.Dir(input), tt.name)
+			got := outputFiles(t, "out")
+			for _, name := range got {
+				b, err := os.ReadFile(filepath.Join("out", name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				path := filepath.Join(golden, name)
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(path, b, 0o644); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				want, err := os.ReadFile(path)
+				if err != nil {
+					t.Errorf("%v (run go test -update to write it)", err)
+					continue
+				}
+				if g, w := strings.ReplaceAll(string(b), "\r\n", "\n"), strings.ReplaceAll(string(want), "\r\n", "\n"); g != w {
+					t.Errorf("%s differs from the golden file:\ngot:\n%s\nwant:\n%s", name, g, w)
+				}
+			}
+			if !*update {
+				if want := outputFiles(t, golden); !slices.Equal(got, want) {
+					t.Errorf("outputs %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+// outputFiles returns the paths, relative to dir, of the files under dir but calculate_meta.csv, which holds the
+// time of the run.
+func outputFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var res []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == "calculate_meta.csv" {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		res = append(res, rel)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
@@ -0,0 +1,341 @@
+package calculate
+
+import (
+	"context"
+	cmdimport "cto-stats/command/import"
+	"cto-stats/connectors/config"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// importedInputs are the files written by import that calculate reads. With import -split-by-repo they live
+// in data/<repo>/ instead of data/.
+var importedInputs = []string{
+	"repository.csv",
+	"project.csv",
+	"issue.csv",
+	"issue_status_event.csv",
+	"issue_project_event.csv",
+	"issue_project_custom_field.csv",
+	"issue_current_project.csv",
+	"pr.csv",
+	"pr_review.csv",
+	"pr_issue_link.csv",
+	"release.csv",
+}
+
+// resolveInputDir returns the directory calculate reads its inputs from. For the combined layout it is base
+// itself. When base holds per-repo directories (import -split-by-repo), their files are concatenated into a
+// temporary directory, which the returned cleanup removes. When both layouts hold the same inputs, typically
+// after switching to -split-by-repo, the one import wrote last is read and the other is logged as ignored.
+func resolveInputDir(base string) (string, func(), error) {
+	noop := func() {}
+	repoDirs, err := splitRepoDirs(base)
+	if err != nil || len(repoDirs) == 0 {
+		return base, noop, err
+	}
+	parts := map[string][]string{}
+	var shared []string
+	var combined, split time.Time
+	for _, name := range importedInputs {
+		var newest time.Time
+		for _, d := range repoDirs {
+			if fi, err := os.Stat(filepath.Join(d, name)); err == nil {
+				parts[name] = append(parts[name], filepath.Join(d, name))
+				if fi.ModTime().After(newest) {
+					newest = fi.ModTime()
+				}
+			}
+		}
+		fi, err := os.Stat(filepath.Join(base, name))
+		if err != nil {
+			continue
+		}
+		if len(parts[name]) == 0 {
+			parts[name] = []string{filepath.Join(base, name)}
+			continue
+		}
+		// the same input in both layouts: compare the files import wrote last in each
+		shared = append(shared, name)
+		if newest.After(split) {
+			split = newest
+		}
+		if fi.ModTime().After(combined) {
+			combined = fi.ModTime()
+		}
+	}
+	if len(shared) > 0 {
+		ignored := "combined"
+		if combined.After(split) {
+			ignored = "per-repo"
+			for _, name := range shared {
+				parts[name] = []string{filepath.Join(base, name)}
+			}
+		}
+		slog.Warn("calculate.inputs.both_layouts", "dir", base, "files", strings.Join(shared, ","),
+			"combined", combined.Format(time.RFC3339), "split", split.Format(time.RFC3339), "ignored", ignored)
+	}
+	tmp, err := os.MkdirTemp("", "cto-stats-inputs-")
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() { _ = os.RemoveAll(tmp) }
+	for _, name := range importedInputs {
+		if len(parts[name]) == 0 {
+			continue
+		}
+		if err := concatCSVFiles(filepath.Join(tmp, name), parts[name]); err != nil {
+			cleanup()
+			return "", noop, err
+		}
+	}
+	slog.Info("calculate.inputs.split", "repos", len(repoDirs))
+	return tmp, cleanup, nil
+}
+
+// mergedInputKeys says how mergeInputDirs recognizes the same item in the imported files of several
+// directories. The rows of an issue or pull request (org, repo, number) belong to the directory whose owner
+// file (issue.csv or pr.csv) lists it first; the other files keep the first directory's row of each key, and
+// release.csv, without key, drops rows repeated by a later directory.
+var mergedInputKeys = map[string]struct {
+	owner string
+	cols  []string
+}{
+	"repository.csv":                 {cols: []string{"org", "repo"}},
+	"project.csv":                    {cols: []string{"project_id"}},
+	"issue.csv":                      {owner: "issue.csv"},
+	"issue_status_event.csv":         {owner: "issue.csv"},
+	"issue_project_event.csv":        {owner: "issue.csv"},
+	"issue_project_custom_field.csv": {owner: "issue.csv"},
+	"issue_current_project.csv":      {owner: "issue.csv"},
+	"pr.csv":                         {owner: "pr.csv"},
+	"pr_review.csv":                  {owner: "pr.csv"},
+	"pr_issue_link.csv":              {owner: "pr.csv"},
+	"release.csv":                    {},
+}
+
+// mergeInputDirs concatenates the imported files of several data directories (calculate -data a,b), each
+// resolved like a single one, into a temporary directory, which the returned cleanup removes. The org column
+// keeps the items of different organizations apart. An issue or pull request imported in more than one
+// directory (e.g. the same org imported twice) is kept, with its events, from the first directory only, and
+// recorded in q as duplicate_across_inputs: a warning when the rows are identical, an error otherwise.
+func mergeInputDirs(dirs []string, q *dataQuality) (string, func(), error) {
+	noop := func() {}
+	tmp, err := os.MkdirTemp("", "cto-stats-merged-inputs-")
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() { _ = os.RemoveAll(tmp) }
+	resolved := make([]string, len(dirs))
+	for i, d := range dirs {
+		in, done, err := resolveInputDir(d)
+		if err != nil {
+			cleanup()
+			return "", noop, err
+		}
+		defer done()
+		resolved[i] = in
+	}
+	owners := map[string]map[string]int{}
+	for _, name := range importedInputs {
+		if err := mergeInputFile(tmp, name, dirs, resolved, owners, q); err != nil {
+			cleanup()
+			return "", noop, err
+		}
+	}
+	slog.Info("calculate.inputs.merged", "dirs", dirs)
+	return tmp, cleanup, nil
+}
+
+// mergeInputFile writes to dir the rows of file name found in the resolved directories, following
+// mergedInputKeys. owners maps each owner file to the index of the directory every key belongs to; the owner
+// file fills it, so it must be merged before the files depending on it. Only the columns every directory has
+// are kept, so an older import makes calculate fall back to its legacy behavior for all the rows instead of
+// half of them.
+func mergeInputFile(dir, name string, dirs, resolved []string, owners map[string]map[string]int, q *dataQuality) error {
+	type part struct {
+		dir  int
+		idx  map[string]int
+		rows [][]string
+	}
+	var parts []part
+	var headers, dropped []string
+	for i, d := range resolved {
+		idx, rows, err := readCSVFile(filepath.Join(d, name))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if headers == nil {
+			for col := range idx {
+				headers = append(headers, col)
+			}
+			sort.Slice(headers, func(a, b int) bool { return idx[headers[a]] < idx[headers[b]] })
+		} else {
+			headers = slices.DeleteFunc(headers, func(col string) bool {
+				_, ok := idx[col]
+				if !ok {
+					dropped = append(dropped, col)
+				}
+				return !ok
+			})
+		}
+		parts = append(parts, part{dir: i, idx: idx, rows: rows})
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	if len(dropped) > 0 {
+		slog.Warn("calculate.inputs.columns", "file", name, "dropped", dropped, "hint", "re-import the older directories to keep these columns")
+	}
+
+	spec := mergedInputKeys[name]
+	owned := owners[spec.owner]
+	if spec.owner == name {
+		owned = map[string]int{}
+		owners[name] = owned
+	}
+	seen := map[string]int{}
+	kept := map[string][]string{}
+	duplicates := 0
+	var out [][]string
+	for _, p := range parts {
+		for _, rec := range p.rows {
+			row := make([]string, len(headers))
+			for j, col := range headers {
+				row[j] = field(p.idx, rec, col)
+			}
+			var id string
+			switch {
+			case spec.owner != "":
+				id = key(field(p.idx, rec, "org"), field(p.idx, rec, "repo"), field(p.idx, rec, "number"))
+			case len(spec.cols) > 0:
+				vals := make([]string, len(spec.cols))
+				for j, col := range spec.cols {
+					vals[j] = field(p.idx, rec, col)
+				}
+				id = strings.Join(vals, "\x1f")
+			default:
+				id = strings.Join(row, "\x1f")
+			}
+			switch {
+			case spec.owner == name:
+				if o, ok := owned[id]; ok && o != p.dir {
+					duplicates++
+					if slices.Equal(kept[id], row) {
+						q.add(severityWarning, "duplicate_across_inputs", id, fmt.Sprintf("%s of %s repeats it identically; the rows of %s are kept", name, dirs[p.dir], dirs[o]))
+					} else {
+						q.add(severityError, "duplicate_across_inputs", id, fmt.Sprintf("%s of %s has a different row; the rows of %s are kept", name, dirs[p.dir], dirs[o]))
+					}
+					continue
+				}
+				if _, ok := owned[id]; !ok {
+					owned[id] = p.dir
+					kept[id] = row
+				}
+			case spec.owner != "":
+				if o, ok := owned[id]; ok && o != p.dir {
+					continue
+				}
+			default:
+				if o, ok := seen[id]; ok && o != p.dir {
+					continue
+				}
+				seen[id] = p.dir
+			}
+			out = append(out, row)
+		}
+	}
+	if duplicates > 0 {
+		slog.Warn("calculate.inputs.duplicate", "file", name, "count", duplicates)
+	}
+	return writeCSVFile(filepath.Join(dir, name), headers, out, false)
+}
+
+// snapshotInputDir rebuilds the imported inputs of the github.org of cfg from the raw GitHub pages saved by
+// import -snapshot in base/snapshots into a temporary directory, which the returned cleanup removes.
+// release.csv, maintained by hand, is taken from base.
+func snapshotInputDir(base string, cfg *config.Config) (string, func(), error) {
+	noop := func() {}
+	tmp, err := os.MkdirTemp("", "cto-stats-snapshot-inputs-")
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() { _ = os.RemoveAll(tmp) }
+	if err := cmdimport.Replay(context.Background(), filepath.Join(base, "snapshots"), tmp, cfg); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	if _, err := os.Stat(filepath.Join(base, "release.csv")); err == nil {
+		if err := concatCSVFiles(filepath.Join(tmp, "release.csv"), []string{filepath.Join(base, "release.csv")}); err != nil {
+			cleanup()
+			return "", noop, err
+		}
+	}
+	slog.Info("calculate.inputs.snapshots", "dir", filepath.Join(base, "snapshots"))
+	return tmp, cleanup, nil
+}
+
+// splitRepoDirs lists the sub-directories of base that hold per-repo import files (issue.csv or pr.csv),
+// sorted by name. Output directories such as data/filtered hold neither and are skipped.
+func splitRepoDirs(base string) ([]string, error) {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var dirs []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		d := filepath.Join(base, e.Name())
+		for _, name := range []string{"issue.csv", "pr.csv"} {
+			if _, err := os.Stat(filepath.Join(d, name)); err == nil {
+				dirs = append(dirs, d)
+				break
+			}
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// concatCSVFiles writes the header of the first non-empty file followed by the data rows of every file to path.
+// Columns are aligned on the first file's header, so files written by older imports with fewer columns
+// still line up.
+func concatCSVFiles(path string, parts []string) error {
+	var headers []string
+	var out [][]string
+	for _, p := range parts {
+		idx, rows, err := readCSVFile(p)
+		if err != nil {
+			return err
+		}
+		if headers == nil && len(idx) > 0 {
+			for col := range idx {
+				headers = append(headers, col)
+			}
+			sort.Slice(headers, func(a, b int) bool { return idx[headers[a]] < idx[headers[b]] })
+		}
+		for _, rec := range rows {
+			row := make([]string, len(headers))
+			for j, col := range headers {
+				row[j] = field(idx, rec, col)
+			}
+			out = append(out, row)
+		}
+	}
+	return writeCSVFile(path, headers, out, false)
+}
@@ -0,0 +1,213 @@
+package calculate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestFile writes content to name in dir.
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestFile returns the content of name in dir.
+func readTestFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// snapshotDir returns the content of every file under dir, keyed by relative path.
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = string(b)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestRunMergesDataDirs(t *testing.T) {
+	// product holds the acme org, platform the globex org plus acme/api#1 again under another title
+	tests := []struct {
+		name        string
+		data        string
+		sameRow     bool // platform repeats acme/api#1 identically
+		wantTitle   string
+		wantQuality string
+	}{
+		{
+			name:        "conflicting duplicate, product first",
+			data:        "product,platform",
+			wantTitle:   "Login fails with SSO",
+			wantQuality: "error,duplicate_across_inputs,acme/api#1,issue.csv of platform has a different row; the rows of product are kept",
+		},
+		{
+			name:        "conflicting duplicate, platform first",
+			data:        "platform,product",
+			wantTitle:   "Login fails with SAML",
+			wantQuality: "error,duplicate_across_inputs,acme/api#1,issue.csv of product has a different row; the rows of platform are kept",
+		},
+		{
+			name:        "identical duplicate",
+			data:        "product,platform",
+			sameRow:     true,
+			wantTitle:   "Login fails with SSO",
+			wantQuality: "warning,duplicate_across_inputs,acme/api#1,issue.csv of platform repeats it identically; the rows of product are kept",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.CopyFS(dir, os.DirFS(filepath.Join("testdata", "multi"))); err != nil {
+				t.Fatal(err)
+			}
+			if tt.sameRow {
+				issues := readTestFile(t, filepath.Join(dir, "platform"), "issue.csv")
+				writeTestFile(t, filepath.Join(dir, "platform"), "issue.csv", strings.Replace(issues, "with SAML", "with SSO", 1))
+			}
+			sources := snapshotDir(t, dir)
+			writeTestFile(t, dir, "config.yml", "github:\n  org: acme\n")
+			t.Setenv("CONFIG_PATH", filepath.Join(dir, "config.yml"))
+			t.Chdir(dir)
+			if err := Run([]string{"-data", tt.data, "-out", "out"}); err != nil {
+				t.Fatal(err)
+			}
+
+			// both orgs are counted, in the -out directory only
+			wantThroughput := "year,week,org,repo,throughput\n" +
+				"2025,10,acme,api,1\n" +
+				"2025,10,globex,infra,1\n" +
+				"2025,11,acme,web,1\n"
+			if got := strings.ReplaceAll(readTestFile(t, "out", "throughput_week_repo.csv"), "\r\n", "\n"); got != wantThroughput {
+				t.Errorf("throughput_week_repo.csv:\n%s\nwant:\n%s", got, wantThroughput)
+			}
+			wantMerged := "year,week,repo,merged_count\n" +
+				"2025,10,api,1\n" +
+				"2025,10,infra,1\n" +
+				"2025,10,ALL,2\n" +
+				"2025,11,web,1\n" +
+				"2025,11,ALL,1\n"
+			if got := strings.ReplaceAll(readTestFile(t, "out", "pr_merged_week.csv"), "\r\n", "\n"); got != wantMerged {
+				t.Errorf("pr_merged_week.csv:\n%s\nwant:\n%s", got, wantMerged)
+			}
+
+			// the duplicate is kept once, from the first directory, and reported
+			rows := readColumnOf(t, filepath.Join("out", "calculated_issue.csv"), "id", "name")
+			if got := rows["acme/api#1"]; got != tt.wantTitle {
+				t.Errorf("acme/api#1 title %q, want %q", got, tt.wantTitle)
+			}
+			if len(rows) != 5 {
+				t.Errorf("%d calculated issues, want 5: %v", len(rows), rows)
+			}
+			if quality := readTestFile(t, "out", "data_quality.csv"); !strings.Contains(quality, tt.wantQuality) {
+				t.Errorf("data_quality.csv misses %q:\n%s", tt.wantQuality, quality)
+			}
+
+			// the source directories are left untouched
+			for name, content := range sources {
+				if got := readTestFile(t, dir, name); got != content {
+					t.Errorf("%s was modified", name)
+				}
+			}
+			for _, src := range []string{"product", "platform"} {
+				if entries, _ := os.ReadDir(filepath.Join(dir, src)); len(entries) != 10 {
+					t.Errorf("%s holds %d files, want its 10 imported files only", src, len(entries))
+				}
+			}
+		})
+	}
+}
+
+func TestResolveInputDirBothLayouts(t *testing.T) {
+	const header = "id,title\n"
+	stale, fresh := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		combinedAt time.Time // modification time of data/issue.csv
+		splitAt    time.Time // modification time of data/<repo>/issue.csv
+		want       string
+	}{
+		{
+			name:       "combined file left over from before -split-by-repo",
+			combinedAt: stale,
+			splitAt:    fresh,
+			want:       header + "acme/api#1,from api\n" + "acme/web#2,from web\n",
+		},
+		{
+			name:       "combined import run after -split-by-repo",
+			combinedAt: fresh,
+			splitAt:    stale,
+			want:       header + "acme/api#1,combined\nacme/web#2,combined\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			writeTestFile(t, base, "issue.csv", header+"acme/api#1,combined\nacme/web#2,combined\n")
+			writeTestFile(t, base, "release.csv", "repo,tag\napi,v1\n")
+			for _, repo := range []string{"api", "web"} {
+				dir := filepath.Join(base, repo)
+				if err := os.Mkdir(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				number := map[string]string{"api": "1", "web": "2"}[repo]
+				writeTestFile(t, dir, "issue.csv", header+"acme/"+repo+"#"+number+",from "+repo+"\n")
+				if err := os.Chtimes(filepath.Join(dir, "issue.csv"), tt.splitAt, tt.splitAt); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.Chtimes(filepath.Join(base, "issue.csv"), tt.combinedAt, tt.combinedAt); err != nil {
+				t.Fatal(err)
+			}
+
+			dir, cleanup, err := resolveInputDir(base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanup()
+			if got := strings.ReplaceAll(readTestFile(t, dir, "issue.csv"), "\r\n", "\n"); got != tt.want {
+				t.Errorf("issue.csv:\n%s\nwant:\n%s", got, tt.want)
+			}
+			// an input of one layout only is read whichever layout wins
+			if got := strings.ReplaceAll(readTestFile(t, dir, "release.csv"), "\r\n", "\n"); got != "repo,tag\napi,v1\n" {
+				t.Errorf("release.csv: %q", got)
+			}
+		})
+	}
+}
+
+// readColumnOf returns the values of column val of the CSV file at path keyed by column key.
+func readColumnOf(t *testing.T, path, key, val string) map[string]string {
+	t.Helper()
+	idx, rows, err := readCSVFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := map[string]string{}
+	for _, rec := range rows {
+		res[field(idx, rec, key)] = field(idx, rec, val)
+	}
+	return res
+}
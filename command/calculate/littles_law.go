@@ -0,0 +1,164 @@
+package calculate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// wipStockColumns are the stocks_week.csv columns counted as work in progress for Little's Law: the stages
+// between cycle time start and end. Backlog and ready items have not entered the cycle yet.
+var wipStockColumns = []string{"in_dev", "in_review", "in_qa", "waiting_to_prod"}
+
+// isoWeekMonday returns Monday 00:00 (in loc) of an ISO week.
+func isoWeekMonday(year, week int, loc *time.Location) time.Time {
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+	return jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+(week-1)*7)
+}
+
+// isoWeekMonth returns the month (YYYY-MM) an ISO week belongs to, taken as the month of its Thursday
+// so that every week falls in exactly one month.
+func isoWeekMonth(year, week int, loc *time.Location) string {
+	return isoWeekMonday(year, week, loc).AddDate(0, 0, 3).Format("2006-01")
+}
+
+// writeLittlesLawMonthly compares, per month and project (plus ALL), the measured average cycle time with the
+// one predicted by Little's Law (average WIP / throughput). WIP comes from the weekly stocks already written to
+// stocksWeekPath; throughput and measured cycle time come from the closed issues, bucketed by their ISO closing
+// week. A ratio far from 1 usually points at data problems such as items closed without board moves.
+func writeLittlesLawMonthly(path, stocksWeekPath string, closed []calculatedIssue, loc *time.Location, bom bool) error {
+	headers := schema.Headers("littles_law_month.csv")
+	idx, rows, err := readCSVFile(stocksWeekPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return writeCSVFile(path, headers, nil, bom)
+		}
+		return err
+	}
+	type proj struct{ id, name string }
+	type agg struct {
+		wipSum     float64
+		closed     int
+		cycleWeeks []float64
+	}
+	all := proj{id: "ALL"}
+	byMonth := map[string]map[proj]*agg{}
+	get := func(m string, p proj) *agg {
+		if byMonth[m] == nil {
+			byMonth[m] = map[proj]*agg{}
+		}
+		if byMonth[m][p] == nil {
+			byMonth[m][p] = &agg{}
+		}
+		return byMonth[m][p]
+	}
+	var first, last time.Time
+	for _, rec := range rows {
+		y, err1 := strconv.Atoi(field(idx, rec, "year"))
+		w, err2 := strconv.Atoi(field(idx, rec, "week"))
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		m := isoWeekMonth(y, w, loc)
+		monday := isoWeekMonday(y, w, loc)
+		if first.IsZero() || monday.Before(first) {
+			first = monday
+		}
+		if monday.After(last) {
+			last = monday
+		}
+		var wip float64
+		for _, col := range wipStockColumns {
+			n, _ := strconv.Atoi(field(idx, rec, col))
+			wip += float64(n)
+		}
+		p := proj{id: field(idx, rec, "project_id"), name: field(idx, rec, "project_name")}
+		get(m, p).wipSum += wip
+		get(m, all).wipSum += wip
+	}
+	// Weeks without any stock have no row in stocks_week.csv but still count in the monthly averages
+	weeksInMonth := map[string]int{}
+	if !first.IsZero() {
+		for cur := first; !cur.After(last); cur = cur.AddDate(0, 0, 7) {
+			weeksInMonth[cur.AddDate(0, 0, 3).Format("2006-01")]++
+		}
+	}
+	for _, r := range closed {
+		if r.EndDatetime == nil {
+			continue
+		}
+		k := isoWeekOf(*r.EndDatetime, loc)
+		m := isoWeekMonth(k.Year, k.Week, loc)
+		if weeksInMonth[m] == 0 {
+			// outside the weekly stocks range: no WIP to compare with
+			continue
+		}
+		for _, p := range []proj{{id: r.ProjectID, name: r.ProjectName}, all} {
+			a := get(m, p)
+			a.closed++
+			if d, ok := r.cycleDays(); ok {
+				a.cycleWeeks = append(a.cycleWeeks, d/7)
+			}
+		}
+	}
+
+	months := make([]string, 0, len(weeksInMonth))
+	for m := range weeksInMonth {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	var out [][]string
+	for _, m := range months {
+		projects := make([]proj, 0, len(byMonth[m]))
+		for p := range byMonth[m] {
+			if p != all {
+				projects = append(projects, p)
+			}
+		}
+		sort.Slice(projects, func(i, j int) bool {
+			if projects[i].id != projects[j].id {
+				return projects[i].id < projects[j].id
+			}
+			return projects[i].name < projects[j].name
+		})
+		weeks := float64(weeksInMonth[m])
+		for _, p := range append(projects, all) {
+			a := byMonth[m][p]
+			if a == nil {
+				a = &agg{}
+			}
+			avgWIP := a.wipSum / weeks
+			throughput := float64(a.closed) / weeks
+			var measured, predicted, ratio *float64
+			if len(a.cycleWeeks) > 0 {
+				v := mean(a.cycleWeeks)
+				measured = &v
+			}
+			if throughput > 0 {
+				v := avgWIP / throughput
+				predicted = &v
+			}
+			if measured != nil && predicted != nil && *predicted > 0 {
+				v := *measured / *predicted
+				ratio = &v
+			}
+			out = append(out, []string{
+				m,
+				p.id,
+				p.name,
+				fmt.Sprintf("%d", weeksInMonth[m]),
+				fmt.Sprintf("%.6f", avgWIP),
+				fmt.Sprintf("%.6f", throughput),
+				formatOptionalFloat(measured),
+				formatOptionalFloat(predicted),
+				formatOptionalFloat(ratio),
+			})
+		}
+	}
+	return writeCSVFile(path, headers, out, bom)
+}
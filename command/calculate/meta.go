@@ -0,0 +1,49 @@
+package calculate
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ccsv "cto-stats/connectors/csv"
+	"cto-stats/domain/buildinfo"
+	"cto-stats/domain/schema"
+)
+
+// importVersion returns the tool_version of the last run recorded in base/import_meta.csv, or "" when the file
+// is missing or was written before the column existed.
+func importVersion(base string) string {
+	idx, rows, err := readCSVFile(filepath.Join(base, "import_meta.csv"))
+	if err != nil || len(rows) == 0 {
+		return ""
+	}
+	return field(idx, rows[len(rows)-1], "tool_version")
+}
+
+// checkImportVersion warns when the inputs were imported by a newer major version than this binary, whose
+// calculations may not understand them.
+func checkImportVersion(imported string) {
+	theirs, ok := buildinfo.Major(imported)
+	if !ok {
+		return
+	}
+	ours, ok := buildinfo.Major(buildinfo.Get().Version)
+	if ok && theirs > ours {
+		slog.Warn("calculate.inputs.newer", "imported_by", imported, "version", buildinfo.Get().String())
+	}
+}
+
+// appendCalculateMeta records the run in dir/calculate_meta.csv, next to the outputs it wrote: when, by which
+// build, for which scopes and from inputs imported by which build.
+func appendCalculateMeta(dir string, scopes []string, imported string, bom bool) {
+	row := []string{
+		time.Now().UTC().Format(time.RFC3339),
+		buildinfo.Get().String(),
+		strings.Join(scopes, ";"),
+		imported,
+	}
+	if err := ccsv.AppendRow(filepath.Join(dir, "calculate_meta.csv"), schema.Headers("calculate_meta.csv"), row, bom); err != nil {
+		slog.Warn("calculate.meta.csv.error", "error", err)
+	}
+}
@@ -0,0 +1,102 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// writeMilestoneBurndown writes, per milestone and ISO week (in loc), how many of its issues existed, were closed
+// and were still open at the end of the week (Sunday 23:59:59). Closing uses the GitHub closed_at of issue.csv,
+// not the project columns, so milestones work for teams without boards. Milestones with the same title in several
+// repositories are counted as one release; due_on is the latest due date among them. Each milestone runs from the
+// week of its first issue to the week its last issue closed, or to the current week while issues are open.
+func writeMilestoneBurndown(path string, issues map[string]issueRow, now time.Time, loc *time.Location, bom bool) error {
+	type milestone struct {
+		dueOn  *time.Time
+		issues []issueRow
+	}
+	byTitle := map[string]*milestone{}
+	for _, is := range issues {
+		if is.Milestone == "" {
+			continue
+		}
+		m := byTitle[is.Milestone]
+		if m == nil {
+			m = &milestone{}
+			byTitle[is.Milestone] = m
+		}
+		if is.MilestoneDueOn != nil && (m.dueOn == nil || is.MilestoneDueOn.After(*m.dueOn)) {
+			m.dueOn = is.MilestoneDueOn
+		}
+		m.issues = append(m.issues, is)
+	}
+	titles := make([]string, 0, len(byTitle))
+	for t := range byTitle {
+		titles = append(titles, t)
+	}
+	// earliest due date first, milestones without due date last
+	sort.Slice(titles, func(i, j int) bool {
+		a, b := byTitle[titles[i]].dueOn, byTitle[titles[j]].dueOn
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		if a != nil && !a.Equal(*b) {
+			return a.Before(*b)
+		}
+		return titles[i] < titles[j]
+	})
+
+	var out [][]string
+	for _, title := range titles {
+		m := byTitle[title]
+		first := m.issues[0].CreatedAt
+		last := now
+		allClosed := true
+		var lastClose time.Time
+		for _, is := range m.issues {
+			if is.CreatedAt.Before(first) {
+				first = is.CreatedAt
+			}
+			if is.ClosedAt == nil {
+				allClosed = false
+			} else if is.ClosedAt.After(lastClose) {
+				lastClose = *is.ClosedAt
+			}
+		}
+		if allClosed {
+			last = lastClose
+		}
+		due := ""
+		if m.dueOn != nil {
+			due = m.dueOn.In(loc).Format("2006-01-02")
+		}
+		start := isoWeekOf(first, loc)
+		for cur := isoWeekMonday(start.Year, start.Week, loc); !cur.After(last); cur = cur.AddDate(0, 0, 7) {
+			cutoff := time.Date(cur.Year(), cur.Month(), cur.Day()+6, 23, 59, 59, int(time.Second-time.Nanosecond), loc)
+			var total, closed int
+			for _, is := range m.issues {
+				if is.CreatedAt.After(cutoff) {
+					continue
+				}
+				total++
+				if is.ClosedAt != nil && !is.ClosedAt.After(cutoff) {
+					closed++
+				}
+			}
+			y, w := cur.ISOWeek()
+			out = append(out, []string{
+				title,
+				due,
+				fmt.Sprintf("%d", y),
+				fmt.Sprintf("%02d", w),
+				fmt.Sprintf("%d", total),
+				fmt.Sprintf("%d", closed),
+				fmt.Sprintf("%d", total-closed),
+			})
+		}
+	}
+	return writeCSVFile(path, schema.Headers("milestone_burndown.csv"), out, bom)
+}
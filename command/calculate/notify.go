@@ -0,0 +1,118 @@
+package calculate
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cto-stats/connectors/config"
+	"cto-stats/connectors/notify"
+)
+
+// runSummary is what a calculate run tells its notification: the config and where the issue outputs went.
+type runSummary struct {
+	cfg    *config.Config
+	loc    *time.Location
+	outDir string // set by unfiltered issues runs only, the ones whose headlines are posted
+}
+
+// notifyRun posts the outcome of a calculate run when notifications are configured: the error on failure, the
+// headline numbers of s after an unfiltered issues run. Help requests and other successful runs post nothing.
+func notifyRun(s runSummary, err error) {
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	cfg := s.cfg
+	if cfg == nil {
+		cfg = loadOptionalConfig()
+	}
+	switch {
+	case err != nil:
+		notify.Post(cfg.Notifications, notify.Failure("calculate", err))
+	case s.outDir != "":
+		notify.Post(cfg.Notifications, notify.Success("calculate", headlines(s.outDir, time.Now().In(s.loc))))
+	}
+}
+
+// loadOptionalConfig loads the config of CONFIG_PATH, or returns an empty one when it is missing or invalid.
+func loadOptionalConfig() *config.Config {
+	cfgPath := os.Getenv("CONFIG_PATH")
+	if cfgPath == "" {
+		cfgPath = "./config.yml"
+	}
+	if _, err := os.Stat(cfgPath); err == nil {
+		if c, err := config.Load(cfgPath); err == nil {
+			return c
+		}
+	}
+	return &config.Config{}
+}
+
+// headlines returns the numbers posted after a run: the issues closed in the last complete ISO week before now,
+// the issues in development, review or QA, and the cloud costs of the newest month of data/. Numbers whose file
+// is missing are left out.
+func headlines(dir string, now time.Time) []notify.Field {
+	var fields []notify.Field
+	if idx, rows, err := readCSVFile(filepath.Join(dir, "throughput_week.csv")); err == nil {
+		cy, cw := now.ISOWeek()
+		current := fmt.Sprintf("%04d-%02d", cy, cw)
+		var week, closed string
+		for _, rec := range rows {
+			if field(idx, rec, "org") != "ALL" {
+				continue
+			}
+			y, _ := strconv.Atoi(field(idx, rec, "year"))
+			w, _ := strconv.Atoi(field(idx, rec, "week"))
+			if k := fmt.Sprintf("%04d-%02d", y, w); k < current && k > week {
+				week, closed = k, field(idx, rec, "throughput")
+			}
+		}
+		if week != "" {
+			fields = append(fields, notify.Field{Name: "Issues closed in " + strings.Replace(week, "-", "-W", 1), Value: closed})
+		}
+	}
+	if idx, rows, err := readCSVFile(filepath.Join(dir, "stocks.csv")); err == nil {
+		wip := 0
+		for _, rec := range rows {
+			for _, col := range []string{"in_dev", "in_review", "in_qa"} {
+				n, _ := strconv.Atoi(field(idx, rec, col))
+				wip += n
+			}
+		}
+		fields = append(fields, notify.Field{Name: "Work in progress", Value: strconv.Itoa(wip)})
+	}
+	// Cloud spending is calculated into data/ only
+	if idx, rows, err := readCSVFile(filepath.Join("data", "cloud_spending_monthly.csv")); err == nil {
+		newest := ""
+		for _, rec := range rows {
+			newest = max(newest, field(idx, rec, "month"))
+		}
+		costs := map[string]float64{}
+		for _, rec := range rows {
+			if field(idx, rec, "month") != newest {
+				continue
+			}
+			cost, _ := strconv.ParseFloat(field(idx, rec, "cost"), 64)
+			costs[field(idx, rec, "currency")] += cost
+		}
+		currencies := make([]string, 0, len(costs))
+		for c := range costs {
+			currencies = append(currencies, c)
+		}
+		sort.Strings(currencies)
+		parts := make([]string, 0, len(currencies))
+		for _, c := range currencies {
+			parts = append(parts, strings.TrimSpace(strconv.FormatFloat(costs[c], 'f', 2, 64)+" "+c))
+		}
+		if newest != "" {
+			fields = append(fields, notify.Field{Name: "Cloud spend " + newest, Value: strings.Join(parts, ", ")})
+		}
+	}
+	return fields
+}
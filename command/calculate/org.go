@@ -0,0 +1,30 @@
+package calculate
+
+import (
+	"sort"
+	"strings"
+)
+
+// allOrgs is the org value of the rows aggregating every organization.
+const allOrgs = "ALL"
+
+// orgOf returns the organization part of an issue id key (org/repo#number, see key).
+func orgOf(id string) string {
+	org, _, _ := strings.Cut(id, "/")
+	return org
+}
+
+// sortedOrgs returns the orgs of byOrg in name order, with the ALL aggregate (when present) last.
+func sortedOrgs[T any](byOrg map[string]T) []string {
+	orgs := make([]string, 0, len(byOrg))
+	for org := range byOrg {
+		if org != allOrgs {
+			orgs = append(orgs, org)
+		}
+	}
+	sort.Strings(orgs)
+	if _, ok := byOrg[allOrgs]; ok {
+		orgs = append(orgs, allOrgs)
+	}
+	return orgs
+}
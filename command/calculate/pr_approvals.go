@@ -0,0 +1,156 @@
+package calculate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// defaultApprovalsRequired is the approval count measured when pr.approvals_required is not set.
+const defaultApprovalsRequired = 2
+
+// reviewApproval is the first APPROVED review of a reviewer (lower-cased login) on a PR.
+type reviewApproval struct {
+	user string
+	at   time.Time
+}
+
+// readApprovalTimes reads pr_review.csv in baseDir and returns, per PR (keyed by key(org, repo, number)), the
+// submission time of the first approval of each reviewer, in order. ok is false when the file is missing.
+func readApprovalTimes(baseDir string) (approvals map[string][]reviewApproval, ok bool, err error) {
+	idx, rows, err := readCSVFile(filepath.Join(baseDir, "pr_review.csv"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	approvals = map[string][]reviewApproval{}
+	for _, rec := range rows {
+		if !strings.EqualFold(strings.TrimSpace(field(idx, rec, "state")), "APPROVED") {
+			continue
+		}
+		at := parseOptionalTime(field(idx, rec, "submitted_at"))
+		if at == nil {
+			continue
+		}
+		k := key(field(idx, rec, "org"), field(idx, rec, "repo"), field(idx, rec, "number"))
+		approvals[k] = append(approvals[k], reviewApproval{
+			user: strings.ToLower(strings.TrimSpace(field(idx, rec, "user"))),
+			at:   *at,
+		})
+	}
+	for k, as := range approvals {
+		sort.SliceStable(as, func(i, j int) bool { return as[i].at.Before(as[j].at) })
+		seen := map[string]bool{}
+		distinct := as[:0]
+		for _, a := range as {
+			if !seen[a.user] {
+				seen[a.user] = true
+				distinct = append(distinct, a)
+			}
+		}
+		approvals[k] = distinct
+	}
+	return approvals, true, nil
+}
+
+// writePRApprovalLatencyWeekly writes, per ISO merge week and repo (plus ALL), the median and p90 hours from
+// creation to the first approval and to the Nth approval (N = required, default 2) of merged PRs, and how many
+// were merged with fewer than N approvals. Only approvals submitted before the merge count, each reviewer once,
+// and self-approvals are ignored. PRs authored by bots are excluded. Without pr_review.csv only the header is
+// written.
+func writePRApprovalLatencyWeekly(outPath string, baseDir string, required int, bots []string, loc *time.Location, bom bool) error {
+	if required <= 0 {
+		required = defaultApprovalsRequired
+	}
+	prs, err := readPullRequests(baseDir)
+	if err != nil {
+		return err
+	}
+	approvals, ok, err := readApprovalTimes(baseDir)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		prs = nil
+	}
+	isBot := botFilter(bots)
+	type agg struct {
+		first, nth    []float64
+		merged, below int
+	}
+	byWeekRepo := map[isoWeek]map[string]*agg{}
+	for _, p := range prs {
+		if p.MergedAt == nil || p.CreatedAt.IsZero() || isBot(p.Creator) {
+			continue
+		}
+		creator := strings.ToLower(strings.TrimSpace(p.Creator))
+		var times []time.Time
+		for _, a := range approvals[key(p.Org, p.Repo, p.Number)] {
+			if a.user != creator && !a.at.After(*p.MergedAt) {
+				times = append(times, a.at)
+			}
+		}
+		k := isoWeekOf(*p.MergedAt, loc)
+		if byWeekRepo[k] == nil {
+			byWeekRepo[k] = map[string]*agg{}
+		}
+		for _, repo := range []string{p.Repo, "ALL"} {
+			a := byWeekRepo[k][repo]
+			if a == nil {
+				a = &agg{}
+				byWeekRepo[k][repo] = a
+			}
+			a.merged++
+			if len(times) > 0 {
+				a.first = append(a.first, times[0].Sub(p.CreatedAt).Hours())
+			}
+			if len(times) >= required {
+				a.nth = append(a.nth, times[required-1].Sub(p.CreatedAt).Hours())
+			} else {
+				a.below++
+			}
+		}
+	}
+	weeks := make([]isoWeek, 0, len(byWeekRepo))
+	for k := range byWeekRepo {
+		weeks = append(weeks, k)
+	}
+	sortISOWeeks(weeks)
+	var out [][]string
+	for _, k := range weeks {
+		m := byWeekRepo[k]
+		var repos []string
+		for repo := range m {
+			if repo != "ALL" {
+				repos = append(repos, repo)
+			}
+		}
+		sort.Strings(repos)
+		for _, repo := range append(repos, "ALL") {
+			a := m[repo]
+			out = append(out, []string{
+				fmt.Sprintf("%d", k.Year),
+				fmt.Sprintf("%02d", k.Week),
+				repo,
+				fmt.Sprintf("%d", a.merged),
+				fmt.Sprintf("%d", required),
+				fmt.Sprintf("%d", len(a.first)),
+				fmt.Sprintf("%.6f", median(a.first)),
+				fmt.Sprintf("%.6f", percentile(a.first, 0.9)),
+				fmt.Sprintf("%d", len(a.nth)),
+				fmt.Sprintf("%.6f", median(a.nth)),
+				fmt.Sprintf("%.6f", percentile(a.nth, 0.9)),
+				fmt.Sprintf("%d", a.below),
+			})
+		}
+	}
+	return writeCSVFile(outPath, schema.Headers("pr_approval_latency_week.csv"), out, bom)
+}
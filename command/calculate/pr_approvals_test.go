@@ -0,0 +1,66 @@
+package calculate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPendingReviewsAreIgnored(t *testing.T) {
+	// cid's review is PENDING and dan's approval has no submitted_at: neither counts anywhere
+	prs := "org,repo,number,title,state,creator,created_at,closed_at,merged_at\n" +
+		"o,api,1,Add login,merged,zed,2025-03-03T08:00:00Z,2025-03-04T08:00:00Z,2025-03-04T08:00:00Z\n"
+	reviews := "org,repo,number,state,submitted_at,user\n" +
+		"o,api,1,APPROVED,2025-03-03T10:00:00Z,ann\n" +
+		"o,api,1,PENDING,,cid\n" +
+		"o,api,1,APPROVED,,dan\n" +
+		"o,api,1,APPROVED,2025-03-03T12:00:00Z,bob\n"
+	tests := []struct {
+		name  string
+		write func(dir string) error
+		file  string
+		want  string
+	}{
+		{
+			name: "approval latency",
+			write: func(dir string) error {
+				return writePRApprovalLatencyWeekly(filepath.Join(dir, "out.csv"), dir, 2, nil, time.UTC, false)
+			},
+			want: "year,week,repo,merged_count,approvals_required,first_approval_count,median_first_approval_hours,p90_first_approval_hours,nth_approval_count,median_nth_approval_hours,p90_nth_approval_hours,merged_below_threshold\n" +
+				"2025,10,api,1,2,1,2.000000,2.000000,1,4.000000,4.000000,0\n" +
+				"2025,10,ALL,1,2,1,2.000000,2.000000,1,4.000000,4.000000,0\n",
+		},
+		{
+			name: "leaderboard",
+			write: func(dir string) error {
+				return writeLeaderboardMonthly(filepath.Join(dir, "out.csv"), dir, nil, time.UTC, false)
+			},
+			want: "month,login,issues_closed,prs_merged,reviews_given,total\n" +
+				"2025-03,ann,0,0,1,1\n" +
+				"2025-03,bob,0,0,1,1\n" +
+				"2025-03,zed,0,1,0,1\n",
+		},
+		{
+			name: "contributors",
+			write: func(dir string) error {
+				return writeContributorsMonthly(filepath.Join(dir, "out.csv"), filepath.Join(dir, "repo.csv"), dir, nil, time.UTC, false)
+			},
+			want: "month,pr_authors,reviewers,issue_closers,active_contributors\n" +
+				"2025-03,1,2,0,3\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFile(t, dir, "pr.csv", prs)
+			writeTestFile(t, dir, "pr_review.csv", reviews)
+			if err := tt.write(dir); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.ReplaceAll(readTestFile(t, dir, "out.csv"), "\r\n", "\n"); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
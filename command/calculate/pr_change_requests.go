@@ -0,0 +1,111 @@
+package calculate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Change-request counting modes (config pr.cr_count_mode / flag -cr-count-mode).
+const (
+	crCountTotal             = "total"              // every CHANGES_REQUESTED review counts
+	crCountDistinctReviewers = "distinct_reviewers" // each reviewer requesting changes counts once per PR
+	crCountRounds            = "rounds"             // bursts of consecutive CHANGES_REQUESTED reviews count once
+)
+
+// defaultCRRoundGap is the idle time after which a CHANGES_REQUESTED review opens a new round in "rounds" mode
+// when pr.cr_round_gap_hours is not set.
+const defaultCRRoundGap = 24 * time.Hour
+
+// crRoundGapOf returns the round gap of pr.cr_round_gap_hours, defaultCRRoundGap when not set.
+func crRoundGapOf(hours int) time.Duration {
+	if hours <= 0 {
+		return defaultCRRoundGap
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// normalizeCRCountMode validates mode, defaulting to total when empty.
+func normalizeCRCountMode(mode string) (string, error) {
+	m := strings.ToLower(strings.TrimSpace(mode))
+	switch m {
+	case "":
+		return crCountTotal, nil
+	case crCountTotal, crCountDistinctReviewers, crCountRounds:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown cr_count_mode %q (expected total, distinct_reviewers or rounds)", mode)
+}
+
+// readChangeRequestCounts reads pr_review.csv in baseDir and returns the number of change requests per PR
+// (keyed by key(org, repo, number)) according to mode. A missing review file yields an empty map.
+//
+// In rounds mode, reviews of a PR are walked in submission order; a CHANGES_REQUESTED review opens a new
+// round unless it directly follows another CHANGES_REQUESTED review submitted less than roundGap earlier.
+func readChangeRequestCounts(baseDir string, mode string, roundGap time.Duration) (map[string]int, error) {
+	res := map[string]int{}
+	idx, rows, err := readCSVFile(filepath.Join(baseDir, "pr_review.csv"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return res, nil
+		}
+		return nil, err
+	}
+	type review struct {
+		state, user string
+		at          *time.Time
+	}
+	byPR := map[string][]review{}
+	for _, rec := range rows {
+		k := key(field(idx, rec, "org"), field(idx, rec, "repo"), field(idx, rec, "number"))
+		byPR[k] = append(byPR[k], review{
+			state: strings.TrimSpace(strings.ToUpper(field(idx, rec, "state"))),
+			user:  strings.ToLower(strings.TrimSpace(field(idx, rec, "user"))),
+			at:    parseOptionalTime(field(idx, rec, "submitted_at")),
+		})
+	}
+	for k, reviews := range byPR {
+		switch mode {
+		case crCountDistinctReviewers:
+			users := map[string]struct{}{}
+			for _, r := range reviews {
+				if r.state == "CHANGES_REQUESTED" {
+					users[r.user] = struct{}{}
+				}
+			}
+			if len(users) > 0 {
+				res[k] = len(users)
+			}
+		case crCountRounds:
+			sort.SliceStable(reviews, func(i, j int) bool {
+				if reviews[i].at == nil || reviews[j].at == nil {
+					return reviews[j].at == nil && reviews[i].at != nil
+				}
+				return reviews[i].at.Before(*reviews[j].at)
+			})
+			var prev *review
+			for i := range reviews {
+				r := &reviews[i]
+				if r.state == "CHANGES_REQUESTED" {
+					sameRound := prev != nil && prev.state == "CHANGES_REQUESTED" &&
+						prev.at != nil && r.at != nil && r.at.Sub(*prev.at) < roundGap
+					if !sameRound {
+						res[k]++
+					}
+				}
+				prev = r
+			}
+		default:
+			for _, r := range reviews {
+				if r.state == "CHANGES_REQUESTED" {
+					res[k]++
+				}
+			}
+		}
+	}
+	return res, nil
+}
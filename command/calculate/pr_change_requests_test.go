@@ -0,0 +1,64 @@
+package calculate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadChangeRequestCounts(t *testing.T) {
+	// the same reviewer requests changes three times on PR 1, 2h then 30h apart; PR 2 has two reviewers
+	reviews := "org,repo,number,user,state,submitted_at\n" +
+		"o,api,1,ann,CHANGES_REQUESTED,2025-03-03T09:00:00Z\n" +
+		"o,api,1,Ann,CHANGES_REQUESTED,2025-03-03T11:00:00Z\n" +
+		"o,api,1,ann,CHANGES_REQUESTED,2025-03-04T17:00:00Z\n" +
+		"o,api,1,bob,APPROVED,2025-03-05T09:00:00Z\n" +
+		"o,api,2,ann,CHANGES_REQUESTED,2025-03-03T09:00:00Z\n" +
+		"o,api,2,bob,CHANGES_REQUESTED,2025-03-03T10:00:00Z\n" +
+		"o,api,3,bob,COMMENTED,2025-03-03T10:00:00Z\n"
+	tests := []struct {
+		name     string
+		mode     string
+		roundGap time.Duration
+		want     map[string]int
+	}{
+		{"total", crCountTotal, defaultCRRoundGap, map[string]int{"1": 3, "2": 2}},
+		{"distinct reviewers", crCountDistinctReviewers, defaultCRRoundGap, map[string]int{"1": 1, "2": 2}},
+		{"rounds", crCountRounds, defaultCRRoundGap, map[string]int{"1": 2, "2": 1}},
+		{"rounds with a 48h gap", crCountRounds, crRoundGapOf(48), map[string]int{"1": 1, "2": 1}},
+		{"rounds with a 1h gap", crCountRounds, crRoundGapOf(1), map[string]int{"1": 3, "2": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFile(t, dir, "pr_review.csv", reviews)
+			got, err := readChangeRequestCounts(dir, tt.mode, tt.roundGap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			for number, want := range tt.want {
+				if n := got[key("o", "api", number)]; n != want {
+					t.Errorf("PR %s: %d change requests, want %d", number, n, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCRRoundGapOf(t *testing.T) {
+	tests := []struct {
+		hours int
+		want  time.Duration
+	}{
+		{0, 24 * time.Hour},
+		{-3, 24 * time.Hour},
+		{6, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := crRoundGapOf(tt.hours); got != tt.want {
+			t.Errorf("crRoundGapOf(%d) = %v, want %v", tt.hours, got, tt.want)
+		}
+	}
+}
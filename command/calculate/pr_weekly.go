@@ -0,0 +1,358 @@
+package calculate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// prRow is a pull request as read from pr.csv. Timestamps that are blank or missing
+// (e.g. older files without merged_at) are left nil.
+type prRow struct {
+	Org, Repo, Number string
+	Title             string
+	State             string
+	Creator           string
+	CreatedAt         time.Time
+	ClosedAt          *time.Time
+	MergedAt          *time.Time
+	// Size and review activity; HasReviewData is false for older pr.csv files without these columns
+	Additions, Deletions int
+	ReviewComments       int
+	HasReviewData        bool
+	// Review threads before merge; HasThreadData is false for older pr.csv files without these columns
+	ThreadsTotal, ThreadsResolved int
+	HasThreadData                 bool
+}
+
+// readPullRequests loads pr.csv from baseDir. A missing file yields no rows and no error.
+func readPullRequests(baseDir string) ([]prRow, error) {
+	idx, rows, err := readCSVFile(filepath.Join(baseDir, "pr.csv"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	_, hasReviewData := idx["review_comments"]
+	_, hasThreadData := idx["threads_total"]
+	res := make([]prRow, 0, len(rows))
+	for _, rec := range rows {
+		created, _ := time.Parse(time.RFC3339, field(idx, rec, "created_at"))
+		adds, _ := strconv.Atoi(field(idx, rec, "additions"))
+		dels, _ := strconv.Atoi(field(idx, rec, "deletions"))
+		comments, _ := strconv.Atoi(field(idx, rec, "review_comments"))
+		threads, _ := strconv.Atoi(field(idx, rec, "threads_total"))
+		resolved, _ := strconv.Atoi(field(idx, rec, "threads_resolved"))
+		res = append(res, prRow{
+			Org:       field(idx, rec, "org"),
+			Repo:      field(idx, rec, "repo"),
+			Number:    field(idx, rec, "number"),
+			Title:     field(idx, rec, "title"),
+			State:     strings.ToLower(strings.TrimSpace(field(idx, rec, "state"))),
+			Creator:   field(idx, rec, "creator"),
+			CreatedAt: created,
+			ClosedAt:  parseOptionalTime(field(idx, rec, "closed_at")),
+			MergedAt:  parseOptionalTime(field(idx, rec, "merged_at")),
+
+			Additions:      adds,
+			Deletions:      dels,
+			ReviewComments: comments,
+			HasReviewData:  hasReviewData,
+
+			ThreadsTotal:    threads,
+			ThreadsResolved: resolved,
+			HasThreadData:   hasThreadData,
+		})
+	}
+	return res, nil
+}
+
+// parseOptionalTime parses an RFC3339 value, returning nil for blank, unparsable or zero timestamps.
+func parseOptionalTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil || t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// isoWeek is an ISO year-week bucket.
+type isoWeek struct{ Year, Week int }
+
+func isoWeekOf(t time.Time, loc *time.Location) isoWeek {
+	y, w := t.In(loc).ISOWeek()
+	return isoWeek{Year: y, Week: w}
+}
+
+func sortISOWeeks(weeks []isoWeek) {
+	sort.Slice(weeks, func(i, j int) bool {
+		if weeks[i].Year != weeks[j].Year {
+			return weeks[i].Year < weeks[j].Year
+		}
+		return weeks[i].Week < weeks[j].Week
+	})
+}
+
+// writePRMergedWeekly counts merged PRs per ISO merge week and repo, plus an ALL row per week.
+// PRs without merged_at are ignored.
+func writePRMergedWeekly(outPath string, baseDir string, loc *time.Location, bom bool) error {
+	prs, err := readPullRequests(baseDir)
+	if err != nil {
+		return err
+	}
+	byWeekRepo := map[isoWeek]map[string]int{}
+	for _, p := range prs {
+		if p.MergedAt == nil {
+			continue
+		}
+		k := isoWeekOf(*p.MergedAt, loc)
+		if byWeekRepo[k] == nil {
+			byWeekRepo[k] = map[string]int{}
+		}
+		byWeekRepo[k][p.Repo]++
+	}
+	weeks := make([]isoWeek, 0, len(byWeekRepo))
+	for k := range byWeekRepo {
+		weeks = append(weeks, k)
+	}
+	sortISOWeeks(weeks)
+	var out [][]string
+	for _, k := range weeks {
+		m := byWeekRepo[k]
+		repos := make([]string, 0, len(m))
+		total := 0
+		for repo, n := range m {
+			repos = append(repos, repo)
+			total += n
+		}
+		sort.Strings(repos)
+		for _, repo := range repos {
+			out = append(out, []string{fmt.Sprintf("%d", k.Year), fmt.Sprintf("%02d", k.Week), repo, fmt.Sprintf("%d", m[repo])})
+		}
+		out = append(out, []string{fmt.Sprintf("%d", k.Year), fmt.Sprintf("%02d", k.Week), "ALL", fmt.Sprintf("%d", total)})
+	}
+	return writeCSVFile(outPath, schema.Headers("pr_merged_week.csv"), out, bom)
+}
+
+// writePRCycleTimeWeekly writes, per ISO merge week and repo (plus ALL), the count, average, median and p90
+// of hours from PR creation to merge. abandoned_count is the number of PRs closed without merge in that week
+// (by close date). PRs authored by bots are excluded.
+func writePRCycleTimeWeekly(outPath string, baseDir string, bots []string, loc *time.Location, bom bool) error {
+	prs, err := readPullRequests(baseDir)
+	if err != nil {
+		return err
+	}
+	isBot := botFilter(bots)
+	type agg struct {
+		hours     []float64
+		abandoned int
+	}
+	byWeekRepo := map[isoWeek]map[string]*agg{}
+	get := func(k isoWeek, repo string) *agg {
+		if byWeekRepo[k] == nil {
+			byWeekRepo[k] = map[string]*agg{}
+		}
+		if byWeekRepo[k][repo] == nil {
+			byWeekRepo[k][repo] = &agg{}
+		}
+		return byWeekRepo[k][repo]
+	}
+	for _, p := range prs {
+		if isBot(p.Creator) {
+			continue
+		}
+		switch {
+		case p.MergedAt != nil:
+			if p.CreatedAt.IsZero() || p.MergedAt.Before(p.CreatedAt) {
+				continue
+			}
+			h := p.MergedAt.Sub(p.CreatedAt).Hours()
+			k := isoWeekOf(*p.MergedAt, loc)
+			get(k, p.Repo).hours = append(get(k, p.Repo).hours, h)
+			get(k, "ALL").hours = append(get(k, "ALL").hours, h)
+		case p.ClosedAt != nil:
+			k := isoWeekOf(*p.ClosedAt, loc)
+			get(k, p.Repo).abandoned++
+			get(k, "ALL").abandoned++
+		}
+	}
+	weeks := make([]isoWeek, 0, len(byWeekRepo))
+	for k := range byWeekRepo {
+		weeks = append(weeks, k)
+	}
+	sortISOWeeks(weeks)
+	var out [][]string
+	for _, k := range weeks {
+		m := byWeekRepo[k]
+		var repos []string
+		for repo := range m {
+			if repo != "ALL" {
+				repos = append(repos, repo)
+			}
+		}
+		sort.Strings(repos)
+		for _, repo := range append(repos, "ALL") {
+			a := m[repo]
+			out = append(out, []string{
+				fmt.Sprintf("%d", k.Year),
+				fmt.Sprintf("%02d", k.Week),
+				repo,
+				fmt.Sprintf("%d", len(a.hours)),
+				fmt.Sprintf("%.6f", mean(a.hours)),
+				fmt.Sprintf("%.6f", median(a.hours)),
+				fmt.Sprintf("%.6f", percentile(a.hours, 0.9)),
+				fmt.Sprintf("%d", a.abandoned),
+			})
+		}
+	}
+	return writeCSVFile(outPath, schema.Headers("pr_cycle_time_week.csv"), out, bom)
+}
+
+// defaultReviewDepthMinLines is the PR size (additions+deletions) under which PRs are left out of the
+// comments-per-line ratio, to avoid divide-by-small noise.
+const defaultReviewDepthMinLines = 10
+
+// writePRReviewDepthWeekly writes, per ISO merge week and repo (plus ALL), the median number of review comments
+// per 100 changed lines for merged PRs of at least minLines changed lines, and the share of merged PRs (of any
+// size) that received no review comment. PRs from older pr.csv files without review data are skipped.
+func writePRReviewDepthWeekly(outPath string, baseDir string, minLines int, loc *time.Location, bom bool) error {
+	prs, err := readPullRequests(baseDir)
+	if err != nil {
+		return err
+	}
+	if minLines <= 0 {
+		minLines = defaultReviewDepthMinLines
+	}
+	type agg struct {
+		ratios       []float64
+		merged, zero int
+	}
+	byWeekRepo := map[isoWeek]map[string]*agg{}
+	for _, p := range prs {
+		if p.MergedAt == nil || !p.HasReviewData {
+			continue
+		}
+		k := isoWeekOf(*p.MergedAt, loc)
+		if byWeekRepo[k] == nil {
+			byWeekRepo[k] = map[string]*agg{}
+		}
+		for _, repo := range []string{p.Repo, "ALL"} {
+			a := byWeekRepo[k][repo]
+			if a == nil {
+				a = &agg{}
+				byWeekRepo[k][repo] = a
+			}
+			a.merged++
+			if p.ReviewComments == 0 {
+				a.zero++
+			}
+			if lines := p.Additions + p.Deletions; lines >= minLines {
+				a.ratios = append(a.ratios, float64(p.ReviewComments)*100/float64(lines))
+			}
+		}
+	}
+	weeks := make([]isoWeek, 0, len(byWeekRepo))
+	for k := range byWeekRepo {
+		weeks = append(weeks, k)
+	}
+	sortISOWeeks(weeks)
+	var out [][]string
+	for _, k := range weeks {
+		m := byWeekRepo[k]
+		var repos []string
+		for repo := range m {
+			if repo != "ALL" {
+				repos = append(repos, repo)
+			}
+		}
+		sort.Strings(repos)
+		for _, repo := range append(repos, "ALL") {
+			a := m[repo]
+			out = append(out, []string{
+				fmt.Sprintf("%d", k.Year),
+				fmt.Sprintf("%02d", k.Week),
+				repo,
+				fmt.Sprintf("%d", a.merged),
+				fmt.Sprintf("%d", len(a.ratios)),
+				fmt.Sprintf("%.6f", median(a.ratios)),
+				fmt.Sprintf("%.6f", float64(a.zero)/float64(a.merged)),
+			})
+		}
+	}
+	return writeCSVFile(outPath, schema.Headers("pr_review_depth_week.csv"), out, bom)
+}
+
+// writePRThreadResolutionWeekly writes, per ISO merge week and repo (plus ALL), the review threads opened before
+// the merge of merged PRs, how many were resolved, and the median per-PR resolved ratio over the PRs with at
+// least one thread. PRs from older pr.csv files without thread data are skipped.
+func writePRThreadResolutionWeekly(outPath string, baseDir string, loc *time.Location, bom bool) error {
+	prs, err := readPullRequests(baseDir)
+	if err != nil {
+		return err
+	}
+	type agg struct {
+		ratios            []float64
+		threads, resolved int
+	}
+	byWeekRepo := map[isoWeek]map[string]*agg{}
+	for _, p := range prs {
+		if p.MergedAt == nil || !p.HasThreadData || p.ThreadsTotal == 0 {
+			continue
+		}
+		k := isoWeekOf(*p.MergedAt, loc)
+		if byWeekRepo[k] == nil {
+			byWeekRepo[k] = map[string]*agg{}
+		}
+		for _, repo := range []string{p.Repo, "ALL"} {
+			a := byWeekRepo[k][repo]
+			if a == nil {
+				a = &agg{}
+				byWeekRepo[k][repo] = a
+			}
+			a.threads += p.ThreadsTotal
+			a.resolved += p.ThreadsResolved
+			a.ratios = append(a.ratios, float64(p.ThreadsResolved)/float64(p.ThreadsTotal))
+		}
+	}
+	weeks := make([]isoWeek, 0, len(byWeekRepo))
+	for k := range byWeekRepo {
+		weeks = append(weeks, k)
+	}
+	sortISOWeeks(weeks)
+	var out [][]string
+	for _, k := range weeks {
+		m := byWeekRepo[k]
+		var repos []string
+		for repo := range m {
+			if repo != "ALL" {
+				repos = append(repos, repo)
+			}
+		}
+		sort.Strings(repos)
+		for _, repo := range append(repos, "ALL") {
+			a := m[repo]
+			out = append(out, []string{
+				fmt.Sprintf("%d", k.Year),
+				fmt.Sprintf("%02d", k.Week),
+				repo,
+				fmt.Sprintf("%d", len(a.ratios)),
+				fmt.Sprintf("%d", a.threads),
+				fmt.Sprintf("%d", a.resolved),
+				fmt.Sprintf("%.6f", median(a.ratios)),
+			})
+		}
+	}
+	return writeCSVFile(outPath, schema.Headers("pr_thread_resolution_week.csv"), out, bom)
+}
@@ -0,0 +1,171 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// quarter is a fiscal quarter: FY is the calendar year in which the fiscal year ends (the calendar year itself
+// when the fiscal year starts in January) and Q is 1-4.
+type quarter struct{ FY, Q int }
+
+// quarterOf returns the fiscal quarter of t (already in the wanted location) for a fiscal year starting on
+// startMonth (1-12, 0 meaning January).
+func quarterOf(t time.Time, startMonth int) quarter {
+	if startMonth < 1 || startMonth > 12 {
+		startMonth = 1
+	}
+	m := int(t.Month())
+	fy := t.Year()
+	if startMonth > 1 && m >= startMonth {
+		fy++
+	}
+	return quarter{FY: fy, Q: (m-startMonth+12)%12/3 + 1}
+}
+
+// label formats q as 2025-Q3 for calendar years, or FY26-Q1 when the fiscal year starts later than January.
+func (q quarter) label(startMonth int) string {
+	if startMonth <= 1 || startMonth > 12 {
+		return fmt.Sprintf("%d-Q%d", q.FY, q.Q)
+	}
+	return fmt.Sprintf("FY%02d-Q%d", q.FY%100, q.Q)
+}
+
+func sortQuarters(qs []quarter) {
+	sort.Slice(qs, func(i, j int) bool {
+		if qs[i].FY != qs[j].FY {
+			return qs[i].FY < qs[j].FY
+		}
+		return qs[i].Q < qs[j].Q
+	})
+}
+
+// writeCycleTimeQuarterly writes, per fiscal quarter of the closing date and org plus an ALL row, the lead and
+// cycle time averages and the cycle time percentiles, recomputed from the closed issues rather than averaged
+// from the monthly rows. Durations are written with df; percentiles of too few cycle times for gate are left empty.
+func writeCycleTimeQuarterly(path string, closed []calculatedIssue, loc *time.Location, fiscalStart int, df durationFormat, gate sampleGate, bom bool) error {
+	type agg struct {
+		issues           int
+		lead, cycle, tpr []float64
+	}
+	byQuarter := map[quarter]map[string]*agg{}
+	for _, r := range closed {
+		if r.EndDatetime == nil {
+			continue
+		}
+		q := quarterOf(r.EndDatetime.In(loc), fiscalStart)
+		if byQuarter[q] == nil {
+			byQuarter[q] = map[string]*agg{}
+		}
+		for _, org := range []string{r.Org, allOrgs} {
+			a := byQuarter[q][org]
+			if a == nil {
+				a = &agg{}
+				byQuarter[q][org] = a
+			}
+			a.issues++
+			if d, ok := r.leadDays(); ok {
+				a.lead = append(a.lead, d)
+			}
+			if d, ok := r.cycleDays(); ok {
+				a.cycle = append(a.cycle, d)
+			}
+			if d, ok := r.timeToPRDays(); ok {
+				a.tpr = append(a.tpr, d)
+			}
+		}
+	}
+	quarters := make([]quarter, 0, len(byQuarter))
+	for q := range byQuarter {
+		quarters = append(quarters, q)
+	}
+	sortQuarters(quarters)
+	var out [][]string
+	for _, q := range quarters {
+		for _, org := range sortedOrgs(byQuarter[q]) {
+			a := byQuarter[q][org]
+			n := len(a.cycle)
+			out = append(out, []string{
+				q.label(fiscalStart),
+				org,
+				fmt.Sprintf("%d", a.issues),
+				df.format(mean(a.lead)),
+				fmt.Sprintf("%d", len(a.lead)),
+				df.format(mean(a.cycle)),
+				fmt.Sprintf("%d", len(a.cycle)),
+				gate.format(n, percentile(a.cycle, 0.50), df.format),
+				gate.format(n, percentile(a.cycle, 0.85), df.format),
+				gate.format(n, percentile(a.cycle, 0.95), df.format),
+				df.format(mean(a.tpr)),
+				df.unit,
+				gate.lowConfidence(n),
+			})
+		}
+	}
+	return writeCSVFile(path, schema.Headers("cycle_time_quarter.csv"), out, bom)
+}
+
+// writeThroughputQuarterly writes the number of issues closed per fiscal quarter (in loc) and org plus an ALL row.
+// It counts the issues themselves, since ISO weeks straddle quarter boundaries.
+func writeThroughputQuarterly(path string, closed []calculatedIssue, loc *time.Location, fiscalStart int, bom bool) error {
+	byQuarter := map[quarter]map[string]int{}
+	for _, r := range closed {
+		if r.EndDatetime == nil {
+			continue
+		}
+		q := quarterOf(r.EndDatetime.In(loc), fiscalStart)
+		if byQuarter[q] == nil {
+			byQuarter[q] = map[string]int{}
+		}
+		byQuarter[q][r.Org]++
+		byQuarter[q][allOrgs]++
+	}
+	quarters := make([]quarter, 0, len(byQuarter))
+	for q := range byQuarter {
+		quarters = append(quarters, q)
+	}
+	sortQuarters(quarters)
+	var out [][]string
+	for _, q := range quarters {
+		for _, org := range sortedOrgs(byQuarter[q]) {
+			out = append(out, []string{q.label(fiscalStart), org, fmt.Sprintf("%d", byQuarter[q][org])})
+		}
+	}
+	return writeCSVFile(path, schema.Headers("throughput_quarter.csv"), out, bom)
+}
+
+// writeCloudSpendingQuarterly sums costs per fiscal quarter, provider and currency (currencies are never mixed).
+func writeCloudSpendingQuarterly(path string, records []cloudCostRecord, fiscalStart int, bom bool) error {
+	type key struct {
+		Quarter  quarter
+		Provider string
+		Currency string
+	}
+	agg := map[key]float64{}
+	for _, r := range records {
+		agg[key{Quarter: quarterOf(r.Month, fiscalStart), Provider: r.Provider, Currency: strings.TrimSpace(r.Currency)}] += r.Cost
+	}
+	keys := make([]key, 0, len(agg))
+	for k := range agg {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Quarter != b.Quarter {
+			return a.Quarter.FY < b.Quarter.FY || (a.Quarter.FY == b.Quarter.FY && a.Quarter.Q < b.Quarter.Q)
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Currency < b.Currency
+	})
+	var out [][]string
+	for _, k := range keys {
+		out = append(out, []string{k.Quarter.label(fiscalStart), k.Provider, fmt.Sprintf("%.2f", agg[k]), k.Currency})
+	}
+	return writeCSVFile(path, schema.Headers("cloud_spending_quarter.csv"), out, bom)
+}
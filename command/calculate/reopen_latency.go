@@ -0,0 +1,102 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// reopening is a close of an issue followed by its reopening.
+type reopening struct {
+	IssueID, Org, Repo   string
+	ClosedAt, ReopenedAt time.Time
+}
+
+// hours returns the time between the close and the reopening, in hours.
+func (g reopening) hours() float64 {
+	return g.ReopenedAt.Sub(g.ClosedAt).Hours()
+}
+
+// findReopenings pairs, in the ordered status events of each issue of rows, every reopened event with the closed
+// event before it. An issue closed and reopened several times yields one pair per cycle; a reopened event
+// without a close before it (history cut by the import window) yields none.
+func findReopenings(rows []calculatedIssue, statusByID map[string][]statusEventRow) []reopening {
+	var res []reopening
+	for _, r := range rows {
+		var closedAt *time.Time
+		for _, ev := range statusByID[r.ID] {
+			switch ev.Type {
+			case "closed":
+				at := ev.At
+				closedAt = &at
+			case "reopened":
+				if closedAt != nil {
+					res = append(res, reopening{IssueID: r.ID, Org: r.Org, Repo: r.Repo, ClosedAt: *closedAt, ReopenedAt: ev.At})
+				}
+				closedAt = nil
+			}
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if !res[i].ReopenedAt.Equal(res[j].ReopenedAt) {
+			return res[i].ReopenedAt.Before(res[j].ReopenedAt)
+		}
+		return res[i].IssueID < res[j].IssueID
+	})
+	return res
+}
+
+// writeReopenLatency writes every close/reopen pair to path.
+func writeReopenLatency(path string, pairs []reopening, bom bool) error {
+	var out [][]string
+	for _, g := range pairs {
+		out = append(out, []string{
+			g.IssueID,
+			g.Org,
+			g.Repo,
+			g.ClosedAt.UTC().Format(time.RFC3339),
+			g.ReopenedAt.UTC().Format(time.RFC3339),
+			fmt.Sprintf("%.6f", g.hours()),
+		})
+	}
+	return writeCSVFile(path, schema.Headers("reopen_latency.csv"), out, bom)
+}
+
+// writeReopenLatencyMonthly writes, per month of the reopening (in loc) and org plus ALL, the number of reopenings
+// and the p50/p90 of their latency in hours. Percentiles of too few reopenings for gate are left empty.
+func writeReopenLatencyMonthly(path string, pairs []reopening, loc *time.Location, gate sampleGate, bom bool) error {
+	byMonth := map[string]map[string][]float64{}
+	for _, g := range pairs {
+		m := g.ReopenedAt.In(loc).Format("2006-01")
+		if byMonth[m] == nil {
+			byMonth[m] = map[string][]float64{}
+		}
+		for _, org := range []string{g.Org, allOrgs} {
+			byMonth[m][org] = append(byMonth[m][org], g.hours())
+		}
+	}
+	months := make([]string, 0, len(byMonth))
+	for m := range byMonth {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	format := func(v float64) string { return fmt.Sprintf("%.6f", v) }
+	var out [][]string
+	for _, m := range months {
+		for _, org := range sortedOrgs(byMonth[m]) {
+			hours := byMonth[m][org]
+			n := len(hours)
+			out = append(out, []string{
+				m,
+				org,
+				fmt.Sprintf("%d", n),
+				gate.format(n, percentile(hours, 0.50), format),
+				gate.format(n, percentile(hours, 0.90), format),
+				gate.lowConfidence(n),
+			})
+		}
+	}
+	return writeCSVFile(path, schema.Headers("reopen_latency_month.csv"), out, bom)
+}
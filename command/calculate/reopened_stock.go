@@ -0,0 +1,139 @@
+package calculate
+
+import (
+	"strings"
+	"time"
+
+	"cto-stats/connectors/config"
+)
+
+// closedPeriod is a time range during which a reopened issue was closed; until is nil once it stays closed.
+type closedPeriod struct {
+	since time.Time
+	until *time.Time
+}
+
+// stageMove is a move of an issue to a board column, with the stocks_week.csv stage the column stands for.
+type stageMove struct {
+	at    time.Time
+	stage string
+}
+
+// stageColumns are the columns standing for a stage of the weekly stocks.
+type stageColumns struct {
+	stage   string
+	columns []string
+}
+
+// projectStageColumns returns the stage columns of pc, furthest stage first.
+func projectStageColumns(pc config.Project) []stageColumns {
+	return []stageColumns{
+		{"waiting_to_prod", pc.WaitingToProdStartCols},
+		{"in_qa", pc.QAStartColumns},
+		{"in_review", pc.ReviewStartColumns},
+		{"in_dev", pc.DevStartColumns},
+		{"in_ready", pc.PutInReadyColumns},
+	}
+}
+
+// legacyStageColumns returns the stage columns of the issues of projects missing from github.projects, from the
+// column_aliases stages.
+func legacyStageColumns(aliases columnAliases) []stageColumns {
+	return []stageColumns{
+		{"waiting_to_prod", aliases.columns("done")},
+		{"in_qa", aliases.columns("qa")},
+		{"in_review", aliases.columns("review")},
+		{"in_dev", aliases.columns("dev")},
+		{"in_ready", aliases.columns("ready")},
+	}
+}
+
+// closedPeriodsOf returns the close-to-reopen periods of the ordered status events of an issue, nil when it was
+// never reopened after a close: its end datetime alone tells when it left the stocks.
+func closedPeriodsOf(status []statusEventRow) []closedPeriod {
+	var res []closedPeriod
+	reopened := false
+	for _, ev := range status {
+		open := len(res) > 0 && res[len(res)-1].until == nil
+		switch {
+		case ev.Type == "closed" && !open:
+			res = append(res, closedPeriod{since: ev.At})
+		case ev.Type == "reopened" && open:
+			at := ev.At
+			res[len(res)-1].until = &at
+			reopened = true
+		}
+	}
+	if !reopened {
+		return nil
+	}
+	return res
+}
+
+// stageMovesOf returns the column moves of the ordered project events with the stage of their column among
+// stages, in_backlogs for the columns of no stage.
+func stageMovesOf(events []projectEventRow, stages []stageColumns) []stageMove {
+	var res []stageMove
+	for _, e := range events {
+		if e.EventType == "removed" || strings.TrimSpace(e.ToColumn) == "" {
+			continue
+		}
+		stage := "in_backlogs"
+		for _, s := range stages {
+			if containsFold(s.columns, e.ToColumn) {
+				stage = s.stage
+				break
+			}
+		}
+		res = append(res, stageMove{at: e.At, stage: stage})
+	}
+	return res
+}
+
+// containsFold reports whether columns holds col, ignoring case and surrounding spaces.
+func containsFold(columns []string, col string) bool {
+	for _, c := range columns {
+		if strings.EqualFold(strings.TrimSpace(c), strings.TrimSpace(col)) {
+			return true
+		}
+	}
+	return false
+}
+
+// outOfStock reports whether r is out of the weekly stocks of the week from weekStart to cutoff: closed before
+// the week and not reopened by its end. Issues never reopened leave the stocks at their end datetime.
+func (r calculatedIssue) outOfStock(weekStart, cutoff time.Time) bool {
+	if len(r.ClosedPeriods) == 0 {
+		return r.EndDatetime != nil && r.EndDatetime.Before(weekStart)
+	}
+	for _, p := range r.ClosedPeriods {
+		if p.since.Before(weekStart) && (p.until == nil || p.until.After(cutoff)) {
+			return true
+		}
+	}
+	return false
+}
+
+// reopenedStageAt returns the stage of a reopened issue open again at cutoff: the stage of its latest column move
+// by then, in_backlogs without one. ok is false before its first close and while it is closed, when the stage
+// timestamps apply.
+func (r calculatedIssue) reopenedStageAt(cutoff time.Time) (stage string, ok bool) {
+	reopened := false
+	for _, p := range r.ClosedPeriods {
+		if p.since.After(cutoff) {
+			break
+		}
+		reopened = p.until != nil && !p.until.After(cutoff)
+	}
+	if !reopened {
+		return "", false
+	}
+	stage = "in_backlogs"
+	for _, m := range r.StageMoves {
+		if m.at.After(cutoff) {
+			break
+		}
+		stage = m.stage
+	}
+	return stage, true
+}
@@ -0,0 +1,159 @@
+package calculate
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestClosedPeriodsOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string // type@day of March 2025
+		want   []string // since-until days, until empty while closed
+	}{
+		{name: "never closed", events: []string{"opened@3"}},
+		{name: "closed once", events: []string{"opened@3", "closed@7"}},
+		{name: "closed and reopened", events: []string{"opened@3", "closed@7", "reopened@12"}, want: []string{"7-12"}},
+		{name: "closed, reopened and closed again", events: []string{"opened@3", "closed@7", "reopened@12", "closed@20"}, want: []string{"7-12", "20-"}},
+		{name: "reopened twice", events: []string{"opened@3", "closed@4", "reopened@5", "closed@6", "reopened@10", "closed@24"}, want: []string{"4-5", "6-10", "24-"}},
+		{name: "close repeated", events: []string{"opened@3", "closed@7", "closed@8", "reopened@12"}, want: []string{"7-12"}},
+		{name: "reopen without a close", events: []string{"opened@3", "reopened@5", "closed@7"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status []statusEventRow
+			for _, e := range tt.events {
+				typ, day, _ := strings.Cut(e, "@")
+				var d int
+				fmt.Sscan(day, &d)
+				status = append(status, statusEventRow{Type: typ, At: march(d, 9, 0)})
+			}
+			var got []string
+			for _, p := range closedPeriodsOf(status) {
+				until := ""
+				if p.until != nil {
+					until = fmt.Sprint(p.until.Day())
+				}
+				got = append(got, fmt.Sprintf("%d-%s", p.since.Day(), until))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("periods %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeeklyStocksOfReopenedIssues(t *testing.T) {
+	const issues = "org,repo,number,title,url,state,type,is_bug,creator,assignees,created_at,closed_at\n" +
+		"acme,api,1,Export to CSV,https://github.com/acme/api/issues/1,closed,feature,false,zed,ann,2025-03-03T08:00:00Z,\n"
+	tests := []struct {
+		name   string
+		status []string // type@RFC3339 time
+		moves  []string // column@RFC3339 time
+		// per ISO week of 2025: in_backlogs,in_ready,in_dev,in_review,in_qa,waiting_to_prod,created_in_week,closed_in_week
+		want []string
+	}{
+		{
+			name:   "closed, reopened in a later week, closed again",
+			status: []string{"closed@2025-03-07T12:00:00Z", "reopened@2025-03-18T09:00:00Z", "closed@2025-03-26T12:00:00Z"},
+			moves:  []string{"Ready@2025-03-03T09:00:00Z", "In Progress@2025-03-04T09:00:00Z", "Done@2025-03-06T09:00:00Z", "In Progress@2025-03-19T09:00:00Z", "Done@2025-03-26T09:00:00Z"},
+			want: []string{
+				"10:0,0,0,0,0,1,1,1", // in stock the week it closes
+				"11:0,0,0,0,0,0,0,0", // closed the whole week
+				"12:0,0,1,0,0,0,0,0", // reopened: the column it moved back to
+				"13:0,0,0,0,0,1,0,0", // closed again in the week
+			},
+		},
+		{
+			name:   "reopened and still open",
+			status: []string{"closed@2025-03-07T12:00:00Z", "reopened@2025-03-12T09:00:00Z"},
+			moves:  []string{"Ready@2025-03-03T09:00:00Z", "In Progress@2025-03-04T09:00:00Z", "In Review@2025-03-24T09:00:00Z"},
+			want: []string{
+				"10:0,0,1,0,0,0,1,1",
+				"11:0,0,1,0,0,0,0,0", // back in the column it was closed in
+				"12:0,0,1,0,0,0,0,0",
+				"13:0,0,0,1,0,0,0,0",
+			},
+		},
+		{
+			name:   "reopened back to the backlog",
+			status: []string{"closed@2025-03-05T12:00:00Z", "reopened@2025-03-11T09:00:00Z"},
+			moves:  []string{"In Progress@2025-03-04T09:00:00Z", "Done@2025-03-05T09:00:00Z", "Backlog@2025-03-11T10:00:00Z", "In Progress@2025-03-18T09:00:00Z"},
+			want: []string{
+				"10:0,0,0,0,0,1,1,1",
+				"11:1,0,0,0,0,0,0,0",
+				"12:0,0,1,0,0,0,0,0",
+			},
+		},
+		{
+			name:   "closed, reopened and closed within a week",
+			status: []string{"closed@2025-03-04T12:00:00Z", "reopened@2025-03-05T09:00:00Z", "closed@2025-03-06T12:00:00Z"},
+			moves:  []string{"In Progress@2025-03-03T09:00:00Z", "Done@2025-03-06T09:00:00Z", "Backlog@2025-03-12T09:00:00Z"},
+			want: []string{
+				"10:0,0,0,0,0,1,1,1",
+				"11:0,0,0,0,0,0,0,0", // a later move does not put it back in stock
+			},
+		},
+		{
+			name:   "reopened twice",
+			status: []string{"closed@2025-03-04T12:00:00Z", "reopened@2025-03-11T09:00:00Z", "closed@2025-03-12T12:00:00Z", "reopened@2025-03-25T09:00:00Z"},
+			moves:  []string{"In Progress@2025-03-03T09:00:00Z", "In Review@2025-03-11T10:00:00Z"},
+			want: []string{
+				"10:0,0,1,0,0,0,1,1",
+				"11:0,0,0,1,0,0,0,0",
+				"12:0,0,0,0,0,0,0,0",
+				"13:0,0,0,1,0,0,0,0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			status := "org,repo,number,type,at,by\nacme,api,1,opened,2025-03-03T08:00:00Z,zed\n"
+			for _, s := range tt.status {
+				typ, at, _ := strings.Cut(s, "@")
+				status += "acme,api,1," + typ + "," + at + ",bob\n"
+			}
+			events := "org,repo,number,project_id,project_name,from_column,to_column,at,by,type\n" +
+				"acme,api,1,101,Platform,,,2025-03-03T08:30:00Z,ann,added\n"
+			from := ""
+			for _, m := range tt.moves {
+				col, at, _ := strings.Cut(m, "@")
+				events += "acme,api,1,101,Platform," + from + "," + col + "," + at + ",ann,moved\n"
+				from = col
+			}
+			writeTestFile(t, dir, "issue.csv", issues)
+			writeTestFile(t, dir, "issue_status_event.csv", status)
+			writeTestFile(t, dir, "issue_project_event.csv", events)
+			// the legacy stage columns: Ready, In Progress, In Review, Done
+			writeTestFile(t, dir, "config.yml", "github:\n  org: acme\n")
+			t.Setenv("CONFIG_PATH", filepath.Join(dir, "config.yml"))
+			t.Setenv("NOTIFY_WEBHOOK_URL", "")
+			t.Chdir(dir)
+			if err := Run([]string{"-data", ".", "-out", "out", "-issues", "-now", "2025-03-31T12:00:00Z"}); err != nil {
+				t.Fatal(err)
+			}
+			idx, rows, err := readCSVFile(filepath.Join("out", "stocks_week.csv"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range rows {
+				if field(idx, r, "year") != "2025" || field(idx, r, "project_id") != "101" {
+					t.Fatalf("row of %s, project %q", field(idx, r, "year"), field(idx, r, "project_id"))
+				}
+				var counts []string
+				for _, col := range []string{"in_backlogs", "in_ready", "in_dev", "in_review", "in_qa", "waiting_to_prod", "created_in_week", "closed_in_week"} {
+					counts = append(counts, field(idx, r, col))
+				}
+				got = append(got, field(idx, r, "week")+":"+strings.Join(counts, ","))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("weeks:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
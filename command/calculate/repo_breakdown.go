@@ -0,0 +1,316 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// otherRepos is the repo of the rows grouping, per month and org, the repos with fewer closed issues than
+// repo_breakdown.min_issues.
+const otherRepos = "other"
+
+// repoLabels maps the closed issues to the repo they are reported under in the per-repo outputs: their own, or
+// otherRepos when their repo closed fewer than minIssues issues in the month (in loc). 0 groups nothing.
+type repoLabels struct {
+	counts    map[string]int // month|org|repo -> closed issues
+	minIssues int
+}
+
+func newRepoLabels(closed []calculatedIssue, loc *time.Location, minIssues int) repoLabels {
+	l := repoLabels{counts: map[string]int{}, minIssues: minIssues}
+	for _, r := range closed {
+		if r.EndDatetime != nil {
+			l.counts[r.EndDatetime.In(loc).Format("2006-01")+"|"+r.Org+"|"+r.Repo]++
+		}
+	}
+	return l
+}
+
+func (l repoLabels) label(month, org, repo string) string {
+	if l.counts[month+"|"+org+"|"+repo] < l.minIssues {
+		return otherRepos
+	}
+	return repo
+}
+
+// writeMonthlyCycleRepo writes cycle_time_repo.csv: the lead and cycle time averages, the cycle time p85 and the
+// counts of the issues closed each month, per org and repo, durations written with df. The p85 of too few cycle
+// times for gate is left empty.
+func writeMonthlyCycleRepo(path string, closed []calculatedIssue, loc *time.Location, labels repoLabels, df durationFormat, gate sampleGate, bom bool) error {
+	type agg struct {
+		issues        int
+		leads, cycles []float64
+	}
+	type groupKey struct{ month, org, repo string }
+	groups := map[groupKey]*agg{}
+	for _, r := range closed {
+		if r.EndDatetime == nil {
+			continue
+		}
+		m := r.EndDatetime.In(loc).Format("2006-01")
+		k := groupKey{m, r.Org, labels.label(m, r.Org, r.Repo)}
+		a := groups[k]
+		if a == nil {
+			a = &agg{}
+			groups[k] = a
+		}
+		a.issues++
+		if d, ok := r.leadDays(); ok {
+			a.leads = append(a.leads, d)
+		}
+		if d, ok := r.cycleDays(); ok {
+			a.cycles = append(a.cycles, d)
+		}
+	}
+	keys := make([]groupKey, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.month != b.month {
+			return a.month < b.month
+		}
+		if a.org != b.org {
+			return a.org < b.org
+		}
+		// other last
+		if (a.repo == otherRepos) != (b.repo == otherRepos) {
+			return b.repo == otherRepos
+		}
+		return a.repo < b.repo
+	})
+	out := make([][]string, 0, len(keys))
+	for _, k := range keys {
+		a := groups[k]
+		out = append(out, []string{
+			k.month,
+			k.org,
+			k.repo,
+			strconv.Itoa(a.issues),
+			df.format(mean(a.leads)),
+			strconv.Itoa(len(a.leads)),
+			df.format(mean(a.cycles)),
+			gate.format(len(a.cycles), percentile(a.cycles, 0.85), df.format),
+			strconv.Itoa(len(a.cycles)),
+			df.unit,
+			gate.lowConfidence(len(a.cycles)),
+		})
+	}
+	return writeCSVFile(path, schema.Headers("cycle_time_repo.csv"), out, bom)
+}
+
+// writeMonthlyCycleByRepo writes cycle_time_by_repo.csv: the cycle time average and p85 and the throughput (closed
+// issues) of each month (in loc), org and repo, durations written with df. Unlike cycle_time_repo.csv, every repo
+// is on its own, never grouped under otherRepos. The p85 of too few cycle times for gate is left empty.
+func writeMonthlyCycleByRepo(path string, closed []calculatedIssue, loc *time.Location, df durationFormat, gate sampleGate, bom bool) error {
+	type agg struct {
+		issues int
+		cycles []float64
+	}
+	type groupKey struct{ month, org, repo string }
+	groups := map[groupKey]*agg{}
+	for _, r := range closed {
+		if r.EndDatetime == nil {
+			continue
+		}
+		k := groupKey{r.EndDatetime.In(loc).Format("2006-01"), r.Org, r.Repo}
+		a := groups[k]
+		if a == nil {
+			a = &agg{}
+			groups[k] = a
+		}
+		a.issues++
+		if d, ok := r.cycleDays(); ok {
+			a.cycles = append(a.cycles, d)
+		}
+	}
+	keys := make([]groupKey, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.month != b.month {
+			return a.month < b.month
+		}
+		if a.org != b.org {
+			return a.org < b.org
+		}
+		return a.repo < b.repo
+	})
+	out := make([][]string, 0, len(keys))
+	for _, k := range keys {
+		a := groups[k]
+		cycleAvg, cycleP85 := "", ""
+		if len(a.cycles) > 0 {
+			cycleAvg = df.format(mean(a.cycles))
+			cycleP85 = gate.format(len(a.cycles), percentile(a.cycles, 0.85), df.format)
+		}
+		out = append(out, []string{
+			k.month,
+			k.org,
+			k.repo,
+			cycleAvg,
+			cycleP85,
+			strconv.Itoa(len(a.cycles)),
+			strconv.Itoa(a.issues),
+			df.unit,
+			gate.lowConfidence(len(a.cycles)),
+		})
+	}
+	return writeCSVFile(path, schema.Headers("cycle_time_by_repo.csv"), out, bom)
+}
+
+// writeWeeklyThroughputRepo writes the issues closed per ISO week (in loc), org and repo. Only weeks with closed
+// issues are written. A repo is grouped under otherRepos in the weeks of the months (isoWeekMonth) it is
+// grouped in.
+func writeWeeklyThroughputRepo(path string, closed []calculatedIssue, loc *time.Location, labels repoLabels, bom bool) error {
+	type groupKey struct {
+		year, week int
+		org, repo  string
+	}
+	counts := map[groupKey]int{}
+	for _, r := range closed {
+		if r.EndDatetime == nil {
+			continue
+		}
+		y, w := r.EndDatetime.In(loc).ISOWeek()
+		counts[groupKey{y, w, r.Org, labels.label(isoWeekMonth(y, w, loc), r.Org, r.Repo)}]++
+	}
+	keys := make([]groupKey, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.year != b.year {
+			return a.year < b.year
+		}
+		if a.week != b.week {
+			return a.week < b.week
+		}
+		if a.org != b.org {
+			return a.org < b.org
+		}
+		if (a.repo == otherRepos) != (b.repo == otherRepos) {
+			return b.repo == otherRepos
+		}
+		return a.repo < b.repo
+	})
+	out := make([][]string, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, []string{fmt.Sprintf("%d", k.year), fmt.Sprintf("%d", k.week), k.org, k.repo, strconv.Itoa(counts[k])})
+	}
+	return writeCSVFile(path, schema.Headers("throughput_week_repo.csv"), out, bom)
+}
+
+// defaultRankingMinIssues is the repo_breakdown.ranking_min_issues used when the config leaves it unset.
+const defaultRankingMinIssues = 5
+
+// writeRepoRanking writes repo_ranking.csv: the repos of every org that closed at least minIssues issues in the
+// latest month of closed issues (in loc), ranked by their cycle time average (1 = fastest) and by their
+// throughput (1 = most closed issues). Equal values share a rank; repos without a cycle time get no cycle rank.
+// Repos are ranked on their own, never grouped under otherRepos.
+func writeRepoRanking(path string, closed []calculatedIssue, loc *time.Location, minIssues int, df durationFormat, bom bool) error {
+	type repoStats struct {
+		org, repo string
+		issues    int
+		cycles    []float64
+		cycleAvg  float64
+		cycleRank int
+		thrRank   int
+	}
+	latest := ""
+	for _, r := range closed {
+		if r.EndDatetime != nil {
+			if m := r.EndDatetime.In(loc).Format("2006-01"); m > latest {
+				latest = m
+			}
+		}
+	}
+	byRepo := map[string]*repoStats{}
+	for _, r := range closed {
+		if r.EndDatetime == nil || r.EndDatetime.In(loc).Format("2006-01") != latest {
+			continue
+		}
+		s := byRepo[r.Org+"|"+r.Repo]
+		if s == nil {
+			s = &repoStats{org: r.Org, repo: r.Repo}
+			byRepo[r.Org+"|"+r.Repo] = s
+		}
+		s.issues++
+		if d, ok := r.cycleDays(); ok {
+			s.cycles = append(s.cycles, d)
+		}
+	}
+	var ranked []*repoStats
+	for _, s := range byRepo {
+		if s.issues >= minIssues {
+			s.cycleAvg = mean(s.cycles)
+			ranked = append(ranked, s)
+		}
+	}
+
+	// throughput: most closed issues first
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.issues != b.issues {
+			return a.issues > b.issues
+		}
+		if a.org != b.org {
+			return a.org < b.org
+		}
+		return a.repo < b.repo
+	})
+	for i, s := range ranked {
+		s.thrRank = i + 1
+		if i > 0 && s.issues == ranked[i-1].issues {
+			s.thrRank = ranked[i-1].thrRank
+		}
+	}
+
+	// cycle time: fastest first, repos without a cycle time last and unranked
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if (len(a.cycles) == 0) != (len(b.cycles) == 0) {
+			return len(b.cycles) == 0
+		}
+		return a.cycleAvg < b.cycleAvg
+	})
+	for i, s := range ranked {
+		if len(s.cycles) == 0 {
+			continue
+		}
+		s.cycleRank = i + 1
+		if i > 0 && s.cycleAvg == ranked[i-1].cycleAvg {
+			s.cycleRank = ranked[i-1].cycleRank
+		}
+	}
+
+	out := make([][]string, 0, len(ranked))
+	for _, s := range ranked {
+		cycleAvg, cycleP85, cycleRank := "", "", ""
+		if len(s.cycles) > 0 {
+			cycleAvg = df.format(s.cycleAvg)
+			cycleP85 = df.format(percentile(s.cycles, 0.85))
+			cycleRank = strconv.Itoa(s.cycleRank)
+		}
+		out = append(out, []string{
+			latest,
+			s.org,
+			s.repo,
+			strconv.Itoa(s.issues),
+			cycleAvg,
+			cycleP85,
+			cycleRank,
+			strconv.Itoa(s.thrRank),
+			df.unit,
+		})
+	}
+	return writeCSVFile(path, schema.Headers("repo_ranking.csv"), out, bom)
+}
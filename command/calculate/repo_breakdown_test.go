@@ -0,0 +1,201 @@
+package calculate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// closedIn returns an issue of org/repo closed at 12:00 UTC on day of month (2025), its cycle starting cycle days
+// earlier, or without a cycle time when cycle is negative.
+func closedIn(repo string, month time.Month, day int, cycle float64) calculatedIssue {
+	return closedAt(repo, time.Date(2025, month, day, 12, 0, 0, 0, time.UTC), cycle)
+}
+
+// closedAt returns an issue of org/repo closed at end, its cycle starting cycle days earlier, or without a cycle
+// time when cycle is negative.
+func closedAt(repo string, end time.Time, cycle float64) calculatedIssue {
+	org, name, _ := strings.Cut(repo, "/")
+	r := calculatedIssue{ID: repo, Org: org, Repo: name, EndDatetime: &end}
+	if cycle >= 0 {
+		start := end.Add(-time.Duration(cycle * 24 * float64(time.Hour)))
+		r.CycleTimeStartDatetime = &start
+	}
+	return r
+}
+
+// repeatClosed returns n issues of repo closed on day of month with a cycle time of cycle days.
+func repeatClosed(n int, repo string, month time.Month, day int, cycle float64) []calculatedIssue {
+	var res []calculatedIssue
+	for range n {
+		res = append(res, closedIn(repo, month, day, cycle))
+	}
+	return res
+}
+
+func TestWriteRepoRanking(t *testing.T) {
+	precision := 1
+	df, err := newDurationFormat("", &precision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		minIssues int
+		closed    [][]calculatedIssue
+		want      string
+	}{
+		{
+			name:      "repos under the minimum issues left out",
+			minIssues: 3,
+			closed: [][]calculatedIssue{
+				repeatClosed(3, "acme/api", time.March, 3, 2),
+				repeatClosed(2, "acme/web", time.March, 4, 1),
+				repeatClosed(4, "globex/infra", time.March, 5, 4),
+			},
+			want: "month,org,repo,issues_count,cycletime_days_avg,cycletime_days_p85,cycle_rank,throughput_rank,unit\n" +
+				"2025-03,acme,api,3,2.0,2.0,1,2,days\n" +
+				"2025-03,globex,infra,4,4.0,4.0,2,1,days\n",
+		},
+		{
+			name:      "ties share a rank",
+			minIssues: 1,
+			closed: [][]calculatedIssue{
+				repeatClosed(2, "acme/api", time.March, 3, 3),
+				repeatClosed(2, "acme/web", time.March, 4, 3),
+				repeatClosed(1, "acme/docs", time.March, 5, 1),
+			},
+			want: "month,org,repo,issues_count,cycletime_days_avg,cycletime_days_p85,cycle_rank,throughput_rank,unit\n" +
+				"2025-03,acme,docs,1,1.0,1.0,1,3,days\n" +
+				"2025-03,acme,api,2,3.0,3.0,2,1,days\n" +
+				"2025-03,acme,web,2,3.0,3.0,2,1,days\n",
+		},
+		{
+			name:      "repo without a cycle time last and unranked on cycle time",
+			minIssues: 1,
+			closed: [][]calculatedIssue{
+				repeatClosed(3, "acme/api", time.March, 3, -1),
+				repeatClosed(1, "acme/web", time.March, 4, 5),
+			},
+			want: "month,org,repo,issues_count,cycletime_days_avg,cycletime_days_p85,cycle_rank,throughput_rank,unit\n" +
+				"2025-03,acme,web,1,5.0,5.0,1,2,days\n" +
+				"2025-03,acme,api,3,,,,1,days\n",
+		},
+		{
+			name:      "latest month only",
+			minIssues: 2,
+			closed: [][]calculatedIssue{
+				repeatClosed(5, "acme/api", time.February, 10, 1),
+				repeatClosed(1, "acme/api", time.March, 3, 2),
+				repeatClosed(2, "acme/web", time.March, 4, 6),
+			},
+			want: "month,org,repo,issues_count,cycletime_days_avg,cycletime_days_p85,cycle_rank,throughput_rank,unit\n" +
+				"2025-03,acme,web,2,6.0,6.0,1,1,days\n",
+		},
+		{
+			name:      "no closed issues",
+			minIssues: 1,
+			want:      "month,org,repo,issues_count,cycletime_days_avg,cycletime_days_p85,cycle_rank,throughput_rank,unit\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var closed []calculatedIssue
+			for _, c := range tt.closed {
+				closed = append(closed, c...)
+			}
+			dir := t.TempDir()
+			if err := writeRepoRanking(filepath.Join(dir, "repo_ranking.csv"), closed, time.UTC, tt.minIssues, df, false); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.ReplaceAll(readTestFile(t, dir, "repo_ranking.csv"), "\r\n", "\n"); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMonthlyCycleByRepo(t *testing.T) {
+	precision := 1
+	df, err := newDurationFormat("", &precision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const header = "month,org,repo,cycletime_days_avg,cycletime_days_p85,cycle_count,throughput,unit,low_confidence\n"
+	tests := []struct {
+		name   string
+		closed [][]calculatedIssue
+		loc    *time.Location
+		gate   sampleGate
+		want   string
+	}{
+		{
+			name: "every repo on its own",
+			closed: [][]calculatedIssue{
+				repeatClosed(1, "acme/api", time.March, 3, 2),
+				repeatClosed(1, "acme/api", time.March, 4, 4),
+				repeatClosed(1, "acme/web", time.March, 5, 1),
+				repeatClosed(1, "globex/api", time.March, 6, 3),
+				repeatClosed(1, "acme/api", time.April, 1, 5),
+			},
+			want: header +
+				"2025-03,acme,api,3.0,4.0,2,2,days,false\n" +
+				"2025-03,acme,web,1.0,1.0,1,1,days,false\n" +
+				"2025-03,globex,api,3.0,3.0,1,1,days,false\n" +
+				"2025-04,acme,api,5.0,5.0,1,1,days,false\n",
+		},
+		{
+			name: "p85 gated below the sample size",
+			closed: [][]calculatedIssue{
+				repeatClosed(2, "acme/api", time.March, 3, 2),
+				repeatClosed(3, "acme/web", time.March, 4, 1),
+			},
+			gate: 3,
+			want: header +
+				"2025-03,acme,api,2.0,,2,2,days,true\n" +
+				"2025-03,acme,web,1.0,1.0,3,3,days,false\n",
+		},
+		{
+			name: "throughput counts the issues without a cycle time",
+			closed: [][]calculatedIssue{
+				repeatClosed(2, "acme/api", time.March, 3, -1),
+				repeatClosed(1, "acme/api", time.March, 4, 2),
+				repeatClosed(1, "acme/web", time.March, 5, -1),
+			},
+			want: header +
+				"2025-03,acme,api,2.0,2.0,1,3,days,false\n" +
+				"2025-03,acme,web,,,0,1,days,false\n",
+		},
+		{
+			name:   "months in the location",
+			closed: [][]calculatedIssue{{closedAt("acme/api", time.Date(2025, 3, 31, 23, 30, 0, 0, time.UTC), 1)}},
+			loc:    paris, // 31 March 23:30 UTC is 1 April in Paris
+			want: header +
+				"2025-04,acme,api,1.0,1.0,1,1,days,false\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var closed []calculatedIssue
+			for _, c := range tt.closed {
+				closed = append(closed, c...)
+			}
+			loc := time.UTC
+			if tt.loc != nil {
+				loc = tt.loc
+			}
+			dir := t.TempDir()
+			if err := writeMonthlyCycleByRepo(filepath.Join(dir, "cycle_time_by_repo.csv"), closed, loc, df, tt.gate, false); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.ReplaceAll(readTestFile(t, dir, "cycle_time_by_repo.csv"), "\r\n", "\n"); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
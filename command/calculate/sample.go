@@ -0,0 +1,40 @@
+package calculate
+
+import (
+	"strconv"
+
+	"cto-stats/connectors/config"
+)
+
+// defaultMinSampleSize is the min_sample_size used when the config leaves it unset.
+const defaultMinSampleSize = 5
+
+// sampleGate blanks the percentiles and control limits computed on fewer values than min_sample_size, so a
+// month of 2 issues shows no p95. 0 lets every sample through.
+type sampleGate int
+
+// newSampleGate returns the gate of cfg.MinSampleSize, defaultMinSampleSize when unset.
+func newSampleGate(cfg *config.Config) sampleGate {
+	if cfg.MinSampleSize != nil {
+		return sampleGate(*cfg.MinSampleSize)
+	}
+	return defaultMinSampleSize
+}
+
+// low reports whether a sample of n values is too small.
+func (g sampleGate) low(n int) bool {
+	return n < int(g)
+}
+
+// lowConfidence formats the low_confidence column of a sample of n values.
+func (g sampleGate) lowConfidence(n int) string {
+	return strconv.FormatBool(g.low(n))
+}
+
+// format formats v with format, or "" when the sample of n values is too small.
+func (g sampleGate) format(n int, v float64, format func(float64) string) string {
+	if g.low(n) {
+		return ""
+	}
+	return format(v)
+}
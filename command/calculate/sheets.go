@@ -0,0 +1,68 @@
+package calculate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cto-stats/connectors/sheets"
+	"cto-stats/domain/schema"
+)
+
+// sheetFiles returns the calculate outputs aggregated per month, week or quarter: the summaries worth a tab.
+// Per-issue and per-PR files are left out, they can exceed what a sheet holds.
+func sheetFiles() []schema.File {
+	var files []schema.File
+	for _, f := range schema.Files {
+		if f.WrittenBy != "calculate" {
+			continue
+		}
+		for _, c := range f.Columns {
+			if c.Type == schema.Month || c.Type == schema.Week || c.Type == schema.Quarter {
+				files = append(files, f)
+				break
+			}
+		}
+	}
+	return files
+}
+
+// exportSheets replaces the contents of one tab per summary file present in base, named after the file without
+// .csv, in the spreadsheet spreadsheetID. Without Google credentials (GCP_SERVICE_ACCOUNT_JSON or Application
+// Default Credentials) the export is skipped with a warning, so a scheduled run still succeeds.
+func exportSheets(spreadsheetID, base string) error {
+	ctx := context.Background()
+	client, err := sheets.New(ctx, os.Getenv("GCP_SERVICE_ACCOUNT_JSON"))
+	if err != nil {
+		slog.Warn("calculate.sheets.skip", "reason", err.Error())
+		return nil
+	}
+	var tabs []sheets.Tab
+	for _, f := range sheetFiles() {
+		idx, rows, err := readCSVFile(filepath.Join(base, f.Name))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return fmt.Errorf("calculate: sheets: %w", err)
+		}
+		headers := make([]string, len(idx))
+		for col, i := range idx {
+			headers[i] = col
+		}
+		tabs = append(tabs, sheets.Tab{Title: strings.TrimSuffix(f.Name, ".csv"), Rows: append([][]string{headers}, rows...)})
+	}
+	if len(tabs) == 0 {
+		slog.Info("calculate.sheets.skip", "reason", "no summary file in "+base)
+		return nil
+	}
+	if err := client.ReplaceTabs(ctx, spreadsheetID, tabs); err != nil {
+		return fmt.Errorf("calculate: %w", err)
+	}
+	slog.Info("calculate.sheets.done", "spreadsheet", spreadsheetID, "tabs", len(tabs))
+	return nil
+}
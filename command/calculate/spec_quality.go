@@ -0,0 +1,77 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// writeSpecQualityMonthly writes, per month (in loc), the median cycle time in days of the issues closed with
+// and without a description (has_description of issue.csv), and the share of the issues created that month
+// without one. Months come from the closing and creation dates within filter. Issues from an older issue.csv
+// without the description flag are left out, so such a file gives only the header.
+func writeSpecQualityMonthly(path string, rows []calculatedIssue, issues map[string]issueRow, loc *time.Location, filter issueFilter, bom bool) error {
+	type agg struct {
+		with, without          []float64
+		created, createdNoDesc int
+	}
+	byMonth := map[string]*agg{}
+	get := func(m string) *agg {
+		if byMonth[m] == nil {
+			byMonth[m] = &agg{}
+		}
+		return byMonth[m]
+	}
+	for _, r := range rows {
+		is, ok := issues[r.ID]
+		if !ok || !is.HasDescriptionData {
+			continue
+		}
+		if filter.contains(r.CreationDatetime) {
+			a := get(r.CreationDatetime.In(loc).Format("2006-01"))
+			a.created++
+			if !is.HasDescription {
+				a.createdNoDesc++
+			}
+		}
+		if r.EndDatetime == nil || !filter.contains(*r.EndDatetime) {
+			continue
+		}
+		days, ok := r.cycleDays()
+		if !ok {
+			continue
+		}
+		a := get(r.EndDatetime.In(loc).Format("2006-01"))
+		if is.HasDescription {
+			a.with = append(a.with, days)
+		} else {
+			a.without = append(a.without, days)
+		}
+	}
+	months := make([]string, 0, len(byMonth))
+	for m := range byMonth {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	var out [][]string
+	for _, m := range months {
+		a := byMonth[m]
+		share := 0.0
+		if a.created > 0 {
+			share = float64(a.createdNoDesc) / float64(a.created)
+		}
+		out = append(out, []string{
+			m,
+			fmt.Sprintf("%d", len(a.with)),
+			fmt.Sprintf("%d", len(a.without)),
+			fmt.Sprintf("%.6f", median(a.with)),
+			fmt.Sprintf("%.6f", median(a.without)),
+			fmt.Sprintf("%d", a.created),
+			fmt.Sprintf("%d", a.createdNoDesc),
+			fmt.Sprintf("%.6f", share),
+		})
+	}
+	return writeCSVFile(path, schema.Headers("spec_quality_month.csv"), out, bom)
+}
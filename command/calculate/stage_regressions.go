@@ -0,0 +1,138 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cto-stats/connectors/config"
+	"cto-stats/domain/schema"
+)
+
+// stageRegression is a move of an issue from a later-stage column back to an earlier-stage one
+// (e.g. QA bouncing an item back to In Progress).
+type stageRegression struct {
+	IssueID                string
+	ProjectID, ProjectName string
+	FromColumn, ToColumn   string
+	At                     time.Time
+}
+
+// stageRanks maps each configured column (lower-cased) of a project to its position in the workflow:
+// lead time < ready < dev < review < qa < waiting to prod < in prod. When a column is listed in several
+// groups, the later stage wins.
+func stageRanks(pc config.Project) map[string]int {
+	ranks := map[string]int{}
+	groups := [][]string{pc.LeadTimeColumns, pc.PutInReadyColumns, pc.DevStartColumns, pc.ReviewStartColumns, pc.QAStartColumns, pc.WaitingToProdStartCols, pc.InProdStartColumns}
+	for rank, cols := range groups {
+		for _, c := range cols {
+			if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+				ranks[c] = rank
+			}
+		}
+	}
+	return ranks
+}
+
+// findStageRegressions scans the ordered project moves of each issue on its own project and records every move
+// to an earlier stage than the previous configured column. Moves to columns that are not configured are ignored,
+// as are issues whose project is not in config.
+func findStageRegressions(rows []calculatedIssue, projByID map[string][]projectEventRow, projCfgByID map[string]config.Project) []stageRegression {
+	var res []stageRegression
+	for _, r := range rows {
+		pc, ok := projCfgByID[r.ProjectID]
+		if !ok {
+			continue
+		}
+		ranks := stageRanks(pc)
+		prevCol, prevRank := "", -1
+		for _, ev := range projByID[r.ID] {
+			if ev.EventType != "moved" || ev.ProjectID != r.ProjectID {
+				continue
+			}
+			rank, ok := ranks[strings.ToLower(strings.TrimSpace(ev.ToColumn))]
+			if !ok {
+				continue
+			}
+			if rank < prevRank {
+				res = append(res, stageRegression{
+					IssueID:     r.ID,
+					ProjectID:   r.ProjectID,
+					ProjectName: r.ProjectName,
+					FromColumn:  prevCol,
+					ToColumn:    ev.ToColumn,
+					At:          ev.At,
+				})
+			}
+			prevCol, prevRank = ev.ToColumn, rank
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if !res[i].At.Equal(res[j].At) {
+			return res[i].At.Before(res[j].At)
+		}
+		return res[i].IssueID < res[j].IssueID
+	})
+	return res
+}
+
+// writeStageRegressions writes every regression to path.
+func writeStageRegressions(path string, regs []stageRegression, bom bool) error {
+	var out [][]string
+	for _, g := range regs {
+		out = append(out, []string{g.IssueID, g.ProjectID, g.ProjectName, g.FromColumn, g.ToColumn, g.At.UTC().Format(time.RFC3339)})
+	}
+	return writeCSVFile(path, schema.Headers("stage_regressions.csv"), out, bom)
+}
+
+// writeStageRegressionsMonthly writes, per month (in loc), the number of regressions that happened, the number of
+// issues closed (by EndDatetime) and the share of those closed issues that went through at least one regression.
+// Only regressions inside the -since/-until window of filter are counted, but any regression of a closed issue
+// marks it as regressed.
+func writeStageRegressionsMonthly(path string, regs []stageRegression, closed []calculatedIssue, loc *time.Location, filter issueFilter, bom bool) error {
+	type agg struct{ regressions, closed, closedWithRegression int }
+	byMonth := map[string]*agg{}
+	get := func(m string) *agg {
+		if byMonth[m] == nil {
+			byMonth[m] = &agg{}
+		}
+		return byMonth[m]
+	}
+	regressed := map[string]bool{}
+	for _, g := range regs {
+		regressed[g.IssueID] = true
+		if filter.contains(g.At) {
+			get(g.At.In(loc).Format("2006-01")).regressions++
+		}
+	}
+	for _, r := range closed {
+		if r.EndDatetime == nil {
+			continue
+		}
+		a := get(r.EndDatetime.In(loc).Format("2006-01"))
+		a.closed++
+		if regressed[r.ID] {
+			a.closedWithRegression++
+		}
+	}
+	var out [][]string
+	for _, m := range continuousMonths(byMonth) {
+		a := byMonth[m]
+		if a == nil {
+			a = &agg{}
+		}
+		share := 0.0
+		if a.closed > 0 {
+			share = float64(a.closedWithRegression) / float64(a.closed)
+		}
+		out = append(out, []string{
+			m,
+			fmt.Sprintf("%d", a.regressions),
+			fmt.Sprintf("%d", a.closed),
+			fmt.Sprintf("%d", a.closedWithRegression),
+			fmt.Sprintf("%.6f", share),
+		})
+	}
+	return writeCSVFile(path, schema.Headers("stage_regressions_month.csv"), out, bom)
+}
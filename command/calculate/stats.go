@@ -0,0 +1,134 @@
+package calculate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// median returns the median of vals (0 for an empty slice). vals is sorted in place.
+func median(vals []float64) float64 {
+	n := len(vals)
+	if n == 0 {
+		return 0
+	}
+	sort.Float64s(vals)
+	if n%2 == 1 {
+		return vals[n/2]
+	}
+	return (vals[n/2-1] + vals[n/2]) / 2.0
+}
+
+// percentile returns the nearest-rank percentile p (0-1] of vals (0 for an empty slice),
+// matching the p90 used by the PR change-request reports. vals is sorted in place.
+func percentile(vals []float64, p float64) float64 {
+	n := len(vals)
+	if n == 0 {
+		return 0
+	}
+	sort.Float64s(vals)
+	rank := int(math.Ceil(p * float64(n)))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > n {
+		rank = n
+	}
+	return vals[rank-1]
+}
+
+// mean returns the arithmetic mean of vals (0 for an empty slice).
+func mean(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	return sum / float64(len(vals))
+}
+
+// rollingMean returns, for each index, the mean of the window values ending there;
+// positions without a full window are nil.
+func rollingMean(vals []float64, window int) []*float64 {
+	res := make([]*float64, len(vals))
+	for i := window - 1; i < len(vals); i++ {
+		m := mean(vals[i-window+1 : i+1])
+		res[i] = &m
+	}
+	return res
+}
+
+// trailingSlope returns, for each index, the least-squares slope (units per step) of the window values
+// ending there; positions without a full window are nil.
+func trailingSlope(vals []float64, window int) []*float64 {
+	res := make([]*float64, len(vals))
+	for i := window - 1; i < len(vals); i++ {
+		ys := vals[i-window+1 : i+1]
+		n := float64(len(ys))
+		var sumX, sumY, sumXY, sumXX float64
+		for x, y := range ys {
+			fx := float64(x)
+			sumX += fx
+			sumY += y
+			sumXY += fx * y
+			sumXX += fx * fx
+		}
+		den := n*sumXX - sumX*sumX
+		if den == 0 {
+			continue
+		}
+		slope := (n*sumXY - sumX*sumY) / den
+		res[i] = &slope
+	}
+	return res
+}
+
+// coefficientOfVariation returns the population standard deviation of vals divided by their mean, or nil when
+// the mean is zero (no throughput at all) or vals is empty.
+func coefficientOfVariation(vals []float64) *float64 {
+	m := mean(vals)
+	if m == 0 {
+		return nil
+	}
+	var sq float64
+	for _, v := range vals {
+		sq += (v - m) * (v - m)
+	}
+	cv := math.Sqrt(sq/float64(len(vals))) / m
+	return &cv
+}
+
+// Variability classes of the weekly throughput, by coefficient of variation.
+const (
+	lowVariabilityMaxCV    = 0.3
+	mediumVariabilityMaxCV = 0.6
+)
+
+// variabilityClass classifies a coefficient of variation as low, medium or high variability ("" for nil).
+func variabilityClass(cv *float64) string {
+	switch {
+	case cv == nil:
+		return ""
+	case *cv < lowVariabilityMaxCV:
+		return "low"
+	case *cv < mediumVariabilityMaxCV:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// formatFloat6 formats v with the usual 6 decimals.
+func formatFloat6(v float64) string {
+	return fmt.Sprintf("%.6f", v)
+}
+
+// formatOptionalFloat formats v with the usual 6 decimals, or "" when nil.
+func formatOptionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.6f", *v)
+}
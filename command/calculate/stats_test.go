@@ -0,0 +1,106 @@
+package calculate
+
+import (
+	"math"
+	"testing"
+)
+
+// optionalFloats formats vals with formatOptionalFloat, "-" standing for nil, for readable failures.
+func optionalFloats(vals []*float64) []string {
+	res := make([]string, len(vals))
+	for i, v := range vals {
+		res[i] = "-"
+		if v != nil {
+			res[i] = formatOptionalFloat(v)
+		}
+	}
+	return res
+}
+
+func sameOptionalFloats(got []*float64, want []*float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if (got[i] == nil) != (want[i] == nil) {
+			return false
+		}
+		if got[i] != nil && math.Abs(*got[i]-*want[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestRollingMean(t *testing.T) {
+	tests := []struct {
+		name   string
+		vals   []float64
+		window int
+		want   []*float64
+	}{
+		{"4 weeks", []float64{2, 4, 6, 8, 3}, 4, []*float64{nil, nil, nil, floatPtr(5), floatPtr(5.25)}},
+		{"window of 1", []float64{2, 4}, 1, []*float64{floatPtr(2), floatPtr(4)}},
+		{"shorter than the window", []float64{2, 4}, 4, []*float64{nil, nil}},
+		{"empty", nil, 4, []*float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rollingMean(tt.vals, tt.window); !sameOptionalFloats(got, tt.want) {
+				t.Errorf("got %v, want %v", optionalFloats(got), optionalFloats(tt.want))
+			}
+		})
+	}
+}
+
+func TestTrailingSlope(t *testing.T) {
+	tests := []struct {
+		name   string
+		vals   []float64
+		window int
+		want   []*float64
+	}{
+		// 1,2,3 rises by 1; 2,3,3 by 0.5 (sxy 1 / sxx 2); 3,3,1 falls by 1 (sxy -2 / sxx 2)
+		{"3 weeks", []float64{1, 2, 3, 3, 1}, 3, []*float64{nil, nil, floatPtr(1), floatPtr(0.5), floatPtr(-1)}},
+		{"flat", []float64{4, 4, 4}, 3, []*float64{nil, nil, floatPtr(0)}},
+		{"window of 1 has no slope", []float64{2, 4}, 1, []*float64{nil, nil}},
+		{"shorter than the window", []float64{2, 4}, 3, []*float64{nil, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trailingSlope(tt.vals, tt.window); !sameOptionalFloats(got, tt.want) {
+				t.Errorf("got %v, want %v", optionalFloats(got), optionalFloats(tt.want))
+			}
+		})
+	}
+}
+
+func TestCoefficientOfVariation(t *testing.T) {
+	tests := []struct {
+		name      string
+		vals      []float64
+		want      *float64
+		wantClass string
+	}{
+		// mean 5, population standard deviation 2
+		{"known series", []float64{2, 4, 4, 4, 5, 5, 7, 9}, floatPtr(0.4), "medium"},
+		{"steady", []float64{10, 10, 10, 10}, floatPtr(0), "low"},
+		// mean 2, standard deviation 2
+		{"bursty", []float64{0, 0, 4, 4}, floatPtr(1), "high"},
+		{"no throughput", []float64{0, 0, 0}, nil, ""},
+		{"empty", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := coefficientOfVariation(tt.vals)
+			if !sameOptionalFloats([]*float64{got}, []*float64{tt.want}) {
+				t.Errorf("cv %v, want %v", optionalFloats([]*float64{got}), optionalFloats([]*float64{tt.want}))
+			}
+			if class := variabilityClass(got); class != tt.wantClass {
+				t.Errorf("class %q, want %q", class, tt.wantClass)
+			}
+		})
+	}
+}
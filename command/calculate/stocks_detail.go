@@ -0,0 +1,60 @@
+package calculate
+
+import (
+	"errors"
+	"os"
+
+	"cto-stats/domain/schema"
+)
+
+// readCurrentColumns loads issue_current_project.csv into issue id -> project id -> board column.
+// A missing file (imports made before it existed) yields an empty map.
+func readCurrentColumns(path string) (map[string]map[string]string, error) {
+	res := map[string]map[string]string{}
+	idx, rows, err := readCSVFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return res, nil
+		}
+		return nil, err
+	}
+	for _, rec := range rows {
+		id := key(field(idx, rec, "org"), field(idx, rec, "repo"), field(idx, rec, "number"))
+		if res[id] == nil {
+			res[id] = map[string]string{}
+		}
+		res[id][field(idx, rec, "project_id")] = field(idx, rec, "column_name")
+	}
+	return res, nil
+}
+
+// currentStage returns the stocks.csv bucket of a not-closed issue: the furthest stage reached wins.
+func currentStage(r calculatedIssue) string {
+	switch {
+	case r.WaitingToPodStartDatetime != nil:
+		return "waiting_to_prod"
+	case r.QAStartDatetime != nil:
+		return "in_qa"
+	case r.ReviewStartDatetime != nil:
+		return "in_review"
+	case r.DevStartDatetime != nil:
+		return "in_dev"
+	case r.PutInReadyStartDatetime != nil:
+		return "in_ready"
+	default:
+		return "in_backlogs"
+	}
+}
+
+// writeStocksDetail writes one row per not-closed issue with its derived stage (as counted in stocks.csv)
+// next to the literal board column it currently sits in, which may not belong to any configured group.
+func writeStocksDetail(path string, rows []calculatedIssue, bom bool) error {
+	var out [][]string
+	for _, r := range rows {
+		if r.EndDatetime != nil {
+			continue
+		}
+		out = append(out, []string{r.ID, r.Name, r.ProjectID, r.ProjectName, currentStage(r), r.CurrentColumn, r.URL})
+	}
+	return writeCSVFile(path, schema.Headers("stocks_detail.csv"), out, bom)
+}
@@ -0,0 +1,53 @@
+package calculate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCurrentColumnOutsideColumnGroups(t *testing.T) {
+	// acme/api#3 went from In Progress to Blocked on vendor, a column of no configured group
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"legacy columns", "github:\n  org: acme\n"},
+		{"configured project", "github:\n  org: acme\n  projects:\n    - id: \"101\"\n      name: Platform\n" +
+			"      lead_time_columns: [Backlog]\n      cycle_time_columns: [In Progress]\n      dev_start_columns: [In Progress]\n" +
+			"      review_start_columns: [In review]\n      qa_start_columns: [QA]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.CopyFS(dir, os.DirFS(filepath.Join("testdata", "multi", "product"))); err != nil {
+				t.Fatal(err)
+			}
+			events := readTestFile(t, dir, "issue_project_event.csv")
+			writeTestFile(t, dir, "issue_project_event.csv", strings.Replace(events,
+				"acme,api,3,101,Platform,Backlog,In Progress,2025-03-11T09:00:00Z,ann,moved\n",
+				"acme,api,3,101,Platform,Backlog,In Progress,2025-03-11T09:00:00Z,ann,moved\n"+
+					"acme,api,3,101,Platform,In Progress,Blocked on vendor,2025-03-12T09:00:00Z,ann,moved\n", 1))
+			current := readTestFile(t, dir, "issue_current_project.csv")
+			writeTestFile(t, dir, "issue_current_project.csv", strings.Replace(current,
+				"acme,api,3,101,Platform,In Progress\n", "acme,api,3,101,Platform,Blocked on vendor\n", 1))
+			writeTestFile(t, dir, "config.yml", tt.config)
+			t.Setenv("CONFIG_PATH", filepath.Join(dir, "config.yml"))
+			t.Chdir(dir)
+			if err := Run([]string{"-data", ".", "-out", "out"}); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, file := range []string{"calculated_issue.csv", "stocks_detail.csv"} {
+				if got := readColumnOf(t, filepath.Join("out", file), "id", "current_column")["acme/api#3"]; got != "Blocked on vendor" {
+					t.Errorf("%s current_column %q, want the literal board column", file, got)
+				}
+			}
+			// the column maps to no stage: the issue stays in the bucket of the furthest stage it reached
+			if got := readColumnOf(t, filepath.Join("out", "stocks_detail.csv"), "id", "stage")["acme/api#3"]; got != "in_dev" {
+				t.Errorf("stocks_detail.csv stage %q, want in_dev", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package calculate
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"time"
+)
+
+const defaultStocksHistoryDays = 400
+
+// writeStocksHistory appends the stocks.csv just written at stocksPath to historyPath as one block of rows keyed
+// by snapshot_date (the run date in loc). Re-running on the same day replaces that day's block, and snapshots
+// older than retentionDays are pruned. Unlike stocks_week.csv, which is rebuilt from events on every run, the
+// history keeps exactly what was calculated at the time.
+func writeStocksHistory(historyPath, stocksPath string, now time.Time, retentionDays int, loc *time.Location, bom bool) error {
+	if retentionDays <= 0 {
+		retentionDays = defaultStocksHistoryDays
+	}
+	idx, rows, err := readCSVFile(stocksPath)
+	if err != nil {
+		return err
+	}
+	// stocks.csv columns in file order, so the history follows its schema
+	cols := make([]string, len(idx))
+	for c, i := range idx {
+		cols[i] = c
+	}
+	snapshot := now.In(loc).Format("2006-01-02")
+	oldest := now.In(loc).AddDate(0, 0, -retentionDays).Format("2006-01-02")
+
+	var out [][]string
+	hIdx, hRows, err := readCSVFile(historyPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, rec := range hRows {
+		d := field(hIdx, rec, "snapshot_date")
+		if d == snapshot || d < oldest {
+			continue
+		}
+		row := []string{d}
+		for _, c := range cols {
+			row = append(row, field(hIdx, rec, c))
+		}
+		out = append(out, row)
+	}
+	for _, rec := range rows {
+		out = append(out, append([]string{snapshot}, rec...))
+	}
+	// blocks ordered by date; rows keep their stocks.csv order inside a block
+	sort.SliceStable(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return writeCSVFile(historyPath, append([]string{"snapshot_date"}, cols...), out, bom)
+}
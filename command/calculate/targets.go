@@ -0,0 +1,54 @@
+package calculate
+
+import (
+	"fmt"
+	"strings"
+
+	"cto-stats/connectors/config"
+)
+
+// resolveTargets returns the targets that apply to the outputs: the org-wide ones, overridden metric by metric
+// by the targets of project (id or name) when the run is restricted to it with -project.
+func resolveTargets(t config.Targets, project string) config.TargetValues {
+	res := t.TargetValues
+	if strings.TrimSpace(project) == "" {
+		return res
+	}
+	for _, pt := range t.Projects {
+		if !equalFoldTrim(pt.Project, project) {
+			continue
+		}
+		if pt.CycleTimeDays != nil {
+			res.CycleTimeDays = pt.CycleTimeDays
+		}
+		if pt.LeadTimeDays != nil {
+			res.LeadTimeDays = pt.LeadTimeDays
+		}
+		if pt.ThroughputPerWeek != nil {
+			res.ThroughputPerWeek = pt.ThroughputPerWeek
+		}
+		if pt.BugRatio != nil {
+			res.BugRatio = pt.BugRatio
+		}
+	}
+	return res
+}
+
+// ConfigWarnings lists config entries that are valid but probably mistaken, such as targets for a project that
+// is not in github.projects (matched by id or name).
+func ConfigWarnings(cfg *config.Config) []string {
+	var warnings []string
+	for i, pt := range cfg.Targets.Projects {
+		known := false
+		for _, p := range cfg.GitHub.Projects {
+			if equalFoldTrim(pt.Project, p.ID) || equalFoldTrim(pt.Project, p.Name) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			warnings = append(warnings, fmt.Sprintf("targets.projects[%d]: unknown project %q", i, pt.Project))
+		}
+	}
+	return warnings
+}
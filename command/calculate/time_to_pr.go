@@ -0,0 +1,75 @@
+package calculate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Definitions of the time to PR (time_to_pr.source) that dashboards follow, written in the time_to_pr_source
+// column of cycle_time.csv.
+const (
+	timeToPRBoard    = "board"     // development start to review start, from the board columns
+	timeToPRLinkedPR = "linked_pr" // development start to the creation of the earliest linked pull request
+)
+
+// normalizeTimeToPRSource validates time_to_pr.source, board when empty.
+func normalizeTimeToPRSource(source string) (string, error) {
+	switch s := strings.ToLower(strings.TrimSpace(source)); s {
+	case "":
+		return timeToPRBoard, nil
+	case timeToPRBoard, timeToPRLinkedPR:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown time_to_pr.source %q (expected board or linked_pr)", source)
+	}
+}
+
+// readFirstPRCreation returns, per issue id, the creation time of the earliest pull request linked to it
+// (pr_issue_link.csv) among the PRs of pr.csv in dir. Missing files yield an empty map.
+func readFirstPRCreation(dir string) (map[string]time.Time, error) {
+	first := map[string]time.Time{}
+	idx, rows, err := readCSVFile(filepath.Join(dir, "pr.csv"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return first, nil
+		}
+		return nil, err
+	}
+	created := map[string]time.Time{}
+	for _, rec := range rows {
+		if t, err := time.Parse(time.RFC3339, field(idx, rec, "created_at")); err == nil {
+			created[key(field(idx, rec, "org"), field(idx, rec, "repo"), field(idx, rec, "number"))] = t
+		}
+	}
+	idx, rows, err = readCSVFile(filepath.Join(dir, "pr_issue_link.csv"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return first, nil
+		}
+		return nil, err
+	}
+	for _, rec := range rows {
+		t, ok := created[key(field(idx, rec, "org"), field(idx, rec, "repo"), field(idx, rec, "number"))]
+		if !ok {
+			continue
+		}
+		issueID := key(field(idx, rec, "issue_org"), field(idx, rec, "issue_repo"), field(idx, rec, "issue_number"))
+		if f, seen := first[issueID]; !seen || t.Before(f) {
+			first[issueID] = t
+		}
+	}
+	return first, nil
+}
+
+// timeToPRActualDays returns the days from development start to the creation of the earliest linked PR of r,
+// false when either is missing, the PR was opened first or r has a clock anomaly.
+func (r calculatedIssue) timeToPRActualDays() (float64, bool) {
+	if r.ClockAnomaly {
+		return 0, false
+	}
+	return daysBetween(r.DevStartDatetime, r.FirstPRCreatedDatetime)
+}
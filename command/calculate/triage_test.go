@@ -0,0 +1,86 @@
+package calculate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTriageColumnsLeadTime(t *testing.T) {
+	const (
+		issues = "org,repo,number,title,url,state,type,is_bug,creator,assignees,created_at,closed_at\n" +
+			"acme,api,1,Export to CSV,https://github.com/acme/api/issues/1,closed,feature,false,zed,ann,2025-03-03T08:00:00Z,2025-03-20T12:00:00Z\n"
+		status = "org,repo,number,type,at,by\n" +
+			"acme,api,1,opened,2025-03-03T08:00:00Z,zed\n" +
+			"acme,api,1,closed,2025-03-20T12:00:00Z,bob\n"
+	)
+	tests := []struct {
+		name          string
+		project       string // config of project 101 under github.projects
+		moves         []string
+		wantLeadStart string
+	}{
+		{
+			name:          "starts when the issue leaves triage",
+			project:       "lead_time_columns: [Backlog]\n      triage_columns: [Triage]",
+			moves:         []string{"Triage 2025-03-03T09:00:00Z", "Backlog 2025-03-10T09:00:00Z", "In Progress 2025-03-12T09:00:00Z", "Done 2025-03-20T11:00:00Z"},
+			wantLeadStart: "2025-03-10T09:00:00Z",
+		},
+		{
+			name:          "rather than at the first move to a lead time column",
+			project:       "lead_time_columns: [Triage, Backlog]\n      triage_columns: [Triage]",
+			moves:         []string{"Triage 2025-03-03T09:00:00Z", "Backlog 2025-03-10T09:00:00Z", "Done 2025-03-20T11:00:00Z"},
+			wantLeadStart: "2025-03-10T09:00:00Z",
+		},
+		{
+			name:          "without triage columns the lead time columns apply",
+			project:       "lead_time_columns: [Triage, Backlog]",
+			moves:         []string{"Triage 2025-03-03T09:00:00Z", "Backlog 2025-03-10T09:00:00Z", "Done 2025-03-20T11:00:00Z"},
+			wantLeadStart: "2025-03-03T09:00:00Z",
+		},
+		{
+			name:          "after every triage column, matched ignoring case",
+			project:       "lead_time_columns: [Backlog]\n      triage_columns: [Triage, Needs Info]",
+			moves:         []string{"Triage 2025-03-03T09:00:00Z", "needs info 2025-03-05T09:00:00Z", "Triage 2025-03-06T09:00:00Z", "Ready 2025-03-11T09:00:00Z", "Done 2025-03-20T11:00:00Z"},
+			wantLeadStart: "2025-03-11T09:00:00Z",
+		},
+		{
+			name:          "straight to development",
+			project:       "lead_time_columns: [Backlog]\n      triage_columns: [Triage]",
+			moves:         []string{"Triage 2025-03-03T09:00:00Z", "In Progress 2025-03-12T09:00:00Z", "Done 2025-03-20T11:00:00Z"},
+			wantLeadStart: "2025-03-12T09:00:00Z",
+		},
+		{
+			name:    "closed without leaving triage",
+			project: "lead_time_columns: [Backlog]\n      triage_columns: [Triage]",
+			moves:   []string{"Triage 2025-03-03T09:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			events := "org,repo,number,project_id,project_name,from_column,to_column,at,by,type\n" +
+				"acme,api,1,101,Platform,,,2025-03-03T09:00:00Z,ann,added\n"
+			from := ""
+			for _, m := range tt.moves {
+				i := strings.LastIndex(m, " ")
+				events += "acme,api,1,101,Platform," + from + "," + m[:i] + "," + m[i+1:] + ",ann,moved\n"
+				from = m[:i]
+			}
+			writeTestFile(t, dir, "issue.csv", issues)
+			writeTestFile(t, dir, "issue_status_event.csv", status)
+			writeTestFile(t, dir, "issue_project_event.csv", events)
+			writeTestFile(t, dir, "config.yml", "github:\n  org: acme\n  projects:\n    - id: \"101\"\n      "+tt.project+"\n")
+			t.Setenv("CONFIG_PATH", filepath.Join(dir, "config.yml"))
+			t.Setenv("NOTIFY_WEBHOOK_URL", "")
+			t.Chdir(dir)
+			if err := Run([]string{"-data", ".", "-out", "out", "-issues"}); err != nil {
+				t.Fatal(err)
+			}
+			starts := readColumnOf(t, filepath.Join("out", "calculated_issue.csv"), "id", "leadtimestartdatetime")
+			if got, ok := starts["acme/api#1"]; !ok || got != tt.wantLeadStart {
+				t.Errorf("lead time start %q, want %q", got, tt.wantLeadStart)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// writeUnboardedIssues writes the issues of issue.csv that have no row in issue_project_event.csv to path, with
+// their state and age in days (to closing for closed issues, to now for open ones), and per repository to
+// summaryPath how many issues are unboarded, plus an ALL row. Those issues never reach a board column, so the
+// flow metrics silently count them in the legacy backlog bucket.
+func writeUnboardedIssues(path, summaryPath string, issues map[string]issueRow, projByID map[string][]projectEventRow, now time.Time, bom bool) error {
+	type repoCount struct{ total, unboarded, open int }
+	counts := map[string]*repoCount{}
+	var ids []string
+	for id, is := range issues {
+		for _, repo := range []string{is.Repo, "ALL"} {
+			c := counts[repo]
+			if c == nil {
+				c = &repoCount{}
+				counts[repo] = c
+			}
+			c.total++
+			if len(projByID[id]) > 0 {
+				continue
+			}
+			c.unboarded++
+			if is.ClosedAt == nil {
+				c.open++
+			}
+		}
+		if len(projByID[id]) == 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := issues[ids[i]], issues[ids[j]]
+		if a.Org != b.Org {
+			return a.Org < b.Org
+		}
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		na, _ := strconv.Atoi(a.Number)
+		nb, _ := strconv.Atoi(b.Number)
+		return na < nb
+	})
+	var out [][]string
+	for _, id := range ids {
+		is := issues[id]
+		state, end := "open", now
+		if is.ClosedAt != nil {
+			state, end = "closed", *is.ClosedAt
+		}
+		age := max(end.Sub(is.CreatedAt).Hours()/24, 0)
+		out = append(out, []string{
+			id,
+			is.Org,
+			is.Repo,
+			is.Number,
+			is.Title,
+			state,
+			formatTime(&is.CreatedAt),
+			formatTime(is.ClosedAt),
+			fmt.Sprintf("%.6f", age),
+		})
+	}
+	if err := writeCSVFile(path, schema.Headers("unboarded_issues.csv"), out, bom); err != nil {
+		return err
+	}
+
+	repos := make([]string, 0, len(counts))
+	for r := range counts {
+		if r != "ALL" {
+			repos = append(repos, r)
+		}
+	}
+	sort.Strings(repos)
+	var summary [][]string
+	if len(counts) > 0 {
+		for _, r := range append(repos, "ALL") {
+			c := counts[r]
+			summary = append(summary, []string{
+				r,
+				fmt.Sprintf("%d", c.total),
+				fmt.Sprintf("%d", c.unboarded),
+				fmt.Sprintf("%d", c.open),
+				fmt.Sprintf("%.6f", float64(c.unboarded)/float64(c.total)),
+			})
+		}
+	}
+	return writeCSVFile(summaryPath, schema.Headers("unboarded_issues_repo.csv"), summary, bom)
+}
@@ -0,0 +1,122 @@
+package calculate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cto-stats/connectors/config"
+	"cto-stats/connectors/notify"
+)
+
+// ValidateConfig reports the config values calculate would reject or silently ignore: an unknown timezone,
+// cr_count_mode or dora.failure_match, a negative pr.approvals_required, pr.cr_round_gap_hours, min_sample_size,
+// repo_breakdown.min_issues, repo_breakdown.ranking_min_issues or wip.personal_limit, an unknown durations.unit or a precision outside 0-6,
+// an unknown time_to_pr.source, an unknown notifications.format or a webhook or dashboard URL that is not
+// http(s), non-positive size weights, empty, repeated or reserved severity labels, cloud accounts listed under
+// two cloud_spending.environments, a fiscal year start month outside 1-12, projects without an id or listed
+// twice, invalid backlog buckets, and unknown or overlapping column_aliases stages.
+func ValidateConfig(cfg *config.Config) error {
+	var errs []error
+	if _, err := loadLocation(cfg.Timezone); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := normalizeCRCountMode(cfg.PR.CRCountMode); err != nil {
+		errs = append(errs, fmt.Errorf("pr: %w", err))
+	}
+	if m := strings.ToLower(strings.TrimSpace(cfg.DORA.FailureMatch)); m != "" {
+		if _, ok := failureMatchers[m]; !ok {
+			errs = append(errs, fmt.Errorf("unknown dora.failure_match %q (expected bug_issue or hotfix_pr)", cfg.DORA.FailureMatch))
+		}
+	}
+	if n := cfg.PR.ApprovalsRequired; n < 0 {
+		errs = append(errs, fmt.Errorf("pr.approvals_required: %d is negative", n))
+	}
+	if n := cfg.PR.CRRoundGapHours; n < 0 {
+		errs = append(errs, fmt.Errorf("pr.cr_round_gap_hours: %d is negative", n))
+	}
+	if n := cfg.MinSampleSize; n != nil && *n < 0 {
+		errs = append(errs, fmt.Errorf("min_sample_size: %d is negative", *n))
+	}
+	if n := cfg.RepoBreakdown.MinIssues; n < 0 {
+		errs = append(errs, fmt.Errorf("repo_breakdown.min_issues: %d is negative", n))
+	}
+	if n := cfg.RepoBreakdown.RankingMinIssues; n != nil && *n < 0 {
+		errs = append(errs, fmt.Errorf("repo_breakdown.ranking_min_issues: %d is negative", *n))
+	}
+	if n := cfg.WIP.PersonalLimit; n < 0 {
+		errs = append(errs, fmt.Errorf("wip.personal_limit: %d is negative", n))
+	}
+	if _, err := newDurationFormat(cfg.Durations.Unit, cfg.Durations.Precision); err != nil {
+		errs = append(errs, fmt.Errorf("durations: %w", err))
+	}
+	if _, err := normalizeTimeToPRSource(cfg.TimeToPR.Source); err != nil {
+		errs = append(errs, err)
+	}
+	if err := notify.Validate(cfg.Notifications); err != nil {
+		errs = append(errs, err)
+	}
+	labels := make([]string, 0, len(cfg.GitHub.SizeWeights))
+	for label := range cfg.GitHub.SizeWeights {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		if w := cfg.GitHub.SizeWeights[label]; w <= 0 {
+			errs = append(errs, fmt.Errorf("github.size_weights: weight %v of label %q is not positive", w, label))
+		}
+	}
+	severities := map[string]bool{}
+	for i, label := range cfg.GitHub.SeverityLabels {
+		l := strings.ToLower(strings.TrimSpace(label))
+		switch {
+		case l == "":
+			errs = append(errs, fmt.Errorf("github.severity_labels[%d] is empty", i))
+		case l == unclassifiedSeverity:
+			errs = append(errs, fmt.Errorf("github.severity_labels: %q is the severity of bugs without a severity label", label))
+		case severities[l]:
+			errs = append(errs, fmt.Errorf("github.severity_labels: %q is listed twice", label))
+		}
+		severities[l] = true
+	}
+	envs := make([]string, 0, len(cfg.CloudSpending.Environments))
+	for env := range cfg.CloudSpending.Environments {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	envOf := map[string]string{}
+	for _, env := range envs {
+		if strings.EqualFold(strings.TrimSpace(env), unmappedEnvironment) {
+			errs = append(errs, fmt.Errorf("cloud_spending.environments: %q is the environment of unmapped accounts", env))
+		}
+		for _, id := range cfg.CloudSpending.Environments[env] {
+			id = strings.ToLower(strings.TrimSpace(id))
+			if prev, ok := envOf[id]; ok && prev != env {
+				errs = append(errs, fmt.Errorf("cloud_spending.environments: %s is listed under %s and %s", id, prev, env))
+			}
+			envOf[id] = env
+		}
+	}
+	if m := cfg.FiscalYearStartMonth; m < 0 || m > 12 {
+		errs = append(errs, fmt.Errorf("fiscal_year_start_month: %d is not a month (1-12)", m))
+	}
+	seen := map[string]bool{}
+	for i, p := range cfg.GitHub.Projects {
+		id := strings.TrimSpace(p.ID)
+		switch {
+		case id == "":
+			errs = append(errs, fmt.Errorf("github.projects[%d] (%s) has no id", i, p.Name))
+		case seen[id]:
+			errs = append(errs, fmt.Errorf("github.projects: id %s is listed twice", id))
+		}
+		seen[id] = true
+	}
+	errs = append(errs, validateColumnAliases(cfg.ColumnAliases)...)
+	for _, b := range cfg.Backlog.AgeBuckets {
+		if b <= 0 {
+			errs = append(errs, fmt.Errorf("backlog.age_buckets: %d is not a positive number of days", b))
+		}
+	}
+	return errors.Join(errs...)
+}
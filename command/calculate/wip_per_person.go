@@ -0,0 +1,113 @@
+package calculate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cto-stats/domain/schema"
+)
+
+// unassignedLogin is the login of the wip_per_person.csv row of in-progress issues without a (non-bot) assignee.
+const unassignedLogin = "(unassigned)"
+
+// writeWIPPerPerson writes to path, for each non-bot assignee, the open issues of rows currently in development,
+// review or QA assigned to them (an issue with two assignees counts for both), the oldest one with its days in
+// progress (since its development start, or the first later stage reached), and whether the count exceeds
+// limit (wip.personal_limit, 0 for none). In-progress issues without assignee share one (unassigned) row, never
+// over the limit. Rows are sorted by WIP, highest first, with the unassigned row last.
+func writeWIPPerPerson(path string, rows []calculatedIssue, issues map[string]issueRow, bots []string, limit int, now time.Time, bom bool) error {
+	isBot := botFilter(bots)
+	type wip struct {
+		login               string
+		total, dev, rev, qa int
+		oldestID            string
+		oldestStart         time.Time
+	}
+	byLogin := map[string]*wip{}
+	for _, r := range rows {
+		if r.EndDatetime != nil {
+			continue
+		}
+		stage := currentStage(r)
+		if stage != "in_dev" && stage != "in_review" && stage != "in_qa" {
+			continue
+		}
+		start := r.CreationDatetime
+		for _, t := range []*time.Time{r.DevStartDatetime, r.ReviewStartDatetime, r.QAStartDatetime} {
+			if t != nil {
+				start = *t
+				break
+			}
+		}
+		var logins []string
+		for _, a := range issues[r.ID].Assignees {
+			if !isBot(a) {
+				logins = append(logins, strings.ToLower(strings.TrimSpace(a)))
+			}
+		}
+		if len(logins) == 0 {
+			logins = []string{unassignedLogin}
+		}
+		for _, l := range logins {
+			w := byLogin[l]
+			if w == nil {
+				w = &wip{login: l}
+				byLogin[l] = w
+			}
+			w.total++
+			switch stage {
+			case "in_dev":
+				w.dev++
+			case "in_review":
+				w.rev++
+			case "in_qa":
+				w.qa++
+			}
+			if w.oldestID == "" || start.Before(w.oldestStart) || (start.Equal(w.oldestStart) && r.ID < w.oldestID) {
+				w.oldestID, w.oldestStart = r.ID, start
+			}
+		}
+	}
+
+	list := make([]*wip, 0, len(byLogin))
+	for _, w := range byLogin {
+		list = append(list, w)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if (a.login == unassignedLogin) != (b.login == unassignedLogin) {
+			return b.login == unassignedLogin
+		}
+		if a.total != b.total {
+			return a.total > b.total
+		}
+		return a.login < b.login
+	})
+	limitStr := ""
+	if limit > 0 {
+		limitStr = fmt.Sprintf("%d", limit)
+	}
+	out := make([][]string, 0, len(list))
+	for _, w := range list {
+		over, rowLimit := false, limitStr
+		if w.login == unassignedLogin {
+			rowLimit = ""
+		} else {
+			over = limit > 0 && w.total > limit
+		}
+		out = append(out, []string{
+			w.login,
+			fmt.Sprintf("%d", w.total),
+			fmt.Sprintf("%d", w.dev),
+			fmt.Sprintf("%d", w.rev),
+			fmt.Sprintf("%d", w.qa),
+			w.oldestID,
+			fmt.Sprintf("%.6f", max(now.Sub(w.oldestStart).Hours()/24, 0)),
+			rowLimit,
+			fmt.Sprintf("%t", over),
+		})
+	}
+	return writeCSVFile(path, schema.Headers("wip_per_person.csv"), out, bom)
+}
@@ -0,0 +1,166 @@
+// Package cli holds what the subcommands share about their command line: the help printed on -h and on flag
+// errors, the top-level usage and the shell completion scripts.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Command describes a subcommand for its help, the top-level usage and the completion scripts.
+type Command struct {
+	Name string
+	// Summary is the one-line description listed by the top-level usage
+	Summary string
+	// Synopsis follows the command name in the usage line, e.g. "[-org <org>] [-since <time>]"
+	Synopsis string
+	// Description is printed under the usage line; may span several lines
+	Description string
+	Examples    []string
+	// Env lists the environment variables the command reads, as "NAME  description"
+	Env []string
+	// Run executes the command with its arguments
+	Run func(args []string) error
+}
+
+// UsageError is returned by Parse for invalid flags. The flag package has already printed the error and the help,
+// so callers only set the exit status.
+type UsageError struct{ Err error }
+
+func (e *UsageError) Error() string { return e.Err.Error() }
+func (e *UsageError) Unwrap() error { return e.Err }
+
+// ExitError is returned by a command that ran but must exit with a specific status, e.g. calculate -strict on
+// data quality errors.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// inspect, when set, receives the flag set of a command instead of its help being printed (see Flags).
+var inspect func(fs *flag.FlagSet)
+
+// SetUsage makes fs print the help of c (usage line, description, flags, examples and environment variables)
+// on -h, -help and flag errors.
+func SetUsage(fs *flag.FlagSet, c Command) {
+	fs.Usage = func() {
+		if inspect != nil {
+			inspect(fs)
+			return
+		}
+		PrintHelp(fs.Output(), fs, c)
+	}
+}
+
+// PrintHelp writes the help of c with the flags of fs to w.
+func PrintHelp(w io.Writer, fs *flag.FlagSet, c Command) {
+	fmt.Fprintf(w, "usage: cto-stats %s %s\n", c.Name, c.Synopsis)
+	if c.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimRight(c.Description, "\n"))
+	}
+	fmt.Fprintln(w, "\nflags:")
+	out := fs.Output()
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fs.SetOutput(out)
+	if len(c.Examples) > 0 {
+		fmt.Fprintln(w, "\nexamples:")
+		for _, e := range c.Examples {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+	if len(c.Env) > 0 {
+		fmt.Fprintln(w, "\nenvironment:")
+		for _, e := range c.Env {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+}
+
+// Parse parses args with fs, returning flag.ErrHelp for -h and -help and a *UsageError for invalid flags.
+func Parse(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return &UsageError{Err: err}
+}
+
+// StringList is a repeatable flag whose values may also be comma-separated.
+type StringList []string
+
+func (l *StringList) String() string { return strings.Join(*l, ",") }
+
+func (l *StringList) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
+
+// Flags returns the flags of c, sorted by name, by running it with -h while the help is captured instead of
+// printed. Every command declares its flags and parses them before doing anything else.
+func Flags(c Command) []*flag.Flag {
+	var flags []*flag.Flag
+	inspect = func(fs *flag.FlagSet) {
+		fs.VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+	}
+	defer func() { inspect = nil }()
+	_ = c.Run([]string{"-h"})
+	return flags
+}
+
+// PrintUsage writes the top-level usage listing commands to w.
+func PrintUsage(w io.Writer, commands []Command) {
+	fmt.Fprintln(w, "usage: cto-stats <command> [flags]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprintln(w, "\nenvironment:")
+	fmt.Fprintln(w, "  CONFIG_PATH  YAML config file (default ./config.yml)")
+	fmt.Fprintln(w, "\nRun 'cto-stats <command> -h' for the flags of a command.")
+}
+
+// Suggest returns the command name closest to name, or "" when none is close enough to be a typo.
+func Suggest(name string, commands []Command) string {
+	best, bestDist := "", 3
+	for _, c := range commands {
+		if strings.HasPrefix(c.Name, name) && name != "" {
+			return c.Name
+		}
+		if d := editDistance(name, c.Name); d < bestDist {
+			best, bestDist = c.Name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
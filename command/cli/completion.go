@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteCompletion writes the completion script of shell (bash, zsh or fish) for commands and their flags to w.
+func WriteCompletion(w io.Writer, shell string, commands []Command) error {
+	names := make([]string, 0, len(commands))
+	flags := make(map[string][]string, len(commands))
+	for _, c := range commands {
+		names = append(names, c.Name)
+		for _, f := range Flags(c) {
+			flags[c.Name] = append(flags[c.Name], "-"+f.Name)
+		}
+	}
+	switch shell {
+	case "bash":
+		fmt.Fprintln(w, "# bash completion for cto-stats; load with: source <(cto-stats completion bash)")
+		fmt.Fprintln(w, "_cto_stats() {")
+		fmt.Fprintln(w, `  local cur="${COMP_WORDS[COMP_CWORD]}"`)
+		fmt.Fprintln(w, `  if [ "$COMP_CWORD" -eq 1 ]; then`)
+		fmt.Fprintf(w, "    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+		fmt.Fprintln(w, "    return")
+		fmt.Fprintln(w, "  fi")
+		fmt.Fprintln(w, `  case "${COMP_WORDS[1]}" in`)
+		for _, n := range names {
+			fmt.Fprintf(w, "    %s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", n, strings.Join(flags[n], " "))
+		}
+		fmt.Fprintln(w, "  esac")
+		fmt.Fprintln(w, "}")
+		fmt.Fprintln(w, "complete -F _cto_stats cto-stats")
+	case "zsh":
+		fmt.Fprintln(w, "#compdef cto-stats")
+		fmt.Fprintln(w, "# zsh completion for cto-stats; load with: source <(cto-stats completion zsh)")
+		fmt.Fprintln(w, "_cto_stats() {")
+		fmt.Fprintln(w, "  if (( CURRENT == 2 )); then")
+		fmt.Fprintf(w, "    compadd -- %s\n", strings.Join(names, " "))
+		fmt.Fprintln(w, "    return")
+		fmt.Fprintln(w, "  fi")
+		fmt.Fprintln(w, "  case $words[2] in")
+		for _, n := range names {
+			fmt.Fprintf(w, "    %s) compadd -- %s ;;\n", n, strings.Join(flags[n], " "))
+		}
+		fmt.Fprintln(w, "  esac")
+		fmt.Fprintln(w, "}")
+		fmt.Fprintln(w, "compdef _cto_stats cto-stats")
+	case "fish":
+		fmt.Fprintln(w, "# fish completion for cto-stats; load with: cto-stats completion fish | source")
+		fmt.Fprintln(w, "complete -c cto-stats -f")
+		for _, c := range commands {
+			fmt.Fprintf(w, "complete -c cto-stats -n __fish_use_subcommand -a %s -d %s\n", c.Name, fishQuote(c.Summary))
+		}
+		for _, c := range commands {
+			for _, f := range Flags(c) {
+				fmt.Fprintf(w, "complete -c cto-stats -n '__fish_seen_subcommand_from %s' -o %s -d %s\n", c.Name, f.Name, fishQuote(firstLine(f.Usage)))
+			}
+		}
+	default:
+		return fmt.Errorf("completion: unknown shell %q (expected bash, zsh or fish)", shell)
+	}
+	return nil
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,310 @@
+package compare
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cto-stats/command/cli"
+	ccsv "cto-stats/connectors/csv"
+	"cto-stats/domain/schema"
+)
+
+// keyColumns are the text columns identifying a row of a summary file, next to its period columns. Other text
+// columns (names, labels) are compared as values.
+var keyColumns = map[string]bool{
+	"org": true, "repo": true, "project_id": true, "provider": true, "currency": true, "group": true,
+	"login": true, "comparison": true, "milestone": true,
+}
+
+// difference is one changed value, or a row present on one side only (empty column).
+type difference struct {
+	file, key, column string
+	a, b              string
+	delta             *float64
+}
+
+// Help describes the compare subcommand for its -h output, the top-level usage and the completion scripts.
+var Help = cli.Command{
+	Name:     "compare",
+	Summary:  "diff the calculate outputs of two data directories",
+	Synopsis: "-a <dir> [-b <dir>] [-csv <file>] [-limit <n>]",
+	Description: `Compares the issues of calculated_issue.csv and every monthly, weekly or quarterly output of two data
+directories, typically copies made before and after a config change, and prints a summary per file.`,
+	Examples: []string{
+		"cp -r data data-before && cto-stats calculate && cto-stats compare -a data-before",
+		"cto-stats compare -a old -b new -csv diff.csv -limit 0",
+	},
+}
+
+// Run executes the compare subcommand: it diffs the calculate outputs of two data directories, typically
+// computed before and after a config change. Issues of calculated_issue.csv are compared on their project and
+// stage timestamps, and the per month, week or quarter summary files on every value.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	cli.SetUsage(fs, Help)
+	fs.SetOutput(os.Stderr)
+	dirA := fs.String("a", "", "first data directory, e.g. a copy of data/ made before changing the config")
+	dirB := fs.String("b", "data", "second data directory")
+	csvPath := fs.String("csv", "", "also write every difference to this CSV file")
+	limit := fs.Int("limit", 20, "differences printed per file in the summary (0 = all)")
+	if err := cli.Parse(fs, args); err != nil {
+		return err
+	}
+	if *dirA == "" || *dirB == "" {
+		return fmt.Errorf("compare: -a and -b data directories are required")
+	}
+
+	var all []difference
+	for _, f := range comparedFiles() {
+		if !exists(filepath.Join(*dirA, f.Name)) && !exists(filepath.Join(*dirB, f.Name)) {
+			continue
+		}
+		diffs, err := compareFile(f, *dirA, *dirB)
+		if err != nil {
+			return fmt.Errorf("compare: %s: %w", f.Name, err)
+		}
+		printSummary(os.Stdout, f.Name, diffs, *limit)
+		all = append(all, diffs...)
+	}
+	slog.Info("compare.done", "a", *dirA, "b", *dirB, "differences", len(all))
+	if *csvPath != "" {
+		return writeDifferences(*csvPath, all)
+	}
+	return nil
+}
+
+// comparedFiles returns calculated_issue.csv followed by the calculate outputs aggregated per month, week or
+// quarter.
+func comparedFiles() []schema.File {
+	var files []schema.File
+	for _, f := range schema.Files {
+		if f.WrittenBy != "calculate" {
+			continue
+		}
+		if f.Name == "calculated_issue.csv" {
+			files = append([]schema.File{f}, files...)
+			continue
+		}
+		for _, c := range f.Columns {
+			if c.Type == schema.Month || c.Type == schema.Week || c.Type == schema.Quarter {
+				files = append(files, f)
+				break
+			}
+		}
+	}
+	return files
+}
+
+// compareFile diffs file f between dirA and dirB. A file missing on both sides yields nothing; missing on one
+// side, every row of the other side is reported as present on that side only.
+func compareFile(f schema.File, dirA, dirB string) ([]difference, error) {
+	var keys, values []schema.Column
+	if f.Name == "calculated_issue.csv" {
+		for _, c := range f.Columns {
+			switch {
+			case c.Name == "id":
+				keys = append(keys, c)
+			case c.Name == "project_id" || c.Type == schema.DateTime:
+				values = append(values, c)
+			}
+		}
+	} else {
+		for _, c := range f.Columns {
+			switch c.Type {
+			case schema.Month, schema.Week, schema.Year, schema.Quarter, schema.Date:
+				keys = append(keys, c)
+			case schema.String:
+				if keyColumns[c.Name] {
+					keys = append(keys, c)
+				} else {
+					values = append(values, c)
+				}
+			default:
+				values = append(values, c)
+			}
+		}
+	}
+
+	rowsA, errA := readRows(filepath.Join(dirA, f.Name), keys)
+	rowsB, errB := readRows(filepath.Join(dirB, f.Name), keys)
+	if errA != nil && !errors.Is(errA, os.ErrNotExist) {
+		return nil, errA
+	}
+	if errB != nil && !errors.Is(errB, os.ErrNotExist) {
+		return nil, errB
+	}
+
+	ids := map[string]bool{}
+	for k := range rowsA {
+		ids[k] = true
+	}
+	for k := range rowsB {
+		ids[k] = true
+	}
+	sorted := make([]string, 0, len(ids))
+	for k := range ids {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var diffs []difference
+	for _, k := range sorted {
+		a, inA := rowsA[k]
+		b, inB := rowsB[k]
+		if !inA || !inB {
+			d := difference{file: f.Name, key: k}
+			if inA {
+				d.a = "present"
+			}
+			if inB {
+				d.b = "present"
+			}
+			diffs = append(diffs, d)
+			continue
+		}
+		for _, c := range values {
+			va, vb := a[c.Name], b[c.Name]
+			if c.Type == schema.Int || c.Type == schema.Float {
+				fa, errA := strconv.ParseFloat(va, 64)
+				fb, errB := strconv.ParseFloat(vb, 64)
+				if errA == nil && errB == nil {
+					if math.Abs(fb-fa) < 1e-9 {
+						continue
+					}
+					// rounded to the 6 decimals of the calculate outputs
+					delta := math.Round((fb-fa)*1e6) / 1e6
+					diffs = append(diffs, difference{file: f.Name, key: k, column: c.Name, a: va, b: vb, delta: &delta})
+					continue
+				}
+			}
+			if va != vb {
+				diffs = append(diffs, difference{file: f.Name, key: k, column: c.Name, a: va, b: vb})
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// readRows reads the CSV file at path as maps of column name to value, keyed by the key columns of the row
+// ("month=2025-03 org=acme"; only the value for calculated_issue.csv ids).
+func readRows(path string, keys []schema.Column) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err == io.EOF {
+		return map[string]map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(header) > 0 {
+		header[0] = ccsv.TrimBOM(header[0])
+	}
+	rows := map[string]map[string]string{}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(header))
+		for i, h := range header {
+			if i < len(rec) {
+				row[h] = rec[i]
+			}
+		}
+		parts := make([]string, 0, len(keys))
+		for _, c := range keys {
+			if len(keys) == 1 && c.Name == "id" {
+				parts = append(parts, row[c.Name])
+			} else {
+				parts = append(parts, c.Name+"="+row[c.Name])
+			}
+		}
+		rows[strings.Join(parts, " ")] = row
+	}
+	return rows, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func printSummary(out io.Writer, file string, diffs []difference, limit int) {
+	changed, onlyA, onlyB := map[string]bool{}, 0, 0
+	for _, d := range diffs {
+		switch {
+		case d.column != "":
+			changed[d.key] = true
+		case d.a != "":
+			onlyA++
+		default:
+			onlyB++
+		}
+	}
+	if len(diffs) == 0 {
+		fmt.Fprintf(out, "%s: no differences\n", file)
+		return
+	}
+	fmt.Fprintf(out, "%s: %d rows changed, %d only in a, %d only in b\n", file, len(changed), onlyA, onlyB)
+	for i, d := range diffs {
+		if limit > 0 && i == limit {
+			fmt.Fprintf(out, "  ... and %d more\n", len(diffs)-limit)
+			break
+		}
+		switch {
+		case d.column == "" && d.a != "":
+			fmt.Fprintf(out, "  %s: only in a\n", d.key)
+		case d.column == "":
+			fmt.Fprintf(out, "  %s: only in b\n", d.key)
+		case d.delta != nil:
+			fmt.Fprintf(out, "  %s %s: %s -> %s (%+g)\n", d.key, d.column, d.a, d.b, *d.delta)
+		default:
+			fmt.Fprintf(out, "  %s %s: %q -> %q\n", d.key, d.column, d.a, d.b)
+		}
+	}
+}
+
+func writeDifferences(path string, diffs []difference) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := ccsv.Create(path, false)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	if err := w.Write(schema.Headers("compare.csv")); err != nil {
+		return err
+	}
+	for _, d := range diffs {
+		delta := ""
+		if d.delta != nil {
+			delta = strconv.FormatFloat(*d.delta, 'f', 6, 64)
+		}
+		if err := w.Write([]string{d.file, d.key, d.column, d.a, d.b, delta}); err != nil {
+			return err
+		}
+	}
+	return w.Error()
+}
@@ -0,0 +1,235 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cmdcalculate "cto-stats/command/calculate"
+	"cto-stats/command/cli"
+	"cto-stats/connectors/azure"
+	"cto-stats/connectors/config"
+	"cto-stats/connectors/gcp"
+	cg "cto-stats/connectors/github"
+	"cto-stats/connectors/useragent"
+)
+
+// check is one line of the doctor checklist.
+type check struct {
+	name   string
+	status string // PASS|FAIL|WARN|SKIP
+	detail string
+	hint   string
+}
+
+// Help describes the doctor subcommand for its -h output, the top-level usage and the completion scripts.
+var Help = cli.Command{
+	Name:     "doctor",
+	Summary:  "check the config, credentials and data files",
+	Synopsis: "[-org <org>] [-data <dir>] [-timeout <duration>]",
+	Description: `Checks the config file, the GitHub token and its scopes, the cloud credentials and the CSV files of the data
+directory, and prints one line per check with a hint for each failure.`,
+	Examples: []string{
+		"GITHUB_TOKEN=ghp_xxx cto-stats doctor",
+	},
+	Env: []string{
+		"GITHUB_TOKEN  GitHub token to check",
+		"CONFIG_PATH   YAML config file (default ./config.yml)",
+	},
+}
+
+// Run executes the doctor subcommand: it checks credentials, configuration and the data directory,
+// prints a checklist and returns an error when at least one check failed.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	cli.SetUsage(fs, Help)
+	fs.SetOutput(os.Stderr)
+	org := fs.String("org", "", "GitHub organization to check (defaults to github.org from config)")
+	dataDir := fs.String("data", "data", "data directory that import and calculate write to")
+	timeout := fs.Duration("timeout", 20*time.Second, "timeout for each network check")
+	if err := cli.Parse(fs, args); err != nil {
+		return err
+	}
+
+	var checks []check
+	add := func(c check) { checks = append(checks, c) }
+
+	// Config
+	cfgPath := os.Getenv("CONFIG_PATH")
+	if cfgPath == "" {
+		cfgPath = "./config.yml"
+	}
+	var cfg *config.Config
+	if _, err := os.Stat(cfgPath); err != nil {
+		add(check{name: "config file", status: "WARN", detail: fmt.Sprintf("%s not found", cfgPath),
+			hint: "calculate --issues needs a config with project column mappings; set CONFIG_PATH or create ./config.yml"})
+	} else if c, err := config.Load(cfgPath); err != nil {
+		hint := "fix the YAML syntax"
+		if errors.Is(err, config.ErrEnvNotSet) {
+			hint = "export the variables, or write $${NAME} for a literal ${NAME}"
+		}
+		add(check{name: "config file", status: "FAIL", detail: fmt.Sprintf("%s: %s", cfgPath, strings.ReplaceAll(err.Error(), "\n", "; ")), hint: hint})
+	} else {
+		cfg = c
+		if err := cmdcalculate.ValidateConfig(cfg); err != nil {
+			add(check{name: "config file", status: "FAIL", detail: strings.ReplaceAll(err.Error(), "\n", "; "), hint: "see the Configuration section of the README"})
+		} else if warnings := cmdcalculate.ConfigWarnings(cfg); len(warnings) > 0 {
+			add(check{name: "config file", status: "WARN", detail: strings.Join(warnings, "; "), hint: "targets.projects entries must match the id or name of a github.projects entry"})
+		} else {
+			add(check{name: "config file", status: "PASS", detail: cfgPath})
+		}
+	}
+	if *org == "" && cfg != nil {
+		*org = cfg.GitHub.Org
+	}
+	if cfg != nil {
+		useragent.Set(cfg.UserAgent)
+	}
+
+	// GitHub token and org
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		add(check{name: "GitHub token", status: "FAIL", detail: "GITHUB_TOKEN is not set", hint: "export GITHUB_TOKEN with the repo, read:org and read:project scopes"})
+		add(check{name: "GitHub org", status: "SKIP", detail: "no token"})
+	} else {
+		ghc := cg.New(nil, token)
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		login, _, err := ghc.Viewer(ctx)
+		cancel()
+		if err != nil {
+			c := check{name: "GitHub token", status: "FAIL", detail: err.Error(), hint: "check network access to api.github.com"}
+			if cg.IsAuthError(err) {
+				c.hint = "the token is invalid or expired: create a new one"
+			}
+			add(c)
+		} else {
+			add(check{name: "GitHub token", status: "PASS", detail: "authenticated as " + login})
+		}
+		switch {
+		case err != nil:
+			add(check{name: "GitHub org", status: "SKIP", detail: "token check failed"})
+		case *org == "":
+			add(check{name: "GitHub org", status: "WARN", detail: "no org given", hint: "pass -org or set github.org in config"})
+		default:
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			err := ghc.CheckOrg(ctx, *org)
+			cancel()
+			if err != nil {
+				add(check{name: "GitHub org", status: "FAIL", detail: err.Error(),
+					hint: "check the org name and that the token is authorized for it (SSO) with read:org"})
+			} else {
+				add(check{name: "GitHub org", status: "PASS", detail: *org})
+			}
+		}
+		if err == nil {
+			add(checkTokenScopes(ghc, *org, *timeout))
+		}
+	}
+
+	// Cloud credentials, only when configured
+	add(checkAzure(*timeout))
+	add(checkGCP(*timeout))
+
+	// Data directory
+	add(checkWritable(*dataDir))
+
+	failed := 0
+	for _, c := range checks {
+		fmt.Printf("[%s] %-14s %s\n", c.status, c.name, c.detail)
+		if c.hint != "" && c.status != "PASS" {
+			fmt.Printf("       hint: %s\n", c.hint)
+		}
+		if c.status == "FAIL" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("doctor: %d check(s) failed", failed)
+	}
+	return nil
+}
+
+func checkTokenScopes(ghc *cg.Client, org string, timeout time.Duration) check {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	rep, err := ghc.VerifyToken(ctx, org, true)
+	if err != nil {
+		return check{name: "GitHub scopes", status: "FAIL", detail: err.Error()}
+	}
+	kind := "fine-grained token"
+	if rep.Classic {
+		kind = "classic token, scopes: " + strings.Join(rep.Scopes, ", ")
+	}
+	if len(rep.Missing) > 0 {
+		return check{name: "GitHub scopes", status: "FAIL", detail: "missing " + strings.Join(rep.Missing, "; "),
+			hint: "regenerate the token with repo, read:org and read:project (" + kind + ")"}
+	}
+	if len(rep.Warnings) > 0 {
+		return check{name: "GitHub scopes", status: "WARN", detail: strings.Join(rep.Warnings, "; ") + " (" + kind + ")"}
+	}
+	return check{name: "GitHub scopes", status: "PASS", detail: kind}
+}
+
+func checkAzure(timeout time.Duration) check {
+	vars := []string{"AZURE_SUBSCRIPTION_ID", "AZURE_TENANT_ID", "AZURE_CLIENT_ID", "AZURE_CLIENT_SECRET"}
+	var missing []string
+	for _, v := range vars {
+		if os.Getenv(v) == "" {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) == len(vars) {
+		return check{name: "Azure", status: "SKIP", detail: "not configured"}
+	}
+	if len(missing) > 0 {
+		return check{name: "Azure", status: "FAIL", detail: "missing " + strings.Join(missing, ", "), hint: "all four AZURE_* variables are needed for --cloudspending"}
+	}
+	subID := strings.TrimSpace(strings.Split(os.Getenv("AZURE_SUBSCRIPTION_ID"), ",")[0])
+	client := azure.NewClient(subID, os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID"), os.Getenv("AZURE_CLIENT_SECRET"))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := client.CheckAuth(ctx); err != nil {
+		return check{name: "Azure", status: "FAIL", detail: err.Error(), hint: "check the tenant, client ID and secret of the service principal"}
+	}
+	return check{name: "Azure", status: "PASS", detail: "service principal authenticated"}
+}
+
+func checkGCP(timeout time.Duration) check {
+	projectID, billing := os.Getenv("GCP_PROJECT_ID"), os.Getenv("GCP_BILLING_ACCOUNT")
+	if projectID == "" && billing == "" {
+		return check{name: "GCP", status: "SKIP", detail: "not configured"}
+	}
+	if projectID == "" || billing == "" {
+		return check{name: "GCP", status: "FAIL", detail: "GCP_PROJECT_ID and GCP_BILLING_ACCOUNT must both be set"}
+	}
+	client := gcp.NewClient(projectID, billing, os.Getenv("GCP_SERVICE_ACCOUNT_JSON"), os.Getenv("GCP_BIGQUERY_LOCATION"))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := client.CheckAuth(ctx); err != nil {
+		return check{name: "GCP", status: "FAIL", detail: err.Error(),
+			hint: "set GCP_SERVICE_ACCOUNT_JSON or application default credentials, with BigQuery access to the project"}
+	}
+	return check{name: "GCP", status: "PASS", detail: "BigQuery reachable for " + projectID}
+}
+
+func checkWritable(dir string) check {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return check{name: "data dir", status: "FAIL", detail: err.Error(), hint: "run from a directory where ./data can be created"}
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return check{name: "data dir", status: "FAIL", detail: err.Error(), hint: "fix the permissions of " + dir}
+	}
+	name := f.Name()
+	_ = f.Close()
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return check{name: "data dir", status: "WARN", detail: err.Error()}
+	}
+	abs, _ := filepath.Abs(dir)
+	return check{name: "data dir", status: "PASS", detail: abs + " is writable"}
+}
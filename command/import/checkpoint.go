@@ -0,0 +1,220 @@
+package cmdimport
+
+import (
+	"bufio"
+	"context"
+	cg "cto-stats/connectors/github"
+	gh "cto-stats/domain/github"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// checkpointDir holds the progress of a PR phase that did not complete. It is removed once pr.csv is written
+// with every repository listed.
+var checkpointDir = filepath.Join("data", "checkpoints")
+
+// prCheckpoint is the progress of the PR listing of a repository, saved after every page so that an interrupted
+// import resumes after the last page read instead of from the first one.
+type prCheckpoint struct {
+	// Since is the -since of the listing: the checkpoint is resumed by the runs whose window it covers
+	Since string `json:"since"`
+	// Cursor is the endCursor of the last page read
+	Cursor string `json:"cursor"`
+	// FirstUpdatedAt is the updatedAt of the first PR of the first page. The PRs updated later moved ahead of
+	// Cursor while the listing ran, and are read again once it is done.
+	FirstUpdatedAt time.Time `json:"first_updated_at"`
+	// Pages is the number of pages read
+	Pages int `json:"pages"`
+	// Done is set once the listing is complete and every PR of the repository saved
+	Done bool `json:"done"`
+}
+
+// covers reports whether the PRs saved by c, listed since c.Since, hold all those of a listing since since.
+func (c *prCheckpoint) covers(since string) bool {
+	if c.Since == since || c.Since == "" {
+		return true
+	}
+	from, err := time.Parse(time.RFC3339, c.Since)
+	if err != nil {
+		return false
+	}
+	to, err := time.Parse(time.RFC3339, since)
+	return err == nil && !from.After(to)
+}
+
+// prCheckpoints are the PR checkpoints of the repositories, keyed by name, saved to pr.json in checkpointDir.
+type prCheckpoints map[string]*prCheckpoint
+
+// loadPRCheckpoints reads the PR checkpoints an interrupted import left, none when it left no file.
+func loadPRCheckpoints() (prCheckpoints, error) {
+	b, err := os.ReadFile(filepath.Join(checkpointDir, "pr.json"))
+	if os.IsNotExist(err) {
+		return prCheckpoints{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cps := prCheckpoints{}
+	if err := json.Unmarshal(b, &cps); err != nil {
+		return nil, err
+	}
+	return cps, nil
+}
+
+// save writes cps to pr.json in checkpointDir, replacing the file in one rename so a crash leaves the previous one.
+func (cps prCheckpoints) save() error {
+	if err := os.MkdirAll(checkpointDir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(cps, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(checkpointDir, "pr.json")
+	if err := os.WriteFile(path+".tmp", b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// checkpointPRsPath returns the file holding the PRs of repo read so far, one JSON object per line.
+func checkpointPRsPath(repo string) string {
+	return filepath.Join(checkpointDir, "pr-"+repo+".jsonl")
+}
+
+// appendCheckpointPRs appends prs to the file at path.
+func appendCheckpointPRs(path string, prs []gh.PullRequest) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, pr := range prs {
+		if err := enc.Encode(pr); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readCheckpointPRs reads the PRs saved to the file at path, leaving out those created before since.
+func readCheckpointPRs(path, since string) ([]gh.PullRequest, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sinceTime, _ := time.Parse(time.RFC3339, since)
+	var prs []gh.PullRequest
+	dec := json.NewDecoder(f)
+	for {
+		var pr gh.PullRequest
+		if err := dec.Decode(&pr); errors.Is(err, io.EOF) {
+			return prs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if pr.CreatedAt.Before(sinceTime) {
+			continue
+		}
+		prs = append(prs, pr)
+	}
+}
+
+// listPullRequests lists the PRs of r created since since as ListAllPullRequests does, least recently updated
+// first. Its progress is saved to cps after every page. When resume is set, the listing of a window covering
+// since that an interrupted import left is resumed after its last page rather than started again.
+func listPullRequests(ctx context.Context, ghc *cg.Client, cps prCheckpoints, r Repo, since string, resume bool) ([]gh.PullRequest, error) {
+	path := checkpointPRsPath(r.Name)
+	cp := cps[r.Name]
+	var prs []gh.PullRequest
+	if cp != nil && resume && cp.covers(since) {
+		saved, err := readCheckpointPRs(path, since)
+		if err != nil {
+			return nil, err
+		}
+		prs = saved
+		slog.Info("phase.prs.resume", "owner", r.Owner.Login, "repo", r.Name, "saved", len(prs), "pages", cp.Pages, "done", cp.Done)
+	} else {
+		cp = &prCheckpoint{Since: since}
+		cps[r.Name] = cp
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if !cp.Done {
+		err := ghc.ListPullRequestPages(ctx, r.Owner.Login, r.Name, since, cp.Cursor, func(p cg.PullRequestPage) error {
+			if err := appendCheckpointPRs(path, p.PullRequests); err != nil {
+				return err
+			}
+			prs = append(prs, p.PullRequests...)
+			if cp.Pages == 0 {
+				cp.FirstUpdatedAt = p.FirstUpdatedAt
+			}
+			cp.Pages++
+			if p.EndCursor != "" {
+				cp.Cursor = p.EndCursor
+			}
+			return cps.save()
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Over several pages, or several runs, PRs updated in between moved ahead of the cursor: read them again
+		// from the first page, down to where the listing started
+		if cp.Pages > 1 {
+			recent, err := ghc.ListPullRequestsUpdatedSince(ctx, r.Owner.Login, r.Name, since, cp.FirstUpdatedAt)
+			if err != nil {
+				return nil, err
+			}
+			prs = mergePullRequests(recent, prs)
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+			if err := appendCheckpointPRs(path, prs); err != nil {
+				return nil, err
+			}
+		}
+		cp.Done = true
+		if err := cps.save(); err != nil {
+			return nil, err
+		}
+	}
+	// least recently updated first, as ListAllPullRequests returns them
+	slices.Reverse(prs)
+	return prs, nil
+}
+
+// mergePullRequests returns recent followed by the PRs of older not in recent, recent being read later.
+func mergePullRequests(recent, older []gh.PullRequest) []gh.PullRequest {
+	seen := map[int]bool{}
+	res := make([]gh.PullRequest, 0, len(recent)+len(older))
+	for _, prs := range [][]gh.PullRequest{recent, older} {
+		for _, pr := range prs {
+			if !seen[pr.Number] {
+				seen[pr.Number] = true
+				res = append(res, pr)
+			}
+		}
+	}
+	return res
+}
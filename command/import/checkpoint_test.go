@@ -0,0 +1,216 @@
+package cmdimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// prServer is a GitHub API serving the pull requests of acme/api most recently updated first, two per page.
+// Its cursors are keyed on updatedAt as GitHub's are: a PR updated after a page was read moves ahead of the
+// cursor of that page.
+type prServer struct {
+	mu     sync.Mutex
+	titles map[int]string
+	update map[int]time.Time
+	afters []string // the after variable of every pullRequests request
+	failAt int      // pullRequests request answered 401 (1 for the first), 0 for none
+}
+
+// newPRServer returns a prServer of PRs 1 to n, PR i created on day i of February 2025 and last updated on day i
+// of March 2025.
+func newPRServer(n int) *prServer {
+	s := &prServer{titles: map[int]string{}, update: map[int]time.Time{}}
+	for i := 1; i <= n; i++ {
+		s.titles[i] = fmt.Sprintf("PR %d", i)
+		s.update[i] = time.Date(2025, 3, i, 9, 0, 0, 0, time.UTC)
+	}
+	return s
+}
+
+// touch updates PR number with title, moving it first.
+func (s *prServer) touch(number int, title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.titles[number] = title
+	s.update[number] = time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC)
+}
+
+func (s *prServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodGet {
+		// the token check
+		w.Header().Set("X-OAuth-Scopes", "repo, read:org")
+		_, _ = w.Write([]byte(`{}`))
+		return
+	}
+	var in struct {
+		Query     string
+		Variables map[string]any
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.Contains(in.Query, "repositories(") {
+		_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"pageInfo":{"hasNextPage":false},"nodes":[
+			{"name":"api","owner":{"login":"acme"},"hasIssuesEnabled":true,"issues":{"totalCount":0},
+			 "pushedAt":"2025-04-01T09:00:00Z","updatedAt":"2025-04-01T09:00:00Z"}]}}}}`))
+		return
+	}
+	after, _ := in.Variables["after"].(string)
+	s.afters = append(s.afters, after)
+	if len(s.afters) == s.failAt {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message":"Bad credentials"}`))
+		return
+	}
+
+	numbers := make([]int, 0, len(s.update))
+	for n := range s.update {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return s.update[numbers[i]].After(s.update[numbers[j]]) })
+	if after != "" {
+		var at int64
+		_, _ = fmt.Sscanf(after, "updated:%d", &at)
+		numbers = slices.DeleteFunc(numbers, func(n int) bool { return s.update[n].Unix() >= at })
+	}
+	page := numbers[:min(2, len(numbers))]
+	var nodes []string
+	for _, n := range page {
+		nodes = append(nodes, fmt.Sprintf(`{"number":%d,"title":%q,"state":"OPEN","url":"https://github.com/acme/api/pull/%d",
+			"createdAt":%q,"updatedAt":%q,"reviewThreads":{"totalCount":0,"pageInfo":{"hasNextPage":false},"nodes":[]},
+			"closingIssuesReferences":{"nodes":[]},"labels":{"nodes":[]}}`,
+			n, s.titles[n], n, time.Date(2025, 2, n, 9, 0, 0, 0, time.UTC).Format(time.RFC3339), s.update[n].Format(time.RFC3339)))
+	}
+	cursor := "null"
+	if len(page) > 0 {
+		cursor = fmt.Sprintf(`"updated:%d"`, s.update[page[len(page)-1]].Unix())
+	}
+	fmt.Fprintf(w, `{"data":{"repository":{"pullRequests":{"pageInfo":{"hasNextPage":%t,"endCursor":%s},"nodes":[%s]}}}}`,
+		len(numbers) > len(page), cursor, strings.Join(nodes, ","))
+}
+
+// cursorOf returns the cursor prServer ends a page with on PR number, as last updated on day of March 2025.
+func cursorOf(day int) string {
+	return fmt.Sprintf("updated:%d", time.Date(2025, 3, day, 9, 0, 0, 0, time.UTC).Unix())
+}
+
+func TestRunResumesPullRequests(t *testing.T) {
+	const since = "2025-01-01T00:00:00Z"
+	tests := []struct {
+		name       string
+		between    func(s *prServer) // changes on GitHub between the interrupted run and the resumed one
+		since      string            // -since of the resumed run
+		wantAfters []string          // of the resumed run
+		wantTitles map[string]string // by number, in pr.csv
+	}{
+		{
+			name:  "remaining pages only",
+			since: since,
+			// page 3 (PRs 2 and 1), then the PRs updated since the listing started, from the first page
+			wantAfters: []string{cursorOf(3), ""},
+			wantTitles: map[string]string{"1": "PR 1", "2": "PR 2", "3": "PR 3", "4": "PR 4", "5": "PR 5", "6": "PR 6"},
+		},
+		{
+			name:    "PR of a remaining page updated in between",
+			between: func(s *prServer) { s.touch(2, "PR 2 rebased") },
+			since:   since,
+			// PR 2 moved ahead of the cursor: page 3 holds PR 1 only, PR 2 is read from the first page (PRs 2
+			// and 6), down to PR 6 where the listing started
+			wantAfters: []string{cursorOf(3), "", cursorOf(6)},
+			wantTitles: map[string]string{"1": "PR 1", "2": "PR 2 rebased", "3": "PR 3", "4": "PR 4", "5": "PR 5", "6": "PR 6"},
+		},
+		{
+			name:       "PR of a saved page updated in between",
+			between:    func(s *prServer) { s.touch(5, "PR 5 merged") },
+			since:      since,
+			wantAfters: []string{cursorOf(3), "", cursorOf(6)},
+			wantTitles: map[string]string{"1": "PR 1", "2": "PR 2", "3": "PR 3", "4": "PR 4", "5": "PR 5 merged", "6": "PR 6"},
+		},
+		{
+			name:  "wider window started again",
+			since: "2024-01-01T00:00:00Z",
+			// three pages from the first, then the first one again
+			wantAfters: []string{"", cursorOf(5), cursorOf(3), ""},
+			wantTitles: map[string]string{"1": "PR 1", "2": "PR 2", "3": "PR 3", "4": "PR 4", "5": "PR 5", "6": "PR 6"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newPRServer(6)
+			setupImport(t, srv)
+
+			// the first run stops on the third page, after saving the first two
+			srv.failAt = 3
+			if err := Run([]string{"-org", "acme", "-pr", "-no-reviews", "-since", since}); err == nil {
+				t.Fatal("interrupted import succeeded")
+			}
+			if want := []string{"", cursorOf(5), cursorOf(3)}; !slices.Equal(srv.afters, want) {
+				t.Fatalf("interrupted run read after %q, want %q", srv.afters, want)
+			}
+			if _, err := os.Stat(filepath.Join(checkpointDir, "pr.json")); err != nil {
+				t.Fatalf("no checkpoint left by the interrupted run: %v", err)
+			}
+
+			if tt.between != nil {
+				tt.between(srv)
+			}
+			srv.afters, srv.failAt = nil, 0
+			if err := Run([]string{"-org", "acme", "-pr", "-no-reviews", "-since", tt.since}); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(srv.afters, tt.wantAfters) {
+				t.Errorf("resumed run read after %q, want %q", srv.afters, tt.wantAfters)
+			}
+			numbers := readColumn(t, filepath.Join("data", "pr.csv"), "number")
+			titles := readColumn(t, filepath.Join("data", "pr.csv"), "title")
+			got := map[string]string{}
+			for i, n := range numbers {
+				if _, dup := got[n]; dup {
+					t.Errorf("PR %s written twice", n)
+				}
+				got[n] = titles[i]
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantTitles) {
+				t.Errorf("pr.csv titles %v, want %v", got, tt.wantTitles)
+			}
+			if _, err := os.Stat(checkpointDir); !os.IsNotExist(err) {
+				t.Errorf("checkpoints kept after a complete run (stat: %v)", err)
+			}
+		})
+	}
+}
+
+func TestPRCheckpointCovers(t *testing.T) {
+	tests := []struct {
+		name         string
+		saved, since string
+		want         bool
+	}{
+		{"same window", "2025-01-01T00:00:00Z", "2025-01-01T00:00:00Z", true},
+		{"narrower window", "2025-01-01T00:00:00Z", "2025-01-02T00:00:00Z", true},
+		{"wider window", "2025-01-02T00:00:00Z", "2025-01-01T00:00:00Z", false},
+		{"saved without since", "", "2025-01-01T00:00:00Z", true},
+		{"resumed without since", "2025-01-01T00:00:00Z", "", false},
+		{"both without since", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &prCheckpoint{Since: tt.saved}
+			if got := cp.covers(tt.since); got != tt.want {
+				t.Errorf("covers(%q) of %q = %v, want %v", tt.since, tt.saved, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package cmdimport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cto-stats/domain/cloudspending"
+)
+
+// costRun is one cloud spending import: the records fetched and whether -overwrite was given.
+type costRun struct {
+	fetched   []cloudspending.CostRecord
+	overwrite bool
+}
+
+// cost returns a fetched record of provider for month (YYYY-MM).
+func cost(provider, service, month string, amount float64, dimension, environment string) cloudspending.CostRecord {
+	m, _ := time.Parse("2006-01", month)
+	return cloudspending.CostRecord{Provider: provider, Service: service, Month: m, Cost: amount, Currency: "EUR",
+		Dimension: dimension, Environment: environment}
+}
+
+func TestUpdateCloudCostsCSV(t *testing.T) {
+	const header = "provider,service,month,cost,currency,dimension,environment\n"
+	azure := []cloudspending.CostRecord{
+		cost("azure", "Storage", "2025-01", 10, "sub-1", ""),
+		cost("azure", "Compute", "2025-02", 20.5, "sub-1", ""),
+	}
+	gcp := []cloudspending.CostRecord{cost("gcp", "BigQuery", "2025-01", 7, "billingAccounts/B", "")}
+	tests := []struct {
+		name     string
+		existing string // cloud_costs.csv before the first run, none when empty
+		runs     []costRun
+		want     string
+	}{
+		{
+			name: "provider A then provider B keeps both",
+			runs: []costRun{{fetched: azure}, {fetched: gcp}},
+			want: header +
+				"azure,Storage,2025-01-01,10.00,EUR,sub-1,\n" +
+				"azure,Compute,2025-02-01,20.50,EUR,sub-1,\n" +
+				"gcp,BigQuery,2025-01-01,7.00,EUR,billingAccounts/B,\n",
+		},
+		{
+			name: "same import twice",
+			runs: []costRun{{fetched: azure}, {fetched: azure}},
+			want: header +
+				"azure,Storage,2025-01-01,10.00,EUR,sub-1,\n" +
+				"azure,Compute,2025-02-01,20.50,EUR,sub-1,\n",
+		},
+		{
+			name: "legacy rows without dimension are replaced",
+			existing: "provider,service,month,cost,currency\n" +
+				"azure,Storage,2025-01-01,9.00,EUR\n" +
+				"gcp,BigQuery,2025-01-01,3.00,EUR\n",
+			runs: []costRun{{fetched: azure}},
+			want: header +
+				"azure,Storage,2025-01-01,10.00,EUR,sub-1,\n" +
+				"azure,Compute,2025-02-01,20.50,EUR,sub-1,\n" +
+				"gcp,BigQuery,2025-01-01,3.00,EUR,,\n",
+		},
+		{
+			name: "changed environment mapping does not double-count",
+			runs: []costRun{
+				{fetched: []cloudspending.CostRecord{cost("azure", "Storage", "2025-01", 10, "sub-1", "staging")}},
+				{fetched: []cloudspending.CostRecord{cost("azure", "Storage", "2025-01", 10, "sub-1", "production")}},
+			},
+			want: header + "azure,Storage,2025-01-01,10.00,EUR,sub-1,production\n",
+		},
+		{
+			name: "-overwrite rebuilds the file",
+			runs: []costRun{{fetched: azure}, {fetched: gcp, overwrite: true}},
+			want: header + "gcp,BigQuery,2025-01-01,7.00,EUR,billingAccounts/B,\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cloud_costs.csv")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var previous string
+			for _, run := range tt.runs {
+				if _, err := updateCloudCostsCSV(path, run.fetched, run.overwrite, false); err != nil {
+					t.Fatal(err)
+				}
+				b, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				previous = string(b)
+			}
+			if got := strings.ReplaceAll(previous, "\r\n", "\n"); got != tt.want {
+				t.Errorf("cloud_costs.csv:\n%s\nwant:\n%s", got, tt.want)
+			}
+
+			// running the last import again leaves the file byte-identical
+			last := tt.runs[len(tt.runs)-1]
+			if _, err := updateCloudCostsCSV(path, last.fetched, last.overwrite, false); err != nil {
+				t.Fatal(err)
+			}
+			if b, _ := os.ReadFile(path); string(b) != previous {
+				t.Errorf("cloud_costs.csv changed when the import ran again:\n%s\nwas:\n%s", b, previous)
+			}
+		})
+	}
+}
+
+func TestUpdateCloudCostsCSVRejectsUnreadableRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     string
+		wantErr string
+	}{
+		{"month not a date", "gcp,BigQuery,2025-13,3.00,EUR,billingAccounts/B,\n", `line 3: month "2025-13"`},
+		{"cost not a number", "gcp,BigQuery,2025-01-01,3€,EUR,billingAccounts/B,\n", `line 3: cost "3€"`},
+		{"cost missing", "gcp,BigQuery,2025-01-01,,EUR,billingAccounts/B,\n", `line 3: cost ""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cloud_costs.csv")
+			existing := "provider,service,month,cost,currency,dimension,environment\n" +
+				"azure,Storage,2025-01-01,10.00,EUR,sub-1,\n" + tt.row
+			if err := os.WriteFile(path, []byte(existing), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := updateCloudCostsCSV(path, []cloudspending.CostRecord{cost("azure", "Storage", "2025-02", 5, "sub-1", "")}, false, false)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+			}
+			if b, _ := os.ReadFile(path); string(b) != existing {
+				t.Errorf("cloud_costs.csv rewritten from a partial read:\n%s", b)
+			}
+		})
+	}
+}
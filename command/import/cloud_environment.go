@@ -0,0 +1,28 @@
+package cmdimport
+
+import (
+	"strings"
+
+	"cto-stats/domain/cloudspending"
+)
+
+// tagEnvironments sets the environment of each record from environments (cloud_spending.environments): the label
+// listing its GCP project, or its Azure subscription. Records of unlisted ones keep no environment.
+func tagEnvironments(records []cloudspending.CostRecord, environments map[string][]string) {
+	byID := map[string]string{}
+	for env, ids := range environments {
+		for _, id := range ids {
+			byID[strings.ToLower(strings.TrimSpace(id))] = strings.TrimSpace(env)
+		}
+	}
+	if len(byID) == 0 {
+		return
+	}
+	for i, r := range records {
+		id := r.Dimension
+		if r.Provider == "gcp" {
+			id = r.Project
+		}
+		records[i].Environment = byID[strings.ToLower(strings.TrimSpace(id))]
+	}
+}
@@ -2,20 +2,36 @@ package cmdimport
 
 import (
 	"context"
+	"cto-stats/command/cli"
 	"cto-stats/connectors/azure"
 	"cto-stats/connectors/config"
 	ccsv "cto-stats/connectors/csv"
 	"cto-stats/connectors/gcp"
 	cg "cto-stats/connectors/github"
+	"cto-stats/connectors/jsonl"
+	"cto-stats/connectors/notify"
+	"cto-stats/connectors/useragent"
+	"cto-stats/domain/buildinfo"
 	"cto-stats/domain/cloudspending"
 	gh "cto-stats/domain/github"
+	"cto-stats/domain/schema"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 // Type aliases to avoid leaking internal domain types to callers while keeping code concise here
@@ -40,12 +56,57 @@ type IssueReport = gh.IssueReport
 
 type CurrentProject = gh.CurrentProject
 
-// checkpoints stores per-repo import progress to allow incremental runs.
-// Note: checkpoint management removed. The import now runs without persisting cursors.
+// Help describes the import subcommand for its -h output, the top-level usage and the completion scripts.
+var Help = cli.Command{
+	Name:     "import",
+	Summary:  "fetch issues, pull requests and cloud costs into data/",
+	Synopsis: "[-org <org>] [-since <time>] [-repo <a,b>] [-issues] [-pr] [-cloudspending] [flags]",
+	Description: `Fetches the issues with their timelines and project moves, the pull requests with their reviews, and the
+cloud costs, and writes them as CSV files in data/. Without a scope flag, the issues and pr scopes run (or the
+scopes of import.scopes in the config). Flags not given default to the import section of the config.`,
+	Examples: []string{
+		"GITHUB_TOKEN=ghp_xxx cto-stats import -org my-org",
+		"GITHUB_TOKEN=ghp_xxx cto-stats import -pr -since 2025-01-01T00:00:00Z -repo api,web",
+		"cto-stats import -cloudspending -provider gcp",
+	},
+	Env: []string{
+		"GITHUB_TOKEN                 GitHub token (repo, read:org and read:project scopes)",
+		"GITHUB_API_URL               GitHub API root for GitHub Enterprise Server, e.g. https://github.example.com/api/v3",
+		"CONFIG_PATH                  YAML config file (default ./config.yml)",
+		"AZURE_TENANT_ID, AZURE_CLIENT_ID, AZURE_CLIENT_SECRET, AZURE_SUBSCRIPTION_ID",
+		"                             Azure cost management credentials (cloudspending scope)",
+		"GCP_PROJECT_ID, GCP_BILLING_ACCOUNT, GCP_SERVICE_ACCOUNT_JSON, GCP_BIGQUERY_LOCATION",
+		"                             GCP billing export settings (cloudspending scope)",
+		"NOTIFY_WEBHOOK_URL           webhook posted a failed run (overrides notifications.webhook_url)",
+	},
+}
 
-// Run executes the import subcommand. It expects flag arguments like: -org, -since, -repo.
+// Run executes the import subcommand. It expects flag arguments like: -org, -since, -repo. A failed import is
+// posted to the webhook of the notifications config, if any; a successful one is reported by the calculate run
+// that follows it.
 func Run(args []string) error {
+	err := run(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		cfg := &config.Config{}
+		if c, loadErr := config.Load(configPath()); loadErr == nil {
+			cfg = c
+		}
+		notify.Post(cfg.Notifications, notify.Failure("import", err))
+	}
+	return err
+}
+
+// configPath returns the config file of CONFIG_PATH, ./config.yml by default.
+func configPath() string {
+	if p := os.Getenv("CONFIG_PATH"); p != "" {
+		return p
+	}
+	return "./config.yml"
+}
+
+func run(args []string) error {
 	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	cli.SetUsage(fs, Help)
 	fs.SetOutput(os.Stderr)
 	org := fs.String("org", "", "GitHub organization (optional if CONFIG_PATH points to config with github.org)")
 	since := fs.String("since", "", "Only issues updated since this ISO8601/RFC3339 time, e.g., 2025-01-01T00:00:00Z (optional)")
@@ -54,36 +115,88 @@ func Run(args []string) error {
 	issuesScope := fs.Bool("issues", false, "Process issues scope: issues, timelines, project moves")
 	prScope := fs.Bool("pr", false, "Process pull-requests scope: PRs and change-request reviews")
 	cloudSpendingScope := fs.Bool("cloudspending", false, "Process cloud spending scope: Azure and GCP costs")
-	if err := fs.Parse(args); err != nil {
+	noReviews := fs.Bool("no-reviews", false, "PR scope: list PRs only and skip per-PR review calls (pr_review.csv is written with headers only)")
+	bom := fs.Bool("bom", false, "Prepend a UTF-8 BOM to written CSV files (helps Excel display non-ASCII text)")
+	reviewConcurrency := fs.Int("review-concurrency", 4, "Number of PRs whose reviews are fetched in parallel (PR scope)")
+	timelineConcurrency := fs.Int("timeline-concurrency", 1, "Number of issues whose timelines are fetched in parallel (issues scope)")
+	var labels cli.StringList
+	fs.Var(&labels, "labels", "Issues scope: only import issues carrying at least one of these labels (repeatable or comma-separated); combines with -since")
+	var providers cli.StringList
+	fs.Var(&providers, "provider", "Cloud spending scope: only import this provider (azure|gcp, the only ones available: aws is not supported); repeatable or comma-separated, default all with credentials")
+	splitByRepo := fs.Bool("split-by-repo", false, "Issues and PR scopes: write the CSVs per repository into data/<repo>/ instead of the combined files in data/")
+	jsonLines := fs.Bool("jsonl", false, "Issues scope: also write data/issues.jsonl, one issue report with its histories per line")
+	activeOnly := fs.Bool("active-only", false, "Issues and PR scopes: skip repositories neither pushed to nor updated since -since (default on when -since is set)")
+	overwrite := fs.Bool("overwrite", false, "Cloud spending scope: rebuild cloud_costs.csv from this run only instead of merging into the existing file")
+	httpTimeout := fs.Duration("http-timeout", 30*time.Second, "Issues and PR scopes: timeout of each GitHub API request")
+	deadline := fs.Duration("deadline", 0, "Issues and PR scopes: overall time budget of the import, e.g. 4h; when reached, import stops, keeps the previous CSVs and exits with an error (0 = none)")
+	descriptionMinLength := fs.Int("description-min-length", defaultDescriptionMinLength, "Issues scope: description length, in characters, above which an issue counts as described (has_description)")
+	skipUnchanged := fs.Bool("skip-unchanged", false, "Issues scope: reuse, from the previous files in data/, the timeline of the issues closed before -since and still closed instead of fetching it again")
+	snapshot := fs.Bool("snapshot", false, "Issues and PR scopes: also save the raw GitHub response pages to data/snapshots/ for audits and calculate -from-snapshots (large)")
+	if err := cli.Parse(fs, args); err != nil {
 		return err
 	}
 
-	// Cloud spending scope is independent
-	if *cloudSpendingScope {
-		return runCloudSpendingImport()
+	// Resolve config: its import section provides defaults for the flags not given, and github.org the org
+	cfgPath := configPath()
+	var cfg *config.Config
+	if _, err := os.Stat(cfgPath); err == nil {
+		if cfg, err = config.Load(cfgPath); err != nil {
+			slog.Warn("import.config.error", "path", cfgPath, "error", err)
+			cfg = nil
+		}
+	}
+	if err := applyConfigDefaults(fs, cfg, time.Now()); err != nil {
+		return err
+	}
+	if !flagGiven(fs, "active-only") {
+		*activeOnly = *since != ""
+	}
+	if *httpTimeout <= 0 || *deadline < 0 {
+		return fmt.Errorf("import: -http-timeout must be positive and -deadline must not be negative")
+	}
+	if *descriptionMinLength < 0 {
+		return fmt.Errorf("import: -description-min-length must not be negative")
+	}
+	var sinceTime time.Time
+	if *skipUnchanged {
+		t, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			return fmt.Errorf("import: -skip-unchanged needs an RFC3339 -since (the issues closed before it are reused), got %q", *since)
+		}
+		sinceTime = t
+	}
+	reportOpts := newReportOptions(cfg, *descriptionMinLength)
+	if *org == "" && cfg != nil {
+		*org = cfg.GitHub.Org
+	}
+	if cfg != nil {
+		useragent.Set(cfg.UserAgent)
 	}
 
 	// Backward compatibility: if no scope is specified, process both issues and PRs
-	if !*issuesScope && !*prScope {
+	if !*issuesScope && !*prScope && !*cloudSpendingScope {
 		*issuesScope = true
 		*prScope = true
 	}
+	slog.Info("import.options", "org", *org, "issues", *issuesScope, "pr", *prScope, "cloudspending", *cloudSpendingScope,
+		"since", *since, "repo", *repoFilter, "labels", []string(labels), "providers", []string(providers),
+		"reviewConcurrency", *reviewConcurrency, "timelineConcurrency", *timelineConcurrency, "noReviews", *noReviews, "activeOnly", *activeOnly, "splitByRepo", *splitByRepo, "bom", *bom, "jsonl", *jsonLines, "overwrite", *overwrite, "snapshot", *snapshot, "httpTimeout", *httpTimeout, "deadline", *deadline, "descriptionMinLength", *descriptionMinLength, "skipUnchanged", *skipUnchanged, "userAgent", useragent.Get())
 
-	// Resolve config and org
-	cfgPath := os.Getenv("CONFIG_PATH")
-	if cfgPath == "" {
-		cfgPath = "./config.yml"
-	}
-	if *org == "" {
-		// Try read org from config if file exists
-		if _, err := os.Stat(cfgPath); err == nil {
-			if cfg, err := config.Load(cfgPath); err == nil {
-				if cfg.GitHub.Org != "" {
-					*org = cfg.GitHub.Org
-				}
-			}
+	// Cloud spending scope is independent: it runs first, then the GitHub scopes if any were selected too
+	if *cloudSpendingScope {
+		selected, err := selectProviders(providers)
+		if err != nil {
+			return err
+		}
+		var environments map[string][]string
+		if cfg != nil {
+			environments = cfg.CloudSpending.Environments
+		}
+		if err := runCloudSpendingImport(selected, *overwrite, environments, *bom); err != nil || (!*issuesScope && !*prScope) {
+			return err
 		}
 	}
+
 	if *org == "" {
 		fmt.Fprintln(os.Stderr, "-org is required when no config file with github.org is provided (set CONFIG_PATH to a config file to provide org)")
 		slog.Error("import.validation.error", "reason", "missing org")
@@ -97,10 +210,45 @@ func Run(args []string) error {
 		return fmt.Errorf("missing GITHUB_TOKEN")
 	}
 
-	slog.Info("import.start", "org", *org, "since", *since, "repoFilter", *repoFilter, "issues", *issuesScope, "pr", *prScope)
+	slog.Info("import.start", "version", buildinfo.Get().String(), "org", *org, "since", *since, "repoFilter", *repoFilter, "labels", []string(labels), "issues", *issuesScope, "pr", *prScope, "noReviews", *noReviews)
 
-	ctx := context.Background()
-	ghc := cg.New(nil, token)
+	// Ctrl-C cancels the run, including a rate limit sleep of up to an hour
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *deadline)
+		defer cancel()
+	}
+	var ghOpts []cg.Option
+	if base := os.Getenv("GITHUB_API_URL"); base != "" {
+		ghOpts = append(ghOpts, cg.WithBaseURL(base))
+	}
+	if *snapshot {
+		ghOpts = append(ghOpts, cg.WithSnapshotDir(filepath.Join("data", "snapshots")))
+	}
+	ghc := cg.New(&http.Client{Timeout: *httpTimeout}, token, ghOpts...)
+	started := time.Now()
+	defer func() { logAPIUsage(ghc, started, *org, *issuesScope, *prScope, stopReason(ctx), *bom) }()
+
+	// Check token scopes up front: a missing read:org or read:project otherwise surfaces as cryptic
+	// GraphQL permission errors in the middle of the run
+	tokenReport, err := ghc.VerifyToken(ctx, *org, *issuesScope)
+	if err != nil {
+		if cg.IsAuthError(err) {
+			return authAbort(err)
+		}
+		slog.Warn("import.token.check.error", "error", err)
+	} else {
+		for _, w := range tokenReport.Warnings {
+			slog.Warn("import.token.warning", "warning", w)
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+		}
+		if len(tokenReport.Missing) > 0 {
+			slog.Error("import.token.missing", "missing", tokenReport.Missing, "scopes", tokenReport.Scopes)
+			return fmt.Errorf("import: GITHUB_TOKEN lacks required access: %s", strings.Join(tokenReport.Missing, "; "))
+		}
+	}
 
 	allowedRepos := map[string]bool{}
 	if *repoFilter != "" {
@@ -111,197 +259,182 @@ func Run(args []string) error {
 
 	repos, err := ghc.ListAllRepos(ctx, *org)
 	if err != nil {
+		if cg.IsAuthError(err) {
+			return authAbort(err)
+		}
+		if ctx.Err() != nil {
+			return stoppedError(ctx)
+		}
 		slog.Error("phase.repos.fetch.error", "org", *org, "error", err)
 		fmt.Fprintf(os.Stderr, "error listing repos: %v\n", err)
 		return err
 	}
 
+	// Dormant repositories have nothing new for either phase. They still go to repository.csv, and with
+	// -split-by-repo their directory is left as the previous run wrote it.
+	inactive := inactiveRepos(selectedRepos(repos, allowedRepos, nil), *since, *activeOnly)
+
 	var reports []IssueReport
+	// repositories whose issues phase is skipped: issues disabled (central tracker), or no issue at all
+	skippedDisabled, skippedEmpty := 0, 0
+	// issues whose timeline was taken from the previous files (-skip-unchanged)
+	reused := 0
 	if *issuesScope {
+		// With -skip-unchanged, the previous files are read before this run overwrites them
+		var prior map[string]*priorTimeline
+		if *skipUnchanged && !*splitByRepo {
+			if prior, err = readPriorTimelines("data"); err != nil {
+				return fmt.Errorf("import: reading the previous issue files for -skip-unchanged: %w", err)
+			}
+		}
+		// Resolve project names centrally: events only carry names for projects with activity.
+		projectNames, err := ghc.ListOrgProjects(ctx, *org)
+		if err != nil {
+			slog.Warn("phase.projects.fetch.error", "org", *org, "error", err)
+			projectNames = map[string]string{}
+		}
 		for _, r := range repos {
 			if *repoFilter != "" && !allowedRepos[r.Name] {
 				continue
 			}
+			if inactive[r.Name] {
+				continue
+			}
+			if stopped(ctx, "issues", r.Name) {
+				break
+			}
+			if !r.HasIssuesEnabled || r.IssueCount == 0 {
+				reason := "no issues"
+				if !r.HasIssuesEnabled {
+					reason = "issues disabled"
+					skippedDisabled++
+				} else {
+					skippedEmpty++
+				}
+				slog.Info("phase.issues.skip", "owner", r.Owner.Login, "repo", r.Name, "reason", reason)
+				continue
+			}
+			if repoProjects, err := ghc.ListRepoProjects(ctx, r.Owner.Login, r.Name); err != nil {
+				slog.Warn("phase.projects.fetch.error", "owner", r.Owner.Login, "repo", r.Name, "error", err)
+			} else {
+				for id, name := range repoProjects {
+					projectNames[id] = name
+				}
+			}
+			if *skipUnchanged && *splitByRepo {
+				if prior, err = readPriorTimelines(ccsv.RepoDir(r.Name)); err != nil {
+					return fmt.Errorf("import: reading the previous issue files of %s for -skip-unchanged: %w", r.Name, err)
+				}
+			}
 			// No checkpoint resume: always start from the beginning or respect the provided -since filter.
 			slog.Info("phase.issues.import.start", "owner", r.Owner.Login, "repo", r.Name, "since", *since)
-			issues, _, err := ghc.ListAllIssues(ctx, r.Owner.Login, r.Name, *since, "")
+			issues, _, err := ghc.ListAllIssues(ctx, r.Owner.Login, r.Name, *since, labels, "")
 			if err != nil {
+				// A rejected token fails every repo the same way: stop instead of writing an empty dataset
+				if cg.IsAuthError(err) {
+					return authAbort(err)
+				}
 				slog.Error("phase.issues.fetch.error", "owner", r.Owner.Login, "repo", r.Name, "error", err)
 				fmt.Fprintf(os.Stderr, "error listing issues for %s/%s: %v\n", r.Owner.Login, r.Name, err)
 				continue
 			}
 			slog.Info("phase.issues.import.fetched", "owner", r.Owner.Login, "repo", r.Name, "count", len(issues))
+			// Closed before the window and still closed: its timeline cannot have changed
+			var toFetch []int
 			for _, is := range issues {
-				// Skip PRs
-				if is.PullRequest != nil {
-					continue
-				}
-				// timeline aggregation below
-				report := IssueReport{
-					Org:                 *org,
-					Repo:                r.Name,
-					Number:              is.Number,
-					Title:               is.Title,
-					URL:                 is.HTMLURL,
-					State:               is.State,
-					Creator:             valueOrEmpty(is.User),
-					Assignees:           usersToLogins(is.Assignees),
-					CreatedAt:           is.CreatedAt,
-					ClosedAt:            is.ClosedAt,
-					ProjectCustomFields: is.ProjectCustomFields,
+				if p := prior[priorKey(*org, r.Name, is.Number)]; !(*skipUnchanged && p.canReuse(is, sinceTime)) {
+					toFetch = append(toFetch, is.Number)
 				}
-				// Prefer GitHub IssueType when available; fallback to label heuristics. Also set IsBug.
-				var typ string
-				if strings.TrimSpace(is.Type) != "" {
-					typ = strings.ToLower(strings.TrimSpace(is.Type))
-				}
-				if typ == "" {
-					for _, l := range is.Labels {
-						name := strings.ToLower(strings.TrimSpace(l.Name))
-						if name == "bug" {
-							report.IsBug = true
-							if typ == "" {
-								typ = "bug"
-							}
-						} else if typ == "" { // only derive if not already known
-							if strings.Contains(name, "feature") {
-								typ = "feature"
-							} else if strings.Contains(name, "chore") || strings.Contains(name, "refactor") {
-								typ = "chore"
-							} else if strings.Contains(name, "doc") {
-								typ = "docs"
-							}
-						}
-					}
-
-					if typ == "" {
-						typ = "task"
-					}
-				}
-				report.Type = typ
-				if strings.EqualFold(typ, "bug") {
-					report.IsBug = true
+			}
+			timelines, err := fetchTimelinesConcurrently(ctx, ghc, r, toFetch, *timelineConcurrency)
+			if err != nil {
+				return authAbort(err)
+			}
+			for _, is := range issues {
+				report := newIssueReport(*org, r.Name, is, reportOpts)
+				if p := prior[priorKey(*org, r.Name, is.Number)]; *skipUnchanged && p.canReuse(is, sinceTime) {
+					p.apply(&report, is)
+					reports = append(reports, report)
+					reused++
+					continue
 				}
 
 				// Timeline aggregation
-				evts, err := ghc.ListAllTimeline(ctx, r.Owner.Login, r.Name, is.Number)
-				if err != nil {
-					slog.Warn("phase.timeline.fetch.error", "owner", r.Owner.Login, "repo", r.Name, "issue", is.Number, "error", err)
-					fmt.Fprintf(os.Stderr, "warning: timeline fetch failed for %s/%s#%d: %v\n", r.Owner.Login, r.Name, is.Number, err)
+				tl, ok := timelines[is.Number]
+				if !ok {
+					// issues whose timeline was not fetched would be written without status history: leave them out
+					continue
+				}
+				if tl.err != nil {
+					slog.Warn("phase.timeline.fetch.error", "owner", r.Owner.Login, "repo", r.Name, "issue", is.Number, "error", tl.err)
+					fmt.Fprintf(os.Stderr, "warning: timeline fetch failed for %s/%s#%d: %v\n", r.Owner.Login, r.Name, is.Number, tl.err)
 				} else {
-					statusHist := make([]StatusEvent, 0, 4)
-					projHist := make([]ProjectMoveEvent, 0, 8)
-					// seed opened
-					statusHist = append(statusHist, StatusEvent{Type: "opened", At: is.CreatedAt, By: valueOrEmpty(is.User)})
-					// Track current per project
-					type current struct {
-						present     bool
-						projectID   string
-						projectName string
-						columnID    int64
-						columnName  string
-					}
-					currentByProject := map[string]*current{}
-
-					for _, ev := range evts {
-						slog.Debug(ev.Event)
-						switch ev.Event {
-						case "closed":
-							statusHist = append(statusHist, StatusEvent{Type: "closed", At: ev.CreatedAt, By: valueOrEmpty(ev.Actor)})
-							// set committer as the actor who closed
-							if report.Committer == "" && ev.Actor != nil {
-								report.Committer = ev.Actor.Login
-							}
-						case "reopened":
-							statusHist = append(statusHist, StatusEvent{Type: "reopened", At: ev.CreatedAt, By: valueOrEmpty(ev.Actor)})
-						case "added_to_project_v2":
-							var projID string
-							var projName string
-							if ev.Project != nil {
-								projID = ev.Project.ID
-								projName = ev.Project.Name
-							}
-							if projID != "" {
-								projHist = append(projHist, ProjectMoveEvent{ProjectID: projID, ProjectName: projName, FromColumn: "", At: ev.CreatedAt, By: valueOrEmpty(ev.Actor), Type: "added"})
-								c := &current{present: true, projectID: projID, projectName: projName}
-								currentByProject[projID] = c
-							}
-						case "project_v2_item_status_changed":
-							var projID string
-							var projName string
-							var colNameTo = ev.ProjectColumnName
-							var colNameFrom = ev.PreviousProjectColumnName
-							// Prefer GraphQL-provided project info
-							if ev.Project != nil {
-								projID = ev.Project.ID
-								projName = ev.Project.Name
-							}
-							if projID != "" {
-								projHist = append(projHist, ProjectMoveEvent{ProjectID: projID, ProjectName: projName, FromColumn: colNameFrom, ToColumn: colNameTo, At: ev.CreatedAt, By: valueOrEmpty(ev.Actor), Type: "moved"})
-								c := currentByProject[projID]
-								if c == nil {
-									c = &current{present: true, projectID: projID, projectName: projName}
-									currentByProject[projID] = c
-								}
-								c.present = true
-								c.projectName = projName
-								c.columnName = colNameTo
-							}
-						case "removed_from_project_v2":
-							var projID string
-							var projName string
-							if ev.Project != nil {
-								projID = ev.Project.ID
-								projName = ev.Project.Name
-							}
-							if projID != "" {
-								projHist = append(projHist, ProjectMoveEvent{ProjectID: projID, ProjectName: projName, FromColumn: "", ToColumn: "", At: ev.CreatedAt, By: valueOrEmpty(ev.Actor), Type: "removed"})
-								c := currentByProject[projID]
-								if c == nil {
-									c = &current{projectID: projID, projectName: projName}
-									currentByProject[projID] = c
-								}
-								c.present = false
-							}
-						}
-					}
-
-					report.StatusHistory = statusHist
-					report.ProjectHistory = projHist
-					for pid, cur := range currentByProject {
-						if cur.present {
-							report.CurrentProjects = append(report.CurrentProjects, CurrentProject{ProjectID: pid, ProjectName: cur.projectName, ColumnID: cur.columnID, ColumnName: cur.columnName})
-						}
-					}
+					applyTimeline(&report, is, tl.evts, reportOpts)
 				}
 
 				reports = append(reports, report)
 			}
 		}
 
-		// Write CSV outputs into data/ directory
-		if err := ccsv.WriteAllCSVs(*org, repos, reports); err != nil {
-			slog.Error("phase.csv.write.error", "error", err)
-			fmt.Fprintf(os.Stderr, "failed to write CSV outputs: %v\n", err)
+		// Write CSV outputs into data/ directory (or data/<repo>/ when split by repo). A run stopped early would
+		// replace them with the issues fetched until then only: the previous files are kept instead.
+		if ctx.Err() != nil {
+			slog.Warn("phase.csv.write.skip", "scope", "issues", "reason", stopReason(ctx), "reports", len(reports))
+		} else {
+			if *splitByRepo {
+				err = ccsv.WriteAllCSVsByRepo(*org, selectedRepos(repos, allowedRepos, inactive), reports, projectNames, *bom)
+			} else {
+				err = ccsv.WriteAllCSVs(*org, repos, reports, projectNames, *bom)
+			}
+			if err != nil {
+				slog.Error("phase.csv.write.error", "error", err)
+				fmt.Fprintf(os.Stderr, "failed to write CSV outputs: %v\n", err)
+			}
+		}
+		if *jsonLines && ctx.Err() == nil {
+			if err := jsonl.WriteIssueReports(filepath.Join("data", "issues.jsonl"), reports); err != nil {
+				slog.Error("phase.jsonl.write.error", "error", err)
+				fmt.Fprintf(os.Stderr, "failed to write issues.jsonl: %v\n", err)
+			}
 		}
 	}
 
 	// New: fetch PRs and reviews and write to unified CSVs (PR scope)
 	prUnifiedPath := "data/pr.csv"
 	rvUnifiedPath := "data/pr_review.csv"
+	linkUnifiedPath := "data/pr_issue_link.csv"
 
 	var allPRs []gh.PullRequest
 	var allReviews []gh.PullRequestReview
 
 	if *prScope {
+		// The PR listing of each repository resumes where an interrupted import left it; with -snapshot it starts
+		// again, the snapshot directory needing every page
+		checkpoints, err := loadPRCheckpoints()
+		if err != nil {
+			slog.Warn("phase.prs.checkpoint.error", "error", err)
+			checkpoints = prCheckpoints{}
+		}
+		// set when a repository was left out, its checkpoint then kept for the next run
+		prIncomplete := false
 		for _, r := range repos {
 			if *repoFilter != "" && !allowedRepos[r.Name] {
 				continue
 			}
+			if inactive[r.Name] {
+				continue
+			}
+			if stopped(ctx, "pr", r.Name) {
+				break
+			}
 			// List PRs opened/updated since
-			prs, err := ghc.ListAllPullRequests(ctx, r.Owner.Login, r.Name, *since)
+			prs, err := listPullRequests(ctx, ghc, checkpoints, r, *since, !*snapshot)
 			if err != nil {
+				if cg.IsAuthError(err) {
+					return authAbort(err)
+				}
 				slog.Warn("phase.prs.fetch.error", "owner", r.Owner.Login, "repo", r.Name, "error", err)
+				prIncomplete = true
 				continue
 			}
 			// Collect all PRs
@@ -311,38 +444,291 @@ func Run(args []string) error {
 			}
 			allPRs = append(allPRs, prs...)
 
-			// For each PR, fetch reviews and collect them
-			for _, pr := range prs {
-				reviews, err := ghc.ListAllPullRequestReviews(ctx, r.Owner.Login, r.Name, pr.Number)
-				if err != nil {
-					slog.Warn("phase.pr.reviews.fetch.error", "repo", r.Name, "pr", pr.Number, "error", err)
-					continue
-				}
-				if len(reviews) == 0 {
-					continue
-				}
-				// Collect all reviews
-				for i := range reviews {
-					reviews[i].Org = *org
-					reviews[i].Repo = r.Name
-					reviews[i].PullRequestNumber = pr.Number
-				}
-				allReviews = append(allReviews, reviews...)
+			if *noReviews {
+				continue
 			}
+			// For each PR, fetch reviews and collect them (bounded pool; pacing is handled per response by the client)
+			reviews, err := fetchReviewsConcurrently(ctx, ghc, *org, r, prs, *reviewConcurrency)
+			if err != nil {
+				return authAbort(err)
+			}
+			allReviews = append(allReviews, reviews...)
 		}
+		// Workers finish in any order: sort so pr_review.csv is stable across runs
+		sort.SliceStable(allReviews, func(i, j int) bool {
+			a, b := allReviews[i], allReviews[j]
+			if a.Repo != b.Repo {
+				return a.Repo < b.Repo
+			}
+			if a.PullRequestNumber != b.PullRequestNumber {
+				return a.PullRequestNumber < b.PullRequestNumber
+			}
+			return a.SubmittedAt.Before(b.SubmittedAt)
+		})
 
-		// Write all collected PRs and reviews at once
-		if err := ccsv.WritePullRequests(prUnifiedPath, allPRs); err != nil {
-			slog.Warn("phase.prs.csv.error", "error", err)
+		// Stopped early: the previous files are kept, and the checkpoints let the next run resume the listings
+		if ctx.Err() != nil {
+			slog.Warn("phase.csv.write.skip", "scope", "pr", "reason", stopReason(ctx), "prs", len(allPRs))
+		} else if *splitByRepo {
+			writeSplitPullRequests(selectedRepos(repos, allowedRepos, inactive), allPRs, allReviews, *bom)
+		} else {
+			// Write all collected PRs and reviews at once
+			if err := ccsv.WritePullRequests(prUnifiedPath, allPRs, *bom); err != nil {
+				slog.Warn("phase.prs.csv.error", "error", err)
+				prIncomplete = true
+			}
+			if err := ccsv.WritePullRequestReviews(rvUnifiedPath, allReviews, *bom); err != nil {
+				slog.Warn("phase.pr.reviews.csv.error", "error", err)
+			}
+			if err := ccsv.WritePullRequestIssueLinks(linkUnifiedPath, allPRs, *bom); err != nil {
+				slog.Warn("phase.pr.links.csv.error", "error", err)
+			}
 		}
-		if err := ccsv.WritePullRequestReviews(rvUnifiedPath, allReviews); err != nil {
-			slog.Warn("phase.pr.reviews.csv.error", "error", err)
+		// Every repository listed and written: the next run starts from the first page again
+		if !prIncomplete && ctx.Err() == nil {
+			if err := os.RemoveAll(checkpointDir); err != nil {
+				slog.Warn("phase.prs.checkpoint.error", "error", err)
+			}
 		}
 	}
-	slog.Info("import.done", "reports", len(reports))
+	if ctx.Err() != nil {
+		slog.Error("import.incomplete", "reports", len(reports), "timelinesReused", reused, "prs", len(allPRs), "skippedIssuesDisabled", skippedDisabled, "skippedNoIssues", skippedEmpty, "skippedInactive", len(inactive), "error", context.Cause(ctx))
+		return stoppedError(ctx)
+	}
+	slog.Info("import.done", "reports", len(reports), "timelinesReused", reused, "skippedIssuesDisabled", skippedDisabled, "skippedNoIssues", skippedEmpty, "skippedInactive", len(inactive))
 	return nil
 }
 
+// stopped reports whether ctx is done (deadline reached or interrupted), logging it once per scope before repo.
+func stopped(ctx context.Context, scope, repo string) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	slog.Warn("import.stopped", "scope", scope, "nextRepo", repo, "error", ctx.Err())
+	return true
+}
+
+// stopReason returns why ctx ended before the import completed: "deadline" when -deadline was reached,
+// "interrupted" on Ctrl-C or SIGTERM, "" while it has not ended.
+func stopReason(ctx context.Context) string {
+	switch {
+	case ctx.Err() == nil:
+		return ""
+	case errors.Is(context.Cause(ctx), context.DeadlineExceeded):
+		return "deadline"
+	default:
+		return "interrupted"
+	}
+}
+
+// stoppedError is the error of an import stopped by the end of ctx, wrapping its cause.
+func stoppedError(ctx context.Context) error {
+	return fmt.Errorf("import: stopped before completion (%s), the previous CSVs were kept: %w", stopReason(ctx), context.Cause(ctx))
+}
+
+// logAPIUsage logs the GitHub API usage of the run and appends it to data/import_meta.csv, with the stopped
+// reason of a run that did not complete.
+func logAPIUsage(ghc *cg.Client, started time.Time, org string, issues, pr bool, stopped string, bom bool) {
+	st := ghc.Stats()
+	elapsed := time.Since(started)
+	var scopes []string
+	if issues {
+		scopes = append(scopes, "issues")
+	}
+	if pr {
+		scopes = append(scopes, "pr")
+	}
+	slog.Info("import.api.usage", "restCalls", st.RESTCalls, "graphqlCalls", st.GraphQLCalls, "graphqlPoints", st.GraphQLPoints,
+		"rateLimitSleep", st.RateLimitSleep, "bytes", st.BytesDownloaded, "errors", st.Errors, "retries", st.Retries, "duration", elapsed, "stopped", stopped)
+	row := []string{
+		started.UTC().Format(time.RFC3339),
+		org,
+		strings.Join(scopes, ";"),
+		fmt.Sprintf("%.3f", elapsed.Seconds()),
+		strconv.FormatInt(st.RESTCalls, 10),
+		strconv.FormatInt(st.GraphQLCalls, 10),
+		strconv.FormatInt(st.GraphQLPoints, 10),
+		fmt.Sprintf("%.3f", st.RateLimitSleep.Seconds()),
+		strconv.FormatInt(st.BytesDownloaded, 10),
+		strconv.FormatInt(st.Errors, 10),
+		strconv.FormatInt(st.Retries, 10),
+		buildinfo.Get().String(),
+		stopped,
+	}
+	if err := ccsv.AppendRow(filepath.Join("data", "import_meta.csv"), schema.Headers("import_meta.csv"), row, bom); err != nil {
+		slog.Warn("import.meta.csv.error", "error", err)
+	}
+}
+
+// selectedRepos returns the repositories kept by the -repo filter (all of them when the filter is empty), minus
+// the skipped ones.
+func selectedRepos(repos []gh.Repo, allowed, skipped map[string]bool) []gh.Repo {
+	if len(allowed) == 0 && len(skipped) == 0 {
+		return repos
+	}
+	var res []gh.Repo
+	for _, r := range repos {
+		if (len(allowed) == 0 || allowed[r.Name]) && !skipped[r.Name] {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// inactiveRepos returns the names of the repositories of repos neither pushed to nor updated since since
+// (RFC3339), each logged. It returns nil when activeOnly is off or since is empty or invalid.
+func inactiveRepos(repos []gh.Repo, since string, activeOnly bool) map[string]bool {
+	if !activeOnly || since == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		slog.Warn("import.active_only.ignored", "since", since, "error", err)
+		return nil
+	}
+	inactive := map[string]bool{}
+	for _, r := range repos {
+		if r.PushedAt.Before(t) && r.UpdatedAt.Before(t) {
+			inactive[r.Name] = true
+			slog.Info("phase.repo.skip.inactive", "owner", r.Owner.Login, "repo", r.Name, "pushedAt", r.PushedAt, "updatedAt", r.UpdatedAt)
+		}
+	}
+	return inactive
+}
+
+// writeSplitPullRequests writes pr.csv, pr_review.csv and pr_issue_link.csv into the directory of each repository (-split-by-repo).
+func writeSplitPullRequests(repos []gh.Repo, prs []gh.PullRequest, reviews []gh.PullRequestReview, bom bool) {
+	prsByRepo := map[string][]gh.PullRequest{}
+	for _, pr := range prs {
+		prsByRepo[pr.Repo] = append(prsByRepo[pr.Repo], pr)
+	}
+	reviewsByRepo := map[string][]gh.PullRequestReview{}
+	for _, rv := range reviews {
+		reviewsByRepo[rv.Repo] = append(reviewsByRepo[rv.Repo], rv)
+	}
+	for _, r := range repos {
+		dir := ccsv.RepoDir(r.Name)
+		if err := ccsv.WritePullRequests(filepath.Join(dir, "pr.csv"), prsByRepo[r.Name], bom); err != nil {
+			slog.Warn("phase.prs.csv.error", "repo", r.Name, "error", err)
+		}
+		if err := ccsv.WritePullRequestReviews(filepath.Join(dir, "pr_review.csv"), reviewsByRepo[r.Name], bom); err != nil {
+			slog.Warn("phase.pr.reviews.csv.error", "repo", r.Name, "error", err)
+		}
+		if err := ccsv.WritePullRequestIssueLinks(filepath.Join(dir, "pr_issue_link.csv"), prsByRepo[r.Name], bom); err != nil {
+			slog.Warn("phase.pr.links.csv.error", "repo", r.Name, "error", err)
+		}
+	}
+}
+
+// authAbort logs and wraps an authentication failure that ends the import.
+func authAbort(err error) error {
+	slog.Error("import.auth.error", "error", err)
+	fmt.Fprintln(os.Stderr, "GitHub rejected the token: check that GITHUB_TOKEN is valid, has the repo and read:org scopes, and is authorized for the organization (SSO).")
+	return fmt.Errorf("import aborted, authentication failed: %w", err)
+}
+
+// fetchReviewsConcurrently lists reviews for prs of repo r using at most concurrency parallel requests.
+// Fetch errors are logged and the PR is skipped, as in the sequential flow, except authentication errors
+// which are returned (the first one seen) once all workers are done.
+func fetchReviewsConcurrently(ctx context.Context, ghc *cg.Client, org string, r Repo, prs []gh.PullRequest, concurrency int) ([]gh.PullRequestReview, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		all     []gh.PullRequestReview
+		authErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, pr := range prs {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(number int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			reviews, err := ghc.ListAllPullRequestReviews(ctx, r.Owner.Login, r.Name, number)
+			if err != nil {
+				if cg.IsAuthError(err) {
+					mu.Lock()
+					if authErr == nil {
+						authErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				slog.Warn("phase.pr.reviews.fetch.error", "repo", r.Name, "pr", number, "error", err)
+				return
+			}
+			if len(reviews) == 0 {
+				return
+			}
+			for i := range reviews {
+				reviews[i].Org = org
+				reviews[i].Repo = r.Name
+				reviews[i].PullRequestNumber = number
+			}
+			mu.Lock()
+			all = append(all, reviews...)
+			mu.Unlock()
+		}(pr.Number)
+	}
+	wg.Wait()
+	return all, authErr
+}
+
+// timelineResult is the outcome of the timeline fetch of one issue.
+type timelineResult struct {
+	evts []TimelineEvent
+	err  error
+}
+
+// fetchTimelinesConcurrently lists the timelines of the issues numbers of repo r using at most concurrency
+// parallel requests. Issues not fetched because ctx ended have no entry. Authentication errors are returned
+// (the first one seen) once all workers are done; other errors are kept in the result of their issue.
+func fetchTimelinesConcurrently(ctx context.Context, ghc *cg.Client, r Repo, numbers []int, concurrency int) (map[int]timelineResult, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		authErr error
+	)
+	res := make(map[int]timelineResult, len(numbers))
+	sem := make(chan struct{}, concurrency)
+	for _, number := range numbers {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(number int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			evts, err := ghc.ListAllTimeline(ctx, r.Owner.Login, r.Name, number)
+			mu.Lock()
+			defer mu.Unlock()
+			if cg.IsAuthError(err) {
+				if authErr == nil {
+					authErr = err
+				}
+				return
+			}
+			// a request cut by the end of ctx leaves the issue out, like one never sent
+			if err != nil && ctx.Err() != nil {
+				return
+			}
+			res[number] = timelineResult{evts: evts, err: err}
+		}(number)
+	}
+	wg.Wait()
+	return res, authErr
+}
+
 func valueOrEmpty(u *User) string {
 	if u == nil {
 		return ""
@@ -360,8 +746,39 @@ func usersToLogins(us []User) []string {
 	return res
 }
 
-// runCloudSpendingImport fetches cloud spending data from Azure and GCP
-func runCloudSpendingImport() error {
+// cloudProviders are the providers runCloudSpendingImport knows how to fetch.
+var cloudProviders = []string{"azure", "gcp"}
+
+// unsupportedProviders are cloud providers asked for that have no connector yet, with the reason given.
+var unsupportedProviders = map[string]string{
+	"aws": "AWS costs cannot be imported yet, there is no AWS connector",
+}
+
+// selectProviders validates the -provider values. An empty list selects every provider.
+func selectProviders(names []string) (map[string]bool, error) {
+	selected := map[string]bool{}
+	if len(names) == 0 {
+		for _, p := range cloudProviders {
+			selected[p] = true
+		}
+		return selected, nil
+	}
+	for _, n := range names {
+		n = strings.ToLower(n)
+		if reason, ok := unsupportedProviders[n]; ok {
+			return nil, fmt.Errorf("import: -provider %s: %s (available: %s)", n, reason, strings.Join(cloudProviders, ", "))
+		}
+		if !slices.Contains(cloudProviders, n) {
+			return nil, fmt.Errorf("import: unsupported -provider %q (supported: %s)", n, strings.Join(cloudProviders, ", "))
+		}
+		selected[n] = true
+	}
+	return selected, nil
+}
+
+// runCloudSpendingImport fetches cloud spending data from the selected providers (Azure, GCP) whose credentials
+// are set and merges it into cloud_costs.csv, unless overwrite is set.
+func runCloudSpendingImport(providers map[string]bool, overwrite bool, environments map[string][]string, bom bool) error {
 	slog.Info("cloudspending.import.start")
 	ctx := context.Background()
 
@@ -374,7 +791,9 @@ func runCloudSpendingImport() error {
 	azureClientID := os.Getenv("AZURE_CLIENT_ID")
 	azureClientSecret := os.Getenv("AZURE_CLIENT_SECRET")
 
-	if azureSubscriptionIDs != "" && azureTenantID != "" && azureClientID != "" && azureClientSecret != "" {
+	if !providers["azure"] {
+		slog.Info("cloudspending.azure.skip", "reason", "not selected with -provider")
+	} else if azureSubscriptionIDs != "" && azureTenantID != "" && azureClientID != "" && azureClientSecret != "" {
 		slog.Info("cloudspending.azure.fetch.start")
 
 		// Split subscription IDs by comma to support multiple subscriptions
@@ -393,6 +812,9 @@ func runCloudSpendingImport() error {
 				slog.Warn("cloudspending.azure.fetch.error", "subscription_id", subID, "error", err)
 				fmt.Fprintf(os.Stderr, "Warning: failed to fetch Azure costs for subscription %s: %v\n", subID, err)
 			} else {
+				for i := range azureRecords {
+					azureRecords[i].Dimension = subID
+				}
 				allRecords = append(allRecords, azureRecords...)
 				slog.Info("cloudspending.azure.fetch.done", "subscription_id", subID, "count", len(azureRecords))
 			}
@@ -407,7 +829,9 @@ func runCloudSpendingImport() error {
 	gcpServiceAccountJSON := os.Getenv("GCP_SERVICE_ACCOUNT_JSON")
 
 	// Allow ADC: proceed if project and billing account are set. Service account JSON is optional now.
-	if gcpProjectID != "" && gcpBillingAccount != "" {
+	if !providers["gcp"] {
+		slog.Info("cloudspending.gcp.skip", "reason", "not selected with -provider")
+	} else if gcpProjectID != "" && gcpBillingAccount != "" {
 		gcpLocation := os.Getenv("GCP_BIGQUERY_LOCATION") // e.g., EU, US, europe-west1
 		if gcpLocation == "" {
 			slog.Info("cloudspending.gcp.fetch.start", "project", gcpProjectID, "billing", gcpBillingAccount)
@@ -420,6 +844,9 @@ func runCloudSpendingImport() error {
 			slog.Warn("cloudspending.gcp.fetch.error", "error", err)
 			fmt.Fprintf(os.Stderr, "Warning: failed to fetch GCP costs: %v\n", err)
 		} else {
+			for i := range gcpRecords {
+				gcpRecords[i].Dimension = gcpBillingAccount
+			}
 			allRecords = append(allRecords, gcpRecords...)
 			slog.Info("cloudspending.gcp.fetch.done", "count", len(gcpRecords))
 		}
@@ -433,24 +860,45 @@ func runCloudSpendingImport() error {
 		return fmt.Errorf("no cloud spending data fetched - check environment variables")
 	}
 
+	tagEnvironments(allRecords, environments)
+
 	outputPath := filepath.Join("data", "cloud_costs.csv")
-	if err := writeCloudCostsCSV(outputPath, allRecords); err != nil {
-		slog.Error("cloudspending.csv.write.error", "error", err)
-		return fmt.Errorf("failed to write cloud costs CSV: %w", err)
+	count, err := updateCloudCostsCSV(outputPath, allRecords, overwrite, bom)
+	if err != nil {
+		return err
 	}
 
-	slog.Info("cloudspending.import.done", "records", len(allRecords), "output", outputPath)
+	slog.Info("cloudspending.import.done", "records", count, "output", outputPath)
 	return nil
 }
 
+// updateCloudCostsCSV merges fetched into the cloud_costs.csv at path, or replaces it when overwrite is set, and
+// returns the number of rows written. An existing file that cannot be read in full is left untouched.
+func updateCloudCostsCSV(path string, fetched []cloudspending.CostRecord, overwrite, bom bool) (int, error) {
+	merged := fetched
+	if !overwrite {
+		existing, err := readCloudCostsCSV(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read existing cloud costs CSV (fix it or rerun with -overwrite): %w", err)
+		}
+		merged = mergeCloudCosts(existing, fetched)
+		slog.Info("cloudspending.csv.merge", "existing", len(existing), "fetched", len(fetched), "merged", len(merged))
+	}
+	if err := writeCloudCostsCSV(path, merged, bom); err != nil {
+		slog.Error("cloudspending.csv.write.error", "error", err)
+		return 0, fmt.Errorf("failed to write cloud costs CSV: %w", err)
+	}
+	return len(merged), nil
+}
+
 // writeCloudCostsCSV writes cloud cost records to a CSV file
-func writeCloudCostsCSV(path string, records []cloudspending.CostRecord) error {
+func writeCloudCostsCSV(path string, records []cloudspending.CostRecord, bom bool) error {
 	// Ensure data directory exists
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return fmt.Errorf("failed to create data directory: %w", err)
 	}
 
-	f, err := os.Create(path)
+	f, err := ccsv.Create(path, bom)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
@@ -460,7 +908,7 @@ func writeCloudCostsCSV(path string, records []cloudspending.CostRecord) error {
 	defer w.Flush()
 
 	// Write header
-	header := []string{"provider", "service", "month", "cost", "currency"}
+	header := schema.Headers("cloud_costs.csv")
 	if err := w.Write(header); err != nil {
 		return fmt.Errorf("failed to write header: %w", err)
 	}
@@ -473,6 +921,8 @@ func writeCloudCostsCSV(path string, records []cloudspending.CostRecord) error {
 			r.Month.Format("2006-01-02"),
 			fmt.Sprintf("%.2f", r.Cost),
 			r.Currency,
+			r.Dimension,
+			r.Environment,
 		}
 		if err := w.Write(row); err != nil {
 			return fmt.Errorf("failed to write row: %w", err)
@@ -481,3 +931,144 @@ func writeCloudCostsCSV(path string, records []cloudspending.CostRecord) error {
 
 	return nil
 }
+
+// cloudCostKey identifies a cloud cost row for upserts.
+type cloudCostKey struct {
+	Provider, Service, Month, Currency, Dimension, Environment string
+}
+
+func cloudCostKeyOf(r cloudspending.CostRecord) cloudCostKey {
+	return cloudCostKey{r.Provider, r.Service, r.Month.Format("2006-01-02"), r.Currency, r.Dimension, r.Environment}
+}
+
+// readCloudCostsCSV loads a previously written cloud_costs.csv. A missing file yields no records.
+// Files written before the dimension column existed are read with an empty dimension. A row whose month or cost
+// does not parse is an error naming its line.
+func readCloudCostsCSV(path string) ([]cloudspending.CostRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	idx := map[string]int{}
+	for i, h := range header {
+		idx[strings.ToLower(strings.TrimSpace(ccsv.TrimBOM(h)))] = i
+	}
+	get := func(row []string, col string) string {
+		i, ok := idx[col]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+
+	var records []cloudspending.CostRecord
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// A row dropped or zeroed here would be lost when the merged file is written back: fail instead
+		line, _ := r.FieldPos(0)
+		month, err := time.Parse("2006-01-02", get(row, "month"))
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: month %q is not a YYYY-MM-DD date", path, line, get(row, "month"))
+		}
+		cost, err := strconv.ParseFloat(get(row, "cost"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: cost %q is not a number", path, line, get(row, "cost"))
+		}
+		records = append(records, cloudspending.CostRecord{
+			Provider:    get(row, "provider"),
+			Service:     get(row, "service"),
+			Month:       month,
+			Cost:        cost,
+			Currency:    get(row, "currency"),
+			Dimension:   get(row, "dimension"),
+			Environment: get(row, "environment"),
+		})
+	}
+	return records, nil
+}
+
+// mergeCloudCosts upserts fetched records into existing ones by (provider, service, month, currency, dimension,
+// environment), so importing one provider keeps the rows of the others. Duplicate keys within fetched are summed.
+// Existing rows without a dimension (older files) are dropped for providers present in fetched, since they cannot
+// be matched to the new per-dimension rows. Existing rows fetched again under any environment are dropped too, so
+// a changed cloud_spending.environments mapping does not count them twice.
+// The result is sorted by provider, month, service, currency, dimension and environment.
+func mergeCloudCosts(existing, fetched []cloudspending.CostRecord) []cloudspending.CostRecord {
+	refreshed := map[string]bool{}
+	refetched := map[cloudCostKey]bool{}
+	for _, r := range fetched {
+		refreshed[r.Provider] = true
+		k := cloudCostKeyOf(r)
+		k.Environment = ""
+		refetched[k] = true
+	}
+	byKey := map[cloudCostKey]cloudspending.CostRecord{}
+	for _, r := range existing {
+		if r.Dimension == "" && refreshed[r.Provider] {
+			continue
+		}
+		k := cloudCostKeyOf(r)
+		k.Environment = ""
+		if refetched[k] {
+			continue
+		}
+		byKey[cloudCostKeyOf(r)] = r
+	}
+	fresh := map[cloudCostKey]bool{}
+	for _, r := range fetched {
+		k := cloudCostKeyOf(r)
+		if fresh[k] {
+			// Same key twice in one run (e.g. overlapping query windows): keep the total like before the merge
+			prev := byKey[k]
+			prev.Cost += r.Cost
+			byKey[k] = prev
+			continue
+		}
+		fresh[k] = true
+		byKey[k] = r
+	}
+	merged := make([]cloudspending.CostRecord, 0, len(byKey))
+	for _, r := range byKey {
+		merged = append(merged, r)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		a, b := cloudCostKeyOf(merged[i]), cloudCostKeyOf(merged[j])
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		if a.Dimension != b.Dimension {
+			return a.Dimension < b.Dimension
+		}
+		return a.Environment < b.Environment
+	})
+	return merged
+}
@@ -0,0 +1,498 @@
+package cmdimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cto-stats/connectors/config"
+	cg "cto-stats/connectors/github"
+	gh "cto-stats/domain/github"
+)
+
+// setupImport runs the test in an empty working directory, without config, against the GitHub API served by h.
+func setupImport(t *testing.T, h http.Handler) {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("CONFIG_PATH", filepath.Join(dir, "config.yml"))
+	t.Setenv("GITHUB_TOKEN", "ghp_test")
+	t.Setenv("GITHUB_API_URL", srv.URL)
+	t.Setenv("NOTIFY_WEBHOOK_URL", "")
+}
+
+// replayHandler serves the GitHub API from the pages saved under dir in the import -snapshot layout.
+func replayHandler(dir string) http.Handler {
+	rt := cg.Replay(dir)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := rt.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer resp.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	})
+}
+
+// readColumn returns the values of column col of the CSV file at path.
+func readColumn(t *testing.T, path, col string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) == 0 {
+		t.Fatalf("%s is empty", path)
+	}
+	i := slices.Index(rows[0], col)
+	if i < 0 {
+		t.Fatalf("%s has no %s column", path, col)
+	}
+	var res []string
+	for _, rec := range rows[1:] {
+		res = append(res, rec[i])
+	}
+	return res
+}
+
+func TestRunAbortsOnAuthError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"bad credentials", http.StatusUnauthorized, `{"message":"Bad credentials"}`},
+		{"SSO not authorized", http.StatusForbidden, `{"message":"Resource protected by organization SAML enforcement"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			setupImport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			err := Run([]string{"-org", "o", "-issues"})
+			if err == nil {
+				t.Fatal("import succeeded, want an authentication error")
+			}
+			if !cg.IsAuthError(err) || !strings.Contains(err.Error(), "authentication failed") {
+				t.Errorf("error %v, want an authentication failure", err)
+			}
+			if calls != 1 {
+				t.Errorf("%d API calls, want the import to stop after the first", calls)
+			}
+			if _, err := os.Stat(filepath.Join("data", "issue.csv")); !os.IsNotExist(err) {
+				t.Errorf("issue.csv written after an authentication failure (stat: %v)", err)
+			}
+		})
+	}
+}
+
+func TestRunKeepsPullRequestsOutOfIssues(t *testing.T) {
+	// issues and pull requests share the number sequence of a repository: issues 1, 3 and 5, PRs 2, 4 and 6
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "github"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setupImport(t, replayHandler(fixtures))
+	if err := Run([]string{"-org", "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		file, col string
+		want      []string
+	}{
+		{"issue.csv", "number", []string{"1", "3", "5"}},
+		{"pr.csv", "number", []string{"2", "4", "6"}},
+		{"pr_review.csv", "number", []string{"2", "2", "2", "2", "4", "4", "6"}},
+	}
+	for _, tt := range tests {
+		got := readColumn(t, filepath.Join("data", tt.file), tt.col)
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s numbers %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestRunHTTPTimeoutFailsHungRequest(t *testing.T) {
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "github"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := replayHandler(fixtures)
+	// the first timeline request hangs until the client gives up on it
+	var hung, aborted atomic.Bool
+	setupImport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if bytes.Contains(body, []byte("timelineItems")) && hung.CompareAndSwap(false, true) {
+			select {
+			case <-r.Context().Done():
+				aborted.Store(true)
+			case <-time.After(10 * time.Second):
+			}
+			return
+		}
+		replay.ServeHTTP(w, r)
+	}))
+	start := time.Now()
+	if err := Run([]string{"-org", "acme", "-issues", "-http-timeout", "500ms"}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("import took %v, want the hung request to fail after -http-timeout", elapsed)
+	}
+	if !hung.Load() || !aborted.Load() {
+		t.Errorf("hung request sent %v, aborted by the client %v, want both", hung.Load(), aborted.Load())
+	}
+	// the issue of the failed timeline is still imported, without history
+	got := readColumn(t, filepath.Join("data", "issue.csv"), "number")
+	slices.Sort(got)
+	if want := []string{"1", "3", "5"}; !slices.Equal(got, want) {
+		t.Errorf("issue.csv numbers %v, want %v", got, want)
+	}
+}
+
+func TestRunDeadlineStopsImport(t *testing.T) {
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "github"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := replayHandler(fixtures)
+	// a slow server: the full import takes well over the deadline
+	setupImport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+		replay.ServeHTTP(w, r)
+	}))
+	const previous = "org,repo,number\nacme,api,1\n"
+	if err := os.MkdirAll("data", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"issue.csv", "pr.csv"} {
+		if err := os.WriteFile(filepath.Join("data", name), []byte(previous), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now()
+	err = Run([]string{"-org", "acme", "-deadline", "250ms"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error %v, want one wrapping context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("import took %v, want it stopped at the deadline", elapsed)
+	}
+	for _, name := range []string{"issue.csv", "pr.csv"} {
+		b, err := os.ReadFile(filepath.Join("data", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != previous {
+			t.Errorf("%s rewritten by the stopped run:\n%s", name, b)
+		}
+	}
+	if got := readColumn(t, filepath.Join("data", "import_meta.csv"), "stopped"); !slices.Equal(got, []string{"deadline"}) {
+		t.Errorf("import_meta.csv stopped %v, want [deadline]", got)
+	}
+}
+
+func TestSelectProviders(t *testing.T) {
+	tests := []struct {
+		names   []string
+		want    []string
+		wantErr string
+	}{
+		{nil, []string{"azure", "gcp"}, ""},
+		{[]string{"GCP"}, []string{"gcp"}, ""},
+		{[]string{"azure", "gcp"}, []string{"azure", "gcp"}, ""},
+		{[]string{"aws"}, nil, "there is no AWS connector"},
+		{[]string{"gcp", "oracle"}, nil, `unsupported -provider "oracle"`},
+	}
+	for _, tt := range tests {
+		got, err := selectProviders(tt.names)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("selectProviders(%q) error %v, want one containing %q", tt.names, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("selectProviders(%q): %v", tt.names, err)
+			continue
+		}
+		var names []string
+		for n := range got {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("selectProviders(%q) = %v, want %v", tt.names, names, tt.want)
+		}
+	}
+}
+
+// redirectTransport sends every request to the test server at base, keeping its path and query.
+type redirectTransport struct{ base *url.URL }
+
+func (t redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.base.Scheme
+	r.URL.Host = t.base.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestFetchReviewsConcurrentlyBound(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		want        int // the most requests in flight at once
+	}{
+		{"sequential", 1, 1},
+		{"zero means one", 0, 1},
+		{"bounded", 3, 3},
+		{"more workers than PRs", 20, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inFlight, most atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					m := most.Load()
+					if n <= m || most.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				// a slow server, so that every worker allowed to run has a request in flight
+				time.Sleep(30 * time.Millisecond)
+				_, _ = w.Write([]byte(`[{"state":"APPROVED","submitted_at":"2025-03-03T10:00:00Z","user":{"login":"ann"}}]`))
+			}))
+			defer srv.Close()
+			base, _ := url.Parse(srv.URL)
+			ghc := cg.New(&http.Client{Transport: redirectTransport{base}}, "token")
+			var prs []gh.PullRequest
+			for n := 1; n <= 8; n++ {
+				prs = append(prs, gh.PullRequest{Number: n})
+			}
+			r := Repo{Name: "api"}
+			r.Owner.Login = "acme"
+			reviews, err := fetchReviewsConcurrently(context.Background(), ghc, "acme", r, prs, tt.concurrency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var numbers []int
+			for _, rv := range reviews {
+				numbers = append(numbers, rv.PullRequestNumber)
+			}
+			slices.Sort(numbers)
+			if !slices.Equal(numbers, []int{1, 2, 3, 4, 5, 6, 7, 8}) {
+				t.Errorf("reviews of PRs %v, want one for each of the 8", numbers)
+			}
+			if got := int(most.Load()); got != tt.want {
+				t.Errorf("%d requests in flight at most, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyConfigDefaultsTimelineConcurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  int
+		args    []string
+		want    int
+		wantErr bool
+	}{
+		{"flag default", 0, nil, 1, false},
+		{"from config", 3, nil, 3, false},
+		{"explicit flag wins", 3, []string{"-timeline-concurrency", "1"}, 1, false},
+		{"negative config", -1, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("import", flag.ContinueOnError)
+			got := fs.Int("timeline-concurrency", 1, "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			cfg := &config.Config{}
+			cfg.Import.TimelineConcurrency = tt.config
+			err := applyConfigDefaults(fs, cfg, time.Now())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "timeline_concurrency") {
+					t.Errorf("error %v, want one naming import.timeline_concurrency", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("-timeline-concurrency %d, want %d", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunTimelineConcurrencyKeepsIssueOrder(t *testing.T) {
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "github"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []map[string]string
+	for _, concurrency := range []string{"1", "3"} {
+		setupImport(t, replayHandler(fixtures))
+		if err := Run([]string{"-org", "acme", "-issues", "-timeline-concurrency", concurrency}); err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]string{}
+		for _, name := range []string{"issue.csv", "issue_status_event.csv", "issue_project_event.csv"} {
+			b, err := os.ReadFile(filepath.Join("data", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[name] = string(b)
+		}
+		files = append(files, got)
+	}
+	for name, want := range files[0] {
+		if files[1][name] != want {
+			t.Errorf("%s with -timeline-concurrency 3:\n%s\nwant the sequential output:\n%s", name, files[1][name], want)
+		}
+	}
+}
+
+// issueRows returns the rows of org/repo#number in the CSV file at path, header first.
+func issueRows(t *testing.T, path, repo, number string) [][]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoCol, numberCol := slices.Index(rows[0], "repo"), slices.Index(rows[0], "number")
+	res := [][]string{rows[0]}
+	for _, rec := range rows[1:] {
+		if rec[repoCol] == repo && rec[numberCol] == number {
+			res = append(res, rec)
+		}
+	}
+	return res
+}
+
+func TestRunSkipUnchangedReusesTimeline(t *testing.T) {
+	// acme/api#1 was closed on 2025-03-07, before -since; #3 is open and web#5 was closed after -since
+	const since = "2025-03-10T00:00:00Z"
+	timelineFiles := []string{"issue.csv", "issue_status_event.csv", "issue_project_event.csv", "issue_current_project.csv"}
+	tests := []struct {
+		name     string
+		closedAt string // closedAt of acme/api#1 in the second run
+		want     []int  // timelines fetched by the second run
+	}{
+		{"still closed", "2025-03-07T12:00:00Z", []int{3, 5}},
+		{"reopened and closed again", "2025-03-08T12:00:00Z", []int{1, 3, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixtures := t.TempDir()
+			if err := os.CopyFS(fixtures, os.DirFS(filepath.Join("testdata", "github"))); err != nil {
+				t.Fatal(err)
+			}
+			replay := replayHandler(fixtures)
+			var mu sync.Mutex
+			var fetched []int
+			setupImport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				var in struct {
+					Query     string
+					Variables struct{ Number int }
+				}
+				if json.Unmarshal(body, &in) == nil && strings.Contains(in.Query, "timelineItems(") {
+					mu.Lock()
+					fetched = append(fetched, in.Variables.Number)
+					mu.Unlock()
+				}
+				replay.ServeHTTP(w, r)
+			}))
+
+			// a full fetch gives the rows of acme/api#1 to compare with
+			if err := Run([]string{"-org", "acme", "-issues", "-since", since}); err != nil {
+				t.Fatal(err)
+			}
+			full := map[string][][]string{}
+			for _, name := range timelineFiles {
+				full[name] = issueRows(t, filepath.Join("data", name), "api", "1")
+				if len(full[name]) < 2 {
+					t.Fatalf("%s has no row of acme/api#1", name)
+				}
+			}
+
+			issues := filepath.Join(fixtures, "api", "issues-1.json")
+			page, err := os.ReadFile(issues)
+			if err != nil {
+				t.Fatal(err)
+			}
+			page = bytes.ReplaceAll(page, []byte(`"closedAt": "2025-03-07T12:00:00Z"`), []byte(`"closedAt": "`+tt.closedAt+`"`))
+			if err := os.WriteFile(issues, page, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			fetched = nil
+			if err := Run([]string{"-org", "acme", "-issues", "-since", since, "-skip-unchanged"}); err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(fetched)
+			if !slices.Equal(fetched, tt.want) {
+				t.Errorf("timelines fetched %v, want %v", fetched, tt.want)
+			}
+			for _, name := range timelineFiles {
+				got := issueRows(t, filepath.Join("data", name), "api", "1")
+				if name == "issue.csv" {
+					// closed_at comes from the issue listing, not the timeline
+					col := slices.Index(got[0], "closed_at")
+					for _, rec := range full[name][1:] {
+						rec[col] = tt.closedAt
+					}
+				}
+				if !slices.EqualFunc(got, full[name], slices.Equal) {
+					t.Errorf("%s rows of acme/api#1:\n%q\nwant those of a full fetch:\n%q", name, got, full[name])
+				}
+			}
+		})
+	}
+}
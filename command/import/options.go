@@ -0,0 +1,100 @@
+package cmdimport
+
+import (
+	"cto-stats/connectors/config"
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importScopes are the scope flags, also accepted in import.scopes.
+var importScopes = []string{"issues", "pr", "cloudspending"}
+
+// flagGiven reports whether the flag called name was set, on the command line or from the config.
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) { given = given || f.Name == name })
+	return given
+}
+
+// applyConfigDefaults sets the flags of fs that were not given on the command line from the import section of
+// cfg. Flags are tracked with fs.Visit, so an explicit -review-concurrency 4 or -since "" still wins over the
+// config. The scopes of the config only apply when no scope flag is given.
+func applyConfigDefaults(fs *flag.FlagSet, cfg *config.Config, now time.Time) error {
+	if cfg == nil {
+		return nil
+	}
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	ic := cfg.Import
+
+	for _, s := range ic.Scopes {
+		if !slices.Contains(importScopes, strings.ToLower(strings.TrimSpace(s))) {
+			return fmt.Errorf("import: unknown scope %q in config import.scopes (supported: %s)", s, strings.Join(importScopes, ", "))
+		}
+	}
+	if ic.SinceDays < 0 {
+		return fmt.Errorf("import: config import.since_days must not be negative, got %d", ic.SinceDays)
+	}
+	if ic.ReviewConcurrency < 0 {
+		return fmt.Errorf("import: config import.review_concurrency must not be negative, got %d", ic.ReviewConcurrency)
+	}
+	if ic.TimelineConcurrency < 0 {
+		return fmt.Errorf("import: config import.timeline_concurrency must not be negative, got %d", ic.TimelineConcurrency)
+	}
+	if ic.DescriptionMinLength < 0 {
+		return fmt.Errorf("import: config import.description_min_length must not be negative, got %d", ic.DescriptionMinLength)
+	}
+
+	defaults := map[string]string{}
+	if !slices.ContainsFunc(importScopes, func(s string) bool { return given[s] }) {
+		for _, s := range ic.Scopes {
+			defaults[strings.ToLower(strings.TrimSpace(s))] = "true"
+		}
+	}
+	if ic.SinceDays > 0 {
+		defaults["since"] = now.UTC().AddDate(0, 0, -ic.SinceDays).Format(time.RFC3339)
+	}
+	if len(ic.Repos) > 0 {
+		defaults["repo"] = strings.Join(ic.Repos, ",")
+	}
+	if len(ic.Labels) > 0 {
+		defaults["labels"] = strings.Join(ic.Labels, ",")
+	}
+	if len(ic.Providers) > 0 {
+		defaults["provider"] = strings.Join(ic.Providers, ",")
+	}
+	if ic.ReviewConcurrency > 0 {
+		defaults["review-concurrency"] = strconv.Itoa(ic.ReviewConcurrency)
+	}
+	if ic.TimelineConcurrency > 0 {
+		defaults["timeline-concurrency"] = strconv.Itoa(ic.TimelineConcurrency)
+	}
+	if ic.NoReviews {
+		defaults["no-reviews"] = "true"
+	}
+	if ic.SplitByRepo {
+		defaults["split-by-repo"] = "true"
+	}
+	if ic.BOM {
+		defaults["bom"] = "true"
+	}
+	if ic.SkipUnchanged {
+		defaults["skip-unchanged"] = "true"
+	}
+	if ic.DescriptionMinLength > 0 {
+		defaults["description-min-length"] = strconv.Itoa(ic.DescriptionMinLength)
+	}
+	for name, v := range defaults {
+		if given[name] {
+			continue
+		}
+		if err := fs.Set(name, v); err != nil {
+			return fmt.Errorf("import: config value for -%s: %w", name, err)
+		}
+	}
+	return nil
+}
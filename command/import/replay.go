@@ -0,0 +1,132 @@
+package cmdimport
+
+import (
+	"context"
+	"cto-stats/connectors/config"
+	ccsv "cto-stats/connectors/csv"
+	cg "cto-stats/connectors/github"
+	gh "cto-stats/domain/github"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Replay rebuilds the issue and PR files of import into outDir from the raw pages saved by import -snapshot in
+// snapshotDir, without calling GitHub: the pages go through the same decoding and report building as a live
+// import. Repositories come from the saved repository list, or else from the sub-directories of snapshotDir, and
+// project names from the saved project lists.
+// Issues whose timeline was not saved keep no status history, like a failed timeline fetch. The org, size
+// weights and description threshold come from cfg, as for an import without flags.
+func Replay(ctx context.Context, snapshotDir, outDir string, cfg *config.Config) error {
+	org := cfg.GitHub.Org
+	descriptionMinLength := cfg.Import.DescriptionMinLength
+	if descriptionMinLength <= 0 {
+		descriptionMinLength = defaultDescriptionMinLength
+	}
+	reportOpts := newReportOptions(cfg, descriptionMinLength)
+	ghc := cg.New(nil, "", cg.WithTransport(cg.Replay(snapshotDir)))
+	repos, err := ghc.ListAllRepos(ctx, org)
+	if err != nil && !cg.IsNotFound(err) {
+		return err
+	}
+	if len(repos) == 0 {
+		if repos, err = snapshotRepos(snapshotDir, org); err != nil {
+			return err
+		}
+	}
+
+	// Snapshots taken before the projects were saved have none: their project_name is left blank
+	projectNames, err := ghc.ListOrgProjects(ctx, org)
+	if err != nil {
+		slog.Warn("replay.projects.missing", "org", org, "error", err)
+		projectNames = map[string]string{}
+	}
+
+	var (
+		reports []IssueReport
+		allPRs  []gh.PullRequest
+		reviews []gh.PullRequestReview
+	)
+	for _, r := range repos {
+		if _, err := os.Stat(filepath.Join(snapshotDir, r.Name)); err != nil {
+			continue
+		}
+		if repoProjects, err := ghc.ListRepoProjects(ctx, r.Owner.Login, r.Name); err == nil {
+			for id, name := range repoProjects {
+				projectNames[id] = name
+			}
+		}
+		issues, _, err := ghc.ListAllIssues(ctx, r.Owner.Login, r.Name, "", nil, "")
+		if err != nil && !cg.IsNotFound(err) {
+			return err
+		}
+		for _, is := range issues {
+			report := newIssueReport(org, r.Name, is, reportOpts)
+			if evts, err := ghc.ListAllTimeline(ctx, r.Owner.Login, r.Name, is.Number); err == nil {
+				applyTimeline(&report, is, evts, reportOpts)
+			} else {
+				slog.Warn("replay.timeline.missing", "repo", r.Name, "issue", is.Number, "error", err)
+			}
+			reports = append(reports, report)
+		}
+
+		prs, _, err := ghc.ListAllPullRequests(ctx, r.Owner.Login, r.Name, "", "")
+		if err != nil && !cg.IsNotFound(err) {
+			return err
+		}
+		for i := range prs {
+			prs[i].Org = org
+			prs[i].Repo = r.Name
+			rvs, err := ghc.ListAllPullRequestReviews(ctx, r.Owner.Login, r.Name, prs[i].Number)
+			if err != nil {
+				// PRs imported with -no-reviews have no saved reviews
+				continue
+			}
+			for j := range rvs {
+				rvs[j].Org = org
+				rvs[j].Repo = r.Name
+				rvs[j].PullRequestNumber = prs[i].Number
+			}
+			reviews = append(reviews, rvs...)
+		}
+		allPRs = append(allPRs, prs...)
+	}
+
+	if err := ccsv.WriteAllCSVsIn(outDir, org, repos, reports, projectNames, false); err != nil {
+		return err
+	}
+	if err := ccsv.WritePullRequests(filepath.Join(outDir, "pr.csv"), allPRs, false); err != nil {
+		return err
+	}
+	if err := ccsv.WritePullRequestReviews(filepath.Join(outDir, "pr_review.csv"), reviews, false); err != nil {
+		return err
+	}
+	if err := ccsv.WritePullRequestIssueLinks(filepath.Join(outDir, "pr_issue_link.csv"), allPRs, false); err != nil {
+		return err
+	}
+	slog.Info("replay.done", "repos", len(repos), "issues", len(reports), "prs", len(allPRs), "reviews", len(reviews))
+	return nil
+}
+
+// snapshotRepos lists the repositories saved in snapshotDir, one per sub-directory.
+func snapshotRepos(snapshotDir, org string) ([]Repo, error) {
+	entries, err := os.ReadDir(snapshotDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("replay: no snapshots in %s (run import -snapshot first)", snapshotDir)
+		}
+		return nil, err
+	}
+	var repos []Repo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		r := Repo{Name: e.Name()}
+		r.Owner.Login = org
+		repos = append(repos, r)
+	}
+	return repos, nil
+}
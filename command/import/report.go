@@ -0,0 +1,330 @@
+package cmdimport
+
+import (
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"cto-stats/connectors/config"
+	gh "cto-stats/domain/github"
+)
+
+// defaultDescriptionMinLength is the description length above which an issue counts as described.
+const defaultDescriptionMinLength = 80
+
+// reportOptions are the settings used to derive the fields of an issue report.
+type reportOptions struct {
+	// descriptionMinLength is the body length above which an issue has a description
+	descriptionMinLength int
+	// sizeWeights maps lower-cased size labels to their weight (github.size_weights)
+	sizeWeights map[string]float64
+	// bugLabels holds the lower-cased labels making an issue a bug (github.bug_labels, default bug)
+	bugLabels map[string]bool
+	// severityLabels are the severity labels (github.severity_labels), most severe first
+	severityLabels []string
+	// epicLabelPrefix is the prefix of the epic labels (github.epic_label_prefix), "" without epics
+	epicLabelPrefix string
+}
+
+// newReportOptions returns the report options of cfg (which may be nil) with the given description threshold.
+func newReportOptions(cfg *config.Config, descriptionMinLength int) reportOptions {
+	opts := reportOptions{descriptionMinLength: descriptionMinLength, sizeWeights: map[string]float64{}, bugLabels: map[string]bool{}}
+	if cfg != nil {
+		for label, w := range cfg.GitHub.SizeWeights {
+			opts.sizeWeights[strings.ToLower(strings.TrimSpace(label))] = w
+		}
+		for _, label := range cfg.GitHub.BugLabels {
+			opts.bugLabels[strings.ToLower(strings.TrimSpace(label))] = true
+		}
+		opts.epicLabelPrefix = strings.TrimSpace(cfg.GitHub.EpicLabelPrefix)
+		for _, label := range cfg.GitHub.SeverityLabels {
+			if label = strings.TrimSpace(label); label != "" {
+				opts.severityLabels = append(opts.severityLabels, label)
+			}
+		}
+	}
+	if len(opts.bugLabels) == 0 {
+		opts.bugLabels["bug"] = true
+	}
+	return opts
+}
+
+// newIssueReport builds the report of issue is of org/repo from the issue fields alone: type, bug flag (with a
+// bug period from creation, refined from the label history by applyTimeline), milestone, description length,
+// size weight and project custom fields. An issue has a description when its
+// body is longer than the description threshold of opts. Status and project histories come from applyTimeline.
+func newIssueReport(org, repo string, is Issue, opts reportOptions) IssueReport {
+	report := IssueReport{
+		Org:                 org,
+		Repo:                repo,
+		Number:              is.Number,
+		Title:               is.Title,
+		URL:                 is.HTMLURL,
+		State:               is.State,
+		Creator:             valueOrEmpty(is.User),
+		Assignees:           usersToLogins(is.Assignees),
+		CreatedAt:           is.CreatedAt,
+		ClosedAt:            is.ClosedAt,
+		BodyLength:          is.BodyLength,
+		HasDescription:      is.BodyLength > opts.descriptionMinLength,
+		SizeWeight:          1,
+		ProjectCustomFields: is.ProjectCustomFields,
+	}
+	if is.Milestone != nil {
+		report.Milestone = is.Milestone.Title
+		report.MilestoneDueOn = is.Milestone.DueOn
+	}
+	var current []string
+	for _, l := range is.Labels {
+		current = append(current, l.Name)
+	}
+	report.Severity = opts.severity(current)
+	if epics := opts.epics(current); len(epics) > 0 {
+		report.Epic, report.OtherEpics = epics[0], epics[1:]
+	}
+	sized := false
+	for _, l := range is.Labels {
+		if w, ok := opts.sizeWeights[strings.ToLower(strings.TrimSpace(l.Name))]; ok && (!sized || w > report.SizeWeight) {
+			report.SizeWeight, sized = w, true
+		}
+	}
+	// Prefer GitHub IssueType when available; fallback to label heuristics. Also set IsBug.
+	var typ string
+	if strings.TrimSpace(is.Type) != "" {
+		typ = strings.ToLower(strings.TrimSpace(is.Type))
+	}
+	if typ == "" {
+		for _, l := range is.Labels {
+			name := strings.ToLower(strings.TrimSpace(l.Name))
+			if opts.bugLabels[name] {
+				report.IsBug = true
+				if typ == "" {
+					typ = "bug"
+				}
+			} else if typ == "" { // only derive if not already known
+				if strings.Contains(name, "feature") {
+					typ = "feature"
+				} else if strings.Contains(name, "chore") || strings.Contains(name, "refactor") {
+					typ = "chore"
+				} else if strings.Contains(name, "doc") {
+					typ = "docs"
+				}
+			}
+		}
+
+		if typ == "" {
+			typ = "task"
+		}
+	}
+	report.Type = typ
+	if strings.EqualFold(typ, "bug") {
+		report.IsBug = true
+	}
+	if report.IsBug {
+		setBugPeriods(&report, []gh.BugPeriod{{Since: is.CreatedAt}})
+	}
+	return report
+}
+
+// severity returns the most severe of the severity labels of opts among labels, "" without any.
+func (opts reportOptions) severity(labels []string) string {
+	for _, sev := range opts.severityLabels {
+		for _, l := range labels {
+			if strings.EqualFold(strings.TrimSpace(l), sev) {
+				return sev
+			}
+		}
+	}
+	return ""
+}
+
+// epics returns the epic names of the epic labels of opts among labels (the label without its prefix), sorted
+// alphabetically ignoring case and without duplicates.
+func (opts reportOptions) epics(labels []string) []string {
+	if opts.epicLabelPrefix == "" {
+		return nil
+	}
+	var res []string
+	for _, l := range labels {
+		l = strings.TrimSpace(l)
+		if len(l) <= len(opts.epicLabelPrefix) || !strings.EqualFold(l[:len(opts.epicLabelPrefix)], opts.epicLabelPrefix) {
+			continue
+		}
+		if name := strings.TrimSpace(l[len(opts.epicLabelPrefix):]); name != "" && !slices.Contains(res, name) {
+			res = append(res, name)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return strings.ToLower(res[i]) < strings.ToLower(res[j]) })
+	return res
+}
+
+// setBugPeriods sets the bug periods of report and its bug_since, the start of the first one.
+func setBugPeriods(report *IssueReport, periods []gh.BugPeriod) {
+	report.BugPeriods = periods
+	report.BugSince = nil
+	if len(periods) > 0 {
+		since := periods[0].Since
+		report.BugSince = &since
+	}
+}
+
+// labelBugPeriods returns the periods during which the issue carried at least one of bugLabels, from the
+// labeled and unlabeled events of evts. Removing a label the history never saw added does not end a period.
+func labelBugPeriods(evts []TimelineEvent, bugLabels map[string]bool) []gh.BugPeriod {
+	var labelEvts []TimelineEvent
+	for _, ev := range evts {
+		if (ev.Event == "labeled" || ev.Event == "unlabeled") && bugLabels[strings.ToLower(strings.TrimSpace(ev.Label))] {
+			labelEvts = append(labelEvts, ev)
+		}
+	}
+	sort.SliceStable(labelEvts, func(i, j int) bool { return labelEvts[i].CreatedAt.Before(labelEvts[j].CreatedAt) })
+	var periods []gh.BugPeriod
+	present := map[string]bool{}
+	for _, ev := range labelEvts {
+		name := strings.ToLower(strings.TrimSpace(ev.Label))
+		was := len(present) > 0
+		if ev.Event == "labeled" {
+			present[name] = true
+		} else {
+			delete(present, name)
+		}
+		switch now := len(present) > 0; {
+		case now && !was:
+			periods = append(periods, gh.BugPeriod{Since: ev.CreatedAt})
+		case !now && was:
+			until := ev.CreatedAt
+			periods[len(periods)-1].Until = &until
+		}
+	}
+	return periods
+}
+
+// labelSeverityChanges returns the changes of the severity of the issue (see reportOptions.severity), from the
+// labeled and unlabeled events of evts on the severity labels of opts. None when the history has no such event.
+func labelSeverityChanges(evts []TimelineEvent, opts reportOptions) []gh.SeverityChange {
+	var labelEvts []TimelineEvent
+	for _, ev := range evts {
+		if (ev.Event == "labeled" || ev.Event == "unlabeled") && opts.severity([]string{ev.Label}) != "" {
+			labelEvts = append(labelEvts, ev)
+		}
+	}
+	sort.SliceStable(labelEvts, func(i, j int) bool { return labelEvts[i].CreatedAt.Before(labelEvts[j].CreatedAt) })
+	var changes []gh.SeverityChange
+	var present []string
+	current := ""
+	for _, ev := range labelEvts {
+		name := strings.ToLower(strings.TrimSpace(ev.Label))
+		present = slices.DeleteFunc(present, func(l string) bool { return l == name })
+		if ev.Event == "labeled" {
+			present = append(present, name)
+		}
+		if sev := opts.severity(present); sev != current {
+			changes = append(changes, gh.SeverityChange{At: ev.CreatedAt, Severity: sev})
+			current = sev
+		}
+	}
+	return changes
+}
+
+// applyTimeline fills the status history, project moves, current project columns and committer of report from
+// the timeline events of is. For issues without a GitHub issue type, the bug periods come from the history of
+// the bug labels of opts; a bug whose history holds none keeps the period from creation set by newIssueReport.
+// The severity changes come from the history of the severity labels of opts.
+func applyTimeline(report *IssueReport, is Issue, evts []TimelineEvent, opts reportOptions) {
+	if strings.TrimSpace(is.Type) == "" {
+		if periods := labelBugPeriods(evts, opts.bugLabels); len(periods) > 0 {
+			setBugPeriods(report, periods)
+		}
+	}
+	report.SeverityChanges = labelSeverityChanges(evts, opts)
+
+	statusHist := make([]StatusEvent, 0, 4)
+	projHist := make([]ProjectMoveEvent, 0, 8)
+	// seed opened
+	statusHist = append(statusHist, StatusEvent{Type: "opened", At: is.CreatedAt, By: valueOrEmpty(is.User)})
+	// Track current per project
+	type current struct {
+		present     bool
+		projectID   string
+		projectName string
+		columnID    int64
+		columnName  string
+	}
+	currentByProject := map[string]*current{}
+
+	for _, ev := range evts {
+		slog.Debug(ev.Event)
+		switch ev.Event {
+		case "closed":
+			statusHist = append(statusHist, StatusEvent{Type: "closed", At: ev.CreatedAt, By: valueOrEmpty(ev.Actor)})
+			// set committer as the actor who closed
+			if report.Committer == "" && ev.Actor != nil {
+				report.Committer = ev.Actor.Login
+			}
+		case "reopened":
+			statusHist = append(statusHist, StatusEvent{Type: "reopened", At: ev.CreatedAt, By: valueOrEmpty(ev.Actor)})
+		case "added_to_project_v2":
+			var projID string
+			var projName string
+			if ev.Project != nil {
+				projID = ev.Project.ID
+				projName = ev.Project.Name
+			}
+			if projID != "" {
+				projHist = append(projHist, ProjectMoveEvent{ProjectID: projID, ProjectName: projName, FromColumn: "", At: ev.CreatedAt, By: valueOrEmpty(ev.Actor), Type: "added"})
+				c := &current{present: true, projectID: projID, projectName: projName}
+				currentByProject[projID] = c
+			}
+		case "project_v2_item_status_changed":
+			var projID string
+			var projName string
+			var colNameTo = ev.ProjectColumnName
+			var colNameFrom = ev.PreviousProjectColumnName
+			// Prefer GraphQL-provided project info
+			if ev.Project != nil {
+				projID = ev.Project.ID
+				projName = ev.Project.Name
+			}
+			if projID != "" {
+				projHist = append(projHist, ProjectMoveEvent{ProjectID: projID, ProjectName: projName, FromColumn: colNameFrom, ToColumn: colNameTo, At: ev.CreatedAt, By: valueOrEmpty(ev.Actor), Type: "moved"})
+				c := currentByProject[projID]
+				if c == nil {
+					c = &current{present: true, projectID: projID, projectName: projName}
+					currentByProject[projID] = c
+				}
+				c.present = true
+				c.projectName = projName
+				c.columnName = colNameTo
+			}
+		case "removed_from_project_v2":
+			var projID string
+			var projName string
+			if ev.Project != nil {
+				projID = ev.Project.ID
+				projName = ev.Project.Name
+			}
+			if projID != "" {
+				projHist = append(projHist, ProjectMoveEvent{ProjectID: projID, ProjectName: projName, FromColumn: "", ToColumn: "", At: ev.CreatedAt, By: valueOrEmpty(ev.Actor), Type: "removed"})
+				c := currentByProject[projID]
+				if c == nil {
+					c = &current{projectID: projID, projectName: projName}
+					currentByProject[projID] = c
+				}
+				c.present = false
+			}
+		}
+	}
+
+	report.StatusHistory = statusHist
+	report.ProjectHistory = projHist
+	for pid, cur := range currentByProject {
+		if cur.present {
+			report.CurrentProjects = append(report.CurrentProjects, CurrentProject{ProjectID: pid, ProjectName: cur.projectName, ColumnID: cur.columnID, ColumnName: cur.columnName})
+		}
+	}
+	// by project, so that imports of the same issues write the same rows
+	sort.Slice(report.CurrentProjects, func(i, j int) bool {
+		return report.CurrentProjects[i].ProjectID < report.CurrentProjects[j].ProjectID
+	})
+}
@@ -0,0 +1,139 @@
+package cmdimport
+
+import (
+	"testing"
+	"time"
+
+	"cto-stats/connectors/config"
+	gh "cto-stats/domain/github"
+)
+
+// march returns 9:00 UTC on day of March 2025.
+func march(day int) time.Time {
+	return time.Date(2025, 3, day, 9, 0, 0, 0, time.UTC)
+}
+
+// labelEvent returns a labeled or unlabeled event of label on day of March 2025.
+func labelEvent(event, label string, day int) TimelineEvent {
+	return TimelineEvent{Event: event, Label: label, CreatedAt: march(day)}
+}
+
+func TestLabelBugPeriods(t *testing.T) {
+	var cfg config.Config
+	cfg.GitHub.BugLabels = []string{"bug", "Defect"}
+	opts := newReportOptions(&cfg, 0)
+	tests := []struct {
+		name string
+		evts []TimelineEvent
+		want [][2]int // since and until days of March, 0 for a period still open
+	}{
+		{"never labeled", []TimelineEvent{labelEvent("labeled", "feature", 2)}, nil},
+		{"added", []TimelineEvent{labelEvent("labeled", "bug", 2)}, [][2]int{{2, 0}}},
+		{"added then removed", []TimelineEvent{
+			labelEvent("labeled", "bug", 2),
+			labelEvent("unlabeled", "bug", 5),
+		}, [][2]int{{2, 5}}},
+		{"added, removed and re-added", []TimelineEvent{
+			labelEvent("labeled", "bug", 2),
+			labelEvent("unlabeled", "bug", 5),
+			labelEvent("labeled", "Bug", 9),
+		}, [][2]int{{2, 5}, {9, 0}}},
+		{"events out of order", []TimelineEvent{
+			labelEvent("unlabeled", "bug", 5),
+			labelEvent("labeled", "bug", 2),
+		}, [][2]int{{2, 5}}},
+		{"two bug labels overlapping", []TimelineEvent{
+			labelEvent("labeled", "bug", 2),
+			labelEvent("labeled", "defect", 3),
+			labelEvent("unlabeled", "bug", 4),
+			labelEvent("unlabeled", "defect", 6),
+		}, [][2]int{{2, 6}}},
+		{"removed without being added", []TimelineEvent{
+			labelEvent("unlabeled", "bug", 1),
+			labelEvent("labeled", "bug", 2),
+		}, [][2]int{{2, 0}}},
+		{"other labels do not end a period", []TimelineEvent{
+			labelEvent("labeled", "bug", 2),
+			labelEvent("unlabeled", "feature", 3),
+		}, [][2]int{{2, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := labelBugPeriods(tt.evts, opts.bugLabels)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d periods %+v, want %v", len(got), got, tt.want)
+			}
+			for i, w := range tt.want {
+				if got[i].Since.Day() != w[0] {
+					t.Errorf("period %d since %v, want day %d", i, got[i].Since, w[0])
+				}
+				switch {
+				case w[1] == 0 && got[i].Until != nil:
+					t.Errorf("period %d until %v, want still open", i, *got[i].Until)
+				case w[1] != 0 && (got[i].Until == nil || got[i].Until.Day() != w[1]):
+					t.Errorf("period %d until %v, want day %d", i, got[i].Until, w[1])
+				}
+			}
+		})
+	}
+}
+
+func TestLabelSeverityChanges(t *testing.T) {
+	var cfg config.Config
+	cfg.GitHub.SeverityLabels = []string{"sev1", "sev2"}
+	opts := newReportOptions(&cfg, 0)
+	tests := []struct {
+		name string
+		evts []TimelineEvent
+		want []gh.SeverityChange
+	}{
+		{"no severity label", []TimelineEvent{labelEvent("labeled", "bug", 2)}, nil},
+		{"added", []TimelineEvent{labelEvent("labeled", "sev2", 2)}, []gh.SeverityChange{
+			{At: march(2), Severity: "sev2"},
+		}},
+		{"added then removed", []TimelineEvent{
+			labelEvent("labeled", "sev2", 2),
+			labelEvent("unlabeled", "sev2", 5),
+		}, []gh.SeverityChange{
+			{At: march(2), Severity: "sev2"},
+			{At: march(5), Severity: ""},
+		}},
+		{"added, removed and re-added", []TimelineEvent{
+			labelEvent("labeled", "sev2", 2),
+			labelEvent("unlabeled", "SEV2", 5),
+			labelEvent("labeled", "sev2", 9),
+		}, []gh.SeverityChange{
+			{At: march(2), Severity: "sev2"},
+			{At: march(5), Severity: ""},
+			{At: march(9), Severity: "sev2"},
+		}},
+		{"escalated while the lower label stays", []TimelineEvent{
+			labelEvent("labeled", "sev2", 2),
+			labelEvent("labeled", "sev1", 3),
+			labelEvent("unlabeled", "sev1", 6),
+		}, []gh.SeverityChange{
+			{At: march(2), Severity: "sev2"},
+			{At: march(3), Severity: "sev1"},
+			{At: march(6), Severity: "sev2"},
+		}},
+		{"lower label added under a higher one", []TimelineEvent{
+			labelEvent("labeled", "sev1", 2),
+			labelEvent("labeled", "sev2", 3),
+		}, []gh.SeverityChange{
+			{At: march(2), Severity: "sev1"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := labelSeverityChanges(tt.evts, opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if !got[i].At.Equal(tt.want[i].At) || got[i].Severity != tt.want[i].Severity {
+					t.Errorf("change %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package cmdimport
+
+import (
+	ccsv "cto-stats/connectors/csv"
+	gh "cto-stats/domain/github"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// priorTimeline is what the timeline of a closed issue gave a previous import, read back from its CSV files.
+type priorTimeline struct {
+	closedAt   time.Time
+	committer  string
+	bugPeriods []gh.BugPeriod
+	severities []gh.SeverityChange
+	status     []StatusEvent
+	projects   []ProjectMoveEvent
+	current    []CurrentProject
+}
+
+// canReuse reports whether the timeline of is, fetched again by an import of the issues updated since since, can
+// be taken from p instead: is is still closed, at the same time as before (not reopened and closed again since),
+// and that was before since.
+func (p *priorTimeline) canReuse(is Issue, since time.Time) bool {
+	return p != nil && strings.EqualFold(is.State, "closed") && is.ClosedAt != nil &&
+		is.ClosedAt.Equal(p.closedAt) && p.closedAt.Before(since)
+}
+
+// apply fills report with the timeline fields of p, as applyTimeline would from the events.
+func (p *priorTimeline) apply(report *IssueReport, is Issue) {
+	if strings.TrimSpace(is.Type) == "" && len(p.bugPeriods) > 0 {
+		setBugPeriods(report, p.bugPeriods)
+	}
+	report.Committer = p.committer
+	report.SeverityChanges = p.severities
+	report.StatusHistory = p.status
+	report.ProjectHistory = p.projects
+	report.CurrentProjects = p.current
+}
+
+// readPriorTimelines reads the closed issues of the issue.csv of dir, with their rows of issue_status_event.csv,
+// issue_project_event.csv and issue_current_project.csv, keyed by org/repo#number. A directory without
+// issue.csv (first import) yields none.
+func readPriorTimelines(dir string) (map[string]*priorTimeline, error) {
+	prior := map[string]*priorTimeline{}
+	err := readCSVRows(filepath.Join(dir, "issue.csv"), func(get func(string) string) error {
+		if !strings.EqualFold(get("state"), "closed") {
+			return nil
+		}
+		closedAt, err := time.Parse(time.RFC3339, get("closed_at"))
+		if err != nil {
+			return nil
+		}
+		p := &priorTimeline{closedAt: closedAt, committer: get("committer")}
+		for _, s := range strings.Split(get("bug_periods"), ";") {
+			since, until, _ := strings.Cut(s, "/")
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				continue
+			}
+			bp := gh.BugPeriod{Since: t}
+			if u, err := time.Parse(time.RFC3339, until); err == nil {
+				bp.Until = &u
+			}
+			p.bugPeriods = append(p.bugPeriods, bp)
+		}
+		for _, s := range strings.Split(get("severity_changes"), ";") {
+			at, sev, _ := strings.Cut(s, "/")
+			if t, err := time.Parse(time.RFC3339, at); err == nil {
+				p.severities = append(p.severities, gh.SeverityChange{At: t, Severity: sev})
+			}
+		}
+		prior[issueKey(get)] = p
+		return nil
+	})
+	if err != nil {
+		return noPriorIfMissing(err)
+	}
+	if len(prior) == 0 {
+		return prior, nil
+	}
+	// An issue.csv without its event files cannot give the timelines back: reuse none
+	err = readCSVRows(filepath.Join(dir, "issue_status_event.csv"), func(get func(string) string) error {
+		p := prior[issueKey(get)]
+		if p == nil {
+			return nil
+		}
+		at, err := time.Parse(time.RFC3339, get("at"))
+		if err != nil {
+			return fmt.Errorf("issue_status_event.csv: %s: bad at %q", issueKey(get), get("at"))
+		}
+		p.status = append(p.status, StatusEvent{Type: get("type"), At: at, By: get("by")})
+		return nil
+	})
+	if err != nil {
+		return noPriorIfMissing(err)
+	}
+	err = readCSVRows(filepath.Join(dir, "issue_project_event.csv"), func(get func(string) string) error {
+		p := prior[issueKey(get)]
+		if p == nil {
+			return nil
+		}
+		at, err := time.Parse(time.RFC3339, get("at"))
+		if err != nil {
+			return fmt.Errorf("issue_project_event.csv: %s: bad at %q", issueKey(get), get("at"))
+		}
+		p.projects = append(p.projects, ProjectMoveEvent{
+			ProjectID:   get("project_id"),
+			ProjectName: get("project_name"),
+			FromColumn:  get("from_column"),
+			ToColumn:    get("to_column"),
+			At:          at,
+			By:          get("by"),
+			Type:        get("type"),
+		})
+		return nil
+	})
+	if err != nil {
+		return noPriorIfMissing(err)
+	}
+	// older imports wrote no issue_current_project.csv: their issues keep no current column, as before
+	err = readCSVRows(filepath.Join(dir, "issue_current_project.csv"), func(get func(string) string) error {
+		if p := prior[issueKey(get)]; p != nil {
+			p.current = append(p.current, CurrentProject{ProjectID: get("project_id"), ProjectName: get("project_name"), ColumnName: get("column_name")})
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return prior, nil
+}
+
+// noPriorIfMissing returns no prior timelines for a missing file, err otherwise.
+func noPriorIfMissing(err error) (map[string]*priorTimeline, error) {
+	if os.IsNotExist(err) {
+		return map[string]*priorTimeline{}, nil
+	}
+	return nil, err
+}
+
+// issueKey returns the org/repo#number key of the row read by get.
+func issueKey(get func(string) string) string {
+	return fmt.Sprintf("%s/%s#%s", get("org"), get("repo"), get("number"))
+}
+
+// priorKey returns the key of readPriorTimelines for issue number of org/repo.
+func priorKey(org, repo string, number int) string {
+	return fmt.Sprintf("%s/%s#%d", org, repo, number)
+}
+
+// readCSVRows calls fn for each row of the CSV file at path, with a getter of its columns by header name (""
+// for a column the file lacks).
+func readCSVRows(path string, fn func(get func(string) string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	idx := map[string]int{}
+	for i, h := range header {
+		idx[strings.ToLower(strings.TrimSpace(ccsv.TrimBOM(h)))] = i
+	}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		get := func(col string) string {
+			i, ok := idx[col]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return row[i]
+		}
+		if err := fn(get); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Chart sizes in pixels. Charts are inline SVG scaled to the width of their container.
+const (
+	chartWidth  = 640
+	chartHeight = 200
+	chartMargin = 24
+)
+
+// barChart renders one vertical bar per value with its label below and its value above. Highlighted bars, e.g.
+// the weeks of the reported month, are drawn in the accent color.
+func barChart(labels []string, values []float64, highlight []bool) template.HTML {
+	var b strings.Builder
+	openSVG(&b, chartHeight)
+	top := maxOf(values)
+	plotH := float64(chartHeight - 2*chartMargin)
+	slot := float64(chartWidth-2*chartMargin) / float64(max(len(values), 1))
+	for i, v := range values {
+		h := 0.0
+		if top > 0 {
+			h = v / top * plotH
+		}
+		x := float64(chartMargin) + float64(i)*slot + slot*0.15
+		y := float64(chartHeight-chartMargin) - h
+		class := "bar"
+		if i < len(highlight) && highlight[i] {
+			class = "bar accent"
+		}
+		fmt.Fprintf(&b, `<rect class="%s" x="%.1f" y="%.1f" width="%.1f" height="%.1f"/>`, class, x, y, slot*0.7, h)
+		fmt.Fprintf(&b, `<text class="value" x="%.1f" y="%.1f" text-anchor="middle">%s</text>`, x+slot*0.35, y-4, number(v))
+		fmt.Fprintf(&b, `<text class="label" x="%.1f" y="%d" text-anchor="middle">%s</text>`, x+slot*0.35, chartHeight-chartMargin+14, template.HTMLEscapeString(labels[i]))
+	}
+	fmt.Fprintf(&b, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%d"/>`, chartMargin, chartHeight-chartMargin, chartWidth-chartMargin, chartHeight-chartMargin)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// lineChart renders the values as a line with a dot, a value and a label per point. Nil values, e.g. months
+// without closed issues, leave a gap.
+func lineChart(labels []string, values []*float64) template.HTML {
+	var b strings.Builder
+	openSVG(&b, chartHeight)
+	var present []float64
+	for _, v := range values {
+		if v != nil {
+			present = append(present, *v)
+		}
+	}
+	top := maxOf(present)
+	plotH := float64(chartHeight - 2*chartMargin - 12)
+	slot := float64(chartWidth-2*chartMargin) / float64(max(len(values), 1))
+	var path []string
+	for i, v := range values {
+		x := float64(chartMargin) + slot*(float64(i)+0.5)
+		fmt.Fprintf(&b, `<text class="label" x="%.1f" y="%d" text-anchor="middle">%s</text>`, x, chartHeight-chartMargin+14, template.HTMLEscapeString(labels[i]))
+		if v == nil {
+			if len(path) > 1 {
+				fmt.Fprintf(&b, `<polyline class="line" points="%s"/>`, strings.Join(path, " "))
+			}
+			path = nil
+			continue
+		}
+		y := float64(chartHeight - chartMargin)
+		if top > 0 {
+			y -= *v / top * plotH
+		}
+		path = append(path, fmt.Sprintf("%.1f,%.1f", x, y))
+		fmt.Fprintf(&b, `<circle class="dot" cx="%.1f" cy="%.1f" r="3"/>`, x, y)
+		fmt.Fprintf(&b, `<text class="value" x="%.1f" y="%.1f" text-anchor="middle">%s</text>`, x, y-8, number(*v))
+	}
+	if len(path) > 1 {
+		fmt.Fprintf(&b, `<polyline class="line" points="%s"/>`, strings.Join(path, " "))
+	}
+	fmt.Fprintf(&b, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%d"/>`, chartMargin, chartHeight-chartMargin, chartWidth-chartMargin, chartHeight-chartMargin)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// hbarChart renders one horizontal bar per value, its label on the left and its value on the right.
+func hbarChart(labels []string, values []float64) template.HTML {
+	const rowH, labelW = 22, 150
+	var b strings.Builder
+	height := len(values)*rowH + 8
+	openSVG(&b, height)
+	top := maxOf(values)
+	plotW := float64(chartWidth - labelW - 2*chartMargin)
+	for i, v := range values {
+		y := 4 + i*rowH
+		w := 0.0
+		if top > 0 {
+			w = v / top * plotW
+		}
+		fmt.Fprintf(&b, `<text class="label" x="%d" y="%d" text-anchor="end">%s</text>`, labelW-6, y+15, template.HTMLEscapeString(labels[i]))
+		fmt.Fprintf(&b, `<rect class="bar" x="%d" y="%d" width="%.1f" height="%d"/>`, labelW, y+3, w, rowH-6)
+		fmt.Fprintf(&b, `<text class="value" x="%.1f" y="%d">%s</text>`, float64(labelW)+w+6, y+15, number(v))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func openSVG(b *strings.Builder, height int) {
+	fmt.Fprintf(b, `<svg class="chart" xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%" role="img">`, chartWidth, height)
+}
+
+func maxOf(values []float64) float64 {
+	m := 0.0
+	for _, v := range values {
+		m = math.Max(m, v)
+	}
+	return m
+}
+
+// number formats v with at most 2 decimals, without trailing zeros.
+func number(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,679 @@
+// Package report renders the monthly report: one self-contained HTML page built from the calculate outputs.
+package report
+
+import (
+	"bytes"
+	"cmp"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cto-stats/command/cli"
+	"cto-stats/connectors/config"
+	"cto-stats/domain/buildinfo"
+)
+
+// defaultTemplate is the page rendered unless -template gives another html/template file, which gets the same
+// page value.
+//
+//go:embed template.html
+var defaultTemplate string
+
+// Periods the charts cover, ending with the reported month.
+const (
+	throughputWeeks = 13
+	cycleTimeMonths = 6
+	topCostMovers   = 5
+)
+
+// Help describes the report subcommand for its -h output, the top-level usage and the completion scripts.
+var Help = cli.Command{
+	Name:     "report",
+	Summary:  "render a monthly HTML report from the calculate outputs",
+	Synopsis: "[-month YYYY-MM] [-data <dir>] [-out <file>] [-template <file>]",
+	Description: `Reads the outputs of calculate and writes one HTML file for a month: weekly throughput, lead and cycle
+times, bug ratio, stocks and the top cloud cost movers, each against the previous month, with a few
+highlights. Styles and SVG charts are inline, without scripts, so the file can be mailed or attached as is.`,
+	Examples: []string{
+		"cto-stats report",
+		"cto-stats report -month 2025-09 -out september.html",
+		"cto-stats report -month 2025-09 -template my-report.html",
+	},
+	Env: []string{
+		"CONFIG_PATH  config file, for the timezone of the bug ratio months (default ./config.yml)",
+	},
+}
+
+// page is what the template renders. Numbers are formatted already; deltas compare with the previous month.
+// Sections whose calculate output is missing have Available false.
+type page struct {
+	Month         string // YYYY-MM
+	Title         string // e.g. September 2025
+	PreviousTitle string
+	GeneratedAt   string
+	Version       string
+	Highlights    []string
+	Throughput    throughputSection
+	CycleTime     cycleTimeSection
+	Bugs          bugSection
+	Stocks        stocksSection
+	Cloud         cloudSection
+}
+
+// delta is a change against the previous month. Class is good, bad or flat, from the direction the metric should
+// take; Text is empty when the previous month has no value.
+type delta struct {
+	Text  string
+	Class string
+}
+
+type throughputSection struct {
+	Available bool
+	Closed    string // issues closed in the month (cycle_time.csv, ALL)
+	Delta     delta
+	Chart     template.HTML // throughput of the last 13 ISO weeks, the weeks ending in the month highlighted
+}
+
+type cycleTimeSection struct {
+	Available bool
+	Unit      string // days or hours
+	Rows      []cycleTimeRow
+	Chart     template.HTML // ALL cycle time average of the last 6 months
+}
+
+type cycleTimeRow struct {
+	Org                                  string
+	Issues                               string
+	Lead, Cycle, TimeToPR                string
+	LeadDelta, CycleDelta, TimeToPRDelta delta
+}
+
+type bugSection struct {
+	Available bool
+	Closed    string // issues closed in the month
+	Bugs      string // bugs among them
+	Ratio     string // e.g. 12.5%
+	Delta     delta  // in percentage points
+}
+
+type stocksSection struct {
+	Available bool
+	Week      string // ISO week of the snapshot, the last one ending in the month, e.g. 2025-W39
+	Rows      []stockRow
+	Chart     template.HTML
+}
+
+type stockRow struct {
+	Stage string
+	Count string
+	Delta delta
+}
+
+type cloudSection struct {
+	Available bool
+	Movers    []costMover
+}
+
+type costMover struct {
+	Provider, Group, Currency string
+	Previous, Cost            string
+	Delta                     delta
+	Change                    string // relative change, e.g. +12.5%, or new
+}
+
+// Run executes the report subcommand: it renders the report of -month (default the previous calendar month)
+// from the calculate outputs of -data into -out.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	cli.SetUsage(fs, Help)
+	monthFlag := fs.String("month", "", "month to report, YYYY-MM (default: the previous calendar month)")
+	dataDir := fs.String("data", "data", "directory holding the calculate outputs")
+	outPath := fs.String("out", "", "HTML file to write (default report-<month>.html)")
+	tmplPath := fs.String("template", "", "html/template file to render instead of the embedded one")
+	if err := cli.Parse(fs, args); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	month := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+	if *monthFlag != "" {
+		t, err := time.Parse("2006-01", *monthFlag)
+		if err != nil {
+			return fmt.Errorf("report: -month %q is not YYYY-MM", *monthFlag)
+		}
+		month = t
+	}
+	if *outPath == "" {
+		*outPath = "report-" + month.Format("2006-01") + ".html"
+	}
+	tmpl, err := parseTemplate(*tmplPath)
+	if err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	p, err := build(*dataDir, month, location(), now)
+	if err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, p); err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	if dir := filepath.Dir(*outPath); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("report: %w", err)
+		}
+	}
+	if err := os.WriteFile(*outPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	slog.Info("report.done", "month", p.Month, "output", *outPath)
+	return nil
+}
+
+func parseTemplate(path string) (*template.Template, error) {
+	text := defaultTemplate
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text = string(b)
+	}
+	tmpl, err := template.New("report").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", cmp.Or(path, "(embedded)"), err)
+	}
+	return tmpl, nil
+}
+
+// location returns the timezone of the config, which calculate cut the months with, or UTC.
+func location() *time.Location {
+	path := cmp.Or(os.Getenv("CONFIG_PATH"), "./config.yml")
+	if _, err := os.Stat(path); err != nil {
+		return time.UTC
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// build reads the calculate outputs of dir for month. At least cycle_time.csv or throughput_week.csv is needed;
+// the other sections are left out when their file is missing.
+func build(dir string, month time.Time, loc *time.Location, now time.Time) (*page, error) {
+	cycle, err := readOptional(dir, "cycle_time.csv")
+	if err != nil {
+		return nil, err
+	}
+	weekly, err := readOptional(dir, "throughput_week.csv")
+	if err != nil {
+		return nil, err
+	}
+	if cycle == nil && weekly == nil {
+		return nil, fmt.Errorf("no cycle_time.csv nor throughput_week.csv in %s, run calculate first", dir)
+	}
+	issues, err := readOptional(dir, "calculated_issue.csv")
+	if err != nil {
+		return nil, err
+	}
+	stocks, err := readOptional(dir, "stocks_week.csv")
+	if err != nil {
+		return nil, err
+	}
+	costs, err := readOptional(dir, "cloud_spending_services.csv")
+	if err != nil {
+		return nil, err
+	}
+
+	prev := month.AddDate(0, -1, 0)
+	p := &page{
+		Month:         month.Format("2006-01"),
+		Title:         month.Format("January 2006"),
+		PreviousTitle: prev.Format("January 2006"),
+		GeneratedAt:   now.UTC().Format("2006-01-02 15:04 MST"),
+		Version:       buildinfo.Get().String(),
+	}
+	p.Throughput = throughputOf(cycle, weekly, month)
+	p.CycleTime = cycleTimeOf(cycle, month)
+	p.Bugs = bugsOf(issues, month, loc)
+	p.Stocks = stocksOf(stocks, month)
+	p.Cloud = cloudOf(costs, month)
+	p.Highlights = highlights(p, cycle, month)
+	return p, nil
+}
+
+func throughputOf(cycle, weekly []map[string]string, month time.Time) throughputSection {
+	s := throughputSection{Available: cycle != nil || weekly != nil}
+	if cycle != nil {
+		cur, prev := monthRow(cycle, month, "ALL"), monthRow(cycle, month.AddDate(0, -1, 0), "ALL")
+		if cur != nil {
+			s.Closed = cur["issues_count"]
+			s.Delta = deltaOf(cur["issues_count"], prev, "issues_count", higherIsBetter)
+		}
+	}
+	if weekly != nil {
+		byWeek := map[string]float64{}
+		for _, r := range weekly {
+			if r["org"] == "ALL" {
+				v, _ := strconv.ParseFloat(r["throughput"], 64)
+				byWeek[r["year"]+"-"+r["week"]] = v
+			}
+		}
+		end := month.AddDate(0, 1, -1)
+		lastSunday := end.AddDate(0, 0, -int(end.Weekday()))
+		var labels []string
+		var values []float64
+		var highlight []bool
+		for i := throughputWeeks - 1; i >= 0; i-- {
+			sunday := lastSunday.AddDate(0, 0, -7*i)
+			y, w := sunday.ISOWeek()
+			labels = append(labels, fmt.Sprintf("W%02d", w))
+			values = append(values, byWeek[fmt.Sprintf("%d-%d", y, w)])
+			highlight = append(highlight, !sunday.Before(month))
+		}
+		s.Chart = barChart(labels, values, highlight)
+	}
+	return s
+}
+
+func cycleTimeOf(cycle []map[string]string, month time.Time) cycleTimeSection {
+	s := cycleTimeSection{Available: cycle != nil, Unit: "days"}
+	prevMonth := month.AddDate(0, -1, 0)
+	for _, r := range cycle {
+		if r["month"] != month.Format("2006-01") {
+			continue
+		}
+		if r["unit"] != "" {
+			s.Unit = r["unit"]
+		}
+		prev := monthRow(cycle, prevMonth, r["org"])
+		// time_to_pr_source (config time_to_pr.source) selects the board or the linked PR definition
+		tprColumn := "time_to_pr"
+		if r["time_to_pr_source"] == "linked_pr" {
+			tprColumn = "time_to_pr_actual"
+		}
+		row := cycleTimeRow{Org: r["org"], Issues: r["issues_count"], Lead: "–", Cycle: "–", TimeToPR: cmp.Or(r[tprColumn], "–")}
+		if r["lead_count"] != "0" {
+			row.Lead = r["leadtime_days_avg"]
+			row.LeadDelta = deltaOf(r["leadtime_days_avg"], withCount(prev, "lead_count"), "leadtime_days_avg", lowerIsBetter)
+		}
+		if r["cycle_count"] != "0" {
+			row.Cycle = r["cycletime_days_avg"]
+			row.CycleDelta = deltaOf(r["cycletime_days_avg"], withCount(prev, "cycle_count"), "cycletime_days_avg", lowerIsBetter)
+		}
+		row.TimeToPRDelta = deltaOf(r[tprColumn], prev, tprColumn, lowerIsBetter)
+		s.Rows = append(s.Rows, row)
+	}
+	var labels []string
+	var values []*float64
+	for i := cycleTimeMonths - 1; i >= 0; i-- {
+		m := month.AddDate(0, -i, 0)
+		labels = append(labels, m.Format("Jan"))
+		var v *float64
+		if r := withCount(monthRow(cycle, m, "ALL"), "cycle_count"); r != nil {
+			if f, err := strconv.ParseFloat(r["cycletime_days_avg"], 64); err == nil {
+				v = &f
+			}
+		}
+		values = append(values, v)
+	}
+	if cycle != nil {
+		s.Chart = lineChart(labels, values)
+	}
+	return s
+}
+
+// bugsOf computes the share of bugs among the issues closed in month (in loc), from calculated_issue.csv, which
+// lists an issue once per project.
+func bugsOf(issues []map[string]string, month time.Time, loc *time.Location) bugSection {
+	if issues == nil {
+		return bugSection{}
+	}
+	type count struct{ closed, bugs int }
+	byMonth := map[string]*count{}
+	seen := map[string]bool{}
+	for _, r := range issues {
+		end, err := time.Parse(time.RFC3339, r["enddatetime"])
+		if err != nil || seen[r["id"]] {
+			continue
+		}
+		seen[r["id"]] = true
+		m := end.In(loc).Format("2006-01")
+		if byMonth[m] == nil {
+			byMonth[m] = &count{}
+		}
+		byMonth[m].closed++
+		if r["bug"] == "true" {
+			byMonth[m].bugs++
+		}
+	}
+	ratio := func(c *count) (float64, bool) {
+		if c == nil || c.closed == 0 {
+			return 0, false
+		}
+		return float64(c.bugs) / float64(c.closed) * 100, true
+	}
+	s := bugSection{Available: true, Closed: "0", Bugs: "0"}
+	cur := byMonth[month.Format("2006-01")]
+	r, ok := ratio(cur)
+	if !ok {
+		return s
+	}
+	s.Closed, s.Bugs = strconv.Itoa(cur.closed), strconv.Itoa(cur.bugs)
+	s.Ratio = fmt.Sprintf("%.1f%%", r)
+	if pr, ok := ratio(byMonth[month.AddDate(0, -1, 0).Format("2006-01")]); ok {
+		s.Delta = newDelta(r-pr, 1, " pts", lowerIsBetter)
+	}
+	return s
+}
+
+// stockStages are the stocks_week.csv columns of the snapshot, in pull order.
+var stockStages = []struct{ column, label string }{
+	{"opened_bugs", "Red bin (bugs)"},
+	{"waiting_to_prod", "Waiting to production"},
+	{"in_qa", "In QA"},
+	{"in_review", "In review"},
+	{"in_dev", "In development"},
+	{"in_ready", "Ready"},
+	{"in_backlogs", "Backlog"},
+}
+
+// stocksOf sums the stocks of every org and project at the end of the last ISO week of stocks_week.csv ending in
+// month, against the same snapshot of the previous month.
+func stocksOf(stocks []map[string]string, month time.Time) stocksSection {
+	snapshot := func(m time.Time) (string, map[string]float64) {
+		end := m.AddDate(0, 1, -1)
+		var year, week string
+		var last time.Time
+		for _, r := range stocks {
+			y, err1 := strconv.Atoi(r["year"])
+			w, err2 := strconv.Atoi(r["week"])
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			if sunday := isoWeekEnd(y, w); !sunday.Before(m) && !sunday.After(end) && sunday.After(last) {
+				year, week, last = r["year"], r["week"], sunday
+			}
+		}
+		if last.IsZero() {
+			return "", nil
+		}
+		sums := map[string]float64{}
+		for _, r := range stocks {
+			if r["year"] != year || r["week"] != week {
+				continue
+			}
+			for _, st := range stockStages {
+				v, _ := strconv.ParseFloat(r[st.column], 64)
+				sums[st.column] += v
+			}
+		}
+		y, w := last.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w), sums
+	}
+	week, cur := snapshot(month)
+	if cur == nil {
+		return stocksSection{}
+	}
+	_, prev := snapshot(month.AddDate(0, -1, 0))
+	s := stocksSection{Available: true, Week: week}
+	var labels []string
+	var values []float64
+	for _, st := range stockStages {
+		row := stockRow{Stage: st.label, Count: number(cur[st.column])}
+		if prev != nil {
+			row.Delta = newDelta(cur[st.column]-prev[st.column], 0, "", neutral)
+		}
+		s.Rows = append(s.Rows, row)
+		labels = append(labels, st.label)
+		values = append(values, cur[st.column])
+	}
+	s.Chart = hbarChart(labels, values)
+	return s
+}
+
+// cloudOf lists the service groups whose cost changed the most from the previous month, in absolute value.
+func cloudOf(costs []map[string]string, month time.Time) cloudSection {
+	if costs == nil {
+		return cloudSection{}
+	}
+	type key struct{ provider, group, currency string }
+	cur, prev := map[key]float64{}, map[key]float64{}
+	m, pm := month.Format("2006-01"), month.AddDate(0, -1, 0).Format("2006-01")
+	for _, r := range costs {
+		k := key{r["provider"], r["group"], r["currency"]}
+		v, _ := strconv.ParseFloat(r["cost"], 64)
+		switch r["month"] {
+		case m:
+			cur[k] += v
+		case pm:
+			prev[k] += v
+		}
+	}
+	keys := map[key]bool{}
+	for k := range cur {
+		keys[k] = true
+	}
+	for k := range prev {
+		keys[k] = true
+	}
+	type move struct {
+		k     key
+		delta float64
+	}
+	var moves []move
+	for k := range keys {
+		if d := cur[k] - prev[k]; math.Abs(d) >= 0.005 {
+			moves = append(moves, move{k, d})
+		}
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		if a, b := math.Abs(moves[i].delta), math.Abs(moves[j].delta); a != b {
+			return a > b
+		}
+		return moves[i].k.provider+moves[i].k.group < moves[j].k.provider+moves[j].k.group
+	})
+	s := cloudSection{Available: len(cur) > 0}
+	for _, mv := range moves[:min(len(moves), topCostMovers)] {
+		c := costMover{
+			Provider: mv.k.provider, Group: mv.k.group, Currency: mv.k.currency,
+			Previous: fmt.Sprintf("%.2f", prev[mv.k]), Cost: fmt.Sprintf("%.2f", cur[mv.k]),
+			Delta:  newDelta(mv.delta, 2, "", lowerIsBetter),
+			Change: "new",
+		}
+		if p := prev[mv.k]; p > 0 {
+			c.Change = fmt.Sprintf("%+.1f%%", mv.delta/p*100)
+		}
+		s.Movers = append(s.Movers, c)
+	}
+	return s
+}
+
+// highlights sums the month up: issues closed, the biggest cycle time change of an org (ALL when there is one
+// org only) and the biggest cloud cost mover.
+func highlights(p *page, cycle []map[string]string, month time.Time) []string {
+	var res []string
+	if t := p.Throughput; t.Closed != "" {
+		line := fmt.Sprintf("%s issues closed in %s", t.Closed, p.Title)
+		if t.Delta.Text != "" {
+			line += fmt.Sprintf(" (%s against %s)", t.Delta.Text, p.PreviousTitle)
+		}
+		res = append(res, line+".")
+	}
+
+	type change struct {
+		org       string
+		from, to  float64
+		precision int
+	}
+	var best *change
+	orgs := 0
+	for _, r := range cycle {
+		if r["month"] == p.Month && r["org"] != "ALL" {
+			orgs++
+		}
+	}
+	for _, r := range cycle {
+		if r["month"] != p.Month || r["cycle_count"] == "0" || (r["org"] == "ALL") != (orgs <= 1) {
+			continue
+		}
+		prev := withCount(monthRow(cycle, month.AddDate(0, -1, 0), r["org"]), "cycle_count")
+		if prev == nil {
+			continue
+		}
+		to, err1 := strconv.ParseFloat(r["cycletime_days_avg"], 64)
+		from, err2 := strconv.ParseFloat(prev["cycletime_days_avg"], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if best == nil || math.Abs(to-from) > math.Abs(best.to-best.from) {
+			best = &change{org: r["org"], from: from, to: to, precision: decimals(r["cycletime_days_avg"])}
+		}
+	}
+	if best != nil {
+		who := "Cycle time"
+		if best.org != "ALL" {
+			who = "Cycle time of " + best.org
+		}
+		verb := "went up"
+		if best.to < best.from {
+			verb = "went down"
+		}
+		res = append(res, fmt.Sprintf("%s %s from %.*f to %.*f %s.", who, verb, best.precision, best.from, best.precision, best.to, p.CycleTime.Unit))
+	}
+
+	if b := p.Bugs; b.Ratio != "" {
+		line := fmt.Sprintf("Bugs were %s of the issues closed", b.Ratio)
+		if b.Delta.Text != "" {
+			line += fmt.Sprintf(" (%s)", b.Delta.Text)
+		}
+		res = append(res, line+".")
+	}
+
+	if len(p.Cloud.Movers) > 0 {
+		m := p.Cloud.Movers[0]
+		res = append(res, fmt.Sprintf("Biggest cloud cost mover: %s %s at %s %s, %s (%s).", m.Provider, m.Group, m.Cost, m.Currency, m.Delta.Text, m.Change))
+	}
+	return res
+}
+
+// isoWeekEnd returns the Sunday of ISO week w of year y.
+func isoWeekEnd(y, w int) time.Time {
+	jan4 := time.Date(y, 1, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7+(w-1)*7)
+	return monday.AddDate(0, 0, 6)
+}
+
+// Directions of the metrics, which give the class of their deltas.
+const (
+	higherIsBetter = iota
+	lowerIsBetter
+	neutral
+)
+
+// deltaOf compares the value cur of column with the one of the previous month's row prev, at the precision of cur.
+func deltaOf(cur string, prev map[string]string, column string, direction int) delta {
+	if prev == nil {
+		return delta{}
+	}
+	c, err1 := strconv.ParseFloat(cur, 64)
+	p, err2 := strconv.ParseFloat(prev[column], 64)
+	if err1 != nil || err2 != nil {
+		return delta{}
+	}
+	return newDelta(c-p, decimals(cur), "", direction)
+}
+
+func newDelta(d float64, precision int, suffix string, direction int) delta {
+	text := strconv.FormatFloat(d, 'f', precision, 64)
+	if !strings.HasPrefix(text, "-") {
+		text = "+" + text
+	}
+	class := "flat"
+	rounded, _ := strconv.ParseFloat(text, 64)
+	switch {
+	case rounded == 0 || direction == neutral:
+	case (rounded > 0) == (direction == higherIsBetter):
+		class = "good"
+	default:
+		class = "bad"
+	}
+	if rounded == 0 {
+		text = strings.Replace(text, "-", "+", 1)
+	}
+	return delta{Text: text + suffix, Class: class}
+}
+
+// decimals returns the number of decimals of the number v as written in a CSV file.
+func decimals(v string) int {
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		return len(v) - i - 1
+	}
+	return 0
+}
+
+// monthRow returns the row of month and org of a monthly output, nil if there is none.
+func monthRow(rows []map[string]string, month time.Time, org string) map[string]string {
+	m := month.Format("2006-01")
+	for _, r := range rows {
+		if r["month"] == m && r["org"] == org {
+			return r
+		}
+	}
+	return nil
+}
+
+// withCount returns r unless its count column is 0, i.e. its average is not a measure.
+func withCount(r map[string]string, count string) map[string]string {
+	if r == nil || r[count] == "0" {
+		return nil
+	}
+	return r
+}
+
+// readOptional reads dir/name as rows keyed by header, nil when the file does not exist.
+func readOptional(dir, name string) ([]map[string]string, error) {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	res := []map[string]string{}
+	if len(records) == 0 {
+		return res, nil
+	}
+	headers := records[0]
+	// Files written with -bom start with a UTF-8 byte order mark
+	headers[0] = strings.TrimPrefix(headers[0], "\ufeff")
+	for _, rec := range records[1:] {
+		row := make(map[string]string, len(headers))
+		for j := 0; j < len(headers) && j < len(rec); j++ {
+			row[headers[j]] = rec[j]
+		}
+		res = append(res, row)
+	}
+	return res, nil
+}
@@ -0,0 +1,133 @@
+package report
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// update rewrites the golden files of the tests with their current output.
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestReportGolden(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	input := filepath.Join("testdata", "input")
+	tests := []struct {
+		golden string
+		files  []string // the calculate outputs of testdata/input available, all when nil
+		month  string
+		loc    *time.Location
+	}{
+		{golden: "full", month: "2025-09", loc: time.UTC},
+		// acme/web#5 closes on 2025-09-30T23:30Z, already October in Paris
+		{golden: "full_paris", month: "2025-09", loc: paris},
+		{golden: "cycle_time_only", files: []string{"cycle_time.csv"}, month: "2025-09", loc: time.UTC},
+		{golden: "no_previous_month", month: "2025-04", loc: time.UTC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			dir := input
+			if tt.files != nil {
+				dir = t.TempDir()
+				for _, name := range tt.files {
+					b, err := os.ReadFile(filepath.Join(input, name))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(filepath.Join(dir, name), b, 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			month, err := time.Parse("2006-01", tt.month)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := build(dir, month, tt.loc, time.Date(2025, 10, 1, 7, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Version = "test"
+			tmpl, err := parseTemplate("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := tmpl.Execute(&got, p); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", tt.golden+".golden.html")
+			if *update {
+				if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("report differs from %s (go test -update to rewrite it):\n%s", path, got.String())
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	input, err := filepath.Abs(filepath.Join("testdata", "input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		template string // "" for the embedded one
+		args     []string
+		want     string // the content of the report, or a substring of it with the embedded template
+		wantErr  bool
+	}{
+		{
+			name:     "template override",
+			template: "{{.Month}} {{.Throughput.Closed}} {{.Bugs.Ratio}} {{len .Cloud.Movers}}\n",
+			args:     []string{"-month", "2025-09"},
+			want:     "2025-09 16 50.0% 4\n",
+		},
+		{name: "embedded template", args: []string{"-month", "2025-09"}, want: "September 2025"},
+		{name: "bad month", args: []string{"-month", "09/2025"}, wantErr: true},
+		{name: "no calculate output", args: []string{"-month", "2025-09", "-data", "."}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+			t.Setenv("CONFIG_PATH", filepath.Join(dir, "config.yml"))
+			args := append([]string{"-data", input, "-out", "out/report.html"}, tt.args...)
+			if tt.template != "" {
+				if err := os.WriteFile("report.tmpl", []byte(tt.template), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "-template", "report.tmpl")
+			}
+			err := Run(args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := os.ReadFile(filepath.Join("out", "report.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.template != "" && string(got) != tt.want || !bytes.Contains(got, []byte(tt.want)) {
+				t.Errorf("report:\n%s\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
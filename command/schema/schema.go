@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"cto-stats/command/cli"
+	dschema "cto-stats/domain/schema"
+)
+
+// Help describes the schema subcommand for its -h output, the top-level usage and the completion scripts.
+var Help = cli.Command{
+	Name:        "schema",
+	Summary:     "describe the columns of every CSV file",
+	Synopsis:    "[-file <name>] [-json]",
+	Description: "Prints the files written by import and calculate with their columns, types and descriptions.",
+	Examples: []string{
+		"cto-stats schema -file cycle_time.csv",
+		"cto-stats schema -json",
+	},
+}
+
+// Run executes the schema subcommand: it prints the columns (name, type, description) of every CSV file of the
+// data directory, or of the one given with -file, as text or as JSON with -json.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	cli.SetUsage(fs, Help)
+	fs.SetOutput(os.Stderr)
+	file := fs.String("file", "", "only print the schema of this file, e.g. cycle_time.csv")
+	asJSON := fs.Bool("json", false, "print JSON instead of text")
+	if err := cli.Parse(fs, args); err != nil {
+		return err
+	}
+
+	files := dschema.Files
+	if name := strings.TrimSpace(*file); name != "" {
+		if !strings.HasSuffix(name, ".csv") {
+			name += ".csv"
+		}
+		f, ok := dschema.Lookup(name)
+		if !ok {
+			return fmt.Errorf("schema: unknown file %q", name)
+		}
+		files = []dschema.File{f}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(files)
+	}
+	return printText(os.Stdout, files)
+}
+
+func printText(out io.Writer, files []dschema.File) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for i, f := range files {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s (%s)\n%s\n", f.Name, f.WrittenBy, f.Description)
+		for _, c := range f.Columns {
+			typ := c.Type
+			if c.Optional {
+				typ += ", optional"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", c.Name, typ, c.Description)
+		}
+	}
+	return w.Flush()
+}
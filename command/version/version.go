@@ -0,0 +1,53 @@
+package version
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"cto-stats/command/cli"
+	"cto-stats/domain/buildinfo"
+)
+
+// Help describes the version subcommand for its -h output, the top-level usage and the completion scripts.
+var Help = cli.Command{
+	Name:        "version",
+	Summary:     "print the version, commit and build date",
+	Synopsis:    "[-json]",
+	Description: "Prints the build of the binary, as recorded in import_meta.csv and calculate_meta.csv by the runs it makes.",
+	Examples: []string{
+		"cto-stats version",
+		"cto-stats version -json",
+	},
+}
+
+// Run executes the version subcommand: it prints the version, commit, build date and Go version of the binary,
+// as text or as JSON with -json.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	cli.SetUsage(fs, Help)
+	asJSON := fs.Bool("json", false, "print JSON instead of text")
+	if err := cli.Parse(fs, args); err != nil {
+		return err
+	}
+
+	info := buildinfo.Get()
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	fmt.Printf("cto-stats %s\n", info.Version)
+	fmt.Printf("commit:  %s\n", orUnknown(info.Commit))
+	fmt.Printf("built:   %s\n", orUnknown(info.Date))
+	fmt.Printf("go:      %s\n", orUnknown(info.GoVersion))
+	return nil
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
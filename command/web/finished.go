@@ -0,0 +1,31 @@
+package web
+
+import (
+	"strconv"
+	"strings"
+)
+
+// filterWeek keeps the rows of finished_detail_week.csv of the ISO year and week given; an empty year or week keeps
+// every value. Numbers are compared as such, so week=7 matches the 07 of the file.
+func filterWeek(rows []map[string]string, year, week string) []map[string]string {
+	res := make([]map[string]string, 0, len(rows))
+	for _, r := range rows {
+		if sameNumber(year, r["year"]) && sameNumber(week, r["week"]) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+func sameNumber(want, v string) bool {
+	want = strings.TrimSpace(want)
+	if want == "" {
+		return true
+	}
+	a, errA := strconv.Atoi(want)
+	b, errB := strconv.Atoi(strings.TrimSpace(v))
+	if errA != nil || errB != nil {
+		return want == strings.TrimSpace(v)
+	}
+	return a == b
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultImportMetaLimit is the number of runs /api/import_meta returns without ?limit=.
+const defaultImportMetaLimit = 10
+
+// filterDataQuality keeps the data_quality.csv rows whose severity and rule are among the comma-separated
+// severities and rules (case-insensitive); an empty list keeps every value.
+func filterDataQuality(rows []map[string]string, severities, rules string) []map[string]string {
+	sevs, rs := splitParam(severities), splitParam(rules)
+	res := make([]map[string]string, 0, len(rows))
+	for _, r := range rows {
+		if matchParam(sevs, r["severity"]) && matchParam(rs, r["rule"]) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+func splitParam(v string) []string {
+	var res []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func matchParam(values []string, v string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	v = strings.ToLower(strings.TrimSpace(v))
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// latestImportRuns returns the limit most recent import_meta.csv rows by started_at, newest first. A limit that is
+// not a positive number falls back to defaultImportMetaLimit.
+func latestImportRuns(rows []map[string]string, limit string) []map[string]string {
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		n = defaultImportMetaLimit
+	}
+	runs := append([]map[string]string(nil), rows...)
+	// RFC3339 UTC timestamps sort as strings
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i]["started_at"] > runs[j]["started_at"] })
+	return runs[:min(n, len(runs))]
+}
+
+// health is the answer of /api/health. Status is warning when the latest calculate found data quality errors or
+// when the data is older than StaleAfterHours; the dashboard shows a banner then.
+type health struct {
+	Status              string  `json:"status"`
+	DataQualityErrors   int     `json:"data_quality_errors"`
+	DataQualityWarnings int     `json:"data_quality_warnings"`
+	CalculatedAt        *string `json:"calculated_at"`
+	ImportedAt          *string `json:"imported_at"`
+	// AgeHours is the time since CalculatedAt, else ImportedAt; null when neither is known
+	AgeHours        *float64 `json:"age_hours"`
+	StaleAfterHours float64  `json:"stale_after_hours"`
+	Stale           bool     `json:"stale"`
+}
+
+// healthOf summarizes the data_quality.csv, calculate_meta.csv and import_meta.csv rows (nil when missing) at now.
+func healthOf(quality, calculateMeta, importMeta []map[string]string, staleAfter time.Duration, now time.Time) health {
+	h := health{Status: "ok", StaleAfterHours: staleAfter.Hours()}
+	for _, r := range quality {
+		switch strings.ToLower(r["severity"]) {
+		case "error":
+			h.DataQualityErrors++
+		case "warning":
+			h.DataQualityWarnings++
+		}
+	}
+	h.CalculatedAt = latestTime(calculateMeta, "calculated_at")
+	h.ImportedAt = latestTime(importMeta, "started_at")
+	ref := h.CalculatedAt
+	if ref == nil {
+		ref = h.ImportedAt
+	}
+	if ref != nil {
+		t, _ := time.Parse(time.RFC3339, *ref)
+		age := now.Sub(t).Hours()
+		h.AgeHours = &age
+		h.Stale = staleAfter > 0 && now.Sub(t) > staleAfter
+	}
+	if h.DataQualityErrors > 0 || h.Stale {
+		h.Status = "warning"
+	}
+	return h
+}
+
+// latestTime returns the latest valid RFC3339 value of column among rows, nil without any.
+func latestTime(rows []map[string]string, column string) *string {
+	var latest *time.Time
+	for _, r := range rows {
+		t, err := time.Parse(time.RFC3339, r[column])
+		if err == nil && (latest == nil || t.After(*latest)) {
+			latest = &t
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	s := latest.UTC().Format(time.RFC3339)
+	return &s
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// coreFiles are the files of the main dashboard; -require refuses to start when none of them is in -data.
+var coreFiles = []string{"cycle_time.csv", "throughput_week.csv", "stocks.csv"}
+
+// scanDataDir logs which of files, the ones the endpoints read, are present in dir and which are missing, so that
+// a wrong -data shows at startup instead of as 404s. With require, it fails when dir holds none of the coreFiles.
+// Files appearing later are served as they come: the scan only reports.
+func scanDataDir(dir string, files []string, require bool) error {
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		slog.Warn("web.data.missing_dir", "data", dir, "hint", "point -data at the output directory of calculate")
+		if require {
+			return fmt.Errorf("web: -data %s is not a directory", dir)
+		}
+		return nil
+	}
+	var present, missing []string
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			present = append(present, f)
+		} else {
+			missing = append(missing, f)
+		}
+	}
+	sort.Strings(present)
+	sort.Strings(missing)
+	slog.Info("web.data", "data", dir, "present", len(present), "missing", len(missing), "files", strings.Join(present, ","))
+	if len(missing) > 0 {
+		slog.Warn("web.data.missing", "data", dir, "files", strings.Join(missing, ","),
+			"hint", "their endpoints answer 404 until calculate writes them (e.g. cloud spending needs calculate -cloudspending)")
+	}
+	for _, f := range coreFiles {
+		if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			return nil
+		}
+	}
+	slog.Warn("web.data.empty", "data", dir, "hint", "none of "+strings.Join(coreFiles, ", ")+" is there: run calculate, or point -data at its output directory")
+	if require {
+		return fmt.Errorf("web: -data %s holds none of %s; run calculate first or fix -data", dir, strings.Join(coreFiles, ", "))
+	}
+	return nil
+}
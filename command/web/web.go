@@ -4,14 +4,35 @@ import (
 	"encoding/csv"
 	"errors"
 	"flag"
+	"log/slog"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"cto-stats/command/cli"
+	"cto-stats/domain/buildinfo"
+	"cto-stats/domain/schema"
 
 	"github.com/labstack/echo/v4"
 )
 
+// Help describes the web subcommand for its -h output, the top-level usage and the completion scripts.
+var Help = cli.Command{
+	Name:        "web",
+	Summary:     "serve the dashboard and the CSV files as JSON",
+	Synopsis:    "[-addr <host:port>] [-data <dir>] [-ui <dir>] [-require] [-stale-after <duration>]",
+	Description: "Serves the built UI and the data files under /api.",
+	Examples: []string{
+		"cto-stats web -addr :8080 -data ./data",
+		"cto-stats web -data /data -require",
+	},
+}
+
 // Run starts a small Echo web server exposing CSV-as-JSON APIs and an optional SPA dashboard.
 //
 // Usage:
@@ -21,63 +42,212 @@ import (
 // Endpoints:
 //
 //	GET /api/cycle_times          -> <data>/cycle_time.csv
+//	GET /api/cycle_times/summary  -> latest month of cycle_time.csv with its p85, delta and 6-month trend
 //	GET /api/stocks               -> <data>/stocks.csv
 //	GET /api/stocks/week          -> <data>/stocks_week.csv
+//	GET /api/stocks/detail        -> <data>/stocks_detail.csv
+//	GET /api/stocks/bugs/week     -> <data>/bug_stock_week.csv
+//	GET /api/burnup               -> <data>/burnup_week.csv
+//	GET /api/stocks/timeline      -> <data>/stocks_week.csv pivoted per week, summed over projects (?project_id=)
+//	GET /api/cycle_scatter        -> <data>/cycle_scatter.csv (typed: numbers and booleans)
 //	GET /api/throughtput/week     -> <data>/throughput_week.csv (404 if missing)
+//	GET /api/throughput/week/repo -> <data>/throughput_week_repo.csv
+//	GET /api/cycle_times/repo     -> <data>/cycle_time_repo.csv
+//	GET /api/cycle_times/by_repo  -> <data>/cycle_time_by_repo.csv
+//	GET /api/committed_to_done    -> <data>/committed_to_done_month.csv
+//	GET /api/epics                -> <data>/epic_progress.csv
+//	GET /api/repo_ranking         -> <data>/repo_ranking.csv
+//	GET /api/velocity/week        -> <data>/velocity_week.csv
+//	GET /api/finished             -> <data>/finished_detail_week.csv (?year=, ?week=)
+//	GET /api/data_quality         -> <data>/data_quality.csv (?severity=, ?rule=, comma-separated)
+//	GET /api/import_meta          -> latest runs of <data>/import_meta.csv, newest first (?limit=, default 10)
+//	                                 or the whole file with ?format=csv
+//	GET /api/health               -> data quality error count and data freshness of the latest runs
+//	GET /api/anomalies            -> <data>/anomalies.csv
+//	GET /api/schema               -> columns of every CSV file, or of one with ?file=<name>
+//	GET /api/version              -> version, commit and build date of the server
+//
+// CSV endpoints accept ?org=<org> to keep the rows of one organization (see filterOrg). With ?format=csv or
+// Accept: text/csv, they send the CSV file itself as a download instead of JSON.
 //
 // When -ui points to a built Vite app (index.html exists), static files are served at / and
 // unknown routes fall back to index.html for SPA routing.
 func Run(args []string) error {
 	fs := flag.NewFlagSet("web", flag.ContinueOnError)
+	cli.SetUsage(fs, Help)
 	addr := fs.String("addr", ":8080", "http listen address (host:port)")
 	dataDir := fs.String("data", "./data", "directory containing CSV files")
 	uiDir := fs.String("ui", "./ui/dist", "directory containing built UI (Vite dist)")
-	if err := fs.Parse(args); err != nil {
+	require := fs.Bool("require", false, "refuse to start when -data holds none of "+strings.Join(coreFiles, ", "))
+	staleAfter := fs.Duration("stale-after", 48*time.Hour, "age of the latest calculate (or import) above which /api/health reports stale data; 0 never does")
+	if err := cli.Parse(fs, args); err != nil {
+		return err
+	}
+
+	e := NewServer(*dataDir, *uiDir, *staleAfter)
+	if err := scanDataDir(*dataDir, dataFiles(), *require); err != nil {
 		return err
 	}
+	slog.Info("web.start", "version", buildinfo.Get().String(), "addr", *addr, "data", *dataDir)
+	return e.Start(*addr)
+}
+
+// csvRoutes are the endpoints serving a CSV file of -data: its rows as JSON, filtered with ?org=, or the file
+// itself with ?format=csv.
+var csvRoutes = []struct{ route, file string }{
+	{"/api/cycle_times", "cycle_time.csv"},
+	{"/api/stocks", "stocks.csv"},
+	{"/api/stocks/week", "stocks_week.csv"},
+	{"/api/stocks/detail", "stocks_detail.csv"},
+	{"/api/stocks/bugs/week", "bug_stock_week.csv"},
+	{"/api/burnup", "burnup_week.csv"},
+	{"/api/throughput/week", "throughput_week.csv"},
+	{"/api/throughput/week/repo", "throughput_week_repo.csv"},
+	{"/api/cycle_times/repo", "cycle_time_repo.csv"},
+	{"/api/cycle_times/by_repo", "cycle_time_by_repo.csv"},
+	{"/api/committed_to_done", "committed_to_done_month.csv"},
+	{"/api/epics", "epic_progress.csv"},
+	{"/api/repo_ranking", "repo_ranking.csv"},
+	{"/api/velocity/week", "velocity_week.csv"},
+	{"/api/pr/change_requests", "pr_change_requests_week.csv"},
+	{"/api/pr/change_requests/repo", "pr_change_requests_repo.csv"},
+	{"/api/pr/change_requests/repo_dist", "pr_change_requests_repo_dist.csv"},
+	{"/api/cloud_spending/monthly", "cloud_spending_monthly.csv"},
+	{"/api/cloud_spending/services", "cloud_spending_services.csv"},
+	{"/api/cloud_spending/compared", "cloud_spending_compared.csv"},
+	{"/api/cloud_spending/environment", "cloud_spending_by_environment.csv"},
+	{"/api/anomalies", "anomalies.csv"},
+}
+
+// dataFiles returns the files of -data read by the endpoints, checked at startup.
+func dataFiles() []string {
+	files := []string{"cycle_scatter.csv"}
+	for _, r := range csvRoutes {
+		files = append(files, r.file)
+	}
+	return append(files, "finished_detail_week.csv", "data_quality.csv", "import_meta.csv")
+}
 
+// NewServer returns the server of the web subcommand: the API over the CSV files of dataDir and, when uiDir
+// holds a built UI, the UI. /api/health reports the data as stale when older than staleAfter (0 never does).
+func NewServer(dataDir, uiDir string, staleAfter time.Duration) *echo.Echo {
 	e := echo.New()
 
-	// Helper to register a GET endpoint serving a specific CSV file
-	serveCSV := func(route string, filename string) {
-		e.GET(route, func(c echo.Context) error {
-			path := filepath.Join(*dataDir, filename)
+	// Endpoints serving a CSV file as is
+	for _, r := range csvRoutes {
+		e.GET(r.route, func(c echo.Context) error {
+			path := filepath.Join(dataDir, r.file)
+			if wantsCSV(c) {
+				// the file as written, for download: ?org= does not apply
+				return serveFile(c, path, r.file)
+			}
 			rows, err := readCSV(path)
 			if err != nil {
-				if errors.Is(err, os.ErrNotExist) {
-					return c.JSON(http.StatusNotFound, map[string]any{
-						"error":   "file not found",
-						"path":    path,
-						"message": "CSV file is missing",
-					})
-				}
-				return c.JSON(http.StatusInternalServerError, map[string]any{
-					"error":   err.Error(),
-					"path":    path,
-					"message": "failed to read CSV",
-				})
+				return csvError(c, path, err)
 			}
-			return c.JSON(http.StatusOK, rows)
+			return c.JSON(http.StatusOK, filterOrg(rows, c.QueryParam("org")))
 		})
 	}
-
-	// APIs
-	serveCSV("/api/cycle_times", "cycle_time.csv")
-	serveCSV("/api/stocks", "stocks.csv")
-	serveCSV("/api/stocks/week", "stocks_week.csv")
-	serveCSV("/api/throughput/week", "throughput_week.csv")
-	serveCSV("/api/pr/change_requests", "pr_change_requests_week.csv")
-	serveCSV("/api/pr/change_requests/repo", "pr_change_requests_repo.csv")
-	serveCSV("/api/pr/change_requests/repo_dist", "pr_change_requests_repo_dist.csv")
-	serveCSV("/api/cloud_spending/monthly", "cloud_spending_monthly.csv")
-	serveCSV("/api/cloud_spending/services", "cloud_spending_services.csv")
-	serveCSV("/api/cloud_spending/compared", "cloud_spending_compared.csv")
+	e.GET("/api/finished", func(c echo.Context) error {
+		path := filepath.Join(dataDir, "finished_detail_week.csv")
+		if wantsCSV(c) {
+			return serveFile(c, path, "finished_detail_week.csv")
+		}
+		rows, err := readCSV(path)
+		if err != nil {
+			return csvError(c, path, err)
+		}
+		return c.JSON(http.StatusOK, filterWeek(filterOrg(rows, c.QueryParam("org")), c.QueryParam("year"), c.QueryParam("week")))
+	})
+	e.GET("/api/data_quality", func(c echo.Context) error {
+		path := filepath.Join(dataDir, "data_quality.csv")
+		if wantsCSV(c) {
+			return serveFile(c, path, "data_quality.csv")
+		}
+		rows, err := readCSV(path)
+		if err != nil {
+			return csvError(c, path, err)
+		}
+		return c.JSON(http.StatusOK, filterDataQuality(rows, c.QueryParam("severity"), c.QueryParam("rule")))
+	})
+	e.GET("/api/import_meta", func(c echo.Context) error {
+		path := filepath.Join(dataDir, "import_meta.csv")
+		if wantsCSV(c) {
+			// every run, oldest first as appended
+			return serveFile(c, path, "import_meta.csv")
+		}
+		rows, err := readCSV(path)
+		if err != nil {
+			return csvError(c, path, err)
+		}
+		return c.JSON(http.StatusOK, latestImportRuns(filterOrg(rows, c.QueryParam("org")), c.QueryParam("limit")))
+	})
+	e.GET("/api/health", func(c echo.Context) error {
+		// every file is optional: a missing one counts as no finding or no run
+		var sources [3][]map[string]string
+		for i, name := range []string{"data_quality.csv", "calculate_meta.csv", "import_meta.csv"} {
+			path := filepath.Join(dataDir, name)
+			rows, err := readCSV(path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return csvError(c, path, err)
+			}
+			sources[i] = rows
+		}
+		return c.JSON(http.StatusOK, healthOf(sources[0], sources[1], sources[2], staleAfter, time.Now()))
+	})
+	e.GET("/api/stocks/timeline", func(c echo.Context) error {
+		path := filepath.Join(dataDir, "stocks_week.csv")
+		rows, err := readCSV(path)
+		if err != nil {
+			return csvError(c, path, err)
+		}
+		return c.JSON(http.StatusOK, stocksTimelineOf(filterOrg(rows, c.QueryParam("org")), c.QueryParam("project_id")))
+	})
+	e.GET("/api/cycle_times/summary", func(c echo.Context) error {
+		path := filepath.Join(dataDir, "cycle_time.csv")
+		rows, err := readCSV(path)
+		if err != nil {
+			return csvError(c, path, err)
+		}
+		// the p85 comes from the per-issue dots; without them it is left null
+		scatter, err := readCSV(filepath.Join(dataDir, "cycle_scatter.csv"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return csvError(c, filepath.Join(dataDir, "cycle_scatter.csv"), err)
+		}
+		org := c.QueryParam("org")
+		if org == "" {
+			org = "ALL"
+		}
+		return c.JSON(http.StatusOK, cycleTimeSummaryOf(filterOrg(rows, org), scatter, org))
+	})
+	e.GET("/api/cycle_scatter", func(c echo.Context) error {
+		path := filepath.Join(dataDir, "cycle_scatter.csv")
+		rows, err := readCSV(path)
+		if err != nil {
+			return csvError(c, path, err)
+		}
+		return c.JSON(http.StatusOK, toCycleScatterPoints(rows))
+	})
+	e.GET("/api/schema", func(c echo.Context) error {
+		name := strings.TrimSpace(c.QueryParam("file"))
+		if name == "" {
+			return c.JSON(http.StatusOK, schema.Files)
+		}
+		f, ok := schema.Lookup(name)
+		if !ok {
+			return c.JSON(http.StatusNotFound, map[string]any{"error": "unknown file", "file": name})
+		}
+		return c.JSON(http.StatusOK, f)
+	})
+	e.GET("/api/version", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, buildinfo.Get())
+	})
 
 	// Static UI (optional)
-	indexPath := filepath.Join(*uiDir, "index.html")
+	indexPath := filepath.Join(uiDir, "index.html")
 	if fi, err := os.Stat(indexPath); err == nil && !fi.IsDir() {
 		// Serve built assets under /
-		e.Static("/", *uiDir)
+		e.Static("/", uiDir)
 		// Root path -> index.html
 		e.GET("/", func(c echo.Context) error { return c.File(indexPath) })
 
@@ -96,7 +266,237 @@ func Run(args []string) error {
 		}
 	}
 
-	return e.Start(*addr)
+	return e
+}
+
+// wantsCSV reports whether the request asks for the CSV file rather than JSON: ?format=csv, or an Accept header
+// preferring text/csv (a browser's default Accept, which lists text/html first, does not).
+func wantsCSV(c echo.Context) bool {
+	if f := c.QueryParam("format"); f != "" {
+		return strings.EqualFold(f, "csv")
+	}
+	accept := strings.TrimSpace(c.Request().Header.Get(echo.HeaderAccept))
+	return strings.HasPrefix(strings.ToLower(accept), "text/csv")
+}
+
+// serveFile sends the CSV file at path as a download named name, or the error of csvError when it cannot.
+func serveFile(c echo.Context, path, name string) error {
+	if _, err := os.Stat(path); err != nil {
+		return csvError(c, path, err)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	return c.Attachment(path, name)
+}
+
+// csvError answers with 404 when the CSV file is missing and 500 for any other read error.
+func csvError(c echo.Context, path string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error":   "file not found",
+			"path":    path,
+			"message": "CSV file is missing",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]any{
+		"error":   err.Error(),
+		"path":    path,
+		"message": "failed to read CSV",
+	})
+}
+
+// filterOrg keeps the rows whose org column matches org (case-insensitive). Without org, every row is returned,
+// including the ALL aggregate rows of cycle_time.csv and throughput_week.csv: ask for org=ALL to get those alone.
+func filterOrg(rows []map[string]string, org string) []map[string]string {
+	if org == "" {
+		return rows
+	}
+	res := make([]map[string]string, 0, len(rows))
+	for _, r := range rows {
+		if strings.EqualFold(r["org"], org) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// cycleScatterPoint is one closed issue of cycle_scatter.csv. lead_days is null when the issue has no lead time start.
+type cycleScatterPoint struct {
+	EndDate   string   `json:"end_date"`
+	CycleDays float64  `json:"cycle_days"`
+	LeadDays  *float64 `json:"lead_days"`
+	Project   string   `json:"project"`
+	Type      string   `json:"type"`
+	Bug       bool     `json:"bug"`
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Outlier   bool     `json:"outlier"`
+	URL       string   `json:"url"`
+}
+
+func toCycleScatterPoints(rows []map[string]string) []cycleScatterPoint {
+	res := make([]cycleScatterPoint, 0, len(rows))
+	for _, r := range rows {
+		cycle, _ := strconv.ParseFloat(r["cycle_days"], 64)
+		p := cycleScatterPoint{
+			EndDate:   r["end_date"],
+			CycleDays: cycle,
+			Project:   r["project"],
+			Type:      r["type"],
+			Bug:       r["bug"] == "true",
+			ID:        r["id"],
+			Name:      r["name"],
+			Outlier:   r["outlier"] == "true",
+			URL:       r["url"],
+		}
+		if lead, err := strconv.ParseFloat(r["lead_days"], 64); err == nil {
+			p.LeadDays = &lead
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+// stocksTimelineWeek is one week of /api/stocks/timeline: the stocks of every stage at the end of the week.
+type stocksTimelineWeek struct {
+	Year    int `json:"year"`
+	Week    int `json:"week"`
+	Backlog int `json:"backlog"`
+	Ready   int `json:"ready"`
+	Dev     int `json:"dev"`
+	Review  int `json:"review"`
+	QA      int `json:"qa"`
+	Waiting int `json:"waiting"`
+}
+
+// stocksTimelineOf pivots the stocks_week.csv rows into one entry per ISO week, oldest first, summing the stage
+// counts of the projects (only of projectID when set).
+func stocksTimelineOf(rows []map[string]string, projectID string) []stocksTimelineWeek {
+	type yw struct{ year, week int }
+	byWeek := map[yw]*stocksTimelineWeek{}
+	for _, r := range rows {
+		if projectID != "" && r["project_id"] != projectID {
+			continue
+		}
+		year, err1 := strconv.Atoi(r["year"])
+		week, err2 := strconv.Atoi(r["week"])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		w := byWeek[yw{year, week}]
+		if w == nil {
+			w = &stocksTimelineWeek{Year: year, Week: week}
+			byWeek[yw{year, week}] = w
+		}
+		count := func(col string) int {
+			n, _ := strconv.Atoi(r[col])
+			return n
+		}
+		w.Backlog += count("in_backlogs")
+		w.Ready += count("in_ready")
+		w.Dev += count("in_dev")
+		w.Review += count("in_review")
+		w.QA += count("in_qa")
+		w.Waiting += count("waiting_to_prod")
+	}
+	res := make([]stocksTimelineWeek, 0, len(byWeek))
+	for _, w := range byWeek {
+		res = append(res, *w)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Year != res[j].Year {
+			return res[i].Year < res[j].Year
+		}
+		return res[i].Week < res[j].Week
+	})
+	return res
+}
+
+// cycleTimeSummaryTrendMonths is the number of months of the trend of /api/cycle_times/summary, latest included.
+const cycleTimeSummaryTrendMonths = 6
+
+// cycleTimeSummary is the answer of /api/cycle_times/summary. Values are null when the month has no closed
+// issue with a cycle time.
+type cycleTimeSummary struct {
+	Org   string   `json:"org"`
+	Month string   `json:"month"`
+	Avg   *float64 `json:"cycletime_days_avg"`
+	P85   *float64 `json:"cycletime_days_p85"`
+	// Delta is the latest average minus the one of the previous calendar month
+	Delta       *float64                `json:"cycletime_days_avg_delta"`
+	IssuesCount int                     `json:"issues_count"`
+	Trend       []cycleTimeSummaryMonth `json:"trend"`
+	// Unit is the unit of the durations, days or hours (calculate durations.unit)
+	Unit string `json:"unit"`
+}
+
+type cycleTimeSummaryMonth struct {
+	Month       string   `json:"month"`
+	Avg         *float64 `json:"cycletime_days_avg"`
+	IssuesCount int      `json:"issues_count"`
+}
+
+// cycleTimeSummaryOf summarizes the cycle_time.csv rows of one org (already filtered): the latest month with its
+// average, the 85th percentile of the cycle times of cycle_scatter.csv closed that month (nearest rank, as the
+// calculate percentiles), the change from the previous calendar month and the averages of the last 6 calendar
+// months, oldest first. Months without a row are null in the trend. Without rows, Month is empty and Trend too.
+func cycleTimeSummaryOf(rows, scatter []map[string]string, org string) cycleTimeSummary {
+	if org == "" {
+		org = "ALL"
+	}
+	res := cycleTimeSummary{Org: org, Trend: []cycleTimeSummaryMonth{}, Unit: "days"}
+	byMonth := map[string]map[string]string{}
+	for _, r := range rows {
+		if _, err := time.Parse("2006-01", r["month"]); err != nil {
+			continue
+		}
+		byMonth[r["month"]] = r
+		res.Month = max(res.Month, r["month"])
+	}
+	if res.Month == "" {
+		return res
+	}
+	if u := byMonth[res.Month]["unit"]; u != "" {
+		res.Unit = u
+	}
+	latest, _ := time.Parse("2006-01", res.Month)
+	for i := cycleTimeSummaryTrendMonths - 1; i >= 0; i-- {
+		m := latest.AddDate(0, -i, 0).Format("2006-01")
+		tm := cycleTimeSummaryMonth{Month: m}
+		if r := byMonth[m]; r != nil {
+			tm.IssuesCount, _ = strconv.Atoi(r["issues_count"])
+			n, _ := strconv.Atoi(r["cycle_count"])
+			if v, err := strconv.ParseFloat(r["cycletime_days_avg"], 64); err == nil && n > 0 {
+				tm.Avg = &v
+			}
+		}
+		res.Trend = append(res.Trend, tm)
+	}
+	last := res.Trend[len(res.Trend)-1]
+	res.Avg, res.IssuesCount = last.Avg, last.IssuesCount
+	if prev := res.Trend[len(res.Trend)-2]; res.Avg != nil && prev.Avg != nil {
+		// rounded to the precision of the CSV values
+		d := math.Round((*res.Avg-*prev.Avg)*1e6) / 1e6
+		res.Delta = &d
+	}
+
+	var days []float64
+	for _, p := range scatter {
+		if !strings.HasPrefix(p["end_date"], res.Month) {
+			continue
+		}
+		if org != "ALL" && !strings.EqualFold(strings.SplitN(p["id"], "/", 2)[0], org) {
+			continue
+		}
+		if v, err := strconv.ParseFloat(p["cycle_days"], 64); err == nil {
+			days = append(days, v)
+		}
+	}
+	if len(days) > 0 {
+		sort.Float64s(days)
+		rank := min(max(int(math.Ceil(0.85*float64(len(days)))), 1), len(days))
+		res.P85 = &days[rank-1]
+	}
+	return res
 }
 
 // readCSV loads a CSV file and returns a slice of objects keyed by headers.
@@ -119,6 +519,10 @@ func readCSV(path string) ([]map[string]string, error) {
 	}
 
 	headers := records[0]
+	if len(headers) > 0 {
+		// Files written with -bom start with a UTF-8 byte order mark
+		headers[0] = strings.TrimPrefix(headers[0], "\ufeff")
+	}
 	res := make([]map[string]string, 0, len(records)-1)
 	for i := 1; i < len(records); i++ {
 		row := records[i]
@@ -0,0 +1,294 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterOrg(t *testing.T) {
+	rows := []map[string]string{
+		{"org": "acme", "month": "2025-03"},
+		{"org": "globex", "month": "2025-03"},
+		{"org": "ALL", "month": "2025-03"},
+	}
+	tests := []struct {
+		name string
+		org  string
+		want []string
+	}{
+		{"no org keeps every row", "", []string{"acme", "globex", "ALL"}},
+		{"one org", "acme", []string{"acme"}},
+		{"case-insensitive", "GLOBEX", []string{"globex"}},
+		{"the ALL rows", "ALL", []string{"ALL"}},
+		{"unknown org", "initech", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range filterOrg(rows, tt.org) {
+				got = append(got, r["org"])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("filterOrg(%q) kept %v, want %v", tt.org, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSVQuotedURL(t *testing.T) {
+	url := "https://github.com/acme/api/issues/1?q=a,b"
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:    "stocks_detail.csv",
+			content: "id,name,project_id,project_name,stage,current_column,url\nacme/api#1,\"Fix a, b\",101,Platform,in_dev,In Progress,\"" + url + "\"\n",
+			want:    map[string]string{"id": "acme/api#1", "name": "Fix a, b", "stage": "in_dev", "url": url},
+		},
+		{
+			name:    "url before other columns",
+			content: "id,url,repo\nacme/api#1,\"" + url + "\",api\n",
+			want:    map[string]string{"id": "acme/api#1", "url": url, "repo": "api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "f.csv")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			rows, err := readCSV(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("%d rows, want 1", len(rows))
+			}
+			for col, want := range tt.want {
+				if got := rows[0][col]; got != want {
+					t.Errorf("%s %q, want %q", col, got, want)
+				}
+			}
+		})
+	}
+}
+
+// get sends a GET of path to srv, with an Accept header when accept is set, and returns the response and its body.
+func get(t *testing.T, srv *httptest.Server, path, accept string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func TestDataQualityAndImportMetaEndpoints(t *testing.T) {
+	const (
+		quality = "severity,rule,issue_id,detail\n" +
+			"error,unparseable_timestamp,acme/api#1,bad date\n" +
+			"warning,duplicate_row,acme/api#2,twice\n" +
+			"warning,unknown_project,acme/web#3,PVT_9\n"
+		imports = "started_at,org,scopes,tool_version\n" +
+			"2025-03-01T02:00:00Z,acme,issues;pr,v1.2.0\n" +
+			"2025-03-03T02:00:00Z,acme,issues;pr,v1.3.0\n" +
+			"2025-03-02T02:00:00Z,globex,issues,v1.3.0\n"
+	)
+	dir := t.TempDir()
+	for name, content := range map[string]string{"data_quality.csv": quality, "import_meta.csv": imports} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(NewServer(dir, "", 0))
+	defer srv.Close()
+	empty := httptest.NewServer(NewServer(t.TempDir(), "", 0))
+	defer empty.Close()
+
+	tests := []struct {
+		name       string
+		srv        *httptest.Server
+		path       string
+		accept     string
+		wantStatus int
+		wantCSV    string   // the file sent as is, "" for a JSON answer
+		column     string   // of the JSON rows
+		want       []string // values of column, in order
+	}{
+		{name: "data quality", srv: srv, path: "/api/data_quality", wantStatus: 200, column: "issue_id", want: []string{"acme/api#1", "acme/api#2", "acme/web#3"}},
+		{name: "data quality by severity", srv: srv, path: "/api/data_quality?severity=WARNING", wantStatus: 200, column: "issue_id", want: []string{"acme/api#2", "acme/web#3"}},
+		{name: "data quality by severity and rule", srv: srv, path: "/api/data_quality?severity=warning,error&rule=duplicate_row,unparseable_timestamp", wantStatus: 200, column: "issue_id", want: []string{"acme/api#1", "acme/api#2"}},
+		{name: "data quality as csv", srv: srv, path: "/api/data_quality?format=csv", wantStatus: 200, wantCSV: quality},
+		{name: "data quality as csv ignores the filters", srv: srv, path: "/api/data_quality?format=csv&severity=error", wantStatus: 200, wantCSV: quality},
+		{name: "data quality accepting csv", srv: srv, path: "/api/data_quality", accept: "text/csv", wantStatus: 200, wantCSV: quality},
+		{name: "data quality missing", srv: empty, path: "/api/data_quality", wantStatus: 404},
+		{name: "data quality missing as csv", srv: empty, path: "/api/data_quality?format=csv", wantStatus: 404},
+		{name: "import runs newest first", srv: srv, path: "/api/import_meta", wantStatus: 200, column: "started_at", want: []string{"2025-03-03T02:00:00Z", "2025-03-02T02:00:00Z", "2025-03-01T02:00:00Z"}},
+		{name: "import runs limited", srv: srv, path: "/api/import_meta?limit=2", wantStatus: 200, column: "started_at", want: []string{"2025-03-03T02:00:00Z", "2025-03-02T02:00:00Z"}},
+		{name: "import runs of an org", srv: srv, path: "/api/import_meta?org=acme", wantStatus: 200, column: "tool_version", want: []string{"v1.3.0", "v1.2.0"}},
+		{name: "import runs as csv", srv: srv, path: "/api/import_meta?format=csv&limit=1", wantStatus: 200, wantCSV: imports},
+		{name: "import runs missing", srv: empty, path: "/api/import_meta", wantStatus: 404},
+		{name: "import runs missing as csv", srv: empty, path: "/api/import_meta?format=csv", wantStatus: 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := get(t, tt.srv, tt.path, tt.accept)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status %d, want %d\n%s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if tt.wantCSV != "" {
+				if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+					t.Errorf("Content-Type %q, want text/csv", ct)
+				}
+				if cd := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment") {
+					t.Errorf("Content-Disposition %q, want an attachment", cd)
+				}
+				if body != tt.wantCSV {
+					t.Errorf("got:\n%s\nwant:\n%s", body, tt.wantCSV)
+				}
+				return
+			}
+			var rows []map[string]string
+			if err := json.Unmarshal([]byte(body), &rows); err != nil {
+				t.Fatalf("%v\n%s", err, body)
+			}
+			var got []string
+			for _, r := range rows {
+				got = append(got, r[tt.column])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s %q, want %q", tt.column, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthEndpoint(t *testing.T) {
+	recent := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	old := time.Now().Add(-72 * time.Hour).UTC().Format(time.RFC3339)
+	tests := []struct {
+		name         string
+		files        map[string]string
+		staleAfter   time.Duration
+		path         string
+		wantStatus   string
+		wantErrors   int
+		wantWarnings int
+		wantStale    bool
+		wantAge      bool // age_hours is set
+	}{
+		{
+			name:       "no file",
+			staleAfter: 48 * time.Hour,
+			path:       "/api/health",
+			wantStatus: "ok",
+		},
+		{
+			name: "recent calculate with warnings",
+			files: map[string]string{
+				"data_quality.csv":   "severity,rule,issue_id,detail\nwarning,duplicate_row,acme/api#2,twice\n",
+				"calculate_meta.csv": "calculated_at,generator_version,scopes,import_version\n" + recent + ",v1.3.0,issues,v1.3.0\n",
+			},
+			staleAfter:   48 * time.Hour,
+			path:         "/api/health",
+			wantStatus:   "ok",
+			wantWarnings: 1,
+			wantAge:      true,
+		},
+		{
+			name: "data quality errors",
+			files: map[string]string{
+				"data_quality.csv":   "severity,rule,issue_id,detail\nerror,unparseable_timestamp,acme/api#1,bad\nerror,unparseable_timestamp,acme/api#3,bad\nwarning,duplicate_row,acme/api#2,twice\n",
+				"calculate_meta.csv": "calculated_at,generator_version,scopes,import_version\n" + recent + ",v1.3.0,issues,v1.3.0\n",
+			},
+			staleAfter:   48 * time.Hour,
+			path:         "/api/health",
+			wantStatus:   "warning",
+			wantErrors:   2,
+			wantWarnings: 1,
+			wantAge:      true,
+		},
+		{
+			name:       "stale import without a calculate",
+			files:      map[string]string{"import_meta.csv": "started_at,org,scopes,tool_version\n" + old + ",acme,issues,v1.3.0\n"},
+			staleAfter: 48 * time.Hour,
+			path:       "/api/health",
+			wantStatus: "warning",
+			wantStale:  true,
+			wantAge:    true,
+		},
+		{
+			name:       "never stale",
+			files:      map[string]string{"import_meta.csv": "started_at,org,scopes,tool_version\n" + old + ",acme,issues,v1.3.0\n"},
+			path:       "/api/health",
+			wantStatus: "ok",
+			wantAge:    true,
+		},
+		{
+			name:       "json with format=csv",
+			files:      map[string]string{"import_meta.csv": "started_at,org,scopes,tool_version\n" + old + ",acme,issues,v1.3.0\n"},
+			staleAfter: 48 * time.Hour,
+			path:       "/api/health?format=csv",
+			wantStatus: "warning",
+			wantStale:  true,
+			wantAge:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			srv := httptest.NewServer(NewServer(dir, "", tt.staleAfter))
+			defer srv.Close()
+			resp, body := get(t, srv, tt.path, "")
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %d\n%s", resp.StatusCode, body)
+			}
+			if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Errorf("Content-Type %q, want JSON", ct)
+			}
+			var h health
+			if err := json.Unmarshal([]byte(body), &h); err != nil {
+				t.Fatalf("%v\n%s", err, body)
+			}
+			if h.Status != tt.wantStatus || h.DataQualityErrors != tt.wantErrors || h.DataQualityWarnings != tt.wantWarnings || h.Stale != tt.wantStale {
+				t.Errorf("got %s, want status %s, %d errors, %d warnings, stale %v", body, tt.wantStatus, tt.wantErrors, tt.wantWarnings, tt.wantStale)
+			}
+			if (h.AgeHours != nil) != tt.wantAge {
+				t.Errorf("age_hours %v, want set %v", h.AgeHours, tt.wantAge)
+			}
+			if h.StaleAfterHours != tt.staleAfter.Hours() {
+				t.Errorf("stale_after_hours %v, want %v", h.StaleAfterHours, tt.staleAfter.Hours())
+			}
+		})
+	}
+}
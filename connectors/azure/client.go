@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"time"
 
+	"cto-stats/connectors/useragent"
 	"cto-stats/domain/cloudspending"
 )
 
@@ -31,7 +32,7 @@ func NewClient(subscriptionID, tenantID, clientID, clientSecret string) *Client
 		tenantID:       tenantID,
 		clientID:       clientID,
 		clientSecret:   clientSecret,
-		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		httpClient:     &http.Client{Timeout: 30 * time.Second, Transport: useragent.Transport(nil)},
 	}
 }
 
@@ -332,3 +333,8 @@ func (c *Client) parseResponse(resp *costQueryResponse, rawData string) ([]cloud
 
 	return records, nil
 }
+
+// CheckAuth verifies the service principal credentials by requesting an access token.
+func (c *Client) CheckAuth(ctx context.Context) error {
+	return c.authenticate(ctx)
+}
@@ -1,11 +1,13 @@
 package config
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"log/slog"
 	"os"
+	"regexp"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,11 +15,60 @@ import (
 // Config represents the structure of config.yaml used by the tool.
 // Only the fields currently needed by commands are modeled.
 type Config struct {
+	// Timezone is the IANA zone (e.g. "Europe/Paris") used for week and month cutoffs. Defaults to UTC.
+	Timezone string `yaml:"timezone"`
+	// UserAgent is sent to GitHub and the cloud APIs (default cto-stats/<version>).
+	UserAgent string `yaml:"user_agent"`
+	// FiscalYearStartMonth (1-12, default 1) is the first month of the fiscal year used by the quarterly outputs.
+	FiscalYearStartMonth int `yaml:"fiscal_year_start_month"`
+
 	GitHub struct {
 		Org       string    `yaml:"org"`
 		BugSource BugSource `yaml:"bug-source"`
 		Projects  []Project `yaml:"projects"`
+		// Bots lists logins to ignore in people-based metrics (e.g. "dependabot", "renovate").
+		// Logins ending with "[bot]" are always treated as bots.
+		Bots []string `yaml:"bots"`
+		// SizeWeights maps size labels (e.g. "size: L" or "5 points", matched ignoring case) to the weight of
+		// their issues in the size-weighted lead and cycle times. Issues without such a label weigh 1; with
+		// several, the heaviest wins.
+		SizeWeights map[string]float64 `yaml:"size_weights"`
+		// BugLabels are the labels (matched ignoring case) that make an issue without a GitHub issue type a bug.
+		// Defaults to ["bug"].
+		BugLabels []string `yaml:"bug_labels"`
+		// SeverityLabels are the severity labels of bugs (matched ignoring case), most severe first, e.g.
+		// [sev1, sev2, sev3]. An issue carrying several has the most severe one.
+		SeverityLabels []string `yaml:"severity_labels"`
+		// EpicLabelPrefix is the prefix (matched ignoring case) of the labels naming the epic of an issue, e.g.
+		// "epic:" for epic:checkout-revamp. Empty: issues have no epic.
+		EpicLabelPrefix string `yaml:"epic_label_prefix"`
 	} `yaml:"github"`
+	// ColumnAliases maps a workflow stage (backlog, ready, dev, review, qa, done or archive) to the project
+	// columns meaning that stage, e.g. dev: [In Progress, WIP]. Columns are matched ignoring case and surrounding
+	// spaces. A project column listed in a stage group matches every alias of its stage, and projects missing
+	// from github.projects use the aliases instead of the built-in column names.
+	ColumnAliases map[string][]string `yaml:"column_aliases"`
+	// Import provides defaults for the import flags; a flag given on the command line wins over its value here.
+	Import struct {
+		// Scopes run when no scope flag is given: issues, pr and/or cloudspending (default issues and pr).
+		Scopes []string `yaml:"scopes"`
+		// SinceDays imports only what was updated in the last N days (the -since flag).
+		SinceDays         int      `yaml:"since_days"`
+		Repos             []string `yaml:"repos"`
+		Labels            []string `yaml:"labels"`
+		Providers         []string `yaml:"providers"`
+		ReviewConcurrency int      `yaml:"review_concurrency"`
+		// TimelineConcurrency is the number of issues whose timelines are fetched in parallel (default 1).
+		TimelineConcurrency int  `yaml:"timeline_concurrency"`
+		NoReviews           bool `yaml:"no_reviews"`
+		SplitByRepo         bool `yaml:"split_by_repo"`
+		BOM                 bool `yaml:"bom"`
+		// DescriptionMinLength is the description length, in characters, above which an issue counts as
+		// described (the -description-min-length flag, default 80).
+		DescriptionMinLength int `yaml:"description_min_length"`
+		// SkipUnchanged reuses the timelines of the issues closed before since_days (the -skip-unchanged flag).
+		SkipUnchanged bool `yaml:"skip_unchanged"`
+	} `yaml:"import"`
 	CloudSpending struct {
 		// Flat list of services to include (legacy/simple mode)
 		Services []string `yaml:"services"`
@@ -25,7 +76,77 @@ type Config struct {
 		DetailedService []DetailedServiceGroup `yaml:"detailed_service"`
 		// Compared services: list of comparisons between two groups of services
 		ComparedService []ComparedService `yaml:"compared_service"`
+		// IncludeOther adds an __other__ row to the services output for the costs left out by the groups or the
+		// flat list, so the shares of a provider-month sum to 100.
+		IncludeOther bool `yaml:"include_other"`
+		// Environments maps an environment label (e.g. prod, staging) to the Azure subscription IDs and GCP
+		// project IDs it runs in, for cloud_spending_by_environment.csv.
+		Environments map[string][]string `yaml:"environments"`
 	} `yaml:"cloud_spending"`
+	DORA    DORA `yaml:"dora"`
+	Backlog struct {
+		// AgeBuckets are the inclusive upper bounds (in days) of the backlog age histogram buckets,
+		// e.g. [7, 30, 90, 180] gives 0-7, 8-30, 31-90, 91-180 and 181+.
+		AgeBuckets []int `yaml:"age_buckets"`
+	} `yaml:"backlog"`
+	Stocks struct {
+		// HistoryDays is how long snapshots are kept in stocks_history.csv (default 400).
+		HistoryDays int `yaml:"history_days"`
+	} `yaml:"stocks"`
+	CycleScatter struct {
+		// Weeks caps cycle_scatter.csv to the items closed in the last N weeks (default 52).
+		Weeks int `yaml:"weeks"`
+	} `yaml:"cycle_scatter"`
+	// Durations sets how the lead, cycle and time-to-PR durations of cycle_time.csv, cycle_time_quarter.csv and
+	// cycle_scatter.csv are written.
+	Durations struct {
+		// Unit is days (default) or hours.
+		Unit string `yaml:"unit"`
+		// Precision is the number of decimals, 0 to 6 (default 2).
+		Precision *int `yaml:"precision"`
+	} `yaml:"durations"`
+	// MinSampleSize is the number of values below which calculate leaves the percentiles and control limits
+	// empty and marks the row low_confidence (default 5, 0 to publish them all).
+	MinSampleSize *int `yaml:"min_sample_size"`
+	RepoBreakdown struct {
+		// MinIssues groups, in cycle_time_repo.csv and throughput_week_repo.csv, the repos with fewer closed
+		// issues in a month under "other" (default 0: every repo on its own).
+		MinIssues int `yaml:"min_issues"`
+		// RankingMinIssues leaves out of repo_ranking.csv the repos with fewer closed issues in the latest month
+		// (default 5).
+		RankingMinIssues *int `yaml:"ranking_min_issues"`
+	} `yaml:"repo_breakdown"`
+	TimeToPR struct {
+		// Source is the time to PR shown by the dashboards: board (default: development start to review start
+		// columns) or linked_pr (development start to the creation of the earliest linked pull request).
+		Source string `yaml:"source"`
+	} `yaml:"time_to_pr"`
+	WIP struct {
+		// PersonalLimit is the number of in-progress issues a person may hold; wip_per_person.csv flags the
+		// people above it. 0 (default) sets no limit.
+		PersonalLimit int `yaml:"personal_limit"`
+	} `yaml:"wip"`
+	Privacy struct {
+		// DisableIndividualMetrics suppresses per-person outputs (e.g. leaderboard_month.csv, wip_per_person.csv).
+		DisableIndividualMetrics bool `yaml:"disable_individual_metrics"`
+	} `yaml:"privacy"`
+	// Targets are the goals drawn as target lines on the charts; calculate copies them into the outputs.
+	Targets Targets `yaml:"targets"`
+	PR      struct {
+		// CRCountMode controls how CHANGES_REQUESTED reviews are counted per PR:
+		// total (default), distinct_reviewers or rounds.
+		CRCountMode string `yaml:"cr_count_mode"`
+		// CRRoundGapHours is the idle time, in hours, after which a CHANGES_REQUESTED review opens a new round
+		// in rounds mode (default 24).
+		CRRoundGapHours int `yaml:"cr_round_gap_hours"`
+		// ReviewDepthMinLines excludes PRs smaller than this many changed lines from the
+		// review comments per line ratio (default 10).
+		ReviewDepthMinLines int `yaml:"review_depth_min_lines"`
+		// ApprovalsRequired is the approval count whose latency pr_approval_latency_week.csv measures next to the
+		// first approval, typically the branch protection setting (default 2).
+		ApprovalsRequired int `yaml:"approvals_required"`
+	} `yaml:"pr"`
+	Notifications Notifications `yaml:"notifications"`
 	// Backward/forward compatibility alias to support alternate YAML shape:
 	// cloudspending:
 	//   detailed_service:
@@ -39,9 +160,54 @@ type Config struct {
 	CloudSpendingAlt struct {
 		DetailedService any               `yaml:"detailed_service"`
 		ComparedService []ComparedService `yaml:"compared_service"`
+		IncludeOther    bool              `yaml:"include_other"`
 	} `yaml:"cloudspending"`
 }
 
+// DORA holds settings for DORA-style delivery metrics.
+type DORA struct {
+	// FailureWindowDays is how long after a deployment a failure is attributed to it (default 7).
+	FailureWindowDays int `yaml:"failure_window_days"`
+	// FailureMatch selects how failures are detected: "bug_issue" (default) matches bug issues
+	// opened on the same repo, "hotfix_pr" matches PRs merged on the same repo carrying a HotfixLabels label.
+	FailureMatch string `yaml:"failure_match"`
+	// HotfixLabels are the PR labels marking a hotfix for failure_match hotfix_pr, matched ignoring case
+	// (default [hotfix]).
+	HotfixLabels []string `yaml:"hotfix_labels"`
+}
+
+// Notifications posts the outcome of import and calculate runs to a webhook, for scheduled pipelines.
+type Notifications struct {
+	// WebhookURL receives the messages; the NOTIFY_WEBHOOK_URL environment variable wins over it. Empty (default)
+	// disables the notifications.
+	WebhookURL string `yaml:"webhook_url"`
+	// Format is slack (default: Slack mrkdwn text, for Slack incoming webhooks) or json (the raw message).
+	Format string `yaml:"format"`
+	// DashboardURL, e.g. the URL of the web server, is linked from the messages.
+	DashboardURL string `yaml:"dashboard_url"`
+}
+
+// TargetValues are per-metric targets. A nil value means no target for that metric.
+type TargetValues struct {
+	CycleTimeDays     *float64 `yaml:"cycle_time_days"`
+	LeadTimeDays      *float64 `yaml:"lead_time_days"`
+	ThroughputPerWeek *float64 `yaml:"throughput_per_week"`
+	// BugRatio is the target share of bugs among the closed issues, from 0 to 1.
+	BugRatio *float64 `yaml:"bug_ratio"`
+}
+
+// Targets holds the org-wide targets and per-project overrides.
+type Targets struct {
+	TargetValues `yaml:",inline"`
+	Projects     []ProjectTargets `yaml:"projects"`
+}
+
+// ProjectTargets overrides some org-wide targets for one project, referenced by id or name.
+type ProjectTargets struct {
+	Project      string `yaml:"project"`
+	TargetValues `yaml:",inline"`
+}
+
 type BugSource struct {
 	CustomFieldName     string `yaml:"custom-field-name"`
 	CustomerFacingValue string `yaml:"customer-facing-value"`
@@ -75,9 +241,53 @@ type Project struct {
 	PutInReadyColumns      []string `yaml:"put_in_ready_columns"`
 	WaitingToProdStartCols []string `yaml:"waitingtoprod_start_columns"`
 	InProdStartColumns     []string `yaml:"inprod_start_columns"`
+	// CommittedColumns are the columns (e.g. a Sprint column) whose first move starts the committed_to_done
+	// delivery clock, distinct from the lead and cycle time starts. Empty: the project has no such clock.
+	CommittedColumns []string `yaml:"committed_columns"`
+	// TriageColumns are the pre-commitment columns (e.g. Triage) issues wait in before the real work is accepted:
+	// with them, the lead time starts at the first move to any other column instead of lead_time_columns.
+	TriageColumns []string `yaml:"triage_columns"`
+
+	// EstimateField names the number field of the project holding the estimate points of its issues, for
+	// velocity_week.csv. Empty: the project is not estimated.
+	EstimateField string `yaml:"estimate_field"`
+}
+
+// ErrEnvNotSet is wrapped by the Load errors of ${NAME} references to unset environment variables.
+var ErrEnvNotSet = errors.New("environment variable not set")
+
+// envRef matches the ${NAME} references expanded by expandEnv, and the $${NAME} escapes left as ${NAME}.
+var envRef = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces each ${NAME} of b with the value of the environment variable NAME, so one file can serve
+// several environments (e.g. org: ${GITHUB_ORG}). $${NAME} is kept as the literal ${NAME}; other $ are left
+// alone. Referencing an unset variable is an error naming it and its line; a variable set to "" expands to "".
+func expandEnv(b []byte) ([]byte, error) {
+	var (
+		out  []byte
+		errs []error
+		last int
+	)
+	for _, m := range envRef.FindAllSubmatchIndex(b, -1) {
+		out = append(out, b[last:m[0]]...)
+		last = m[1]
+		if b[m[0]+1] == '$' {
+			out = append(out, b[m[0]+1:m[1]]...)
+			continue
+		}
+		name := string(b[m[2]:m[3]])
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			line := bytes.Count(b[:m[0]], []byte("\n")) + 1
+			errs = append(errs, fmt.Errorf("line %d: ${%s}: %w", line, name, ErrEnvNotSet))
+		}
+		out = append(out, v...)
+	}
+	return append(out, b[last:]...), errors.Join(errs...)
 }
 
-// Load parses the YAML configuration file at path.
+// Load parses the YAML configuration file at path, after expanding its ${NAME} environment variable references.
+// The files of its includes list (e.g. includes: [teams/platform.yml]) are merged into it, see mergedDocument.
 func Load(path string) (*Config, error) {
 	b, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -86,6 +296,23 @@ func Load(path string) (*Config, error) {
 		}
 		return nil, err
 	}
+	if b, err = expandEnv(b); err != nil {
+		return nil, err
+	}
+	// Files listed in includes are merged into this one first, and the result decoded as a single file
+	includes, err := includesOf(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(includes) > 0 {
+		doc, err := mergedDocument(path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if b, err = yaml.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
 	var c Config
 	if err := yaml.Unmarshal(b, &c); err != nil {
 		return nil, err
@@ -133,6 +360,9 @@ func Load(path string) (*Config, error) {
 			}
 		}
 	}
+	if c.CloudSpendingAlt.IncludeOther {
+		c.CloudSpending.IncludeOther = true
+	}
 	// If only the new canonical grouped field is provided under cloud_spending, keep as is.
 	if len(c.CloudSpendingAlt.ComparedService) > 0 {
 		c.CloudSpending.ComparedService = c.CloudSpendingAlt.ComparedService
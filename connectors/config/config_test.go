@@ -0,0 +1,200 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes content to name in dir and returns its path.
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadExpandsEnv(t *testing.T) {
+	t.Setenv("CTO_TEST_ORG", "acme")
+	t.Setenv("CTO_TEST_PROJECT", "101")
+	t.Setenv("CTO_TEST_EMPTY", "")
+	tests := []struct {
+		name        string
+		yaml        string
+		wantOrg     string
+		wantProject string
+		wantErr     string // substring of the error, "" for none
+	}{
+		{
+			name:        "plain YAML",
+			yaml:        "github:\n  org: acme\n  projects:\n    - id: \"7\"\n",
+			wantOrg:     "acme",
+			wantProject: "7",
+		},
+		{
+			name:        "substituted",
+			yaml:        "github:\n  org: ${CTO_TEST_ORG}\n  projects:\n    - id: \"${CTO_TEST_PROJECT}\"\n",
+			wantOrg:     "acme",
+			wantProject: "101",
+		},
+		{
+			name:        "inside a value",
+			yaml:        "github:\n  org: ${CTO_TEST_ORG}-labs\n  projects:\n    - id: p-${CTO_TEST_PROJECT}\n",
+			wantOrg:     "acme-labs",
+			wantProject: "p-101",
+		},
+		{
+			name:    "set to empty",
+			yaml:    "github:\n  org: \"${CTO_TEST_EMPTY}\"\n",
+			wantOrg: "",
+		},
+		{
+			name:    "escaped",
+			yaml:    "github:\n  org: $${CTO_TEST_ORG}\n",
+			wantOrg: "${CTO_TEST_ORG}",
+		},
+		{
+			name:    "other dollars left alone",
+			yaml:    "github:\n  org: $CTO_TEST_ORG$\n",
+			wantOrg: "$CTO_TEST_ORG$",
+		},
+		{
+			name:    "missing variable",
+			yaml:    "github:\n  org: acme\n  projects:\n    - id: ${CTO_TEST_UNSET}\n",
+			wantErr: "line 4: ${CTO_TEST_UNSET}: environment variable not set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Load(writeConfig(t, t.TempDir(), "config.yml", tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !errors.Is(err, ErrEnvNotSet) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.GitHub.Org != tt.wantOrg {
+				t.Errorf("github.org %q, want %q", c.GitHub.Org, tt.wantOrg)
+			}
+			if tt.wantProject != "" && (len(c.GitHub.Projects) != 1 || c.GitHub.Projects[0].ID != tt.wantProject) {
+				t.Errorf("github.projects %+v, want the id %q", c.GitHub.Projects, tt.wantProject)
+			}
+		})
+	}
+}
+
+func TestLoadIncludes(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string // config.yml and the files it includes
+		wantOrg      string
+		wantTimezone string
+		wantProjects []string
+		wantErr      string // substring of the error, "" for none
+	}{
+		{
+			name: "lists appended, scalars overridden by later files",
+			files: map[string]string{
+				"config.yml":         "includes: [teams/platform.yml, teams/product.yml]\ntimezone: UTC\ngithub:\n  org: acme\n  projects:\n    - id: \"1\"\n",
+				"teams/platform.yml": "github:\n  projects:\n    - id: \"2\"\n",
+				"teams/product.yml":  "timezone: Europe/Paris\ngithub:\n  projects:\n    - id: \"3\"\n",
+			},
+			wantOrg:      "acme",
+			wantTimezone: "Europe/Paris",
+			wantProjects: []string{"1", "2", "3"},
+		},
+		{
+			name: "nested includes relative to their file",
+			files: map[string]string{
+				"config.yml":         "includes: [teams/all.yml]\ngithub:\n  org: acme\n",
+				"teams/all.yml":      "includes: [platform.yml]\ngithub:\n  projects:\n    - id: \"2\"\n",
+				"teams/platform.yml": "github:\n  org: acme-platform\n  projects:\n    - id: \"4\"\n",
+			},
+			wantOrg:      "acme-platform",
+			wantProjects: []string{"2", "4"},
+		},
+		{
+			name: "the same file twice is not a cycle",
+			files: map[string]string{
+				"config.yml": "includes: [a.yml, b.yml]\n",
+				"a.yml":      "includes: [shared.yml]\n",
+				"b.yml":      "includes: [shared.yml]\n",
+				"shared.yml": "github:\n  projects:\n    - id: \"5\"\n",
+			},
+			wantProjects: []string{"5", "5"},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"config.yml": "includes: [a.yml]\n",
+				"a.yml":      "includes: [b.yml]\n",
+				"b.yml":      "includes: [a.yml]\n",
+			},
+			wantErr: "include cycle: ",
+		},
+		{
+			name: "self include",
+			files: map[string]string{
+				"config.yml": "includes: [./config.yml]\n",
+			},
+			wantErr: "include cycle: ",
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"config.yml": "includes: [teams/none.yml]\n",
+			},
+			wantErr: "none.yml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeConfig(t, dir, name, content)
+			}
+			c, err := Load(filepath.Join(dir, "config.yml"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.GitHub.Org != tt.wantOrg || c.Timezone != tt.wantTimezone {
+				t.Errorf("org %q and timezone %q, want %q and %q", c.GitHub.Org, c.Timezone, tt.wantOrg, tt.wantTimezone)
+			}
+			var ids []string
+			for _, p := range c.GitHub.Projects {
+				ids = append(ids, p.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantProjects, ",") {
+				t.Errorf("projects %v, want %v", ids, tt.wantProjects)
+			}
+		})
+	}
+}
+
+func TestIncludeCycleNamesTheChain(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "config.yml", "includes: [a.yml]\n")
+	writeConfig(t, dir, "a.yml", "includes: [b.yml]\n")
+	writeConfig(t, dir, "b.yml", "includes: [a.yml]\n")
+	_, err := Load(filepath.Join(dir, "config.yml"))
+	want := "include cycle: " + filepath.Join(dir, "a.yml") + " -> " + filepath.Join(dir, "b.yml") + " -> " + filepath.Join(dir, "a.yml")
+	if err == nil || err.Error() != want {
+		t.Errorf("error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includesOf returns the includes list of the YAML document b, nil when it has none.
+func includesOf(b []byte) ([]string, error) {
+	var doc struct {
+		Includes []string `yaml:"includes"`
+	}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return doc.Includes, nil
+}
+
+// mergedDocument returns the YAML document of path (its ${NAME} references expanded) merged with the files of its
+// includes list, in order, each merged with its own includes first. Include paths are relative to the directory
+// of the file listing them. stack holds the files being merged, to report include cycles.
+func mergedDocument(path string, stack []string) (map[string]any, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range stack {
+		if p == abs {
+			chain := append(append([]string(nil), stack[i:]...), abs)
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = expandEnv(b); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	includes, err := includesOf(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	delete(doc, "includes")
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(path), inc)
+		}
+		sub, err := mergedDocument(inc, append(stack, abs))
+		if err != nil {
+			return nil, err
+		}
+		mergeYAML(doc, sub)
+	}
+	return doc, nil
+}
+
+// mergeYAML merges src into dst: mappings are merged key by key, lists are appended (e.g. the github.projects of
+// each team file) and any other value of src replaces the one of dst.
+func mergeYAML(dst, src map[string]any) {
+	for k, v := range src {
+		switch sv := v.(type) {
+		case map[string]any:
+			if dv, ok := dst[k].(map[string]any); ok {
+				mergeYAML(dv, sv)
+				continue
+			}
+		case []any:
+			if dv, ok := dst[k].([]any); ok {
+				dst[k] = append(dv, sv...)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
@@ -2,54 +2,85 @@ package csv
 
 import (
 	gh "cto-stats/domain/github"
+	"cto-stats/domain/schema"
 	"encoding/csv"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
 // WriteAllCSVs writes all CSV outputs into the data/ directory.
-func WriteAllCSVs(org string, repos []gh.Repo, reports []gh.IssueReport) error {
-	dir := filepath.Join("data")
+// projectNames (project id -> title, may be nil) backfills project names missing from events. With bom, every
+// file starts with a UTF-8 BOM.
+func WriteAllCSVs(org string, repos []gh.Repo, reports []gh.IssueReport, projectNames map[string]string, bom bool) error {
+	return WriteAllCSVsIn(filepath.Join("data"), org, repos, reports, projectNames, bom)
+}
+
+// WriteAllCSVsByRepo writes the same outputs as WriteAllCSVs split per repository, into RepoDir(repo) for each
+// of repos. Each directory only holds its own repository row and the issues (with their events) of that repository.
+func WriteAllCSVsByRepo(org string, repos []gh.Repo, reports []gh.IssueReport, projectNames map[string]string, bom bool) error {
+	byRepo := map[string][]gh.IssueReport{}
+	for _, rep := range reports {
+		byRepo[rep.Repo] = append(byRepo[rep.Repo], rep)
+	}
+	for _, r := range repos {
+		if err := WriteAllCSVsIn(RepoDir(r.Name), org, []gh.Repo{r}, byRepo[r.Name], projectNames, bom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RepoDir returns the per-repository directory used by import -split-by-repo: data/<repo>.
+func RepoDir(repo string) string {
+	return filepath.Join("data", repo)
+}
+
+// WriteAllCSVsIn writes the outputs of WriteAllCSVs into dir.
+func WriteAllCSVsIn(dir string, org string, repos []gh.Repo, reports []gh.IssueReport, projectNames map[string]string, bom bool) error {
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return err
 	}
-	if err := WriteRepositoryCSV(filepath.Join(dir, "repository.csv"), org, repos); err != nil {
+	if err := WriteRepositoryCSV(filepath.Join(dir, "repository.csv"), org, repos, bom); err != nil {
 		return err
 	}
-	if err := WriteProjectCSV(filepath.Join(dir, "project.csv"), reports); err != nil {
+	if err := WriteProjectCSV(filepath.Join(dir, "project.csv"), reports, projectNames, bom); err != nil {
 		return err
 	}
-	if err := WriteIssueCSV(filepath.Join(dir, "issue.csv"), reports); err != nil {
+	if err := WriteIssueCSV(filepath.Join(dir, "issue.csv"), reports, bom); err != nil {
 		return err
 	}
-	if err := WriteIssueStatusCSV(filepath.Join(dir, "issue_status_event.csv"), reports); err != nil {
+	if err := WriteIssueStatusCSV(filepath.Join(dir, "issue_status_event.csv"), reports, bom); err != nil {
 		return err
 	}
-	if err := WriteIssueProjectCSV(filepath.Join(dir, "issue_project_event.csv"), reports); err != nil {
+	if err := WriteIssueProjectCSV(filepath.Join(dir, "issue_project_event.csv"), reports, bom); err != nil {
 		return err
 	}
-	if err := WriteIssueProjectCustomFieldCSV(filepath.Join(dir, "issue_project_custom_field.csv"), reports); err != nil {
+	if err := WriteIssueProjectCustomFieldCSV(filepath.Join(dir, "issue_project_custom_field.csv"), reports, bom); err != nil {
+		return err
+	}
+	if err := WriteIssueCurrentProjectCSV(filepath.Join(dir, "issue_current_project.csv"), reports, bom); err != nil {
 		return err
 	}
 	return nil
 }
 
-func WriteRepositoryCSV(path string, org string, repos []gh.Repo) error {
-	f, err := os.Create(path)
+func WriteRepositoryCSV(path string, org string, repos []gh.Repo, bom bool) error {
+	f, err := Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	if err := w.Write([]string{"org", "repo", "owner", "private"}); err != nil {
+	if err := w.Write(schema.Headers("repository.csv")); err != nil {
 		return err
 	}
 	for _, r := range repos {
-		row := []string{org, r.Name, r.Owner.Login, strconv.FormatBool(r.Private)}
+		row := []string{org, r.Name, r.Owner.Login, strconv.FormatBool(r.Private), strconv.FormatBool(r.HasIssuesEnabled), strconv.Itoa(r.IssueCount)}
 		if err := w.Write(row); err != nil {
 			return err
 		}
@@ -57,7 +88,9 @@ func WriteRepositoryCSV(path string, org string, repos []gh.Repo) error {
 	return w.Error()
 }
 
-func WriteProjectCSV(path string, reports []gh.IssueReport) error {
+// WriteProjectCSV writes the projects seen in reports. Names come from events; when empty they are
+// backfilled from projectNames (typically the org and repo ProjectV2 listings).
+func WriteProjectCSV(path string, reports []gh.IssueReport, projectNames map[string]string, bom bool) error {
 	// collect unique projects by ID
 	projects := map[string]string{}
 	for _, rep := range reports {
@@ -80,18 +113,28 @@ func WriteProjectCSV(path string, reports []gh.IssueReport) error {
 			}
 		}
 	}
-	f, err := os.Create(path)
+	for id, name := range projects {
+		if name == "" && projectNames[id] != "" {
+			projects[id] = projectNames[id]
+		}
+	}
+	f, err := Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	if err := w.Write([]string{"project_id", "project_name"}); err != nil {
+	if err := w.Write(schema.Headers("project.csv")); err != nil {
 		return err
 	}
-	for id, name := range projects {
-		row := []string{id, name}
+	ids := make([]string, 0, len(projects))
+	for id := range projects {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		row := []string{id, projects[id]}
 		if err := w.Write(row); err != nil {
 			return err
 		}
@@ -99,15 +142,15 @@ func WriteProjectCSV(path string, reports []gh.IssueReport) error {
 	return w.Error()
 }
 
-func WriteIssueCSV(path string, reports []gh.IssueReport) error {
-	f, err := os.Create(path)
+func WriteIssueCSV(path string, reports []gh.IssueReport, bom bool) error {
+	f, err := Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	headers := []string{"org", "repo", "number", "title", "url", "state", "type", "is_bug", "creator", "assignees", "created_at", "closed_at", "committer"}
+	headers := schema.Headers("issue.csv")
 	if err := w.Write(headers); err != nil {
 		return err
 	}
@@ -118,6 +161,26 @@ func WriteIssueCSV(path string, reports []gh.IssueReport) error {
 		if rep.ClosedAt != nil {
 			closed = rep.ClosedAt.UTC().Format(time.RFC3339)
 		}
+		due := ""
+		if rep.MilestoneDueOn != nil {
+			due = rep.MilestoneDueOn.UTC().Format(time.RFC3339)
+		}
+		bugSince := ""
+		if rep.BugSince != nil {
+			bugSince = rep.BugSince.UTC().Format(time.RFC3339)
+		}
+		periods := make([]string, 0, len(rep.BugPeriods))
+		for _, p := range rep.BugPeriods {
+			until := ""
+			if p.Until != nil {
+				until = p.Until.UTC().Format(time.RFC3339)
+			}
+			periods = append(periods, p.Since.UTC().Format(time.RFC3339)+"/"+until)
+		}
+		changes := make([]string, 0, len(rep.SeverityChanges))
+		for _, c := range rep.SeverityChanges {
+			changes = append(changes, c.At.UTC().Format(time.RFC3339)+"/"+c.Severity)
+		}
 		row := []string{
 			rep.Org,
 			rep.Repo,
@@ -132,6 +195,17 @@ func WriteIssueCSV(path string, reports []gh.IssueReport) error {
 			created,
 			closed,
 			rep.Committer,
+			rep.Milestone,
+			due,
+			strconv.Itoa(rep.BodyLength),
+			strconv.FormatBool(rep.HasDescription),
+			strconv.FormatFloat(rep.SizeWeight, 'f', -1, 64),
+			bugSince,
+			strings.Join(periods, ";"),
+			rep.Severity,
+			strings.Join(changes, ";"),
+			rep.Epic,
+			strings.Join(rep.OtherEpics, ";"),
 		}
 		if err := w.Write(row); err != nil {
 			return err
@@ -140,15 +214,15 @@ func WriteIssueCSV(path string, reports []gh.IssueReport) error {
 	return w.Error()
 }
 
-func WriteIssueStatusCSV(path string, reports []gh.IssueReport) error {
-	f, err := os.Create(path)
+func WriteIssueStatusCSV(path string, reports []gh.IssueReport, bom bool) error {
+	f, err := Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	if err := w.Write([]string{"org", "repo", "number", "type", "at", "by"}); err != nil {
+	if err := w.Write(schema.Headers("issue_status_event.csv")); err != nil {
 		return err
 	}
 	for _, rep := range reports {
@@ -169,15 +243,15 @@ func WriteIssueStatusCSV(path string, reports []gh.IssueReport) error {
 	return w.Error()
 }
 
-func WriteIssueProjectCSV(path string, reports []gh.IssueReport) error {
-	f, err := os.Create(path)
+func WriteIssueProjectCSV(path string, reports []gh.IssueReport, bom bool) error {
+	f, err := Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	headers := []string{"org", "repo", "number", "project_id", "project_name", "from_column", "to_column", "at", "by", "type"}
+	headers := schema.Headers("issue_project_event.csv")
 	if err := w.Write(headers); err != nil {
 		return err
 	}
@@ -203,15 +277,46 @@ func WriteIssueProjectCSV(path string, reports []gh.IssueReport) error {
 	return w.Error()
 }
 
-func WriteIssueProjectCustomFieldCSV(path string, reports []gh.IssueReport) error {
-	f, err := os.Create(path)
+// WriteIssueCurrentProjectCSV writes the board column each issue currently sits in, one row per project.
+func WriteIssueCurrentProjectCSV(path string, reports []gh.IssueReport, bom bool) error {
+	f, err := Create(path, bom)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	headers := schema.Headers("issue_current_project.csv")
+	if err := w.Write(headers); err != nil {
+		return err
+	}
+	for _, rep := range reports {
+		for _, cur := range rep.CurrentProjects {
+			row := []string{
+				rep.Org,
+				rep.Repo,
+				strconv.Itoa(rep.Number),
+				cur.ProjectID,
+				cur.ProjectName,
+				cur.ColumnName,
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Error()
+}
+
+func WriteIssueProjectCustomFieldCSV(path string, reports []gh.IssueReport, bom bool) error {
+	f, err := Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	headers := []string{"org", "repo", "number", "project_id", "project_name", "field_name", "field_value"}
+	headers := schema.Headers("issue_project_custom_field.csv")
 	if err := w.Write(headers); err != nil {
 		return err
 	}
@@ -0,0 +1,99 @@
+package csv
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// utf8BOM is the byte order mark some spreadsheet tools (Excel) need to detect UTF-8.
+const utf8BOM = "\ufeff"
+
+// Create creates (or truncates) a CSV file at path, writing a UTF-8 BOM first when bom is set.
+func Create(path string, bom bool) (*os.File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if bom {
+		if _, err := f.WriteString(utf8BOM); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// AppendRow appends row to the CSV file at path, creating it with headers (and the BOM, when bom is set) first.
+// A file whose header lacks trailing columns of headers, written by an older version, gets its header
+// extended so the new columns can be read back; its earlier rows simply leave them empty.
+func AppendRow(path string, headers, row []string, bom bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := extendHeader(path, headers); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if fi.Size() == 0 {
+		if bom {
+			if _, err := f.WriteString(utf8BOM); err != nil {
+				return err
+			}
+		}
+		if err := w.Write(headers); err != nil {
+			return err
+		}
+	}
+	if err := w.Write(row); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// extendHeader rewrites the header line of the file at path with headers when the current one is a strict
+// prefix of it. A missing file is left alone.
+func extendHeader(path string, headers []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	bom := strings.HasPrefix(string(data), utf8BOM)
+	rest := strings.TrimPrefix(string(data), utf8BOM)
+	line, body, _ := strings.Cut(rest, "\n")
+	current, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil || len(current) >= len(headers) || !slices.Equal(current, headers[:len(current)]) {
+		return nil
+	}
+	var b strings.Builder
+	if bom {
+		b.WriteString(utf8BOM)
+	}
+	w := csv.NewWriter(&b)
+	if err := w.Write(headers); err != nil {
+		return err
+	}
+	w.Flush()
+	b.WriteString(body)
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// TrimBOM removes a leading UTF-8 BOM, typically from the first header cell of a CSV file.
+func TrimBOM(s string) string {
+	return strings.TrimPrefix(s, utf8BOM)
+}
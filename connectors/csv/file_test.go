@@ -0,0 +1,64 @@
+package csv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppendRow(t *testing.T) {
+	tests := []struct {
+		name     string
+		bom      bool
+		existing string // file content before the append, "" for none
+		want     string
+	}{
+		{
+			name: "new file",
+			want: "id,title\n1,\"a, \"\"b\"\"\"\n",
+		},
+		{
+			name: "new file with BOM",
+			bom:  true,
+			want: "\ufeffid,title\n1,\"a, \"\"b\"\"\"\n",
+		},
+		{
+			name:     "older header is extended, BOM kept",
+			existing: "\ufeffid\n0\n",
+			want:     "\ufeffid,title\n0\n1,\"a, \"\"b\"\"\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "f.csv")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := AppendRow(path, []string{"id", "title"}, []string{"1", `a, "b"`}, tt.bom); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimBOM(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"\ufeffid", "id"},
+		{"id", "id"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := TrimBOM(tt.in); got != tt.want {
+			t.Errorf("TrimBOM(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
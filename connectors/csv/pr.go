@@ -2,27 +2,30 @@ package csv
 
 import (
 	gh "cto-stats/domain/github"
+	"cto-stats/domain/schema"
 	"encoding/csv"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // WritePullRequestCSV writes a complete CSV snapshot of PRs for a repository.
-// Headers: org, repo, number, title, url, state, created_at, closed_at, merged_at, creator
-func WritePullRequests(path string, prs []gh.PullRequest) error {
+// Headers: org, repo, number, title, url, state, created_at, closed_at, merged_at, creator,
+// additions, deletions, changed_files, review_threads, review_comments, threads_total, threads_resolved, labels
+func WritePullRequests(path string, prs []gh.PullRequest, bom bool) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
-	f, err := os.Create(path)
+	f, err := Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	if err := w.Write([]string{"org", "repo", "number", "title", "url", "state", "created_at", "closed_at", "merged_at", "creator"}); err != nil {
+	if err := w.Write(schema.Headers("pr.csv")); err != nil {
 		return err
 	}
 	for _, pr := range prs {
@@ -39,7 +42,17 @@ func WritePullRequests(path string, prs []gh.PullRequest) error {
 		if pr.User != nil {
 			creator = pr.User.Login
 		}
-		row := []string{pr.Org, pr.Repo, strconv.Itoa(pr.Number), pr.Title, pr.HTMLURL, pr.State, created, closed, merged, creator}
+		labels := make([]string, 0, len(pr.Labels))
+		for _, l := range pr.Labels {
+			labels = append(labels, l.Name)
+		}
+		row := []string{
+			pr.Org, pr.Repo, strconv.Itoa(pr.Number), pr.Title, pr.HTMLURL, pr.State, created, closed, merged, creator,
+			strconv.Itoa(pr.Additions), strconv.Itoa(pr.Deletions), strconv.Itoa(pr.ChangedFiles),
+			strconv.Itoa(pr.ReviewThreads), strconv.Itoa(pr.ReviewComments),
+			strconv.Itoa(pr.ThreadsTotal), strconv.Itoa(pr.ThreadsResolved),
+			strings.Join(labels, ";"),
+		}
 		if err := w.Write(row); err != nil {
 			return err
 		}
@@ -47,22 +60,26 @@ func WritePullRequests(path string, prs []gh.PullRequest) error {
 	return w.Error()
 }
 
-func WritePullRequestReviews(path string, reviews []gh.PullRequestReview) error {
+func WritePullRequestReviews(path string, reviews []gh.PullRequestReview, bom bool) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
-	f, err := os.Create(path)
+	f, err := Create(path, bom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	if err := w.Write([]string{"org", "repo", "number", "state", "submitted_at", "user"}); err != nil {
+	if err := w.Write(schema.Headers("pr_review.csv")); err != nil {
 		return err
 	}
 	for _, rv := range reviews {
-		sub := rv.SubmittedAt.UTC().Format(time.RFC3339)
+		// PENDING reviews have no submitted_at: leave it blank rather than writing 0001-01-01
+		sub := ""
+		if !rv.SubmittedAt.IsZero() {
+			sub = rv.SubmittedAt.UTC().Format(time.RFC3339)
+		}
 		user := ""
 		if rv.User != nil {
 			user = rv.User.Login
@@ -74,3 +91,29 @@ func WritePullRequestReviews(path string, reviews []gh.PullRequestReview) error
 	}
 	return w.Error()
 }
+
+// WritePullRequestIssueLinks writes one row per (PR, closing issue) pair of prs.
+func WritePullRequestIssueLinks(path string, prs []gh.PullRequest, bom bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := Create(path, bom)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	if err := w.Write(schema.Headers("pr_issue_link.csv")); err != nil {
+		return err
+	}
+	for _, pr := range prs {
+		for _, is := range pr.ClosingIssues {
+			row := []string{pr.Org, pr.Repo, strconv.Itoa(pr.Number), is.Org, is.Repo, strconv.Itoa(is.Number)}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Error()
+}
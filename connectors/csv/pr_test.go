@@ -0,0 +1,31 @@
+package csv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gh "cto-stats/domain/github"
+)
+
+func TestWritePullRequestReviewsLeavesPendingBlank(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pr_review.csv")
+	reviews := []gh.PullRequestReview{
+		{Org: "o", Repo: "api", PullRequestNumber: 1, State: "APPROVED", SubmittedAt: time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC), User: &gh.User{Login: "ann"}},
+		{Org: "o", Repo: "api", PullRequestNumber: 1, State: "PENDING", User: &gh.User{Login: "cid"}},
+	}
+	if err := WritePullRequestReviews(path, reviews, false); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "org,repo,number,state,submitted_at,user\n" +
+		"o,api,1,APPROVED,2025-03-03T10:00:00Z,ann\n" +
+		"o,api,1,PENDING,,cid\n"
+	if got := string(b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
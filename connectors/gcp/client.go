@@ -15,6 +15,7 @@ import (
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 
+	"cto-stats/connectors/useragent"
 	"cto-stats/domain/cloudspending"
 )
 
@@ -30,11 +31,32 @@ type Client struct {
 func NewClient(projectID, billingAccount, serviceAccountJSON string, location string) *Client {
 	// Build an OAuth2-enabled HTTP client using ADC or the provided service account JSON
 	ctx := context.Background()
-	scopes := []string{
+	creds, _ := Credentials(ctx, serviceAccountJSON,
 		"https://www.googleapis.com/auth/cloud-billing.readonly",
 		"https://www.googleapis.com/auth/bigquery.readonly",
+	)
+
+	var httpClient *http.Client
+	if creds != nil {
+		httpClient = oauth2.NewClient(ctx, creds.TokenSource)
+	} else {
+		// Last resort: unauthenticated client (requests will fail), but keep timeout
+		httpClient = &http.Client{}
+	}
+	httpClient.Timeout = 30 * time.Second
+	httpClient.Transport = useragent.Transport(httpClient.Transport)
+
+	return &Client{
+		projectID:      projectID,
+		billingAccount: billingAccount,
+		location:       strings.TrimSpace(location),
+		httpClient:     httpClient,
 	}
+}
 
+// Credentials returns the credentials for scopes from serviceAccountJSON (raw JSON or a path to a JSON file),
+// falling back to Application Default Credentials (GOOGLE_APPLICATION_CREDENTIALS or the metadata server).
+func Credentials(ctx context.Context, serviceAccountJSON string, scopes ...string) (*google.Credentials, error) {
 	var creds *google.Credentials
 	var err error
 
@@ -60,24 +82,9 @@ func NewClient(projectID, billingAccount, serviceAccountJSON string, location st
 
 	if creds == nil || err != nil {
 		// Fallback to ADC (e.g., GOOGLE_APPLICATION_CREDENTIALS or metadata server)
-		creds, _ = google.FindDefaultCredentials(ctx, scopes...)
-	}
-
-	var httpClient *http.Client
-	if creds != nil {
-		httpClient = oauth2.NewClient(ctx, creds.TokenSource)
-	} else {
-		// Last resort: unauthenticated client (requests will fail), but keep timeout
-		httpClient = &http.Client{}
-	}
-	httpClient.Timeout = 30 * time.Second
-
-	return &Client{
-		projectID:      projectID,
-		billingAccount: billingAccount,
-		location:       strings.TrimSpace(location),
-		httpClient:     httpClient,
+		creds, err = google.FindDefaultCredentials(ctx, scopes...)
 	}
+	return creds, err
 }
 
 // Note: Authentication is now handled by the oauth2 transport in httpClient
@@ -107,7 +114,7 @@ type bigQueryResponse struct {
 	TotalRows string `json:"totalRows"`
 }
 
-// FetchCosts retrieves cost data grouped by service for the last N months
+// FetchCosts retrieves cost data grouped by service and project for the last N months
 func (c *Client) FetchCosts(ctx context.Context) ([]cloudspending.CostRecord, error) {
 
 	// Format dates for BigQuery (YYYYMMDD)
@@ -120,12 +127,13 @@ func (c *Client) FetchCosts(ctx context.Context) ([]cloudspending.CostRecord, er
 		SELECT
 			FORMAT_DATE('%%Y%%m01', DATE(usage_start_time)) AS month,
 			service.description AS service_name,
+			project.id AS project_id,
 			SUM(cost) AS total_cost,
 			currency
 		FROM
 			`+"`%[1]s.billing_export.gcp_billing_export_*`"+`
 		GROUP BY
-			month, service_name, currency
+			month, service_name, project_id, currency
 		ORDER BY
 			month, service_name
 	`, c.projectID)
@@ -190,6 +198,7 @@ func (c *Client) parseResponse(resp *bigQueryResponse, rawData string) ([]clouds
 	serviceIdx := -1
 	costIdx := -1
 	currencyIdx := -1
+	projectIdx := -1
 
 	for i, field := range resp.Schema.Fields {
 		switch field.Name {
@@ -201,6 +210,8 @@ func (c *Client) parseResponse(resp *bigQueryResponse, rawData string) ([]clouds
 			costIdx = i
 		case "currency":
 			currencyIdx = i
+		case "project_id":
+			projectIdx = i
 		}
 	}
 
@@ -251,15 +262,42 @@ func (c *Client) parseResponse(resp *bigQueryResponse, rawData string) ([]clouds
 			}
 		}
 
+		// Parse project (null for the costs not tied to a project, e.g. support)
+		project := ""
+		if projectIdx >= 0 && len(row.F) > projectIdx {
+			project, _ = row.F[projectIdx].V.(string)
+		}
+
 		records = append(records, cloudspending.CostRecord{
 			Provider: "gcp",
 			Service:  service,
 			Month:    monthTime,
 			Cost:     cost,
 			Currency: currency,
+			Project:  project,
 			RawData:  rawData,
 		})
 	}
 
 	return records, nil
 }
+
+// CheckAuth verifies the credentials and project access with a cheap BigQuery dataset listing (no query is run,
+// so nothing is billed).
+func (c *Client) CheckAuth(ctx context.Context) error {
+	url := fmt.Sprintf("https://bigquery.googleapis.com/bigquery/v2/projects/%s/datasets?maxResults=1", c.projectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach BigQuery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("BigQuery returned %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
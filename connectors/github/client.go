@@ -9,10 +9,13 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"cto-stats/connectors/useragent"
 	gh "cto-stats/domain/github"
 )
 
@@ -29,19 +32,67 @@ const (
 	rateSafetyMargin      = 2 * time.Second
 )
 
+// Secondary rate limits: each wait is capped, and a request gives up after this many retries.
+const (
+	maxSecondaryWait    = 5 * time.Minute
+	maxSecondaryRetries = 5
+)
+
+// apiVersion pins the REST API version so GitHub changes do not alter responses mid-run.
+const apiVersion = "2022-11-28"
+
 // Client is a thin wrapper over http.Client with token auth and helper methods.
 // Use New to construct it.
 
 type Client struct {
 	c     *http.Client
 	token string
+	usage usage
+	// apiBase and graphqlURL default to api.github.com; see WithBaseURL
+	apiBase    string
+	graphqlURL string
+	// snapshotDir receives the raw issue and PR pages when set; see WithSnapshotDir
+	snapshotDir string
+	// wait pauses the client on rate limits (waitFor); tests replace it to skip the real waits
+	wait func(ctx context.Context, d time.Duration) error
+}
+
+// Option customizes a Client built by New.
+type Option func(*Client)
+
+// WithTransport makes the client send its requests through rt, e.g. to record or replay responses.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(hc *Client) {
+		c := *hc.c
+		c.Transport = rt
+		hc.c = &c
+	}
 }
 
-func New(c *http.Client, token string) *Client {
+// WithBaseURL points the client at another API root, such as a GitHub Enterprise Server
+// (https://github.example.com/api/v3) or an httptest.Server. GraphQL requests go to <base>/graphql, except for
+// GitHub Enterprise Server whose GraphQL endpoint is <host>/api/graphql.
+func WithBaseURL(base string) Option {
+	return func(hc *Client) {
+		base = strings.TrimRight(base, "/")
+		hc.apiBase = base
+		if root, ok := strings.CutSuffix(base, "/api/v3"); ok {
+			hc.graphqlURL = root + "/api/graphql"
+		} else {
+			hc.graphqlURL = base + "/graphql"
+		}
+	}
+}
+
+func New(c *http.Client, token string, opts ...Option) *Client {
 	if c == nil {
 		c = &http.Client{Timeout: 30 * time.Second}
 	}
-	return &Client{c: c, token: token}
+	hc := &Client{c: c, token: token, apiBase: githubAPIBase, graphqlURL: githubGraphQLEndpoint, wait: waitFor}
+	for _, opt := range opts {
+		opt(hc)
+	}
+	return hc
 }
 
 func (hc *Client) newRequest(ctx context.Context, method, rawURL string) (*http.Request, error) {
@@ -57,27 +108,56 @@ func (hc *Client) newRequest(ctx context.Context, method, rawURL string) (*http.
 }
 
 func (hc *Client) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", useragent.Get())
+	req.Header.Set("X-GitHub-Api-Version", apiVersion)
+	attempt := 0
 	for {
+		hc.countCall(req.URL.String())
 		resp, err := hc.c.Do(req)
 		if err != nil {
+			hc.usage.errors.Add(1)
 			return nil, err
 		}
+		hc.countResponse(resp)
 		if resp.StatusCode == 403 && resp.Header.Get("X-RateLimit-Remaining") == "0" {
 			reset := resp.Header.Get("X-RateLimit-Reset")
 			_ = drainAndClose(resp.Body)
+			hc.usage.retries.Add(1)
 			if reset != "" {
 				if sec, err := strconv.ParseInt(reset, 10, 64); err == nil {
 					wait := time.Until(time.Unix(sec, 0)) + rateSafetyMargin
 					if wait > 0 {
 						slog.Warn("rate.limit.sleep", "wait", wait, "resetAt", time.Unix(sec, 0))
 						fmt.Fprintf(io.Discard, "Rate limit reached. Sleeping %s until reset...\n", wait)
-						time.Sleep(wait)
+						if err := hc.sleep(ctx, wait); err != nil {
+							return nil, err
+						}
+					}
+					if err := rewindBody(req); err != nil {
+						return nil, err
 					}
 					continue
 				}
 			}
+			hc.usage.errors.Add(1)
 			return nil, errors.New("rate limited by GitHub API")
 		}
+		// Secondary (abuse) limits answer 403 or 429 with Retry-After, independently of the remaining budget
+		if (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) && attempt < maxSecondaryRetries {
+			if wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				_ = drainAndClose(resp.Body)
+				if err := rewindBody(req); err != nil {
+					return nil, err
+				}
+				attempt++
+				hc.usage.retries.Add(1)
+				slog.Warn("rate.limit.secondary.sleep", "wait", wait, "status", resp.StatusCode, "attempt", attempt)
+				if err := hc.sleep(ctx, wait); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			// Simple rate-aware pacing without concurrency: after each successful response,
 			// inspect X-RateLimit headers and optionally sleep to avoid hitting the cap.
@@ -92,7 +172,10 @@ func (hc *Client) do(ctx context.Context, req *http.Request) (*http.Response, er
 							sleep := time.Until(resetAt) + rateSafetyMargin
 							if sleep > 0 {
 								slog.Warn("rate.pacing.sleep.empty", "sleep", sleep, "resetAt", resetAt)
-								time.Sleep(sleep)
+								if err := hc.sleep(ctx, sleep); err != nil {
+									_ = resp.Body.Close()
+									return nil, err
+								}
 							}
 						} else if rem < 100 {
 							// Low budget remaining; spread remaining calls evenly until reset.
@@ -109,7 +192,10 @@ func (hc *Client) do(ctx context.Context, req *http.Request) (*http.Response, er
 								sleep := perReq + jitter/10
 								if sleep > 0 {
 									slog.Info("rate.pacing.sleep", "sleep", sleep, "remaining", rem, "resetAt", resetAt)
-									time.Sleep(sleep)
+									if err := hc.sleep(ctx, sleep); err != nil {
+										_ = resp.Body.Close()
+										return nil, err
+									}
 								}
 							}
 						}
@@ -121,8 +207,40 @@ func (hc *Client) do(ctx context.Context, req *http.Request) (*http.Response, er
 		// read body for diagnostics and return error
 		b, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("github API %s %s returned %d: %s", req.Method, req.URL.String(), resp.StatusCode, string(b))
+		hc.usage.errors.Add(1)
+		return nil, &APIError{Method: req.Method, URL: req.URL.String(), StatusCode: resp.StatusCode, Body: string(b)}
+	}
+}
+
+// retryAfter parses a Retry-After header (delay in seconds or HTTP date) into a wait capped at
+// maxSecondaryWait. ok is false when the header is missing or invalid.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
 	}
+	var wait time.Duration
+	if sec, err := strconv.Atoi(v); err == nil {
+		wait = time.Duration(sec) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		wait = t.Sub(now)
+	} else {
+		return 0, false
+	}
+	return min(max(wait, time.Second), maxSecondaryWait), true
+}
+
+// rewindBody resets the body of req before it is sent again (POST bodies are consumed by the first attempt).
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
 }
 
 func drainAndClose(rc io.ReadCloser) error {
@@ -132,10 +250,11 @@ func drainAndClose(rc io.ReadCloser) error {
 
 // sleepUntilResetIfRateLimited checks GraphQL error messages for rate limit hints
 // and sleeps until the reset time advertised by GitHub headers. The sleep time
-// is capped to 1 hour as requested. Returns true if it slept and caller should retry.
-func sleepUntilResetIfRateLimited(resp *http.Response, messages []string) bool {
+// is capped to 1 hour as requested. Returns true if it slept and caller should retry, or ctx.Err() when ctx is
+// done before the reset. Other GraphQL errors are counted in the client stats.
+func (hc *Client) sleepUntilResetIfRateLimited(ctx context.Context, resp *http.Response, messages []string) (bool, error) {
 	if resp == nil {
-		return false
+		return false, nil
 	}
 	rateLimited := false
 	for _, m := range messages {
@@ -146,8 +265,10 @@ func sleepUntilResetIfRateLimited(resp *http.Response, messages []string) bool {
 		}
 	}
 	if !rateLimited {
-		return false
+		hc.usage.errors.Add(1)
+		return false, nil
 	}
+	hc.usage.retries.Add(1)
 	// Default wait to 1h cap unless header gives a nearer reset
 	wait := 1 * time.Hour
 	if reset := resp.Header.Get("X-RateLimit-Reset"); reset != "" {
@@ -163,17 +284,90 @@ func sleepUntilResetIfRateLimited(resp *http.Response, messages []string) bool {
 		}
 	}
 	slog.Warn("graphql.rate.limit.sleep", "sleep", wait, "resetAt", resp.Header.Get("X-RateLimit-Reset"))
-	time.Sleep(wait)
-	return true
+	if err := hc.sleep(ctx, wait); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-// ListAllPullRequests lists PRs for a repo, optionally filtered by created since (ISO8601 string). Uses GraphQL.
-func (hc *Client) ListAllPullRequests(ctx context.Context, owner, repo, since string) ([]gh.PullRequest, error) {
-	slog.Info("phase.prs.fetch.start", "owner", owner, "repo", repo, "since", since)
+// ListAllPullRequests lists PRs for a repo, optionally filtered by created since (ISO8601 string) and starting
+// after a given cursor. Uses GraphQL.
+// The pull requests connection has no since filter: pages are read most recently updated first, and pagination
+// stops at the first PR updated before since, as neither it nor the ones after it can have been created since.
+// The PRs are returned least recently updated first, with the last endCursor so callers can persist checkpoints.
+func (hc *Client) ListAllPullRequests(ctx context.Context, owner, repo, since, after string) ([]gh.PullRequest, *string, error) {
 	var all []gh.PullRequest
+	var lastCursor *string
+	err := hc.ListPullRequestPages(ctx, owner, repo, since, after, func(p PullRequestPage) error {
+		all = append(all, p.PullRequests...)
+		if p.EndCursor != "" {
+			lastCursor = &p.EndCursor
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// least recently updated first, the order pr.csv was written in before the pages were read newest first
+	slices.Reverse(all)
+	return all, lastCursor, nil
+}
+
+// PullRequestPage is a page of pull requests read by ListPullRequestPages.
+type PullRequestPage struct {
+	// PullRequests are the PRs of the page in the window, most recently updated first
+	PullRequests []gh.PullRequest
+	// EndCursor resumes the listing after this page, empty on a last page without one
+	EndCursor string
+	// FirstUpdatedAt is the updatedAt of the first PR of the page, in the window or not
+	FirstUpdatedAt time.Time
+}
+
+// ListPullRequestPages reads the PRs of a repo as ListAllPullRequests does, most recently updated first from
+// after on, and calls page after every page read, so callers can save their progress. An error of page stops
+// the listing and is returned.
+//
+// Cursors follow the updatedAt order: a PR updated while the pages are read moves ahead of the cursor and is not
+// read again. ListPullRequestsUpdatedSince reads those once the listing is done.
+func (hc *Client) ListPullRequestPages(ctx context.Context, owner, repo, since, after string, page func(PullRequestPage) error) error {
+	var sinceTime *time.Time
+	if since != "" {
+		if t, err := time.Parse(time.RFC3339, since); err == nil {
+			sinceTime = &t
+		}
+	}
+	return hc.listPullRequests(ctx, owner, repo, sinceTime, sinceTime, after, true, page)
+}
+
+// ListPullRequestsUpdatedSince returns the PRs of a repo updated at or after updated, most recently updated
+// first, from the first page on; PRs created before since (ISO8601 string) are left out. It reads the PRs that
+// moved ahead of the cursor of a ListPullRequestPages listing while it ran, updated being the FirstUpdatedAt of
+// its first page. Its pages are not saved to the snapshot directory, which holds those of the listing.
+func (hc *Client) ListPullRequestsUpdatedSince(ctx context.Context, owner, repo, since string, updated time.Time) ([]gh.PullRequest, error) {
+	var createdSince *time.Time
+	if since != "" {
+		if t, err := time.Parse(time.RFC3339, since); err == nil {
+			createdSince = &t
+		}
+	}
+	var all []gh.PullRequest
+	err := hc.listPullRequests(ctx, owner, repo, createdSince, &updated, "", false, func(p PullRequestPage) error {
+		all = append(all, p.PullRequests...)
+		return nil
+	})
+	return all, err
+}
+
+// listPullRequests reads the PRs of a repo most recently updated first from after on, calling page after every
+// page. PRs created before createdSince are left out, and pagination stops at the first PR updated before
+// updatedSince; either may be nil. Pages are saved to the snapshot directory when snapshot is set.
+func (hc *Client) listPullRequests(ctx context.Context, owner, repo string, createdSince, updatedSince *time.Time, after string, snapshot bool, page func(PullRequestPage) error) error {
+	slog.Info("phase.prs.fetch.start", "owner", owner, "repo", repo, "createdSince", createdSince, "updatedSince", updatedSince, "after", after)
+	// PRs and pages read, and whether reading stopped at updatedSince rather than at the last page, for the done log
+	count, pages, stoppedAtSince := 0, 0, false
 	query := `query($owner:String!, $name:String!, $pageSize:Int!, $after:String){
   repository(owner:$owner, name:$name){
-    pullRequests(first:$pageSize, after:$after, orderBy:{field:UPDATED_AT, direction:ASC}, states:[OPEN, MERGED, CLOSED]){
+    pullRequests(first:$pageSize, after:$after, orderBy:{field:UPDATED_AT, direction:DESC}, states:[OPEN, MERGED, CLOSED]){
       pageInfo{hasNextPage endCursor}
       nodes{
         number
@@ -185,23 +379,37 @@ func (hc *Client) ListAllPullRequests(ctx context.Context, owner, repo, since st
         closedAt
         mergedAt
         author{login}
+        additions
+        deletions
+        changedFiles
+        reviewThreads(first:100){totalCount pageInfo{hasNextPage endCursor} nodes{isResolved comments(first:1){totalCount nodes{createdAt}}}}
+        closingIssuesReferences(first:10){nodes{number repository{name owner{login}}}}
+        labels(first:20){nodes{name}}
       }
     }
   }
 }`
 	vars := map[string]any{"owner": owner, "name": repo, "pageSize": perPage}
-	for {
+	if after != "" {
+		vars["after"] = after
+	}
+	for pageNum := 1; ; {
 		body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, githubGraphQLEndpoint, bytes.NewReader(body))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hc.graphqlURL, bytes.NewReader(body))
 		if err != nil {
-			return nil, err
+			return err
 		}
 		req.Header.Set("Authorization", "Bearer "+hc.token)
 		req.Header.Set("Accept", "application/json")
 		req.Header.Set("Content-Type", "application/json")
 		resp, err := hc.do(ctx, req)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if snapshot {
+			if err := hc.snapshot(resp, repo, "pr", pageNum); err != nil {
+				return err
+			}
 		}
 		var out struct {
 			Data struct {
@@ -223,6 +431,26 @@ func (hc *Client) ListAllPullRequests(ctx context.Context, owner, repo, since st
 							Author    *struct {
 								Login string `json:"login"`
 							} `json:"author"`
+							Additions               int                    `json:"additions"`
+							Deletions               int                    `json:"deletions"`
+							ChangedFiles            int                    `json:"changedFiles"`
+							ReviewThreads           reviewThreadConnection `json:"reviewThreads"`
+							ClosingIssuesReferences struct {
+								Nodes []struct {
+									Number     int `json:"number"`
+									Repository struct {
+										Name  string `json:"name"`
+										Owner struct {
+											Login string `json:"login"`
+										} `json:"owner"`
+									} `json:"repository"`
+								} `json:"nodes"`
+							} `json:"closingIssuesReferences"`
+							Labels struct {
+								Nodes []struct {
+									Name string `json:"name"`
+								} `json:"nodes"`
+							} `json:"labels"`
 						} `json:"nodes"`
 					} `json:"pullRequests"`
 				} `json:"repository"`
@@ -230,7 +458,7 @@ func (hc *Client) ListAllPullRequests(ctx context.Context, owner, repo, since st
 			Errors []struct{ Message string } `json:"errors"`
 		}
 		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-			return nil, err
+			return err
 		}
 		if len(out.Errors) > 0 {
 			// Handle GraphQL rate limit (HTTP 200 + errors)
@@ -238,14 +466,29 @@ func (hc *Client) ListAllPullRequests(ctx context.Context, owner, repo, since st
 			for _, e := range out.Errors {
 				msgs = append(msgs, e.Message)
 			}
-			if sleepUntilResetIfRateLimited(resp, msgs) {
+			retry, err := hc.sleepUntilResetIfRateLimited(ctx, resp, msgs)
+			if err != nil {
+				_ = resp.Body.Close()
+				return err
+			}
+			if retry {
 				_ = resp.Body.Close()
 				// retry same page after sleep
 				continue
 			}
-			return nil, fmt.Errorf("graphql: %s", out.Errors[0].Message)
+			return fmt.Errorf("graphql: %s", out.Errors[0].Message)
+		}
+		nodes := out.Data.Repository.PullRequests.Nodes
+		var p PullRequestPage
+		if len(nodes) > 0 {
+			p.FirstUpdatedAt = nodes[0].UpdatedAt
 		}
-		for _, n := range out.Data.Repository.PullRequests.Nodes {
+		reachedSince := false
+		for _, n := range nodes {
+			if updatedSince != nil && n.UpdatedAt.Before(*updatedSince) {
+				reachedSince = true
+				break
+			}
 			pr := gh.PullRequest{
 				Number:    n.Number,
 				Title:     n.Title,
@@ -255,30 +498,159 @@ func (hc *Client) ListAllPullRequests(ctx context.Context, owner, repo, since st
 				UpdatedAt: n.UpdatedAt,
 				ClosedAt:  n.ClosedAt,
 				MergedAt:  n.MergedAt,
+
+				Additions:     n.Additions,
+				Deletions:     n.Deletions,
+				ChangedFiles:  n.ChangedFiles,
+				ReviewThreads: n.ReviewThreads.TotalCount,
 			}
 			if n.Author != nil {
 				pr.User = &gh.User{Login: n.Author.Login}
 			}
-			// Optional client-side filter by createdAt >= since
-			if since != "" {
-				if t, err := time.Parse(time.RFC3339, since); err == nil {
-					if pr.CreatedAt.Before(t) {
-						continue
-					}
+			threads := n.ReviewThreads.Nodes
+			if pi := n.ReviewThreads.PageInfo; pi.HasNextPage && pi.EndCursor != nil {
+				// Rare huge PRs: fetch the threads beyond the first 100 on their own
+				more, err := hc.listReviewThreads(ctx, owner, repo, n.Number, *pi.EndCursor)
+				if err != nil {
+					_ = resp.Body.Close()
+					return err
 				}
+				threads = append(threads, more...)
+			}
+			applyReviewThreads(&pr, threads)
+			for _, ci := range n.ClosingIssuesReferences.Nodes {
+				pr.ClosingIssues = append(pr.ClosingIssues, gh.IssueRef{Org: ci.Repository.Owner.Login, Repo: ci.Repository.Name, Number: ci.Number})
+			}
+			for _, l := range n.Labels.Nodes {
+				pr.Labels = append(pr.Labels, gh.Label{Name: l.Name})
 			}
-			all = append(all, pr)
+			// Updated since, but created before: left out
+			if createdSince != nil && pr.CreatedAt.Before(*createdSince) {
+				continue
+			}
+			p.PullRequests = append(p.PullRequests, pr)
 		}
+		_ = resp.Body.Close()
 		pi := out.Data.Repository.PullRequests.PageInfo
-		if !pi.HasNextPage || pi.EndCursor == nil {
-			_ = resp.Body.Close()
+		if pi.EndCursor != nil {
+			p.EndCursor = *pi.EndCursor
+		}
+		count += len(p.PullRequests)
+		if err := page(p); err != nil {
+			return err
+		}
+		if reachedSince || !pi.HasNextPage || pi.EndCursor == nil {
+			pages, stoppedAtSince = pageNum, reachedSince
 			break
 		}
 		vars["after"] = *pi.EndCursor
+		pageNum++
+	}
+	slog.Info("phase.prs.fetch.done", "owner", owner, "repo", repo, "count", count, "pages", pages, "stoppedAtSince", stoppedAtSince)
+	return nil
+}
+
+// reviewThreadConnection is the reviewThreads connection of a pull request, with the first comment of each
+// thread.
+type reviewThreadConnection struct {
+	TotalCount int `json:"totalCount"`
+	PageInfo   struct {
+		HasNextPage bool    `json:"hasNextPage"`
+		EndCursor   *string `json:"endCursor"`
+	} `json:"pageInfo"`
+	Nodes []reviewThread `json:"nodes"`
+}
+
+type reviewThread struct {
+	IsResolved bool `json:"isResolved"`
+	Comments   struct {
+		TotalCount int `json:"totalCount"`
+		Nodes      []struct {
+			CreatedAt time.Time `json:"createdAt"`
+		} `json:"nodes"`
+	} `json:"comments"`
+}
+
+// applyReviewThreads sets the review comment and thread counts of pr from all its threads. Threads opened after
+// the merge are left out of ThreadsTotal and ThreadsResolved, which measure the review before merge.
+func applyReviewThreads(pr *gh.PullRequest, threads []reviewThread) {
+	for _, t := range threads {
+		pr.ReviewComments += t.Comments.TotalCount
+		if pr.MergedAt != nil && len(t.Comments.Nodes) > 0 && t.Comments.Nodes[0].CreatedAt.After(*pr.MergedAt) {
+			continue
+		}
+		pr.ThreadsTotal++
+		if t.IsResolved {
+			pr.ThreadsResolved++
+		}
+	}
+}
+
+// listReviewThreads lists the review threads of pull request number after cursor after, for the PRs with more
+// threads than the PR listing fetches.
+func (hc *Client) listReviewThreads(ctx context.Context, owner, repo string, number int, after string) ([]reviewThread, error) {
+	query := `query($owner:String!, $name:String!, $number:Int!, $pageSize:Int!, $after:String){
+  repository(owner:$owner, name:$name){
+    pullRequest(number:$number){
+      reviewThreads(first:$pageSize, after:$after){totalCount pageInfo{hasNextPage endCursor} nodes{isResolved comments(first:1){totalCount nodes{createdAt}}}}
+    }
+  }
+}`
+	vars := map[string]any{"owner": owner, "name": repo, "number": number, "pageSize": perPage, "after": after}
+	var all []reviewThread
+	for page := 1; ; {
+		body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hc.graphqlURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+hc.token)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := hc.do(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if err := hc.snapshot(resp, repo, fmt.Sprintf("threads-%d", number), page); err != nil {
+			return nil, err
+		}
+		var out struct {
+			Data struct {
+				Repository struct {
+					PullRequest struct {
+						ReviewThreads reviewThreadConnection `json:"reviewThreads"`
+					} `json:"pullRequest"`
+				} `json:"repository"`
+			} `json:"data"`
+			Errors []struct{ Message string } `json:"errors"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&out)
 		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(out.Errors) > 0 {
+			msgs := make([]string, 0, len(out.Errors))
+			for _, e := range out.Errors {
+				msgs = append(msgs, e.Message)
+			}
+			retry, err := hc.sleepUntilResetIfRateLimited(ctx, resp, msgs)
+			if err != nil {
+				return nil, err
+			}
+			if retry {
+				continue
+			}
+			return nil, fmt.Errorf("graphql: %s", out.Errors[0].Message)
+		}
+		conn := out.Data.Repository.PullRequest.ReviewThreads
+		all = append(all, conn.Nodes...)
+		if !conn.PageInfo.HasNextPage || conn.PageInfo.EndCursor == nil {
+			return all, nil
+		}
+		vars["after"] = *conn.PageInfo.EndCursor
+		page++
 	}
-	slog.Info("phase.prs.fetch.done", "owner", owner, "repo", repo, "count", len(all))
-	return all, nil
 }
 
 // ListAllPullRequestReviews lists reviews for a given PR number via REST API.
@@ -287,7 +659,7 @@ func (hc *Client) ListAllPullRequestReviews(ctx context.Context, owner, repo str
 	var all []gh.PullRequestReview
 	page := 1
 	for {
-		url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=%d&page=%d", githubAPIBase, owner, repo, number, perPage, page)
+		url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=%d&page=%d", hc.apiBase, owner, repo, number, perPage, page)
 		req, err := hc.newRequest(ctx, http.MethodGet, url)
 		if err != nil {
 			return nil, err
@@ -296,6 +668,9 @@ func (hc *Client) ListAllPullRequestReviews(ctx context.Context, owner, repo str
 		if err != nil {
 			return nil, err
 		}
+		if err := hc.snapshot(resp, repo, fmt.Sprintf("reviews-%d", number), page); err != nil {
+			return nil, err
+		}
 		var out []struct {
 			State       string    `json:"state"`
 			SubmittedAt time.Time `json:"submitted_at"`
@@ -336,15 +711,19 @@ func (hc *Client) ListAllRepos(ctx context.Context, org string) ([]gh.Repo, erro
         name
         isPrivate
         owner{login}
+        hasIssuesEnabled
+        issues(states:[OPEN, CLOSED]){totalCount}
+        pushedAt
+        updatedAt
       }
     }
   }
 }`
 	vars := map[string]any{"login": org, "pageSize": perPage}
-	for {
+	for page := 1; ; {
 		// build request
 		body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, githubGraphQLEndpoint, bytes.NewReader(body))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hc.graphqlURL, bytes.NewReader(body))
 		if err != nil {
 			return nil, err
 		}
@@ -355,6 +734,9 @@ func (hc *Client) ListAllRepos(ctx context.Context, org string) ([]gh.Repo, erro
 		if err != nil {
 			return nil, err
 		}
+		if err := hc.snapshot(resp, "", "repos", page); err != nil {
+			return nil, err
+		}
 		var out struct {
 			Data struct {
 				Organization struct {
@@ -369,6 +751,12 @@ func (hc *Client) ListAllRepos(ctx context.Context, org string) ([]gh.Repo, erro
 							Owner     struct {
 								Login string `json:"login"`
 							} `json:"owner"`
+							HasIssuesEnabled bool `json:"hasIssuesEnabled"`
+							Issues           struct {
+								TotalCount int `json:"totalCount"`
+							} `json:"issues"`
+							PushedAt  time.Time `json:"pushedAt"`
+							UpdatedAt time.Time `json:"updatedAt"`
 						} `json:"nodes"`
 					} `json:"repositories"`
 				} `json:"organization"`
@@ -391,7 +779,12 @@ func (hc *Client) ListAllRepos(ctx context.Context, org string) ([]gh.Repo, erro
 			for _, e := range out.Errors {
 				msgs = append(msgs, e.Message)
 			}
-			if sleepUntilResetIfRateLimited(resp, msgs) {
+			retry, err := hc.sleepUntilResetIfRateLimited(ctx, resp, msgs)
+			if err != nil {
+				_ = resp.Body.Close()
+				return nil, err
+			}
+			if retry {
 				_ = resp.Body.Close()
 				// retry same page after sleeping
 				continue
@@ -403,31 +796,142 @@ func (hc *Client) ListAllRepos(ctx context.Context, org string) ([]gh.Repo, erro
 		for _, n := range out.Data.Organization.Repositories.Nodes {
 			all = append(all, gh.Repo{Name: n.Name, Private: n.IsPrivate, Owner: struct {
 				Login string `json:"login"`
-			}{Login: n.Owner.Login}})
+			}{Login: n.Owner.Login}, HasIssuesEnabled: n.HasIssuesEnabled, IssueCount: n.Issues.TotalCount,
+				PushedAt: n.PushedAt, UpdatedAt: n.UpdatedAt})
 		}
 		pi := out.Data.Organization.Repositories.PageInfo
 		if !pi.HasNextPage || pi.EndCursor == nil {
 			break
 		}
 		vars["after"] = *pi.EndCursor
+		page++
 	}
 	slog.Info("phase.repos.fetch.done", "org", org, "repos", len(all))
 	return all, nil
 }
 
-// ListAllIssues lists all issues for a repo, optionally since a time.
+// ListOrgProjects lists the ProjectV2 boards owned by an organization as id (fullDatabaseId) -> title.
+func (hc *Client) ListOrgProjects(ctx context.Context, org string) (map[string]string, error) {
+	query := `query($login:String!, $pageSize:Int!, $after:String){
+  organization(login:$login){
+    projectsV2(first:$pageSize, after:$after){
+      pageInfo{hasNextPage endCursor}
+      nodes{ fullDatabaseId title }
+    }
+  }
+}`
+	return hc.listProjects(ctx, query, map[string]any{"login": org}, "organization")
+}
+
+// ListRepoProjects lists the ProjectV2 boards linked to a repository as id (fullDatabaseId) -> title.
+func (hc *Client) ListRepoProjects(ctx context.Context, owner, repo string) (map[string]string, error) {
+	query := `query($owner:String!, $name:String!, $pageSize:Int!, $after:String){
+  repository(owner:$owner, name:$name){
+    projectsV2(first:$pageSize, after:$after){
+      pageInfo{hasNextPage endCursor}
+      nodes{ fullDatabaseId title }
+    }
+  }
+}`
+	return hc.listProjects(ctx, query, map[string]any{"owner": owner, "name": repo}, "repository")
+}
+
+// listProjects pages through a projectsV2 connection found under data.<root>. Its pages are saved as
+// projects-<page>.json, under <repo>/ for the projects of a repository.
+func (hc *Client) listProjects(ctx context.Context, query string, vars map[string]any, root string) (map[string]string, error) {
+	all := map[string]string{}
+	vars["pageSize"] = perPage
+	repo, _ := vars["name"].(string)
+	for page := 1; ; page++ {
+		body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hc.graphqlURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+hc.token)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := hc.do(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if err := hc.snapshot(resp, repo, "projects", page); err != nil {
+			return nil, err
+		}
+		type projectsConn struct {
+			ProjectsV2 struct {
+				PageInfo struct {
+					HasNextPage bool    `json:"hasNextPage"`
+					EndCursor   *string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []struct {
+					FullDatabaseId string `json:"fullDatabaseId"`
+					Title          string `json:"title"`
+				} `json:"nodes"`
+			} `json:"projectsV2"`
+		}
+		var out struct {
+			Data   map[string]*projectsConn   `json:"data"`
+			Errors []struct{ Message string } `json:"errors"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			_ = resp.Body.Close()
+			return nil, err
+		}
+		if len(out.Errors) > 0 {
+			msgs := make([]string, 0, len(out.Errors))
+			for _, e := range out.Errors {
+				msgs = append(msgs, e.Message)
+			}
+			retry, err := hc.sleepUntilResetIfRateLimited(ctx, resp, msgs)
+			if err != nil {
+				_ = resp.Body.Close()
+				return nil, err
+			}
+			if retry {
+				_ = resp.Body.Close()
+				// retry same page after sleeping
+				page--
+				continue
+			}
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("graphql: %s", out.Errors[0].Message)
+		}
+		_ = resp.Body.Close()
+		conn := out.Data[root]
+		if conn == nil {
+			break
+		}
+		for _, n := range conn.ProjectsV2.Nodes {
+			if n.FullDatabaseId != "" {
+				all[n.FullDatabaseId] = n.Title
+			}
+		}
+		pi := conn.ProjectsV2.PageInfo
+		if !pi.HasNextPage || pi.EndCursor == nil {
+			break
+		}
+		vars["after"] = *pi.EndCursor
+	}
+	return all, nil
+}
+
 // ListAllIssues lists all issues for a repo, optionally since a time and starting after a given cursor.
+// When labels is not empty, only issues carrying at least one of them are returned (filtered server-side,
+// combined with since).
 // It returns the collected issues and the last endCursor so callers can persist checkpoints.
-func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string, after string) ([]gh.Issue, *string, error) {
-	slog.Info("phase.issues.fetch.start", "owner", owner, "repo", repo, "since", since)
+// Unlike the REST issues endpoint, the GraphQL issues connection is typed and never includes pull requests.
+func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string, labels []string, after string) ([]gh.Issue, *string, error) {
+	slog.Info("phase.issues.fetch.start", "owner", owner, "repo", repo, "since", since, "labels", labels)
 	var all []gh.Issue
-	query := `query($owner:String!, $name:String!, $pageSize:Int!, $after:String, $since:DateTime){
+	query := `query($owner:String!, $name:String!, $pageSize:Int!, $after:String, $since:DateTime, $labels:[String!]){
   repository(owner:$owner, name:$name){
-    issues(first:$pageSize, after:$after, orderBy:{field:UPDATED_AT, direction:ASC}, states:[OPEN, CLOSED], filterBy:{since:$since}){
+    issues(first:$pageSize, after:$after, orderBy:{field:UPDATED_AT, direction:ASC}, states:[OPEN, CLOSED], filterBy:{since:$since, labels:$labels}){
       pageInfo{hasNextPage endCursor}
       nodes{
         number
         title
+        bodyText
         state
         url
         createdAt
@@ -436,6 +940,7 @@ func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string,
         author{login}
         assignees(first:20){nodes{login}}
         labels(first:50){nodes{name}}
+        milestone{title dueOn}
         issueType { name }
         projectItems(first:10) {
           nodes {
@@ -498,13 +1003,16 @@ func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string,
 	if since != "" {
 		vars["since"] = since
 	}
+	if len(labels) > 0 {
+		vars["labels"] = labels
+	}
 	if strings.TrimSpace(after) != "" {
 		vars["after"] = after
 	}
 	var lastCursor *string
-	for {
+	for page := 1; ; {
 		body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, githubGraphQLEndpoint, bytes.NewReader(body))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hc.graphqlURL, bytes.NewReader(body))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -515,6 +1023,9 @@ func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string,
 		if err != nil {
 			return nil, nil, err
 		}
+		if err := hc.snapshot(resp, repo, "issues", page); err != nil {
+			return nil, nil, err
+		}
 		var out struct {
 			Data struct {
 				Repository struct {
@@ -526,6 +1037,7 @@ func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string,
 						Nodes []struct {
 							Number    int        `json:"number"`
 							Title     string     `json:"title"`
+							BodyText  string     `json:"bodyText"`
 							State     string     `json:"state"`
 							URL       string     `json:"url"`
 							CreatedAt time.Time  `json:"createdAt"`
@@ -544,6 +1056,10 @@ func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string,
 									Name string `json:"name"`
 								}
 							} `json:"labels"`
+							Milestone *struct {
+								Title string     `json:"title"`
+								DueOn *time.Time `json:"dueOn"`
+							} `json:"milestone"`
 							IssueType *struct {
 								Name string `json:"name"`
 							} `json:"issueType"`
@@ -583,7 +1099,12 @@ func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string,
 			for _, e := range out.Errors {
 				msgs = append(msgs, e.Message)
 			}
-			if sleepUntilResetIfRateLimited(resp, msgs) {
+			retry, err := hc.sleepUntilResetIfRateLimited(ctx, resp, msgs)
+			if err != nil {
+				_ = resp.Body.Close()
+				return nil, nil, err
+			}
+			if retry {
 				_ = resp.Body.Close()
 				// retry same page after sleeping
 				continue
@@ -591,14 +1112,16 @@ func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string,
 			return nil, nil, fmt.Errorf("graphql: %s", out.Errors[0].Message)
 		}
 		for _, n := range out.Data.Repository.Issues.Nodes {
+			// only the length of the body is kept
 			iss := gh.Issue{
-				Number:    n.Number,
-				Title:     n.Title,
-				State:     strings.ToLower(n.State),
-				HTMLURL:   n.URL,
-				CreatedAt: n.CreatedAt,
-				UpdatedAt: n.UpdatedAt,
-				ClosedAt:  n.ClosedAt,
+				Number:     n.Number,
+				Title:      n.Title,
+				BodyLength: utf8.RuneCountInString(strings.TrimSpace(n.BodyText)),
+				State:      strings.ToLower(n.State),
+				HTMLURL:    n.URL,
+				CreatedAt:  n.CreatedAt,
+				UpdatedAt:  n.UpdatedAt,
+				ClosedAt:   n.ClosedAt,
 			}
 			if n.Author != nil {
 				iss.User = &gh.User{Login: n.Author.Login}
@@ -612,6 +1135,9 @@ func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string,
 			if n.IssueType != nil {
 				iss.Type = strings.ToLower(strings.TrimSpace(n.IssueType.Name))
 			}
+			if n.Milestone != nil {
+				iss.Milestone = &gh.Milestone{Title: n.Milestone.Title, DueOn: n.Milestone.DueOn}
+			}
 			for _, pi := range n.ProjectItems.Nodes {
 				for _, fv := range pi.FieldValues.Nodes {
 					val := ""
@@ -650,11 +1176,8 @@ func (hc *Client) ListAllIssues(ctx context.Context, owner, repo, since string,
 			return all, lastCursor, nil
 		}
 		vars["after"] = *pi.EndCursor
+		page++
 	}
-	// Unreachable, but keep compiler happy
-	// slog.Info placed above on return; here as a fallback
-	slog.Info("phase.issues.fetch.done", "owner", owner, "repo", repo, "count", len(all))
-	return all, nil, nil
 }
 
 // ListAllTimeline lists timeline events for a given issue number.
@@ -664,7 +1187,7 @@ func (hc *Client) ListAllTimeline(ctx context.Context, owner, repo string, numbe
 	query := `query($owner:String!, $name:String!, $number:Int!, $pageSize:Int!, $after:String){
   repository(owner:$owner, name:$name){
     issue(number:$number){
-      timelineItems(first:$pageSize, after:$after, itemTypes:[CLOSED_EVENT, REOPENED_EVENT, ADDED_TO_PROJECT_V2_EVENT, PROJECT_V2_ITEM_STATUS_CHANGED_EVENT, REMOVED_FROM_PROJECT_V2_EVENT]){
+      timelineItems(first:$pageSize, after:$after, itemTypes:[CLOSED_EVENT, REOPENED_EVENT, ADDED_TO_PROJECT_V2_EVENT, PROJECT_V2_ITEM_STATUS_CHANGED_EVENT, REMOVED_FROM_PROJECT_V2_EVENT, LABELED_EVENT, UNLABELED_EVENT]){
         pageInfo{hasNextPage endCursor}
         nodes{
           __typename
@@ -673,15 +1196,17 @@ func (hc *Client) ListAllTimeline(ctx context.Context, owner, repo string, numbe
           ... on AddedToProjectV2Event{ createdAt actor{login} project{fullDatabaseId title} }
           ... on ProjectV2ItemStatusChangedEvent{ createdAt actor{login} project{fullDatabaseId title} status previousStatus }
           ... on RemovedFromProjectV2Event{ createdAt actor{login} project{fullDatabaseId title} }
+          ... on LabeledEvent{ createdAt actor{login} label{name} }
+          ... on UnlabeledEvent{ createdAt actor{login} label{name} }
         }
       }
     }
   }
 }`
 	vars := map[string]any{"owner": owner, "name": repo, "number": number, "pageSize": perPage}
-	for {
+	for page := 1; ; {
 		body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, githubGraphQLEndpoint, bytes.NewReader(body))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hc.graphqlURL, bytes.NewReader(body))
 		if err != nil {
 			return nil, err
 		}
@@ -692,6 +1217,9 @@ func (hc *Client) ListAllTimeline(ctx context.Context, owner, repo string, numbe
 		if err != nil {
 			return nil, err
 		}
+		if err := hc.snapshot(resp, repo, fmt.Sprintf("timeline-%d", number), page); err != nil {
+			return nil, err
+		}
 		var out struct {
 			Data struct {
 				Repository struct {
@@ -713,6 +1241,9 @@ func (hc *Client) ListAllTimeline(ctx context.Context, owner, repo string, numbe
 								} `json:"project"`
 								ProjectColumnName         string `json:"status"`
 								PreviousProjectColumnName string `json:"previousStatus"`
+								Label                     *struct {
+									Name string `json:"name"`
+								} `json:"label"`
 							} `json:"nodes"`
 						} `json:"timelineItems"`
 					} `json:"issue"`
@@ -735,7 +1266,12 @@ func (hc *Client) ListAllTimeline(ctx context.Context, owner, repo string, numbe
 			for _, e := range out.Errors {
 				msgs = append(msgs, e.Message)
 			}
-			if sleepUntilResetIfRateLimited(resp, msgs) {
+			retry, err := hc.sleepUntilResetIfRateLimited(ctx, resp, msgs)
+			if err != nil {
+				_ = resp.Body.Close()
+				return nil, err
+			}
+			if retry {
 				_ = resp.Body.Close()
 				// retry same page after sleeping
 				continue
@@ -755,6 +1291,9 @@ func (hc *Client) ListAllTimeline(ctx context.Context, owner, repo string, numbe
 			}
 			ev.ProjectColumnName = n.ProjectColumnName
 			ev.PreviousProjectColumnName = n.PreviousProjectColumnName
+			if n.Label != nil {
+				ev.Label = n.Label.Name
+			}
 			switch n.Typename {
 			case "ClosedEvent":
 				ev.Event = "closed"
@@ -766,6 +1305,10 @@ func (hc *Client) ListAllTimeline(ctx context.Context, owner, repo string, numbe
 				ev.Event = "project_v2_item_status_changed"
 			case "RemovedFromProjectV2Event":
 				ev.Event = "removed_from_project_v2"
+			case "LabeledEvent":
+				ev.Event = "labeled"
+			case "UnlabeledEvent":
+				ev.Event = "unlabeled"
 			default:
 				continue
 			}
@@ -776,7 +1319,69 @@ func (hc *Client) ListAllTimeline(ctx context.Context, owner, repo string, numbe
 			break
 		}
 		vars["after"] = *pi.EndCursor
+		page++
 	}
 	slog.Info("phase.timeline.fetch.done", "owner", owner, "repo", repo, "issue", number, "events", len(all))
 	return all, nil
 }
+
+// queryGraphQL runs a single GraphQL query and decodes its data into data. It returns the response headers
+// (e.g. X-OAuth-Scopes). GraphQL errors are returned as a plain error.
+func (hc *Client) queryGraphQL(ctx context.Context, query string, vars map[string]any, data any) (http.Header, error) {
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hc.graphqlURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+hc.token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := hc.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out := struct {
+		Data   any                        `json:"data"`
+		Errors []struct{ Message string } `json:"errors"`
+	}{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	if len(out.Errors) > 0 {
+		hc.usage.errors.Add(1)
+		return resp.Header, fmt.Errorf("graphql: %s", out.Errors[0].Message)
+	}
+	return resp.Header, nil
+}
+
+// Viewer returns the login of the token owner and the OAuth scopes granted to the token
+// (empty for fine-grained tokens, which do not report scopes).
+func (hc *Client) Viewer(ctx context.Context) (login string, scopes string, err error) {
+	var data struct {
+		Viewer struct {
+			Login string `json:"login"`
+		} `json:"viewer"`
+	}
+	h, err := hc.queryGraphQL(ctx, `query{viewer{login}}`, nil, &data)
+	if err != nil {
+		return "", "", err
+	}
+	return data.Viewer.Login, h.Get("X-OAuth-Scopes"), nil
+}
+
+// CheckOrg verifies that the organization exists and is visible to the token.
+func (hc *Client) CheckOrg(ctx context.Context, org string) error {
+	var data struct {
+		Organization *struct {
+			Login string `json:"login"`
+		} `json:"organization"`
+	}
+	if _, err := hc.queryGraphQL(ctx, `query($org:String!){organization(login:$org){login}}`, map[string]any{"org": org}, &data); err != nil {
+		return err
+	}
+	if data.Organization == nil {
+		return fmt.Errorf("organization %q not found", org)
+	}
+	return nil
+}
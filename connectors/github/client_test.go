@@ -0,0 +1,394 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gh "cto-stats/domain/github"
+)
+
+// newRecordedClient returns a Client answered from the responses recorded under testdata/recorded in the
+// -snapshot layout: Replay picks the file from the operation of each request (the GraphQL listing or the REST
+// reviews path) and its variables (repository, number and page cursor), so the requests may come in any order.
+// Requests without a recorded response get a 404.
+func newRecordedClient() *Client {
+	return New(nil, "token", WithTransport(Replay(filepath.Join("testdata", "recorded"))))
+}
+
+func TestListAllIssuesDecoding(t *testing.T) {
+	hc := newRecordedClient()
+	issues, cursor, err := hc.ListAllIssues(context.Background(), "acme", "api", "", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor == nil || *cursor != "aXNzdWVz" {
+		t.Errorf("cursor %v, want the endCursor of the page", cursor)
+	}
+	tests := []struct {
+		name     string
+		number   int
+		wantUser string // "" for no user
+		wantType string
+	}{
+		{"author and type", 1, "ann", "bug"},
+		{"null author (deleted account)", 2, "", "feature"},
+		{"missing issueType", 3, "bob", ""},
+		{"null issueType", 4, "bob", ""},
+	}
+	if len(issues) != len(tests) {
+		t.Fatalf("decoded %d issues, want %d", len(issues), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := issues[i]
+			if is.Number != tt.number {
+				t.Fatalf("issue %d at position %d, want %d", is.Number, i, tt.number)
+			}
+			user := ""
+			if is.User != nil {
+				user = is.User.Login
+			}
+			if user != tt.wantUser {
+				t.Errorf("user %q, want %q", user, tt.wantUser)
+			}
+			if is.Type != tt.wantType {
+				t.Errorf("type %q, want %q", is.Type, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestListAllTimelineDecoding(t *testing.T) {
+	hc := newRecordedClient()
+	evts, err := hc.ListAllTimeline(context.Background(), "acme", "api", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		event, actor, project, column, previous, label string
+	}{
+		{event: "added_to_project_v2", actor: "ann", project: "101"},
+		{event: "project_v2_item_status_changed", project: "101", column: "In Progress", previous: "Backlog"},
+		{event: "removed_from_project_v2", actor: "ann", project: "101"},
+		{event: "labeled", actor: "ann", label: "bug"},
+		// the CrossReferencedEvent in between is not one the import asks for: it is skipped
+		{event: "closed", actor: "bob"},
+	}
+	if len(evts) != len(tests) {
+		t.Fatalf("decoded %d events, want %d: %+v", len(evts), len(tests), evts)
+	}
+	for i, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			ev := evts[i]
+			got := gh.TimelineEvent{Event: ev.Event, ProjectColumnName: ev.ProjectColumnName, PreviousProjectColumnName: ev.PreviousProjectColumnName, Label: ev.Label}
+			want := gh.TimelineEvent{Event: tt.event, ProjectColumnName: tt.column, PreviousProjectColumnName: tt.previous, Label: tt.label}
+			if got != want {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+			if actor := loginOf(ev.Actor); actor != tt.actor {
+				t.Errorf("actor %q, want %q", actor, tt.actor)
+			}
+			project := ""
+			if ev.Project != nil {
+				project = ev.Project.ID
+			}
+			if project != tt.project {
+				t.Errorf("project %q, want %q", project, tt.project)
+			}
+		})
+	}
+}
+
+func TestListAllPullRequestsDecoding(t *testing.T) {
+	hc := newRecordedClient()
+	prs, _, err := hc.ListAllPullRequests(context.Background(), "acme", "api", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pages are read most recently updated first and returned the other way round
+	var numbers []int
+	for _, pr := range prs {
+		numbers = append(numbers, pr.Number)
+	}
+	if !slices.Equal(numbers, []int{5, 6}) {
+		t.Fatalf("PR numbers %v, want [5 6]", numbers)
+	}
+	if prs[0].User != nil || prs[0].MergedAt != nil || prs[0].State != "open" {
+		t.Errorf("PR 5 (null author, open): %+v", prs[0])
+	}
+	if loginOf(prs[1].User) != "ann" || prs[1].MergedAt == nil || prs[1].State != "merged" {
+		t.Errorf("PR 6 (merged by ann): %+v", prs[1])
+	}
+}
+
+func TestListAllReposDecoding(t *testing.T) {
+	hc := newRecordedClient()
+	// two pages: the second one is only found with the endCursor of the first
+	repos, err := hc.ListAllRepos(context.Background(), "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		private    bool
+		hasIssues  bool
+		issueCount int
+		pushedAt   string // "" for a repository never pushed to
+	}{
+		{"api", false, true, 12, "2025-03-04T08:00:00Z"},
+		{"infra", true, true, 0, "2025-02-01T08:00:00Z"},
+		{"web", false, false, 0, ""},
+	}
+	if len(repos) != len(tests) {
+		t.Fatalf("decoded %d repos, want %d: %+v", len(repos), len(tests), repos)
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := repos[i]
+			if r.Name != tt.name || r.Owner.Login != "acme" {
+				t.Fatalf("repo %s/%s at position %d, want acme/%s", r.Owner.Login, r.Name, i, tt.name)
+			}
+			if r.Private != tt.private || r.HasIssuesEnabled != tt.hasIssues || r.IssueCount != tt.issueCount {
+				t.Errorf("private %v, issues enabled %v, %d issues, want %v, %v, %d", r.Private, r.HasIssuesEnabled, r.IssueCount, tt.private, tt.hasIssues, tt.issueCount)
+			}
+			pushed := ""
+			if !r.PushedAt.IsZero() {
+				pushed = r.PushedAt.Format(time.RFC3339)
+			}
+			if pushed != tt.pushedAt {
+				t.Errorf("pushedAt %q, want %q", pushed, tt.pushedAt)
+			}
+		})
+	}
+}
+
+func TestListAllPullRequestReviewsDecoding(t *testing.T) {
+	type review struct{ state, user, submitted string }
+	tests := []struct {
+		name    string
+		number  int
+		want    []review
+		wantErr bool
+	}{
+		{name: "states and null user", number: 6, want: []review{
+			{"COMMENTED", "bob", "2025-03-03T09:00:00Z"},
+			// lower case states are upper-cased; a deleted account has no user
+			{"CHANGES_REQUESTED", "", "2025-03-03T10:00:00Z"},
+			{"APPROVED", "bob", "2025-03-04T07:00:00Z"},
+		}},
+		{name: "no review", number: 5},
+		{name: "not recorded", number: 7, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := newRecordedClient()
+			reviews, err := hc.ListAllPullRequestReviews(context.Background(), "acme", "api", tt.number)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("reviews %+v of an unrecorded PR, want an error", reviews)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []review
+			for _, rv := range reviews {
+				got = append(got, review{rv.State, loginOf(rv.User), rv.SubmittedAt.Format(time.RFC3339)})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("reviews %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func loginOf(u *gh.User) string {
+	if u == nil {
+		return ""
+	}
+	return u.Login
+}
+
+// recordWaits replaces the rate limit waits of hc with a no-op recording the durations asked for.
+func recordWaits(hc *Client) *[]time.Duration {
+	var mu sync.Mutex
+	waits := &[]time.Duration{}
+	hc.wait = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		*waits = append(*waits, d)
+		return ctx.Err()
+	}
+	return waits
+}
+
+func TestSecondaryRateLimitRetry(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		limited    int // responses rate limited before the reviews are served
+		wantWaits  int
+		wantErr    bool
+	}{
+		{name: "Retry-After in seconds", status: http.StatusForbidden, retryAfter: "5", limited: 1, wantWaits: 1},
+		{name: "429", status: http.StatusTooManyRequests, retryAfter: "5", limited: 2, wantWaits: 2},
+		{name: "Retry-After as a date", status: http.StatusForbidden, retryAfter: now.Add(90 * time.Second).UTC().Format(http.TimeFormat), limited: 1, wantWaits: 1},
+		{name: "no Retry-After", status: http.StatusForbidden, limited: 1, wantErr: true},
+		{name: "too many retries", status: http.StatusForbidden, retryAfter: "5", limited: maxSecondaryRetries + 1, wantWaits: maxSecondaryRetries, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= tt.limited {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.status)
+					_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+					return
+				}
+				_, _ = w.Write([]byte(`[{"state":"APPROVED","submitted_at":"2025-03-03T10:00:00Z","user":{"login":"ann"}}]`))
+			}))
+			defer srv.Close()
+			hc := New(nil, "token", WithBaseURL(srv.URL))
+			waits := recordWaits(hc)
+			reviews, err := hc.ListAllPullRequestReviews(context.Background(), "acme", "api", 2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(reviews) != 1 {
+				t.Errorf("%d reviews after the retries, want 1", len(reviews))
+			}
+			if len(*waits) != tt.wantWaits {
+				t.Fatalf("waited %v, want %d waits", *waits, tt.wantWaits)
+			}
+			for _, d := range *waits {
+				if d < 5*time.Second || d > 90*time.Second {
+					t.Errorf("waited %v, want the Retry-After delay", d)
+				}
+			}
+			if got := hc.Stats().Retries; got != int64(tt.wantWaits) {
+				t.Errorf("%d retries counted, want %d", got, tt.wantWaits)
+			}
+		})
+	}
+}
+
+func TestRateLimitWaitStopsOnCancel(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+	}{
+		{name: "primary limit", header: http.Header{
+			"X-Ratelimit-Remaining": {"0"},
+			"X-Ratelimit-Reset":     {fmt.Sprint(time.Now().Add(time.Hour).Unix())},
+		}},
+		{name: "secondary limit", header: http.Header{"Retry-After": {"300"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(http.StatusForbidden)
+			}))
+			defer srv.Close()
+			// the real wait: the client must give up as soon as ctx is cancelled, not after the hour or five minutes
+			hc := New(nil, "token", WithBaseURL(srv.URL))
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			start := time.Now()
+			_, err := hc.ListAllPullRequestReviews(ctx, "acme", "api", 2)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("error %v, want context.Canceled", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("returned after %v, want promptly after the cancel", elapsed)
+			}
+		})
+	}
+}
+
+// pagedPullRequests serves PRs 1 to n of a repository most recently updated first, three per page, PR i created two
+// days before it was last updated, on day i of March 2025, and counts the pages requested.
+func pagedPullRequests(t *testing.T, n int, requests *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ Variables map[string]any }
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Error(err)
+		}
+		*requests++
+		// the cursor is the number of the last PR of the previous page
+		last := n + 1
+		if after, ok := in.Variables["after"].(string); ok {
+			last, _ = strconv.Atoi(after)
+		}
+		var nodes []string
+		number := last - 1
+		for ; number >= 1 && len(nodes) < 3; number-- {
+			nodes = append(nodes, fmt.Sprintf(`{"number":%d,"state":"OPEN","createdAt":%q,"updatedAt":%q,
+				"reviewThreads":{"pageInfo":{}},"closingIssuesReferences":{},"labels":{}}`, number,
+				time.Date(2025, 3, number-2, 9, 0, 0, 0, time.UTC).Format(time.RFC3339),
+				time.Date(2025, 3, number, 9, 0, 0, 0, time.UTC).Format(time.RFC3339)))
+		}
+		fmt.Fprintf(w, `{"data":{"repository":{"pullRequests":{"pageInfo":{"hasNextPage":%t,"endCursor":"%d"},"nodes":[%s]}}}}`,
+			number >= 1, number+1, strings.Join(nodes, ","))
+	}))
+}
+
+func TestListAllPullRequestsSincePages(t *testing.T) {
+	tests := []struct {
+		name        string
+		since       string
+		wantPages   int
+		wantNumbers []int
+	}{
+		// pages of PRs 10-8, 7-5, 4-2 and 1
+		{name: "no since", wantPages: 4, wantNumbers: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{name: "bound in the second page", since: "2025-03-06T00:00:00Z", wantPages: 2, wantNumbers: []int{8, 9, 10}},
+		{name: "bound on a page boundary", since: "2025-03-08T00:00:00Z", wantPages: 2, wantNumbers: []int{10}},
+		{name: "nothing updated since", since: "2025-04-01T00:00:00Z", wantPages: 1},
+		// PR 10 is updated since, but was created before
+		{name: "created before, updated since", since: "2025-03-10T00:00:00Z", wantPages: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			srv := pagedPullRequests(t, 10, &requests)
+			defer srv.Close()
+			hc := New(nil, "token", WithBaseURL(srv.URL))
+			prs, _, err := hc.ListAllPullRequests(context.Background(), "acme", "api", tt.since, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requests != tt.wantPages {
+				t.Errorf("%d pages requested, want %d", requests, tt.wantPages)
+			}
+			var numbers []int
+			for _, pr := range prs {
+				numbers = append(numbers, pr.Number)
+			}
+			if !slices.Equal(numbers, tt.wantNumbers) {
+				t.Errorf("PRs %v, want %v", numbers, tt.wantNumbers)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError is returned for non-2xx GitHub responses so callers can tell bad credentials
+// from a missing repository.
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("github API %s %s returned %d: %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+// IsAuthError reports whether err is a 401, or a 403 that is not a rate limit: the token is invalid,
+// expired, lacks a scope or is not authorized for the organization (SSO).
+func IsAuthError(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusForbidden:
+		return !strings.Contains(strings.ToLower(apiErr.Body), "rate limit")
+	}
+	return false
+}
+
+// IsNotFound reports whether err is a 404 from the GitHub API.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
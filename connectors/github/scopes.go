@@ -0,0 +1,92 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// TokenReport describes what a token can do for the importer.
+type TokenReport struct {
+	// Classic is true for classic PATs, which report their scopes in X-OAuth-Scopes.
+	// Fine-grained tokens do not, so their capabilities are probed with minimal queries instead.
+	Classic  bool
+	Scopes   []string
+	Missing  []string // capabilities the import needs and the token lacks
+	Warnings []string
+}
+
+// scopeImplied lists, for each required scope, the scopes that grant it.
+var scopeImplied = map[string][]string{
+	"read:org":     {"read:org", "write:org", "admin:org"},
+	"read:project": {"read:project", "project"},
+}
+
+// VerifyToken checks up front that the token can read the org (read:org) and, when needProjects is set,
+// ProjectV2 boards (read:project). A classic token is checked from the X-OAuth-Scopes header of a cheap REST
+// call; a fine-grained token is probed with minimal org and projects queries. The returned error is only set
+// when the checks themselves could not run (e.g. invalid token, network).
+func (hc *Client) VerifyToken(ctx context.Context, org string, needProjects bool) (*TokenReport, error) {
+	req, err := hc.newRequest(ctx, http.MethodGet, hc.apiBase+"/rate_limit")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := hc.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	_ = drainAndClose(resp.Body)
+	rep := &TokenReport{}
+	if header, ok := resp.Header["X-Oauth-Scopes"]; ok {
+		rep.Classic = true
+		for _, s := range strings.Split(strings.Join(header, ","), ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				rep.Scopes = append(rep.Scopes, s)
+			}
+		}
+		has := func(scope string) bool {
+			for _, granted := range scopeImplied[scope] {
+				if slices.Contains(rep.Scopes, granted) {
+					return true
+				}
+			}
+			return false
+		}
+		switch {
+		case slices.Contains(rep.Scopes, "repo"):
+		case slices.Contains(rep.Scopes, "public_repo"):
+			rep.Warnings = append(rep.Warnings, "token has public_repo but not repo: private repositories will be skipped")
+		default:
+			rep.Missing = append(rep.Missing, "repo (read repositories, issues and pull requests)")
+		}
+		if !has("read:org") {
+			rep.Missing = append(rep.Missing, "read:org (list the organization repositories)")
+		}
+		if needProjects && !has("read:project") {
+			rep.Missing = append(rep.Missing, "read:project (read ProjectV2 boards and status moves)")
+		}
+		return rep, nil
+	}
+
+	// Fine-grained token: try what the import will do
+	if org != "" {
+		if err := hc.CheckOrg(ctx, org); err != nil {
+			rep.Missing = append(rep.Missing, fmt.Sprintf("organization access to %s (resource owner and Members: read): %v", org, err))
+		} else if needProjects {
+			var data struct {
+				Organization *struct {
+					ProjectsV2 struct {
+						TotalCount int `json:"totalCount"`
+					} `json:"projectsV2"`
+				} `json:"organization"`
+			}
+			q := `query($org:String!){organization(login:$org){projectsV2(first:1){totalCount}}}`
+			if _, err := hc.queryGraphQL(ctx, q, map[string]any{"org": org}, &data); err != nil {
+				rep.Missing = append(rep.Missing, fmt.Sprintf("projects access (organization Projects: read): %v", err))
+			}
+		}
+	}
+	return rep, nil
+}
@@ -0,0 +1,197 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// WithSnapshotDir makes the client save the raw response of every repository, project, issue, timeline, pull
+// request and review page, as received, under dir: repos-<page>.json and projects-<page>.json (the org's
+// projects), then <repo>/projects-<page>.json, <repo>/issues-<page>.json,
+// <repo>/timeline-<number>-<page>.json, <repo>/pr-<page>.json, <repo>/threads-<number>-<page>.json (review
+// threads beyond the first 100 of a PR) and <repo>/reviews-<number>-<page>.json. Pages are numbered from 1 for
+// each listing, so a new run replaces the snapshots of the previous one. Issue descriptions are redacted.
+func WithSnapshotDir(dir string) Option {
+	return func(hc *Client) { hc.snapshotDir = dir }
+}
+
+// snapshot saves the body of resp as page of kind for repo when a snapshot directory is set, and puts an
+// in-memory copy back in resp so the caller can still decode it.
+func (hc *Client) snapshot(resp *http.Response, repo, kind string, page int) error {
+	if hc.snapshotDir == "" {
+		return nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if data, err = redactBodies(data); err != nil {
+		return err
+	}
+	dir := filepath.Join(hc.snapshotDir, repo)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(snapshotPath(hc.snapshotDir, repo, kind, page), data, 0o644)
+}
+
+// redactBodies replaces every issue bodyText of a GraphQL response with as many x as it has characters, so
+// issue descriptions never reach the disk while a replay still gives the same body lengths.
+func redactBodies(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(`"bodyText"`)) {
+		return data, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var walk func(v any)
+	walk = func(v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			for k, c := range t {
+				if s, ok := c.(string); ok && k == "bodyText" {
+					t[k] = strings.Repeat("x", utf8.RuneCountInString(strings.TrimSpace(s)))
+					continue
+				}
+				walk(c)
+			}
+		case []any:
+			for _, c := range t {
+				walk(c)
+			}
+		}
+	}
+	walk(v)
+	return json.Marshal(v)
+}
+
+func snapshotPath(dir, repo, kind string, page int) string {
+	return filepath.Join(dir, repo, fmt.Sprintf("%s-%d.json", kind, page))
+}
+
+// Replay returns a transport answering the listing requests of a Client from the pages saved under dir with
+// WithSnapshotDir, so the import outputs can be rebuilt without calling GitHub (use it with WithTransport).
+// Requests whose page was not saved, and every other request, get a 404.
+func Replay(dir string) http.RoundTripper {
+	return &replay{dir: dir, pages: map[string]int{}}
+}
+
+type replay struct {
+	dir string
+	mu  sync.Mutex
+	// pages maps a listing and the endCursor of one of its pages to the number of the next page
+	pages map[string]int
+}
+
+var reviewsPath = regexp.MustCompile(`/repos/[^/]+/([^/]+)/pulls/(\d+)/reviews$`)
+
+func (rp *replay) RoundTrip(req *http.Request) (*http.Response, error) {
+	repo, kind, page := "", "", 0
+	if m := reviewsPath.FindStringSubmatch(req.URL.Path); m != nil && req.Method == http.MethodGet {
+		repo, kind = m[1], "reviews-"+m[2]
+		page, _ = strconv.Atoi(req.URL.Query().Get("page"))
+		page = max(page, 1)
+	} else if req.Method == http.MethodPost && req.Body != nil {
+		var in struct {
+			Query     string
+			Variables map[string]any
+		}
+		dec := json.NewDecoder(req.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&in); err != nil {
+			return nil, err
+		}
+		repo, _ = in.Variables["name"].(string)
+		switch {
+		// the repository listing also asks for the issue count of each repository: match it before the issues
+		case strings.Contains(in.Query, "repositories("):
+			kind = "repos"
+		case strings.Contains(in.Query, "projectsV2("):
+			kind = "projects"
+		case strings.Contains(in.Query, "timelineItems("):
+			kind = fmt.Sprintf("timeline-%v", in.Variables["number"])
+		case strings.Contains(in.Query, "pullRequests("):
+			kind = "pr"
+		case strings.Contains(in.Query, "reviewThreads("):
+			kind = fmt.Sprintf("threads-%v", in.Variables["number"])
+		case strings.Contains(in.Query, "issues("):
+			kind = "issues"
+		}
+		page = 1
+		// an unknown cursor starts a listing, such as the threads of a PR beyond those of the PR page
+		if after, _ := in.Variables["after"].(string); after != "" {
+			rp.mu.Lock()
+			if p, ok := rp.pages[repo+"/"+kind+"/"+after]; ok {
+				page = p
+			}
+			rp.mu.Unlock()
+		}
+	}
+	if kind == "" || page == 0 {
+		return replayResponse(req, http.StatusNotFound, []byte(`{"message":"not in snapshot"}`)), nil
+	}
+	data, err := os.ReadFile(snapshotPath(rp.dir, repo, kind, page))
+	if os.IsNotExist(err) {
+		return replayResponse(req, http.StatusNotFound, []byte(`{"message":"not in snapshot"}`)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var body any
+	if json.Unmarshal(data, &body) == nil {
+		if cursor := endCursor(body); cursor != "" {
+			rp.mu.Lock()
+			rp.pages[repo+"/"+kind+"/"+cursor] = page + 1
+			rp.mu.Unlock()
+		}
+	}
+	return replayResponse(req, http.StatusOK, data), nil
+}
+
+func replayResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}
+}
+
+// endCursor returns the endCursor of the first pageInfo found in a decoded GraphQL response.
+func endCursor(v any) string {
+	switch t := v.(type) {
+	case map[string]any:
+		if pi, ok := t["pageInfo"].(map[string]any); ok {
+			s, _ := pi["endCursor"].(string)
+			return s
+		}
+		for _, c := range t {
+			if s := endCursor(c); s != "" {
+				return s
+			}
+		}
+	case []any:
+		for _, c := range t {
+			if s := endCursor(c); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
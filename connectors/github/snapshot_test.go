@@ -0,0 +1,80 @@
+package github
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayRepos(t *testing.T) {
+	dir := t.TempDir()
+	pages := map[string]string{
+		"repos-1.json": `{"data":{"organization":{"repositories":{"pageInfo":{"hasNextPage":true,"endCursor":"c1"},` +
+			`"nodes":[{"name":"api","owner":{"login":"acme"},"hasIssuesEnabled":true,"issues":{"totalCount":2}}]}}}}`,
+		"repos-2.json": `{"data":{"organization":{"repositories":{"pageInfo":{"hasNextPage":false,"endCursor":"c2"},` +
+			`"nodes":[{"name":"web","owner":{"login":"acme"},"hasIssuesEnabled":false,"issues":{"totalCount":0}}]}}}}`,
+	}
+	for name, body := range pages {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hc := New(nil, "", WithTransport(Replay(dir)))
+	repos, err := hc.ListAllRepos(context.Background(), "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 2 || repos[0].Name != "api" || repos[1].Name != "web" {
+		t.Fatalf("replayed repos %+v, want api then web", repos)
+	}
+	if !repos[0].HasIssuesEnabled || repos[0].IssueCount != 2 || repos[1].HasIssuesEnabled {
+		t.Errorf("issue settings not replayed: %+v", repos)
+	}
+}
+
+func TestReplayProjects(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "api"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pages := map[string]string{
+		"projects-1.json": `{"data":{"organization":{"projectsV2":{"pageInfo":{"hasNextPage":true,"endCursor":"p1"},` +
+			`"nodes":[{"fullDatabaseId":"11","title":"Roadmap"}]}}}}`,
+		"projects-2.json": `{"data":{"organization":{"projectsV2":{"pageInfo":{"hasNextPage":false,"endCursor":"p2"},` +
+			`"nodes":[{"fullDatabaseId":"12","title":"Support"}]}}}}`,
+		filepath.Join("api", "projects-1.json"): `{"data":{"repository":{"projectsV2":{"pageInfo":{"hasNextPage":false,"endCursor":"r1"},` +
+			`"nodes":[{"fullDatabaseId":"21","title":"API board"}]}}}}`,
+	}
+	for name, body := range pages {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hc := New(nil, "", WithTransport(Replay(dir)))
+	tests := []struct {
+		name string
+		list func() (map[string]string, error)
+		want map[string]string
+	}{
+		{"org", func() (map[string]string, error) { return hc.ListOrgProjects(context.Background(), "acme") },
+			map[string]string{"11": "Roadmap", "12": "Support"}},
+		{"repository", func() (map[string]string, error) { return hc.ListRepoProjects(context.Background(), "acme", "api") },
+			map[string]string{"21": "API board"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.list()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("replayed projects %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := hc.ListRepoProjects(context.Background(), "acme", "web"); !IsNotFound(err) {
+		t.Errorf("projects of a repository without snapshot: error %v, want not found", err)
+	}
+}
@@ -0,0 +1,119 @@
+package github
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats summarizes the API usage of a Client since it was created.
+type Stats struct {
+	RESTCalls    int64
+	GraphQLCalls int64
+	// GraphQLPoints is the rate limit budget spent, from the X-RateLimit-Used headers of the GraphQL responses.
+	// Other clients sharing the token during the run are counted too.
+	GraphQLPoints   int64
+	RateLimitSleep  time.Duration
+	BytesDownloaded int64
+	Errors          int64
+	Retries         int64
+}
+
+// usage holds the counters behind Stats. Clients are shared by the import workers, so counters are atomic.
+type usage struct {
+	restCalls, graphQLCalls atomic.Int64
+	sleepNanos              atomic.Int64
+	bytes                   atomic.Int64
+	errors, retries         atomic.Int64
+
+	mu sync.Mutex
+	// used is the lowest and highest X-RateLimit-Used seen per GraphQL rate limit window (keyed by reset time)
+	used map[string][2]int64
+}
+
+// Stats returns the API usage of the client so far.
+func (hc *Client) Stats() Stats {
+	u := &hc.usage
+	s := Stats{
+		RESTCalls:       u.restCalls.Load(),
+		GraphQLCalls:    u.graphQLCalls.Load(),
+		RateLimitSleep:  time.Duration(u.sleepNanos.Load()),
+		BytesDownloaded: u.bytes.Load(),
+		Errors:          u.errors.Load(),
+		Retries:         u.retries.Load(),
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, mm := range u.used {
+		// the lowest value already includes the cost of the first query seen in the window
+		s.GraphQLPoints += mm[1] - mm[0] + 1
+	}
+	return s
+}
+
+// sleep waits d, or until ctx is done, and records the time waited as rate limit sleeping time.
+func (hc *Client) sleep(ctx context.Context, d time.Duration) error {
+	start := time.Now()
+	defer func() { hc.usage.sleepNanos.Add(int64(time.Since(start))) }()
+	return hc.wait(ctx, d)
+}
+
+// waitFor waits d, or until ctx is done. It is the wait of the clients built by New.
+func waitFor(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// countCall records one request to url.
+func (hc *Client) countCall(url string) {
+	if url == hc.graphqlURL {
+		hc.usage.graphQLCalls.Add(1)
+	} else {
+		hc.usage.restCalls.Add(1)
+	}
+}
+
+// countResponse records the rate limit usage of a GraphQL response and wraps its body to count downloaded bytes.
+func (hc *Client) countResponse(resp *http.Response) {
+	if resp.Header.Get("X-RateLimit-Resource") == "graphql" {
+		if used, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Used"), 10, 64); err == nil {
+			reset := resp.Header.Get("X-RateLimit-Reset")
+			u := &hc.usage
+			u.mu.Lock()
+			if u.used == nil {
+				u.used = map[string][2]int64{}
+			}
+			mm, ok := u.used[reset]
+			if !ok {
+				mm = [2]int64{used, used}
+			}
+			mm[0] = min(mm[0], used)
+			mm[1] = max(mm[1], used)
+			u.used[reset] = mm
+			u.mu.Unlock()
+		}
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, n: &hc.usage.bytes}
+}
+
+// countingBody adds the bytes read from a response body to n.
+type countingBody struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,32 @@
+// Package jsonl writes import outputs as JSON Lines: one JSON object per line, for jq and streaming loaders.
+package jsonl
+
+import (
+	"bufio"
+	gh "cto-stats/domain/github"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// WriteIssueReports writes one IssueReport per line to path, with its status, project and custom field
+// histories, replacing the file.
+func WriteIssueReports(path string, reports []gh.IssueReport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, r := range reports {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,247 @@
+// Package notify posts the outcome of a run (import, calculate) to a chat or generic webhook, e.g. a Slack
+// incoming webhook, so that a scheduled pipeline reports its headline numbers or its failure.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"cto-stats/connectors/config"
+	"cto-stats/connectors/useragent"
+)
+
+// Formats of the posted body (notifications.format).
+const (
+	FormatSlack = "slack" // {"text": ...} with Slack mrkdwn, for Slack incoming webhooks and compatible chats
+	FormatJSON  = "json"  // the Message as JSON, for generic webhooks
+)
+
+// WebhookEnv is the environment variable holding the webhook URL; it wins over notifications.webhook_url, so the
+// URL, a secret, can stay out of the config file.
+const WebhookEnv = "NOTIFY_WEBHOOK_URL"
+
+const (
+	timeout  = 5 * time.Second // of each attempt
+	attempts = 3
+	backoff  = time.Second // before the second attempt, doubled before each next one
+)
+
+// Field is a named value of a message, e.g. Throughput last week: 12.
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Message is what is posted: a title, a status (success or failure), named values and a link.
+type Message struct {
+	Title  string  `json:"title"`
+	Status string  `json:"status"`
+	Fields []Field `json:"fields,omitempty"`
+	Link   string  `json:"link,omitempty"`
+}
+
+// Notifier posts messages to one webhook.
+type Notifier struct {
+	url       string
+	format    string
+	dashboard string
+	client    *http.Client
+	backoff   time.Duration
+}
+
+// New returns a Notifier posting to webhookURL in format (FormatSlack when empty). dashboard, when set, is linked
+// from every message.
+func New(webhookURL, format, dashboard string) (*Notifier, error) {
+	if err := validateURL(webhookURL); err != nil {
+		return nil, fmt.Errorf("notify: webhook url: %w", err)
+	}
+	format, err := normalizeFormat(format)
+	if err != nil {
+		return nil, fmt.Errorf("notify: %w", err)
+	}
+	return &Notifier{
+		url:       webhookURL,
+		format:    format,
+		dashboard: dashboard,
+		client:    &http.Client{Timeout: timeout, Transport: useragent.Transport(nil)},
+		backoff:   backoff,
+	}, nil
+}
+
+// FromConfig returns the Notifier of the notifications section, with the webhook URL of WebhookEnv when set, or
+// nil when no webhook URL is configured.
+func FromConfig(c config.Notifications) (*Notifier, error) {
+	webhookURL := strings.TrimSpace(os.Getenv(WebhookEnv))
+	if webhookURL == "" {
+		webhookURL = strings.TrimSpace(c.WebhookURL)
+	}
+	if webhookURL == "" {
+		return nil, nil
+	}
+	return New(webhookURL, c.Format, c.DashboardURL)
+}
+
+// Validate reports an unknown format and webhook or dashboard URLs that are not http(s), as config errors.
+func Validate(c config.Notifications) error {
+	var errs []error
+	if _, err := normalizeFormat(c.Format); err != nil {
+		errs = append(errs, fmt.Errorf("notifications.format: %w", err))
+	}
+	if c.WebhookURL != "" {
+		if err := validateURL(c.WebhookURL); err != nil {
+			errs = append(errs, fmt.Errorf("notifications.webhook_url: %w", err))
+		}
+	}
+	if c.DashboardURL != "" {
+		if err := validateURL(c.DashboardURL); err != nil {
+			errs = append(errs, fmt.Errorf("notifications.dashboard_url: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// normalizeFormat returns format in lower case, FormatSlack when empty.
+func normalizeFormat(format string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(format)); f {
+	case "":
+		return FormatSlack, nil
+	case FormatSlack, FormatJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (expected slack or json)", format)
+	}
+}
+
+// validateURL checks that u is an absolute http(s) URL.
+func validateURL(u string) error {
+	p, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if (p.Scheme != "http" && p.Scheme != "https") || p.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", u)
+	}
+	return nil
+}
+
+// Success returns the message of a run of command that went well, with its headline values.
+func Success(command string, fields []Field) Message {
+	return Message{Title: "cto-stats " + command + " succeeded", Status: "success", Fields: fields}
+}
+
+// Failure returns the message of a run that failed in phase (e.g. import or calculate) with err.
+func Failure(phase string, err error) Message {
+	return Message{Title: "cto-stats " + phase + " failed", Status: "failure", Fields: []Field{
+		{Name: "Phase", Value: phase},
+		{Name: "Error", Value: err.Error()},
+	}}
+}
+
+// Post sends m with the Notifier of c, if any. Errors are logged, not returned: a notification never changes the
+// outcome of the run it reports.
+func Post(c config.Notifications, m Message) {
+	n, err := FromConfig(c)
+	if err == nil && n != nil {
+		err = n.Send(context.Background(), m)
+		if err == nil {
+			slog.Info("notify.sent", "title", m.Title)
+		}
+	}
+	if err != nil {
+		slog.Warn("notify.error", "error", err)
+	}
+}
+
+// Send posts m, with the dashboard link when m has none. Network errors, 429 and 5xx answers are retried, up to 3
+// attempts of 5 seconds each.
+func (n *Notifier) Send(ctx context.Context, m Message) error {
+	if m.Link == "" {
+		m.Link = n.dashboard
+	}
+	body, err := n.encode(m)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	wait := n.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == attempts {
+			return fmt.Errorf("notify: %w", err)
+		}
+		slog.Warn("notify.retry", "attempt", attempt, "error", err, "wait", wait)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("notify: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// post makes one attempt, telling whether a failure is worth retrying.
+func (n *Notifier) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("webhook answered %d %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+func (n *Notifier) encode(m Message) ([]byte, error) {
+	if n.format == FormatJSON {
+		return json.Marshal(m)
+	}
+	return json.Marshal(struct {
+		Text string `json:"text"`
+	}{Text: slackText(m)})
+}
+
+// slackText renders m in Slack mrkdwn: the title in bold with a status emoji, one line per field and the link.
+func slackText(m Message) string {
+	var b strings.Builder
+	icon := ":white_check_mark:"
+	if m.Status == "failure" {
+		icon = ":x:"
+	}
+	fmt.Fprintf(&b, "%s *%s*", icon, slackEscape(m.Title))
+	for _, f := range m.Fields {
+		v := slackEscape(f.Value)
+		if strings.Contains(v, "\n") {
+			v = "\n```" + v + "```"
+		}
+		fmt.Fprintf(&b, "\n• %s: %s", slackEscape(f.Name), v)
+	}
+	if m.Link != "" {
+		fmt.Fprintf(&b, "\n<%s|Open the dashboard>", m.Link)
+	}
+	return b.String()
+}
+
+// slackEscape escapes the characters Slack reserves for links and mentions.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cto-stats/connectors/config"
+)
+
+func TestSend(t *testing.T) {
+	msg := Message{Title: "cto-stats import succeeded", Status: "success", Fields: []Field{{Name: "Throughput last week", Value: "12 <issues>"}}}
+	tests := []struct {
+		name         string
+		format       string
+		dashboard    string
+		statuses     []int // answered in order, 200 once exhausted
+		wantAttempts int
+		wantErr      bool
+		wantBody     string
+	}{
+		{
+			name:         "slack",
+			dashboard:    "https://dash.example.com",
+			wantAttempts: 1,
+			wantBody:     `{"text":":white_check_mark: *cto-stats import succeeded*\n• Throughput last week: 12 &lt;issues&gt;\n<https://dash.example.com|Open the dashboard>"}`,
+		},
+		{
+			name:         "json",
+			format:       "JSON",
+			wantAttempts: 1,
+			wantBody:     `{"title":"cto-stats import succeeded","status":"success","fields":[{"name":"Throughput last week","value":"12 <issues>"}]}`,
+		},
+		{name: "5xx retried", statuses: []int{http.StatusBadGateway}, wantAttempts: 2},
+		{name: "429 retried", statuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}, wantAttempts: 3},
+		{name: "gives up after 3 attempts", statuses: []int{500, 500, 500, 500}, wantAttempts: 3, wantErr: true},
+		{name: "4xx not retried", statuses: []int{http.StatusNotFound}, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu     sync.Mutex
+				bodies []string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				bodies = append(bodies, string(b))
+				n := len(bodies)
+				mu.Unlock()
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("%s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+				}
+				if n <= len(tt.statuses) {
+					http.Error(w, "unavailable", tt.statuses[n-1])
+				}
+			}))
+			defer srv.Close()
+			n, err := New(srv.URL, tt.format, tt.dashboard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n.backoff = time.Millisecond
+			err = n.Send(context.Background(), msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if len(bodies) != tt.wantAttempts {
+				t.Errorf("%d attempts, want %d", len(bodies), tt.wantAttempts)
+			}
+			if tt.wantBody != "" {
+				var got, want any
+				if err := json.Unmarshal([]byte(bodies[0]), &got); err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Unmarshal([]byte(tt.wantBody), &want); err != nil {
+					t.Fatal(err)
+				}
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(want)
+				if string(gotJSON) != string(wantJSON) {
+					t.Errorf("posted %s, want %s", bodies[0], tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func TestSendTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	n, err := New(srv.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.client.Timeout = 20 * time.Millisecond
+	n.backoff = time.Millisecond
+	start := time.Now()
+	if err := n.Send(context.Background(), Failure("import", errors.New("boom"))); err == nil {
+		t.Fatal("no error from a webhook that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("gave up after %v, want after 3 timed out attempts", elapsed)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		cfg     config.Notifications
+		wantURL string // "" for no notifier
+		wantErr bool
+	}{
+		{name: "not configured"},
+		{name: "config", cfg: config.Notifications{WebhookURL: "https://hooks.example.com/a"}, wantURL: "https://hooks.example.com/a"},
+		{name: "environment wins", env: " https://hooks.example.com/env ", cfg: config.Notifications{WebhookURL: "https://hooks.example.com/a"}, wantURL: "https://hooks.example.com/env"},
+		{name: "not http", cfg: config.Notifications{WebhookURL: "ftp://hooks.example.com"}, wantErr: true},
+		{name: "unknown format", cfg: config.Notifications{WebhookURL: "https://hooks.example.com/a", Format: "teams"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(WebhookEnv, tt.env)
+			n, err := FromConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			url := ""
+			if n != nil {
+				url = n.url
+			}
+			if url != tt.wantURL {
+				t.Errorf("notifier of %q, want %q", url, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestSlackTextOfFailure(t *testing.T) {
+	got := slackText(Failure("calculate", errors.New("issue.csv: line 3\nbad timestamp")))
+	want := ":x: *cto-stats calculate failed*\n• Phase: calculate\n• Error: \n```issue.csv: line 3\nbad timestamp```"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+// Package sheets writes tables into the tabs of a Google spreadsheet through the Sheets API.
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"cto-stats/connectors/gcp"
+	"cto-stats/connectors/useragent"
+)
+
+const scope = "https://www.googleapis.com/auth/spreadsheets"
+
+// Client writes to spreadsheets shared with the account of its credentials.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// Tab is a table to write into the tab called Title, header row first.
+type Tab struct {
+	Title string
+	Rows  [][]string
+}
+
+// New returns a client authenticated with the GCP credentials also used for cloud spending:
+// serviceAccountJSON (raw JSON or a path) or else Application Default Credentials. It fails when neither is
+// available.
+func New(ctx context.Context, serviceAccountJSON string) (*Client, error) {
+	creds, err := gcp.Credentials(ctx, serviceAccountJSON, scope)
+	if err != nil {
+		return nil, fmt.Errorf("sheets: no Google credentials: %w", err)
+	}
+	hc := oauth2.NewClient(ctx, creds.TokenSource)
+	hc.Timeout = 60 * time.Second
+	hc.Transport = useragent.Transport(hc.Transport)
+	return &Client{httpClient: hc, baseURL: "https://sheets.googleapis.com/v4"}, nil
+}
+
+// ReplaceTabs writes each tab into spreadsheetID, creating the missing tabs and clearing the previous contents
+// of the existing ones. Other tabs are left untouched. Cells that parse as numbers are written as numbers.
+func (c *Client) ReplaceTabs(ctx context.Context, spreadsheetID string, tabs []Tab) error {
+	base := c.baseURL + "/spreadsheets/" + url.PathEscape(spreadsheetID)
+
+	var meta struct {
+		Sheets []struct {
+			Properties struct {
+				Title string `json:"title"`
+			} `json:"properties"`
+		} `json:"sheets"`
+	}
+	if err := c.call(ctx, http.MethodGet, base+"?fields=sheets.properties.title", nil, &meta); err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, s := range meta.Sheets {
+		existing[s.Properties.Title] = true
+	}
+
+	var add []any
+	var ranges []string
+	var data []any
+	for _, t := range tabs {
+		if !existing[t.Title] {
+			add = append(add, map[string]any{"addSheet": map[string]any{"properties": map[string]any{"title": t.Title}}})
+		}
+		ranges = append(ranges, quoteTitle(t.Title))
+		values := make([][]any, len(t.Rows))
+		for i, row := range t.Rows {
+			values[i] = make([]any, len(row))
+			for j, v := range row {
+				values[i][j] = cell(v)
+			}
+		}
+		data = append(data, map[string]any{"range": quoteTitle(t.Title) + "!A1", "values": values})
+	}
+	if len(add) > 0 {
+		if err := c.call(ctx, http.MethodPost, base+":batchUpdate", map[string]any{"requests": add}, nil); err != nil {
+			return err
+		}
+	}
+	if err := c.call(ctx, http.MethodPost, base+"/values:batchClear", map[string]any{"ranges": ranges}, nil); err != nil {
+		return err
+	}
+	return c.call(ctx, http.MethodPost, base+"/values:batchUpdate", map[string]any{"valueInputOption": "RAW", "data": data}, nil)
+}
+
+// call sends in as JSON and decodes the response into out when not nil.
+func (c *Client) call(ctx context.Context, method, u string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sheets: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("sheets: %s returned %d: %s", method, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// quoteTitle quotes a tab title for A1 notation.
+func quoteTitle(title string) string {
+	return "'" + strings.ReplaceAll(title, "'", "''") + "'"
+}
+
+// cell returns v as a number when it is one, so the sheet can chart and sum it.
+func cell(v string) any {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return v
+	}
+	return f
+}
@@ -0,0 +1,44 @@
+// Package useragent holds the User-Agent sent by the GitHub and cloud connectors, so API providers can attribute
+// the traffic (GitHub applies stricter limits to requests without a descriptive one).
+package useragent
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"cto-stats/domain/buildinfo"
+)
+
+var override atomic.Value // string
+
+// Set replaces the User-Agent (config user_agent). An empty value restores the default.
+func Set(ua string) { override.Store(strings.TrimSpace(ua)) }
+
+// Get returns the User-Agent: the configured one, or cto-stats/<version>.
+func Get() string {
+	if ua, _ := override.Load().(string); ua != "" {
+		return ua
+	}
+	return "cto-stats/" + buildinfo.Get().Version
+}
+
+// Transport wraps base (http.DefaultTransport when nil) to set the User-Agent on requests that have none.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripper{base: base}
+}
+
+type roundTripper struct{ base http.RoundTripper }
+
+func (rt roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") != "" {
+		return rt.base.RoundTrip(req)
+	}
+	// RoundTrippers must not modify the caller's request
+	r := req.Clone(req.Context())
+	r.Header.Set("User-Agent", Get())
+	return rt.base.RoundTrip(r)
+}
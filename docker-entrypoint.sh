@@ -9,6 +9,9 @@ ENV_FILE=/etc/cron.env
   if [ -n "${GITHUB_TOKEN:-}" ]; then
     echo "export GITHUB_TOKEN=\"${GITHUB_TOKEN}\""
   fi
+  if [ -n "${NOTIFY_WEBHOOK_URL:-}" ]; then
+    echo "export NOTIFY_WEBHOOK_URL=\"${NOTIFY_WEBHOOK_URL}\""
+  fi
 } > "$ENV_FILE"
 chmod 600 "$ENV_FILE"
 
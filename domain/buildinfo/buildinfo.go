@@ -0,0 +1,98 @@
+// Package buildinfo identifies the build of the binary, so a data directory can be traced back to the version
+// that produced it.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Version, Commit and Date are set at build time, e.g.
+// -ldflags "-X cto-stats/domain/buildinfo.Version=1.4.0 -X cto-stats/domain/buildinfo.Commit=$(git rev-parse HEAD)
+// -X cto-stats/domain/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)".
+// When empty, they are taken from the module and VCS information embedded by go build.
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+// Info is the build of the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build of the running binary. Version is "dev" when neither -ldflags nor a tagged module
+// version tell it; Commit ends with "-dirty" when built from a modified work tree.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = bi.GoVersion
+		// untagged builds get a v0.0.0-<time>-<commit> pseudo-version, which says nothing the commit does not
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" && !strings.HasPrefix(bi.Main.Version, "v0.0.0-") {
+			info.Version = bi.Main.Version
+		}
+		var revision, modified string
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value
+			}
+		}
+		if info.Commit == "" && revision != "" {
+			info.Commit = revision
+			if modified == "true" {
+				info.Commit += "-dirty"
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// String returns the version followed by the short commit and the date when known, e.g. "1.4.0 (3e3769c, 2025-06-02T10:00:00Z)".
+func (i Info) String() string {
+	var extra []string
+	if i.Commit != "" {
+		c, dirty := strings.CutSuffix(i.Commit, "-dirty")
+		if len(c) > 7 {
+			c = c[:7]
+		}
+		if dirty {
+			c += "-dirty"
+		}
+		extra = append(extra, c)
+	}
+	if i.Date != "" {
+		extra = append(extra, i.Date)
+	}
+	if len(extra) == 0 {
+		return i.Version
+	}
+	return fmt.Sprintf("%s (%s)", i.Version, strings.Join(extra, ", "))
+}
+
+// Major returns the major number of a semantic version such as "1.4.0" or "v2.0.1-rc1", and false for "dev" or
+// anything else that is not one.
+func Major(version string) (int, bool) {
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	head, _, _ := strings.Cut(v, ".")
+	n, err := strconv.Atoi(head)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
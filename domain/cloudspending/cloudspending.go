@@ -9,7 +9,14 @@ type CostRecord struct {
 	Month    time.Time // First day of the month
 	Cost     float64   // Cost in the billing currency
 	Currency string    // Currency code (e.g., "USD", "EUR")
-	RawData  string    // JSON string of raw response data for debugging
+	// Dimension is the billing scope the cost was fetched for (Azure subscription ID, GCP billing account)
+	Dimension string
+	// Project is the GCP project the cost was incurred in, empty for Azure
+	Project string
+	// Environment is the label of the subscription or GCP project (cloud_spending.environments), empty when
+	// unmapped
+	Environment string
+	RawData     string // JSON string of raw response data for debugging
 }
 
 // MonthlyCost represents aggregated cost per provider per month
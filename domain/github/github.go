@@ -9,12 +9,20 @@ type Repo struct {
 		Login string `json:"login"`
 	} `json:"owner"`
 	Private bool `json:"private"`
+	// HasIssuesEnabled and IssueCount (open and closed) let import skip repositories without issues
+	HasIssuesEnabled bool `json:"has_issues"`
+	IssueCount       int  `json:"issue_count"`
+	// PushedAt and UpdatedAt let import -active-only skip dormant repositories
+	PushedAt  time.Time `json:"pushed_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// Issue represents a GitHub issue (excluding PRs which have PullRequest != nil)
+// Issue represents a GitHub issue. Pull requests are never returned as issues: the GraphQL issues
+// connection only yields Issue nodes (PRs are a separate type, although they share the number sequence of the repo).
 type Issue struct {
 	Number              int                  `json:"number"`
 	Title               string               `json:"title"`
+	BodyLength          int                  `json:"body_length"` // characters of the body; the body itself is not kept
 	State               string               `json:"state"`
 	HTMLURL             string               `json:"html_url"`
 	CreatedAt           time.Time            `json:"created_at"`
@@ -24,10 +32,16 @@ type Issue struct {
 	Assignees           []User               `json:"assignees"`
 	Labels              []Label              `json:"labels"`
 	Type                string               `json:"type"`
-	PullRequest         *struct{}            `json:"pull_request"`
+	Milestone           *Milestone           `json:"milestone,omitempty"`
 	ProjectCustomFields []ProjectCustomField `json:"project_custom_fields,omitempty"`
 }
 
+// Milestone is the milestone an issue is planned in. DueOn is nil when the milestone has no due date.
+type Milestone struct {
+	Title string     `json:"title"`
+	DueOn *time.Time `json:"due_on,omitempty"`
+}
+
 type User struct {
 	Login string `json:"login"`
 }
@@ -49,6 +63,25 @@ type PullRequest struct {
 	ClosedAt  *time.Time `json:"closed_at"`
 	MergedAt  *time.Time `json:"merged_at"`
 	User      *User      `json:"user"`
+	// Size and review activity
+	Additions      int `json:"additions"`
+	Deletions      int `json:"deletions"`
+	ChangedFiles   int `json:"changed_files"`
+	ReviewThreads  int `json:"review_threads"`
+	ReviewComments int `json:"review_comments"`
+	// Review threads opened before the merge (all of them for unmerged PRs), and how many of them are resolved
+	ThreadsTotal    int `json:"threads_total"`
+	ThreadsResolved int `json:"threads_resolved"`
+	// Issues the PR closes when merged (closing keywords or the development sidebar)
+	ClosingIssues []IssueRef `json:"closing_issues"`
+	Labels        []Label    `json:"labels"`
+}
+
+// IssueRef identifies an issue, possibly in another repository than the one referencing it.
+type IssueRef struct {
+	Org    string `json:"org"`
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
 }
 
 // PullRequestReview represents a review on a PR
@@ -72,6 +105,8 @@ type TimelineEvent struct {
 	// For moved events, GitHub often provides these names
 	ProjectColumnName         string `json:"project_column_name"`
 	PreviousProjectColumnName string `json:"previous_project_column_name"`
+	// Label is the label added or removed by labeled and unlabeled events
+	Label string `json:"label,omitempty"`
 }
 
 type ProjectCard struct {
@@ -119,12 +154,36 @@ type IssueReport struct {
 	CreatedAt           time.Time            `json:"created_at"`
 	ClosedAt            *time.Time           `json:"closed_at,omitempty"`
 	Committer           string               `json:"committer,omitempty"`
+	Milestone           string               `json:"milestone,omitempty"`
+	MilestoneDueOn      *time.Time           `json:"milestone_due_on,omitempty"`
+	BodyLength          int                  `json:"body_length"`
+	HasDescription      bool                 `json:"has_description"`
+	SizeWeight          float64              `json:"size_weight"`
+	BugSince            *time.Time           `json:"bug_since,omitempty"`
+	BugPeriods          []BugPeriod          `json:"bug_periods,omitempty"`
+	Severity            string               `json:"severity,omitempty"`
+	SeverityChanges     []SeverityChange     `json:"severity_changes,omitempty"`
+	Epic                string               `json:"epic,omitempty"`
+	OtherEpics          []string             `json:"other_epics,omitempty"`
 	StatusHistory       []StatusEvent        `json:"status_history"`
 	ProjectHistory      []ProjectMoveEvent   `json:"project_history"`
 	CurrentProjects     []CurrentProject     `json:"current_projects"`
 	ProjectCustomFields []ProjectCustomField `json:"project_custom_fields,omitempty"`
 }
 
+// BugPeriod is a time range during which an issue counted as a bug; Until is nil while it still does.
+type BugPeriod struct {
+	Since time.Time  `json:"since"`
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// SeverityChange is a change of the severity label of an issue (github.severity_labels); Severity is empty when
+// its last severity label was removed.
+type SeverityChange struct {
+	At       time.Time `json:"at"`
+	Severity string    `json:"severity"`
+}
+
 type ProjectCustomField struct {
 	ProjectID   string `json:"project_id"`
 	ProjectName string `json:"project_name"`
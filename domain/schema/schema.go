@@ -0,0 +1,758 @@
+// Package schema is the registry of the CSV files written by import and calculate. Writers take their headers
+// from it (see Headers), so the documentation served by the schema command and /api/schema follows the files.
+package schema
+
+import "fmt"
+
+// Column types.
+const (
+	String   = "string"
+	Int      = "int"
+	Float    = "float"
+	Bool     = "bool"
+	DateTime = "datetime" // RFC3339, UTC
+	Date     = "date"     // YYYY-MM-DD
+	Month    = "month"    // YYYY-MM
+	Year     = "year"     // ISO year
+	Week     = "week"     // ISO week, two digits
+	Quarter  = "quarter"  // 2025-Q3 or FY26-Q1
+)
+
+// Column describes one CSV column. Optional columns are left empty when there is no value.
+type Column struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Optional    bool   `json:"optional,omitempty"`
+}
+
+// File describes one CSV file of the data directory and the command that writes it.
+type File struct {
+	Name        string   `json:"name"`
+	WrittenBy   string   `json:"written_by"`
+	Description string   `json:"description"`
+	Columns     []Column `json:"columns"`
+}
+
+func col(name, typ, desc string) Column { return Column{Name: name, Type: typ, Description: desc} }
+
+func opt(name, typ, desc string) Column {
+	return Column{Name: name, Type: typ, Description: desc, Optional: true}
+}
+
+var (
+	yearWeek = []Column{col("year", Year, "ISO year"), col("week", Week, "ISO week")}
+
+	stockCounts = []Column{
+		col("opened_bugs", Int, "open bugs"),
+		col("opened_bugs_customer_facing", Int, "open bugs whose source is customer facing"),
+		col("opened_bugs_internal", Int, "open bugs whose source is internal"),
+		col("opened_bugs_dev_process", Int, "open bugs whose source is the development process"),
+		col("in_backlogs", Int, "issues in the backlog"),
+		col("in_ready", Int, "issues ready for development"),
+		col("in_dev", Int, "issues in development"),
+		col("in_review", Int, "issues in review"),
+		col("in_qa", Int, "issues in QA"),
+		col("waiting_to_prod", Int, "issues waiting to go to production"),
+	}
+
+	stocksColumns = concat([]Column{
+		col("org", String, "organization"),
+		col("project_id", String, "project id, empty for issues in no project"),
+		col("project_name", String, "project name"),
+	}, stockCounts)
+)
+
+func concat(parts ...[]Column) []Column {
+	var res []Column
+	for _, p := range parts {
+		res = append(res, p...)
+	}
+	return res
+}
+
+// Files lists every CSV file of the data directory.
+var Files = []File{
+	// import --issues
+	{Name: "repository.csv", WrittenBy: "import", Description: "Repositories of the organization.", Columns: []Column{
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("owner", String, "repository owner login"),
+		col("private", Bool, "whether the repository is private"),
+		col("has_issues", Bool, "whether issues are enabled"),
+		col("issue_count", Int, "open and closed issues at import time"),
+	}},
+	{Name: "project.csv", WrittenBy: "import", Description: "ProjectV2 boards seen on the imported issues.", Columns: []Column{
+		col("project_id", String, "project id"),
+		col("project_name", String, "project title"),
+	}},
+	{Name: "issue.csv", WrittenBy: "import", Description: "One row per issue.", Columns: []Column{
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("number", Int, "issue number"),
+		col("title", String, "issue title"),
+		col("url", String, "issue URL"),
+		col("state", String, "open or closed"),
+		opt("type", String, "GitHub issue type, or derived from labels"),
+		col("is_bug", Bool, "whether the issue is a bug"),
+		col("creator", String, "login of the author"),
+		opt("assignees", String, "assignee logins separated by ;"),
+		col("created_at", DateTime, "creation time"),
+		opt("closed_at", DateTime, "closing time"),
+		opt("committer", String, "login of the person who closed the issue"),
+		opt("milestone", String, "milestone title"),
+		opt("milestone_due_on", DateTime, "milestone due date"),
+		opt("body_length", Int, "characters of the issue description; the description itself is never written"),
+		opt("has_description", Bool, "whether body_length exceeds the import description threshold"),
+		opt("size_weight", Float, "weight of the size label of the issue (github.size_weights), 1 without one"),
+		opt("bug_since", DateTime, "when the issue became a bug: its creation for a bug issue type, else when it first got a bug label (github.bug_labels)"),
+		opt("bug_periods", String, "periods the issue was a bug, as since/until pairs separated by ;, until empty while it still is"),
+		opt("severity", String, "most severe severity label of the issue (github.severity_labels)"),
+		opt("severity_changes", String, "severity changes from the label history, as at/severity pairs separated by ;, severity empty once the last severity label is removed"),
+		opt("epic", String, "epic of the issue from its github.epic_label_prefix labels, the first alphabetically when it has several"),
+		opt("other_epics", String, "the other epics of the issue separated by ;, left out of epic_progress.csv"),
+	}},
+	{Name: "issue_status_event.csv", WrittenBy: "import", Description: "Open, close and reopen events of the issues.", Columns: []Column{
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("number", Int, "issue number"),
+		col("type", String, "opened, closed or reopened"),
+		col("at", DateTime, "event time"),
+		opt("by", String, "login of the actor"),
+	}},
+	{Name: "issue_project_event.csv", WrittenBy: "import", Description: "Project board events of the issues.", Columns: []Column{
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("number", Int, "issue number"),
+		col("project_id", String, "project id"),
+		col("project_name", String, "project title"),
+		opt("from_column", String, "column before the move"),
+		opt("to_column", String, "column after the move"),
+		col("at", DateTime, "event time"),
+		opt("by", String, "login of the actor"),
+		col("type", String, "added, moved or removed"),
+	}},
+	{Name: "issue_project_custom_field.csv", WrittenBy: "import", Description: "Custom field values of the issues on their projects.", Columns: []Column{
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("number", Int, "issue number"),
+		col("project_id", String, "project id"),
+		col("project_name", String, "project title"),
+		col("field_name", String, "custom field name"),
+		col("field_value", String, "custom field value"),
+	}},
+	{Name: "issue_current_project.csv", WrittenBy: "import", Description: "Board column each issue currently sits in, per project.", Columns: []Column{
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("number", Int, "issue number"),
+		col("project_id", String, "project id"),
+		col("project_name", String, "project title"),
+		opt("column_name", String, "current column"),
+	}},
+	// import --pr
+	{Name: "pr.csv", WrittenBy: "import", Description: "One row per pull request.", Columns: []Column{
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("number", Int, "pull request number"),
+		col("title", String, "pull request title"),
+		col("url", String, "pull request URL"),
+		col("state", String, "open, closed or merged"),
+		col("created_at", DateTime, "creation time"),
+		opt("closed_at", DateTime, "closing time"),
+		opt("merged_at", DateTime, "merge time"),
+		col("creator", String, "login of the author"),
+		col("additions", Int, "added lines"),
+		col("deletions", Int, "deleted lines"),
+		col("changed_files", Int, "changed files"),
+		col("review_threads", Int, "review threads"),
+		col("review_comments", Int, "comments in the review threads"),
+		opt("threads_total", Int, "review threads opened before the merge (all threads when not merged); missing in older files"),
+		opt("threads_resolved", Int, "threads of threads_total resolved"),
+		opt("labels", String, "label names separated by ;, missing in older files"),
+	}},
+	{Name: "pr_review.csv", WrittenBy: "import", Description: "Reviews submitted on the pull requests.", Columns: []Column{
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("number", Int, "pull request number"),
+		col("state", String, "review state, e.g. APPROVED or CHANGES_REQUESTED"),
+		opt("submitted_at", DateTime, "submission time, empty for pending reviews"),
+		col("user", String, "login of the reviewer"),
+	}},
+	{Name: "pr_issue_link.csv", WrittenBy: "import", Description: "Issues each pull request closes (closing keywords or the development sidebar).", Columns: []Column{
+		col("org", String, "organization of the pull request"),
+		col("repo", String, "repository of the pull request"),
+		col("number", Int, "pull request number"),
+		col("issue_org", String, "organization of the issue"),
+		col("issue_repo", String, "repository of the issue"),
+		col("issue_number", Int, "issue number"),
+	}},
+	{Name: "import_meta.csv", WrittenBy: "import", Description: "GitHub API usage of each issues/PR import run, one row appended per run.", Columns: []Column{
+		col("started_at", DateTime, "start of the run"),
+		col("org", String, "organization"),
+		col("scopes", String, "scopes of the run separated by ;"),
+		col("duration_seconds", Float, "duration of the run"),
+		col("rest_calls", Int, "REST requests"),
+		col("graphql_calls", Int, "GraphQL requests"),
+		col("graphql_points", Int, "GraphQL rate limit points spent (from X-RateLimit-Used, other clients of the token included)"),
+		col("rate_limit_sleep_seconds", Float, "time spent waiting for rate limits"),
+		col("bytes_downloaded", Int, "response bytes read"),
+		col("errors", Int, "failed requests and GraphQL errors"),
+		col("retries", Int, "requests retried after a rate limit"),
+		opt("tool_version", String, "version of cto-stats that ran the import, with its commit and build date"),
+		opt("stopped", String, "why the run stopped before completion, its CSVs then left as they were: deadline (-deadline reached) or interrupted; empty for a complete run"),
+	}},
+	// import --cloudspending
+	{Name: "cloud_costs.csv", WrittenBy: "import", Description: "Monthly cloud costs per provider, service and account.", Columns: []Column{
+		col("provider", String, "azure or gcp"),
+		col("service", String, "service name"),
+		col("month", Date, "first day of the month"),
+		col("cost", Float, "cost"),
+		col("currency", String, "currency code"),
+		opt("dimension", String, "Azure subscription ID or GCP billing account"),
+		opt("environment", String, "environment of the Azure subscription or GCP project (cloud_spending.environments), empty when unmapped"),
+	}},
+	// provided by hand, read by calculate
+	{Name: "release.csv", WrittenBy: "manual", Description: "Deployments used by the change failure rate, one row per deployment. No command writes it: put it in data/ by hand, or export it from the CI/CD or the GitHub releases (see the README). Other columns are ignored.", Columns: []Column{
+		col("repo", String, "repository name, without the org, as in the repo column of issue.csv"),
+		col("published_at", DateTime, "deployment time, RFC3339 (e.g. 2025-03-04T15:00:00Z); a created_at column is accepted instead; rows without a valid time are skipped"),
+	}},
+
+	// calculate --issues
+	{Name: "calculated_issue.csv", WrittenBy: "calculate", Description: "One row per issue with its stage timestamps.", Columns: []Column{
+		col("id", String, "org/repo#number"),
+		col("org", String, "organization"),
+		col("name", String, "issue title"),
+		opt("project_id", String, "project id"),
+		opt("project_name", String, "project name"),
+		col("creationdatetime", DateTime, "creation time"),
+		opt("leadtimestartdatetime", DateTime, "lead time start"),
+		opt("cycletimestartdatetime", DateTime, "cycle time start"),
+		opt("putinreadystartdatetime", DateTime, "first move to a ready column"),
+		opt("devstartdatetime", DateTime, "development start"),
+		opt("reviewstartdatetime", DateTime, "review start"),
+		opt("qastartdatetime", DateTime, "QA start"),
+		opt("waitingtopodstartdateime", DateTime, "waiting to production start"),
+		opt("enddatetime", DateTime, "end (closed or in production)"),
+		col("bug", Bool, "whether the issue is a bug"),
+		col("bug_customer_facing", Bool, "bug whose source is customer facing"),
+		col("bug_internal", Bool, "bug whose source is internal"),
+		col("bug_dev_process", Bool, "bug whose source is the development process"),
+		opt("type", String, "issue type"),
+		opt("current_column", String, "board column of an open issue"),
+		opt("size_weight", Float, "size weight from issue.csv, 1 without a size label"),
+		opt("url", String, "GitHub page of the issue (issue.csv url)"),
+		opt("repo", String, "repository name"),
+		opt("estimate", Float, "estimate points from the estimate_field of its project, empty when unestimated"),
+		opt("committeddatetime", DateTime, "first move to a committed_columns column of its project"),
+		opt("committed_to_done", Float, "days from committeddatetime to enddatetime, empty when either is missing"),
+	}},
+	{Name: "cycle_time.csv", WrittenBy: "calculate", Description: "Lead and cycle time averages per closing month, per org plus ALL.", Columns: []Column{
+		col("month", Month, "closing month"),
+		col("org", String, "organization, or ALL"),
+		col("issues_count", Int, "closed issues"),
+		col("leadtime_days_avg", Float, "average lead time in days"),
+		col("lead_count", Int, "issues with a lead time"),
+		col("cycletime_days_avg", Float, "average cycle time in days"),
+		col("cycle_count", Int, "issues with a cycle time"),
+		col("time_to_pr", Float, "average days from development start to review start"),
+		opt("leadtime_target_days", Float, "lead time target (config targets)"),
+		opt("cycletime_target_days", Float, "cycle time target (config targets)"),
+		opt("weighted_leadtime_days_avg", Float, "lead time average weighted by the issue size weights"),
+		opt("weighted_cycletime_days_avg", Float, "cycle time average weighted by the issue size weights"),
+		opt("unit", String, "unit of the durations (durations.unit): days, or hours despite the column names"),
+		opt("time_to_pr_actual", Float, "average days from development start to the creation of the earliest linked pull request; empty without linked PRs"),
+		opt("time_to_pr_source", String, "time to PR shown by the dashboards (time_to_pr.source): board (time_to_pr) or linked_pr (time_to_pr_actual)"),
+	}},
+	{Name: "committed_to_done_month.csv", WrittenBy: "calculate", Description: "Committed-to-done delivery times per closing month, per org plus ALL, for the projects with committed_columns.", Columns: []Column{
+		col("month", Month, "closing month"),
+		col("org", String, "organization, or ALL"),
+		col("issues_count", Int, "closed issues with a commitment"),
+		col("committed_to_done_days_avg", Float, "average time from the commitment to the end"),
+		col("committed_to_done_days_p50", Float, "median, empty below min_sample_size"),
+		col("committed_to_done_days_p85", Float, "85th percentile, empty below min_sample_size"),
+		col("unit", String, "unit of the durations (durations.unit)"),
+		opt("low_confidence", Bool, "fewer issues than min_sample_size"),
+	}},
+	{Name: "cycle_time_repo.csv", WrittenBy: "calculate", Description: "Lead and cycle time averages per closing month, org and repo; repos below repo_breakdown.min_issues closed issues in the month are grouped under other.", Columns: []Column{
+		col("month", Month, "closing month"),
+		col("org", String, "organization"),
+		col("repo", String, "repository name, or other"),
+		col("issues_count", Int, "closed issues"),
+		col("leadtime_days_avg", Float, "average lead time in days"),
+		col("lead_count", Int, "issues with a lead time"),
+		col("cycletime_days_avg", Float, "average cycle time in days"),
+		col("cycletime_days_p85", Float, "p85 cycle time in days, empty below min_sample_size cycle times"),
+		col("cycle_count", Int, "issues with a cycle time"),
+		col("unit", String, "unit of the durations (durations.unit): days, or hours despite the column names"),
+		opt("low_confidence", Bool, "fewer cycle times than min_sample_size"),
+	}},
+	{Name: "cycle_time_by_repo.csv", WrittenBy: "calculate", Description: "Cycle time average and p85 and throughput per closing month, org and repo, every repo on its own.", Columns: []Column{
+		col("month", Month, "closing month"),
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("cycletime_days_avg", Float, "average cycle time in days, empty without a cycle time"),
+		col("cycletime_days_p85", Float, "p85 cycle time in days, empty below min_sample_size cycle times"),
+		col("cycle_count", Int, "issues with a cycle time"),
+		col("throughput", Int, "closed issues"),
+		col("unit", String, "unit of the durations (durations.unit): days, or hours despite the column names"),
+		col("low_confidence", Bool, "fewer cycle times than min_sample_size"),
+	}},
+	{Name: "repo_ranking.csv", WrittenBy: "calculate", Description: "Repos ranked on the latest month of closed issues by cycle time and throughput; repos with fewer than repo_breakdown.ranking_min_issues closed issues that month are left out.", Columns: []Column{
+		col("month", Month, "latest closing month"),
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("issues_count", Int, "closed issues (throughput)"),
+		col("cycletime_days_avg", Float, "average cycle time in days, empty without a cycle time"),
+		col("cycletime_days_p85", Float, "p85 cycle time in days, empty without a cycle time"),
+		col("cycle_rank", Int, "rank by average cycle time, 1 = fastest; equal values share a rank, empty without a cycle time"),
+		col("throughput_rank", Int, "rank by closed issues, 1 = most; equal values share a rank"),
+		col("unit", String, "unit of the durations (durations.unit): days, or hours despite the column names"),
+	}},
+	{Name: "cycle_scatter.csv", WrittenBy: "calculate", Description: "One dot per closed issue for the cycle time scatterplot.", Columns: []Column{
+		col("end_date", Date, "end date"),
+		col("cycle_days", Float, "cycle time in days"),
+		opt("lead_days", Float, "lead time in days"),
+		opt("project", String, "project name"),
+		opt("type", String, "issue type"),
+		col("bug", Bool, "whether the issue is a bug"),
+		col("id", String, "org/repo#number"),
+		col("name", String, "issue title"),
+		col("outlier", Bool, "above the p95 of the trailing 13 weeks"),
+		opt("unit", String, "unit of cycle_days and lead_days (durations.unit): days or hours"),
+		opt("url", String, "GitHub page of the issue"),
+	}},
+	{Name: "finished_detail_week.csv", WrittenBy: "calculate", Description: "Closed issues in the ISO week of their end, the issues behind throughput_week.csv.", Columns: concat(yearWeek, []Column{
+		col("org", String, "organization"),
+		col("id", String, "org/repo#number"),
+		col("title", String, "issue title"),
+		opt("type", String, "issue type"),
+		opt("project_id", String, "project id"),
+		opt("project_name", String, "project name"),
+		opt("cycle_days", Float, "cycle time in days, empty without a cycle time start or with a clock anomaly"),
+		opt("unit", String, "unit of the durations (durations.unit): days, or hours despite the column names"),
+		opt("url", String, "GitHub page of the issue"),
+	})},
+	{Name: "throughput_week.csv", WrittenBy: "calculate", Description: "Closed issues per ISO week with control limits, per org plus ALL.", Columns: concat(yearWeek, []Column{
+		col("org", String, "organization, or ALL"),
+		col("throughput", Int, "issues closed in the week"),
+		col("center", Float, "throughput of the week"),
+		col("ucl", Float, "upper control limit, empty when computed on fewer weeks than min_sample_size"),
+		col("lcl", Float, "lower control limit, empty when computed on fewer weeks than min_sample_size"),
+		opt("rolling_avg_4w", Float, "mean throughput of the last 4 weeks"),
+		opt("trend_slope_12w", Float, "least-squares slope of the last 12 weeks"),
+		opt("throughput_target", Float, "throughput target per week (config targets)"),
+		opt("throughput_cv", Float, "coefficient of variation of the throughput over the control limits window"),
+		opt("variability", String, "low (cv < 0.3), medium (cv < 0.6) or high"),
+		opt("low_confidence", Bool, "control limits computed on fewer weeks than min_sample_size"),
+		opt("bug_ratio", Float, "share of bugs among the issues closed in the week, empty without any"),
+		opt("bug_ratio_target", Float, "bug ratio target (config targets)"),
+	})},
+	{Name: "throughput_week_repo.csv", WrittenBy: "calculate", Description: "Closed issues per ISO week, org and repo, weeks with closed issues only; repos below repo_breakdown.min_issues closed issues in the month of the week are grouped under other.", Columns: concat(yearWeek, []Column{
+		col("org", String, "organization"),
+		col("repo", String, "repository name, or other"),
+		col("throughput", Int, "issues closed in the week"),
+	})},
+	{Name: "velocity_week.csv", WrittenBy: "calculate", Description: "Estimate points of the issues closed per ISO week and project, for the projects with an estimate_field; weeks with closed issues only.", Columns: concat(yearWeek, []Column{
+		col("project_id", String, "project id"),
+		col("project_name", String, "project title"),
+		col("points", Float, "sum of the estimates of the issues closed in the week"),
+		col("estimated_count", Int, "closed issues with an estimate"),
+		col("unestimated_count", Int, "closed issues without an estimate, or with a non-numeric one"),
+	})},
+	{Name: "cycle_time_quarter.csv", WrittenBy: "calculate", Description: "Lead and cycle times per fiscal quarter of the closing date, per org plus ALL.", Columns: []Column{
+		col("quarter", Quarter, "fiscal quarter"),
+		col("org", String, "organization, or ALL"),
+		col("issues_count", Int, "closed issues"),
+		col("leadtime_days_avg", Float, "average lead time in days"),
+		col("lead_count", Int, "issues with a lead time"),
+		col("cycletime_days_avg", Float, "average cycle time in days"),
+		col("cycle_count", Int, "issues with a cycle time"),
+		col("cycletime_p50_days", Float, "median cycle time in days, empty below min_sample_size cycle times"),
+		col("cycletime_p85_days", Float, "p85 cycle time in days, empty below min_sample_size cycle times"),
+		col("cycletime_p95_days", Float, "p95 cycle time in days, empty below min_sample_size cycle times"),
+		col("time_to_pr", Float, "average days from development start to review start"),
+		opt("unit", String, "unit of the durations (durations.unit): days, or hours despite the column names"),
+		opt("low_confidence", Bool, "fewer cycle times than min_sample_size"),
+	}},
+	{Name: "cycle_percentile_trend.csv", WrittenBy: "calculate", Description: "Cycle time percentiles per closing month and their change since the previous month, per org plus ALL.", Columns: []Column{
+		col("month", Month, "closing month"),
+		col("org", String, "organization, or ALL"),
+		col("cycle_count", Int, "closed issues with a cycle time"),
+		col("cycletime_p50_days", Float, "median cycle time, empty below min_sample_size cycle times"),
+		col("cycletime_p85_days", Float, "p85 cycle time, empty below min_sample_size cycle times"),
+		col("cycletime_p95_days", Float, "p95 cycle time, empty below min_sample_size cycle times"),
+		col("p50_delta", Float, "change of the p50 since the previous calendar month, empty when either has no p50"),
+		col("p85_delta", Float, "change of the p85 since the previous calendar month, empty when either has no p85"),
+		col("p95_delta", Float, "change of the p95 since the previous calendar month, empty when either has no p95"),
+		col("p85_rising_months", Int, "consecutive months, up to this one, the p85 increased"),
+		col("drift_alert", Bool, "true when the p85 increased 3 months in a row"),
+		opt("unit", String, "unit of the durations (durations.unit): days, or hours despite the column names"),
+		opt("low_confidence", Bool, "fewer cycle times than min_sample_size"),
+	}},
+	{Name: "throughput_quarter.csv", WrittenBy: "calculate", Description: "Closed issues per fiscal quarter, per org plus ALL.", Columns: []Column{
+		col("quarter", Quarter, "fiscal quarter"),
+		col("org", String, "organization, or ALL"),
+		col("throughput", Int, "issues closed in the quarter"),
+	}},
+	{Name: "stocks.csv", WrittenBy: "calculate", Description: "Current open issues per stage, per org and project.", Columns: stocksColumns},
+	{Name: "stocks_history.csv", WrittenBy: "calculate", Description: "stocks.csv as calculated on each run day.", Columns: concat([]Column{
+		col("snapshot_date", Date, "run date"),
+	}, stocksColumns)},
+	{Name: "stocks_detail.csv", WrittenBy: "calculate", Description: "Open issues with their stage and board column.", Columns: []Column{
+		col("id", String, "org/repo#number"),
+		col("name", String, "issue title"),
+		opt("project_id", String, "project id"),
+		opt("project_name", String, "project name"),
+		col("stage", String, "stocks.csv column the issue is counted in"),
+		opt("current_column", String, "board column"),
+		opt("url", String, "GitHub page of the issue"),
+	}},
+	{Name: "epic_progress.csv", WrittenBy: "calculate", Description: "Progress of each epic of github.epic_label_prefix.", Columns: []Column{
+		col("epic", String, "epic name, the label without its prefix"),
+		col("issues", Int, "issues of the epic"),
+		col("closed", Int, "of which closed"),
+		col("open", Int, "of which not closed"),
+		col("in_backlogs", Int, "open issues in the backlog"),
+		col("in_ready", Int, "open issues ready"),
+		col("in_dev", Int, "open issues in development"),
+		col("in_review", Int, "open issues in review"),
+		col("in_qa", Int, "open issues in QA"),
+		col("waiting_to_prod", Int, "open issues waiting for production"),
+		opt("first_start", DateTime, "earliest cycle time start of its issues, empty when none started"),
+		opt("last_end", DateTime, "latest end of its closed issues, empty when none is closed"),
+		col("pct_complete", Float, "closed issues in percent of its issues"),
+	}},
+	{Name: "wip_per_person.csv", WrittenBy: "calculate", Description: "Open issues in development, review or QA per assignee, against wip.personal_limit (not written when privacy.disable_individual_metrics is set).", Columns: []Column{
+		col("login", String, "assignee, or (unassigned) for in-progress issues without one"),
+		col("wip", Int, "in-progress issues assigned; an issue with two assignees counts for both"),
+		col("in_dev", Int, "of which in development"),
+		col("in_review", Int, "of which in review"),
+		col("in_qa", Int, "of which in QA"),
+		col("oldest_issue_id", String, "in-progress issue that started first"),
+		col("oldest_age_days", Float, "days since the oldest issue started development (or its first later stage)"),
+		opt("personal_limit", Int, "wip.personal_limit, empty without one and on the unassigned row"),
+		col("over_limit", Bool, "wip above personal_limit"),
+	}},
+	{Name: "stocks_week.csv", WrittenBy: "calculate", Description: "Stocks at the end of each ISO week, per org and project.", Columns: concat(yearWeek, stocksColumns, []Column{
+		col("created_in_week", Int, "issues created in the week"),
+		col("closed_in_week", Int, "issues closed in the week"),
+	})},
+	{Name: "bug_stock_week.csv", WrittenBy: "calculate", Description: "Open bugs at the end of each ISO week per org and severity label.", Columns: concat(yearWeek, []Column{
+		col("org", String, "organization"),
+		col("severity", String, "severity label (github.severity_labels), or unclassified"),
+		col("open_bugs", Int, "open bugs of that severity at the end of the week"),
+	})},
+	{Name: "littles_law_month.csv", WrittenBy: "calculate", Description: "Measured versus Little's Law predicted cycle time, per month and project plus ALL.", Columns: []Column{
+		col("month", Month, "month"),
+		col("project_id", String, "project id, or ALL"),
+		col("project_name", String, "project name"),
+		col("weeks", Int, "ISO weeks in the month"),
+		col("avg_wip", Float, "average weekly work in progress"),
+		col("throughput_per_week", Float, "issues closed per week"),
+		opt("measured_cycle_weeks", Float, "average measured cycle time in weeks"),
+		opt("predicted_cycle_weeks", Float, "avg_wip / throughput_per_week"),
+		opt("measured_to_predicted_ratio", Float, "measured / predicted"),
+	}},
+	{Name: "backlog_age_histogram.csv", WrittenBy: "calculate", Description: "Open issues by age bucket, per project plus ALL.", Columns: []Column{
+		col("project_id", String, "project id, or ALL"),
+		col("project_name", String, "project name"),
+		col("bucket", String, "age bucket label, or summary"),
+		opt("min_days", Int, "bucket lower bound in days"),
+		opt("max_days", Int, "bucket upper bound in days, empty for the last bucket"),
+		col("issue_count", Int, "open issues"),
+		opt("p50_age_days", Float, "median age in days (summary rows)"),
+		opt("p90_age_days", Float, "p90 age in days (summary rows)"),
+	}},
+	{Name: "stage_regressions.csv", WrittenBy: "calculate", Description: "Moves of an issue back to an earlier stage.", Columns: []Column{
+		col("issue_id", String, "org/repo#number"),
+		col("project_id", String, "project id"),
+		col("project_name", String, "project name"),
+		col("from_column", String, "column before the move"),
+		col("to_column", String, "earlier-stage column"),
+		col("at", DateTime, "move time"),
+	}},
+	{Name: "stage_regressions_month.csv", WrittenBy: "calculate", Description: "Stage regressions and regressed closed issues per month.", Columns: []Column{
+		col("month", Month, "month"),
+		col("regressions", Int, "regressions in the month"),
+		col("closed_issues", Int, "issues closed in the month"),
+		col("closed_with_regression", Int, "closed issues that went through a regression"),
+		col("regression_share", Float, "closed_with_regression / closed_issues"),
+	}},
+	{Name: "reopen_latency.csv", WrittenBy: "calculate", Description: "Each reopening of a closed issue, with the time since its close.", Columns: []Column{
+		col("issue_id", String, "org/repo#number"),
+		col("org", String, "organization"),
+		col("repo", String, "repository"),
+		col("closed_at", DateTime, "close before the reopening"),
+		col("reopened_at", DateTime, "reopening"),
+		col("latency_hours", Float, "hours from the close to the reopening"),
+	}},
+	{Name: "reopen_latency_month.csv", WrittenBy: "calculate", Description: "Reopenings and their latency percentiles per month, per org plus ALL.", Columns: []Column{
+		col("month", Month, "month of the reopenings"),
+		col("org", String, "organization, or ALL"),
+		col("reopens", Int, "reopenings in the month"),
+		col("latency_hours_p50", Float, "median hours from close to reopening, empty below min_sample_size"),
+		col("latency_hours_p90", Float, "90th percentile of the hours from close to reopening, empty below min_sample_size"),
+		opt("low_confidence", Bool, "true when reopens is below min_sample_size"),
+	}},
+	{Name: "burnup_week.csv", WrittenBy: "calculate", Description: "Cumulative created and closed issues per project at the end of each ISO week.", Columns: []Column{
+		col("year", Year, "ISO year"),
+		col("week", Week, "ISO week"),
+		opt("project_id", String, "project id, empty for issues on no board"),
+		opt("project_name", String, "project name"),
+		col("cumulative_created", Int, "issues created by the end of the week"),
+		col("cumulative_closed", Int, "of which closed by the end of the week"),
+		col("scope_change", Int, "issues created in the week"),
+	}},
+	{Name: "milestone_burndown.csv", WrittenBy: "calculate", Description: "Issues of each milestone at the end of each ISO week.", Columns: []Column{
+		col("milestone", String, "milestone title"),
+		opt("due_on", Date, "latest due date of the milestone"),
+		col("year", Year, "ISO year"),
+		col("week", Week, "ISO week"),
+		col("total", Int, "issues created so far"),
+		col("closed", Int, "issues closed so far"),
+		col("open", Int, "issues still open"),
+	}},
+	{Name: "unboarded_issues.csv", WrittenBy: "calculate", Description: "Issues that were never added to a project board.", Columns: []Column{
+		col("id", String, "org/repo#number"),
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("number", Int, "issue number"),
+		col("title", String, "issue title"),
+		col("state", String, "open or closed"),
+		col("created_at", DateTime, "creation time"),
+		opt("closed_at", DateTime, "closing time, empty while open"),
+		col("age_days", Float, "days from creation to closing, or to now while open"),
+	}},
+	{Name: "unboarded_issues_repo.csv", WrittenBy: "calculate", Description: "Issues never added to a project board, per repo plus ALL.", Columns: []Column{
+		col("repo", String, "repository name, or ALL"),
+		col("issues", Int, "issues of issue.csv"),
+		col("unboarded", Int, "issues without project event"),
+		col("unboarded_open", Int, "unboarded issues still open"),
+		col("unboarded_share", Float, "unboarded / issues"),
+	}},
+	{Name: "spec_quality_month.csv", WrittenBy: "calculate", Description: "Cycle time of closed issues with and without a description, and created issues lacking one, per month.", Columns: []Column{
+		col("month", Month, "month"),
+		col("closed_with_description", Int, "issues closed in the month with a description and a cycle time"),
+		col("closed_without_description", Int, "issues closed in the month without a description and with a cycle time"),
+		col("median_cycle_days_with_description", Float, "median cycle time in days of closed_with_description"),
+		col("median_cycle_days_without_description", Float, "median cycle time in days of closed_without_description"),
+		col("created_count", Int, "issues created in the month"),
+		col("created_without_description", Int, "issues of created_count without a description"),
+		col("without_description_share", Float, "created_without_description / created_count"),
+	}},
+	{Name: "anomalies.csv", WrittenBy: "calculate", Description: "Issues whose timestamps cannot be right, left out of the lead, cycle and time-to-PR durations.", Columns: []Column{
+		col("issue_id", String, "org/repo#number"),
+		col("project_id", String, "project id"),
+		col("rule", String, "stage_before_creation or end_before_start"),
+		col("stage", String, "stage whose timestamp is wrong, e.g. dev_start or end"),
+		col("at", DateTime, "timestamp of the stage"),
+		col("reference", String, "what it should follow: created, lead_time_start or cycle_time_start"),
+		col("reference_at", DateTime, "timestamp of the reference"),
+	}},
+	{Name: "data_quality.csv", WrittenBy: "calculate", Description: "Data quality findings of the issues scope, errors first.", Columns: []Column{
+		col("severity", String, "error (numbers of the issue are wrong) or warning (less precise, e.g. a legacy fallback was applied)"),
+		col("rule", String, "unknown_project, closed_without_board_history, negative_stage_gap, unparseable_timestamp, duplicate_row or duplicate_across_inputs"),
+		col("issue_id", String, "org/repo#number (of a pull request for duplicate_across_inputs in pr.csv)"),
+		col("detail", String, "what was found"),
+	}},
+	{Name: "close_age.csv", WrittenBy: "calculate", Description: "Created-to-closed age of closed issues per closing month, per repo plus ALL.", Columns: []Column{
+		col("month", Month, "closing month"),
+		col("repo", String, "repository name, or ALL"),
+		col("closed_count", Int, "closed issues"),
+		col("p50_days", Float, "median age at close in days"),
+		col("p85_days", Float, "p85 age at close in days"),
+		col("p95_days", Float, "p95 age at close in days"),
+	}},
+
+	// calculate --pr
+	{Name: "pr_change_requests_week.csv", WrittenBy: "calculate", Description: "Change requests per PR by ISO week of PR creation, per repo plus ALL.", Columns: concat(yearWeek, []Column{
+		col("repo", String, "repository name, or ALL"),
+		col("avg", Float, "average change requests per PR"),
+		col("median", Float, "median change requests per PR, empty below min_sample_size pull requests"),
+		col("p90", Float, "p90 change requests per PR, empty below min_sample_size pull requests"),
+		col("pr_count", Int, "pull requests"),
+		col("cr_total", Int, "change requests"),
+		opt("low_confidence", Bool, "fewer pull requests than min_sample_size"),
+	})},
+	{Name: "pr_change_requests_repo.csv", WrittenBy: "calculate", Description: "Change requests per PR, per repo.", Columns: []Column{
+		col("repo", String, "repository name"),
+		col("median", Float, "median change requests per PR, empty below min_sample_size pull requests"),
+		col("pr_count", Int, "pull requests"),
+		col("cr_total", Int, "change requests"),
+		opt("low_confidence", Bool, "fewer pull requests than min_sample_size"),
+	}},
+	{Name: "pr_change_requests_repo_dist.csv", WrittenBy: "calculate", Description: "Distribution of change requests per PR, per repo.", Columns: []Column{
+		col("repo", String, "repository name"),
+		col("cr", Int, "change requests on a PR"),
+		col("pr_count", Int, "pull requests with that many change requests"),
+	}},
+	{Name: "pr_merged_week.csv", WrittenBy: "calculate", Description: "Merged PRs per ISO week of merge, per repo plus ALL.", Columns: concat(yearWeek, []Column{
+		col("repo", String, "repository name, or ALL"),
+		col("merged_count", Int, "merged pull requests"),
+	})},
+	{Name: "pr_cycle_time_week.csv", WrittenBy: "calculate", Description: "Hours from PR creation to merge per ISO week of merge, per repo plus ALL.", Columns: concat(yearWeek, []Column{
+		col("repo", String, "repository name, or ALL"),
+		col("merged_count", Int, "merged pull requests"),
+		col("avg_hours", Float, "average hours to merge"),
+		col("median_hours", Float, "median hours to merge"),
+		col("p90_hours", Float, "p90 hours to merge"),
+		col("abandoned_count", Int, "pull requests closed without merge"),
+	})},
+	{Name: "pr_review_depth_week.csv", WrittenBy: "calculate", Description: "Review comments per 100 changed lines per ISO week of merge, per repo plus ALL.", Columns: concat(yearWeek, []Column{
+		col("repo", String, "repository name, or ALL"),
+		col("merged_count", Int, "merged pull requests"),
+		col("sized_count", Int, "merged pull requests large enough for the ratio"),
+		col("median_comments_per_100_lines", Float, "median review comments per 100 changed lines"),
+		col("zero_comment_share", Float, "share of merged pull requests without review comment"),
+	})},
+	{Name: "pr_approval_latency_week.csv", WrittenBy: "calculate", Description: "Hours from pull request creation to its first and Nth approval (pr.approvals_required), per ISO week of merge, per repo plus ALL.", Columns: concat(yearWeek, []Column{
+		col("repo", String, "repository name, or ALL"),
+		col("merged_count", Int, "merged pull requests"),
+		col("approvals_required", Int, "N, the approval count measured"),
+		col("first_approval_count", Int, "merged pull requests approved before merge"),
+		col("median_first_approval_hours", Float, "median hours from creation to the first approval"),
+		col("p90_first_approval_hours", Float, "90th percentile hours from creation to the first approval"),
+		col("nth_approval_count", Int, "merged pull requests with N approvals before merge"),
+		col("median_nth_approval_hours", Float, "median hours from creation to the Nth approval"),
+		col("p90_nth_approval_hours", Float, "90th percentile hours from creation to the Nth approval"),
+		col("merged_below_threshold", Int, "merged pull requests with fewer than N approvals before merge"),
+	})},
+	{Name: "pr_thread_resolution_week.csv", WrittenBy: "calculate", Description: "Review threads opened before merge and resolved, per ISO week of merge, per repo plus ALL.", Columns: concat(yearWeek, []Column{
+		col("repo", String, "repository name, or ALL"),
+		col("pr_count", Int, "merged pull requests with at least one review thread"),
+		col("threads_total", Int, "review threads opened before the merge"),
+		col("threads_resolved", Int, "threads of threads_total resolved"),
+		col("median_resolved_ratio", Float, "median of the per pull request resolved / total threads"),
+	})},
+
+	// calculate (issues and PRs)
+	{Name: "coding_time.csv", WrittenBy: "calculate", Description: "Per issue closed by a pull request, the time from the first linked PR creation to its merge against the dev-to-review stage time.", Columns: []Column{
+		col("issue_id", String, "org/repo#number"),
+		col("org", String, "organization"),
+		col("pr_id", String, "org/repo#number of the first linked pull request"),
+		col("linked_prs", Int, "pull requests linked to the issue"),
+		col("pr_created_at", DateTime, "creation time of the first linked pull request"),
+		opt("pr_merged_at", DateTime, "merge time of that pull request"),
+		opt("coding_days", Float, "days from pr_created_at to pr_merged_at"),
+		opt("dev_to_review_days", Float, "days from development start to review start (calculated_issue.csv)"),
+		opt("difference_days", Float, "coding_days - dev_to_review_days"),
+	}},
+	{Name: "contributors_month.csv", WrittenBy: "calculate", Description: "Distinct active people per month.", Columns: []Column{
+		col("month", Month, "month"),
+		col("pr_authors", Int, "pull request authors"),
+		col("reviewers", Int, "reviewers"),
+		col("issue_closers", Int, "people who closed an issue"),
+		col("active_contributors", Int, "people with any of these activities"),
+	}},
+	{Name: "contributors_month_repo.csv", WrittenBy: "calculate", Description: "Distinct active people per month, org and repo.", Columns: []Column{
+		col("month", Month, "month"),
+		col("org", String, "organization"),
+		col("repo", String, "repository name"),
+		col("pr_authors", Int, "pull request authors"),
+		col("reviewers", Int, "reviewers"),
+		col("issue_closers", Int, "people who closed an issue"),
+		col("active_contributors", Int, "people with any of these activities"),
+	}},
+	{Name: "leaderboard_month.csv", WrittenBy: "calculate", Description: "Activity per month and person (not written when privacy.disable_individual_metrics is set).", Columns: []Column{
+		col("month", Month, "month"),
+		col("login", String, "person"),
+		col("issues_closed", Int, "issues closed"),
+		col("prs_merged", Int, "pull requests merged"),
+		col("reviews_given", Int, "reviews submitted"),
+		col("total", Int, "sum of the activities"),
+	}},
+	{Name: "closed_by_author_month.csv", WrittenBy: "calculate", Description: "Issues closed per month and person, every month for every person (not written when privacy.disable_individual_metrics is set).", Columns: []Column{
+		col("month", Month, "month"),
+		col("login", String, "person who closed the issues (issue.csv committer)"),
+		col("issues_closed", Int, "issues closed that month, 0 included"),
+	}},
+	{Name: "change_failure_rate_month.csv", WrittenBy: "calculate", Description: "Deployments followed by a failure, per month and repo plus ALL.", Columns: []Column{
+		col("month", Month, "month"),
+		col("repo", String, "repository name, or ALL"),
+		col("deployments", Int, "deployments"),
+		col("failed_deployments", Int, "deployments followed by a failure"),
+		col("failure_rate", Float, "failed_deployments / deployments"),
+	}},
+
+	// calculate --cloudspending
+	{Name: "cloud_spending_monthly.csv", WrittenBy: "calculate", Description: "Costs per month and provider.", Columns: []Column{
+		col("month", Month, "month"),
+		col("provider", String, "azure or gcp"),
+		col("cost", Float, "cost"),
+		col("currency", String, "currency code"),
+	}},
+	{Name: "cloud_spending_by_environment.csv", WrittenBy: "calculate", Description: "Costs per month and environment (cloud_spending.environments).", Columns: []Column{
+		col("month", Month, "month"),
+		col("environment", String, "environment label, or unmapped"),
+		col("cost", Float, "cost"),
+		col("currency", String, "currency code"),
+	}},
+	{Name: "cloud_spending_yoy.csv", WrittenBy: "calculate", Description: "Costs per month against the same month one year earlier, per provider plus ALL; months without prior-year costs are skipped.", Columns: []Column{
+		col("month", Month, "month"),
+		col("provider", String, "azure or gcp, or ALL"),
+		col("currency", String, "currency code"),
+		col("cost", Float, "cost of the month"),
+		col("cost_prior_year", Float, "cost of the same month one year earlier"),
+		col("delta", Float, "cost - cost_prior_year"),
+		opt("change_pct", Float, "delta / cost_prior_year in percent"),
+	}},
+	{Name: "cloud_spending_services.csv", WrittenBy: "calculate", Description: "Costs per month, provider and service group (or service when no groups are configured).", Columns: []Column{
+		col("month", Month, "month"),
+		col("provider", String, "azure or gcp"),
+		col("group", String, "configured service group, or __other__ (include_other); the column is named service when no groups are configured"),
+		col("cost", Float, "cost"),
+		col("currency", String, "currency code"),
+		opt("share_pct", Float, "cost / provider-month total in percent, the total including the services left out"),
+	}},
+	{Name: "cloud_spending_compared.csv", WrittenBy: "calculate", Description: "Costs of the configured service comparisons per month.", Columns: []Column{
+		col("comparison", String, "comparison name"),
+		col("month", Month, "month"),
+		col("group", String, "group of the comparison"),
+		col("cost", Float, "cost"),
+		col("currency", String, "currency code"),
+	}},
+	{Name: "cloud_spending_quarter.csv", WrittenBy: "calculate", Description: "Costs per fiscal quarter and provider.", Columns: []Column{
+		col("quarter", Quarter, "fiscal quarter"),
+		col("provider", String, "azure or gcp"),
+		col("cost", Float, "cost"),
+		col("currency", String, "currency code"),
+	}},
+	{Name: "calculate_meta.csv", WrittenBy: "calculate", Description: "Builds that calculated the outputs of the directory, one row appended per run.", Columns: []Column{
+		col("calculated_at", DateTime, "end of the run"),
+		col("generator_version", String, "version of cto-stats that ran calculate, with its commit and build date"),
+		col("scopes", String, "scopes of the run separated by ;"),
+		col("import_version", String, "tool_version of the last import recorded in import_meta.csv, empty when unknown"),
+	}},
+	{Name: "compare.csv", WrittenBy: "compare", Description: "Differences between the calculate outputs of two data directories, written where compare -csv points.", Columns: []Column{
+		col("file", String, "compared file"),
+		col("key", String, "issue id, or key columns of the row as name=value pairs"),
+		opt("column", String, "changed column, blank when the row is present on one side only"),
+		opt("a", String, "value in the first directory, or present"),
+		opt("b", String, "value in the second directory, or present"),
+		opt("delta", Float, "b - a for numeric values"),
+	}},
+}
+
+// Lookup returns the schema of the file called name.
+func Lookup(name string) (File, bool) {
+	for _, f := range Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return File{}, false
+}
+
+// Headers returns the column names of the file called name, in order. It panics for a file missing from the
+// registry: every writer must be registered.
+func Headers(name string) []string {
+	f, ok := Lookup(name)
+	if !ok {
+		panic(fmt.Sprintf("schema: %s is not registered", name))
+	}
+	headers := make([]string, len(f.Columns))
+	for i, c := range f.Columns {
+		headers[i] = c.Name
+	}
+	return headers
+}
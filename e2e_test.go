@@ -0,0 +1,192 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	cmdcalculate "cto-stats/command/calculate"
+	cmdimport "cto-stats/command/import"
+	cmdweb "cto-stats/command/web"
+	cg "cto-stats/connectors/github"
+)
+
+// e2eNow is the reference time of the end-to-end calculate run, a Monday after the last change of the fixtures.
+const e2eNow = "2025-03-10T12:00:00Z"
+
+// e2eVolatile are the outputs holding the time or duration of the run, left out of the golden comparison.
+var e2eVolatile = []string{"import_meta.csv", "calculate_meta.csv"}
+
+// TestEndToEnd imports the synthetic acme org recorded under testdata/e2e/github, calculates the outputs and
+// serves them, comparing every CSV and a few API payloads with testdata/e2e/golden. Run with -update to rewrite
+// the goldens after an intended change.
+func TestEndToEnd(t *testing.T) {
+	golden, err := filepath.Abs(filepath.Join("testdata", "e2e", "golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setupEndToEnd(t)
+	if err := cmdimport.Run([]string{"-org", "acme"}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if err := cmdcalculate.Run([]string{"-now", e2eNow}); err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		entries, err := os.ReadDir("data")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			if e.IsDir() || filepath.Ext(e.Name()) != ".csv" || slices.Contains(e2eVolatile, e.Name()) {
+				continue
+			}
+			got = append(got, e.Name())
+			b, err := os.ReadFile(filepath.Join("data", e.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			compareGolden(t, filepath.Join(golden, "data", e.Name()), b)
+		}
+		if *update {
+			return
+		}
+		// an output no longer written, or written without a golden
+		entries, err = os.ReadDir(filepath.Join(golden, "data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []string
+		for _, e := range entries {
+			want = append(want, e.Name())
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("outputs %q, want %q", got, want)
+		}
+	})
+
+	t.Run("api", func(t *testing.T) {
+		srv := httptest.NewServer(cmdweb.NewServer("data", "", 0))
+		defer srv.Close()
+		tests := []struct {
+			path   string
+			golden string
+		}{
+			{"/api/throughput/week", "throughput_week.json"},
+			{"/api/cycle_times/summary", "cycle_times_summary.json"},
+			{"/api/repo_ranking", "repo_ranking.json"},
+			{"/api/stocks/week?format=csv", "stocks_week.csv"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.golden, func(t *testing.T) {
+				resp, err := http.Get(srv.URL + tt.path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				b, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("GET %s: %s\n%s", tt.path, resp.Status, b)
+				}
+				compareGolden(t, filepath.Join(golden, "api", tt.golden), b)
+			})
+		}
+	})
+}
+
+// setupEndToEnd runs the test in an empty working directory with a config for the acme org, against the GitHub
+// API replayed from testdata/e2e/github.
+func setupEndToEnd(t *testing.T) {
+	t.Helper()
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "e2e", "github"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := cg.Replay(fixtures)
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := rt.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer resp.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(gh.Close)
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("CONFIG_PATH", filepath.Join(dir, "config.yml"))
+	t.Setenv("GITHUB_TOKEN", "ghp_test")
+	t.Setenv("GITHUB_API_URL", gh.URL)
+	t.Setenv("NOTIFY_WEBHOOK_URL", "")
+	config := "github:\n  org: acme\n  bug_labels: [bug]\n  severity_labels: [sev1, sev2]\nrepo_breakdown:\n  ranking_min_issues: 1\n"
+	if err := os.WriteFile("config.yml", []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBOMPerCommand runs import and calculate in one process: the -bom of one command must not carry over to the
+// next one.
+func TestBOMPerCommand(t *testing.T) {
+	setupEndToEnd(t)
+	hasBOM := func(name string) bool {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join("data", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.HasPrefix(string(b), "\ufeff")
+	}
+	if err := cmdimport.Run([]string{"-org", "acme", "-bom"}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if err := cmdcalculate.Run([]string{"-now", e2eNow}); err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	if !hasBOM("issue.csv") || hasBOM("cycle_time.csv") {
+		t.Errorf("BOM in issue.csv %v, in cycle_time.csv %v, want it in the files of import -bom only", hasBOM("issue.csv"), hasBOM("cycle_time.csv"))
+	}
+	if err := cmdcalculate.Run([]string{"-now", e2eNow, "-bom"}); err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	if err := cmdimport.Run([]string{"-org", "acme"}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if hasBOM("issue.csv") || !hasBOM("cycle_time.csv") {
+		t.Errorf("BOM in issue.csv %v, in cycle_time.csv %v, want it in the files of calculate -bom only", hasBOM("issue.csv"), hasBOM("cycle_time.csv"))
+	}
+}
+
+// compareGolden compares got with the golden file at path, rewriting it instead with -update.
+func compareGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to write it)", err)
+	}
+	if g, w := strings.ReplaceAll(string(got), "\r\n", "\n"), strings.ReplaceAll(string(want), "\r\n", "\n"); g != w {
+		t.Errorf("%s differs from the golden file:\ngot:\n%s\nwant:\n%s", filepath.Base(path), g, w)
+	}
+}
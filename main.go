@@ -2,9 +2,17 @@ package main
 
 import (
 	cmdcalculate "cto-stats/command/calculate"
+	"cto-stats/command/cli"
+	cmdcompare "cto-stats/command/compare"
+	cmddoctor "cto-stats/command/doctor"
 	cmdimport "cto-stats/command/import"
+	cmdreport "cto-stats/command/report"
+	cmdschema "cto-stats/command/schema"
+	cmdversion "cto-stats/command/version"
 	cmdweb "cto-stats/command/web"
 	gh "cto-stats/domain/github"
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
@@ -39,37 +47,74 @@ type IssueReport = gh.IssueReport
 
 type CurrentProject = gh.CurrentProject
 
+// commands are the subcommands listed by the usage, in that order.
+func commands() []cli.Command {
+	cmds := []cli.Command{cmdimport.Help, cmdcalculate.Help, cmdweb.Help, cmdcompare.Help, cmdreport.Help, cmddoctor.Help, cmdschema.Help, cmdversion.Help}
+	runs := []func([]string) error{cmdimport.Run, cmdcalculate.Run, cmdweb.Run, cmdcompare.Run, cmdreport.Run, cmddoctor.Run, cmdschema.Run, cmdversion.Run}
+	for i := range cmds {
+		cmds[i].Run = runs[i]
+	}
+	return cmds
+}
+
 func main() {
 	args := os.Args
 	// Initialize slog logger (text to stderr, DEBUG level for now)
 	h := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo})
 	slog.SetDefault(slog.New(h))
 
-	if len(args) > 1 {
-		sub := args[1]
-		rest := append([]string{}, args[2:]...)
-		switch sub {
-		case "import":
-			if err := cmdimport.Run(rest); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				os.Exit(1)
-			}
-			return
-		case "calculate":
-			if err := cmdcalculate.Run(rest); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				os.Exit(1)
-			}
-			return
-		case "web":
-			if err := cmdweb.Run(rest); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				os.Exit(1)
-			}
-			return
+	cmds := commands()
+	if len(args) < 2 {
+		cli.PrintUsage(os.Stderr, cmds)
+		os.Exit(2)
+	}
+	sub := args[1]
+	rest := append([]string{}, args[2:]...)
+	switch sub {
+	case "-h", "-help", "--help", "help":
+		cli.PrintUsage(os.Stdout, cmds)
+		return
+	case "-version", "--version":
+		sub = "version"
+	case "completion":
+		// hidden: prints the completion script of a shell
+		if len(rest) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: cto-stats completion bash|zsh|fish")
+			os.Exit(2)
+		}
+		if err := cli.WriteCompletion(os.Stdout, rest[0], cmds); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
 		}
+		return
+	}
+	for _, c := range cmds {
+		if c.Name != sub {
+			continue
+		}
+		err := c.Run(rest)
+		var usageErr *cli.UsageError
+		var exitErr *cli.ExitError
+		switch {
+		case err == nil:
+		case errors.Is(err, flag.ErrHelp):
+		case errors.As(err, &usageErr):
+			os.Exit(2)
+		case errors.As(err, &exitErr):
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitErr.Code)
+		default:
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if s := cli.Suggest(sub, cmds); s != "" {
+		fmt.Fprintf(os.Stderr, "cto-stats: unknown command %q, did you mean %q?\n\n", sub, s)
+	} else {
+		fmt.Fprintf(os.Stderr, "cto-stats: unknown command %q\n\n", sub)
 	}
-	fmt.Fprintln(os.Stderr, "usage: github-stats import -org <org> [-since <ts>] [-repo <list>] | calculate | web [-addr :8080] [-data ./data]\nENV: set CONFIG_PATH to point to a YAML config file (default ./config.yml)")
+	cli.PrintUsage(os.Stderr, cmds)
 	os.Exit(2)
 }
 
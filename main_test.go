@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs main instead of the tests when CTO_STATS_RUN_MAIN is set, so tests can check exit codes by
+// running the test binary itself as the cto-stats command.
+func TestMain(m *testing.M) {
+	if os.Getenv("CTO_STATS_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// update rewrites the golden files of the tests with their current output.
+var update = flag.Bool("update", false, "update the golden files")
+
+// runMain runs cto-stats with args in dir and returns its exit code, stdout and stderr.
+func runMain(t *testing.T, dir string, env []string, args ...string) (int, string, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), append([]string{"CTO_STATS_RUN_MAIN=1"}, env...)...)
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), stdout.String(), stderr.String()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0, stdout.String(), stderr.String()
+}
+
+func TestImportAuthFailureExitCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message":"Bad credentials"}`))
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	code, _, stderr := runMain(t, dir, []string{
+		"CONFIG_PATH=" + filepath.Join(dir, "config.yml"),
+		"GITHUB_TOKEN=ghp_test",
+		"GITHUB_API_URL=" + srv.URL,
+		"NOTIFY_WEBHOOK_URL=",
+	}, "import", "-org", "o", "-issues")
+	if code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	if !strings.Contains(stderr, "GitHub rejected the token") || !strings.Contains(stderr, "authentication failed") {
+		t.Errorf("stderr does not explain the failure:\n%s", stderr)
+	}
+}
+
+func TestHelpOutput(t *testing.T) {
+	tests := []struct {
+		golden   string
+		args     []string
+		wantCode int
+		stderr   bool // the output is on stderr rather than stdout
+	}{
+		{"usage", []string{"help"}, 0, false},
+		{"no_command", nil, 2, true},
+		{"unknown_command", []string{"improt"}, 2, true},
+		{"import", []string{"import", "-h"}, 0, true},
+		{"calculate", []string{"calculate", "-help"}, 0, true},
+		{"web", []string{"web", "-h"}, 0, true},
+		{"compare", []string{"compare", "-h"}, 0, true},
+		{"report", []string{"report", "-h"}, 0, true},
+		{"doctor", []string{"doctor", "-h"}, 0, true},
+		{"schema", []string{"schema", "-h"}, 0, true},
+		{"version", []string{"version", "-h"}, 0, true},
+		{"flag_error", []string{"calculate", "-no-such-flag"}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			code, stdout, stderr := runMain(t, t.TempDir(), []string{"CONFIG_PATH=", "NO_COLOR=1"}, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code %d, want %d", code, tt.wantCode)
+			}
+			got := stdout
+			if tt.stderr {
+				got = stderr
+			}
+			path := filepath.Join("testdata", "help", tt.golden+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("output differs from %s (go test -update to rewrite it):\n%s", path, got)
+			}
+		})
+	}
+}
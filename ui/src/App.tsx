@@ -1,5 +1,5 @@
 import React, { useEffect, useMemo, useRef, useState } from 'react'
-import { useCycleTimes, useStocks, useStocksWeek, useThroughputWeek, usePRChangeRequestsWeek, useCloudSpendingMonthly, useCloudSpendingServices, useCloudSpendingCompared } from './api'
+import { useCycleTimes, useStocks, useStocksWeek, useThroughputWeek, usePRChangeRequestsWeek, useCloudSpendingMonthly, useCloudSpendingServices, useCloudSpendingCompared, useHealth } from './api'
 import { Card, CardContent, CardHeader, CardTitle } from './components/ui/card'
 import { Sparkline } from './components/Sparkline'
 import { LineChart, Point } from './components/LineChart'
@@ -20,12 +20,31 @@ function BigNumber({ label, value, unit }: { label: string; value: number | null
   )
 }
 
+// HealthBanner warns when the latest calculate found data quality errors or when the data is older than the
+// freshness threshold of the server (/api/health). Nothing is shown while the data is fine or unknown.
+function HealthBanner() {
+  const { t } = useTranslation()
+  const { data } = useHealth()
+  if (!data || data.status !== 'warning') return null
+  return (
+    <div className="rounded border border-amber-300 bg-amber-50 px-4 py-3 text-sm text-amber-900 space-y-1">
+      {data.data_quality_errors > 0 ? (
+        <div>{t('health.dataQualityErrors', { count: data.data_quality_errors })}</div>
+      ) : null}
+      {data.stale && data.age_hours != null ? (
+        <div>{t('health.stale', { hours: Math.round(data.age_hours), threshold: Math.round(data.stale_after_hours) })}</div>
+      ) : null}
+    </div>
+  )
+}
+
 export default function App() {
   const { t } = useTranslation()
   const [activeTab, setActiveTab] = useState<'general' | 'dev' | 'cloudspending'>('general')
   return (
     <div className="min-h-full p-6 space-y-8">
       <h1 className="text-2xl font-semibold tracking-tight">{t('common.appTitle')}</h1>
+      <HealthBanner />
       <div className="border-b mb-4">
         <div className="flex gap-4">
           <button
@@ -90,8 +109,11 @@ function LeadCycleBlock() {
     cycle: parseNumber(
       r['cycletime_days_avg'] ?? r['cycle_time'] ?? r['cycle'] ?? r['cycletime']
     ),
+    // time_to_pr_source (config time_to_pr.source) selects the board or the linked PR definition
     timeToPR: parseNumber(
-      r['time_to_pr'] ?? r['time_to_pr_days'] ?? r['timeto_pr'] ?? r['time_to_pr_avg']
+      r['time_to_pr_source'] === 'linked_pr'
+        ? r['time_to_pr_actual']
+        : r['time_to_pr'] ?? r['time_to_pr_days'] ?? r['timeto_pr'] ?? r['time_to_pr_avg']
     ),
   }))
   const leadSeries = points.map((p) => p.lead ?? 0)
@@ -100,40 +122,42 @@ function LeadCycleBlock() {
   const leadLast = points.length ? points[points.length - 1].lead : null
   const cycleLast = points.length ? points[points.length - 1].cycle : null
   const tprLast = points.length ? points[points.length - 1].timeToPR : null
+  // calculate writes the durations in days unless durations.unit says hours
+  const unit = data?.length && data[data.length - 1]['unit'] === 'hours' ? t('units.hours') : t('units.days')
   return (
     <section>
       <h2 className="text-xl font-semibold mb-3">{t('leadCycle.sectionTitle')}</h2>
       <div className="grid grid-cols-1 md:grid-cols-3 gap-4">
         <Card>
           <CardHeader>
-            <CardTitle>{t('leadCycle.leadCardTitle')}</CardTitle>
+            <CardTitle>{t('leadCycle.leadCardTitle', { unit: unit.toLowerCase() })}</CardTitle>
           </CardHeader>
           <CardContent>
             <div className="flex items-end justify-between">
               <Sparkline data={leadSeries} width={300} />
-              <BigNumber label={t('common.current')} value={leadLast} unit={t('units.days')} />
+              <BigNumber label={t('common.current')} value={leadLast} unit={unit} />
             </div>
           </CardContent>
         </Card>
         <Card>
           <CardHeader>
-            <CardTitle>{t('leadCycle.cycleCardTitle')}</CardTitle>
+            <CardTitle>{t('leadCycle.cycleCardTitle', { unit: unit.toLowerCase() })}</CardTitle>
           </CardHeader>
           <CardContent>
             <div className="flex items-end justify-between">
               <Sparkline data={cycleSeries} width={300} />
-              <BigNumber label={t('common.current')} value={cycleLast} unit={t('units.days')} />
+              <BigNumber label={t('common.current')} value={cycleLast} unit={unit} />
             </div>
           </CardContent>
         </Card>
         <Card>
           <CardHeader>
-            <CardTitle>{t('leadCycle.timeToPRCardTitle')}</CardTitle>
+            <CardTitle>{t('leadCycle.timeToPRCardTitle', { unit: unit.toLowerCase() })}</CardTitle>
           </CardHeader>
           <CardContent>
             <div className="flex items-end justify-between">
               <Sparkline data={tprSeries} width={300} />
-              <BigNumber label={t('common.current')} value={tprLast} unit={t('units.days')} />
+              <BigNumber label={t('common.current')} value={tprLast} unit={unit} />
             </div>
           </CardContent>
         </Card>
@@ -11,7 +11,7 @@ async function fetchJSON<T>(url: string): Promise<T> {
 export function useCycleTimes() {
   return useQuery<Row[]>({
     queryKey: ['cycle_times'],
-    queryFn: () => fetchJSON('/api/cycle_times'),
+    queryFn: () => fetchJSON('/api/cycle_times?org=ALL'),
   })
 }
 
@@ -33,7 +33,7 @@ export function useStocks() {
 export function useThroughputWeek() {
   return useQuery<Row[]>({
     queryKey: ['throughput_week'],
-    queryFn: () => fetchJSON('/api/throughput/week'),
+    queryFn: () => fetchJSON('/api/throughput/week?org=ALL'),
   })
 }
 
@@ -78,3 +78,21 @@ export function useCloudSpendingCompared() {
     queryFn: () => fetchJSON('/api/cloud_spending/compared'),
   })
 }
+
+export type Health = {
+  status: 'ok' | 'warning'
+  data_quality_errors: number
+  data_quality_warnings: number
+  calculated_at: string | null
+  imported_at: string | null
+  age_hours: number | null
+  stale_after_hours: number
+  stale: boolean
+}
+
+export function useHealth() {
+  return useQuery<Health>({
+    queryKey: ['health'],
+    queryFn: () => fetchJSON('/api/health'),
+  })
+}